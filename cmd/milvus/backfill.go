@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"diabetes-agent-backend/dao"
+	"diabetes-agent-backend/model"
+	"diabetes-agent-backend/service/knowledge-base/vectorstore"
+	"flag"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/milvus-io/milvus/client/v2/column"
+	"github.com/milvus-io/milvus/client/v2/entity"
+	"github.com/milvus-io/milvus/client/v2/milvusclient"
+)
+
+// runBackfill 为引入文档 ID 之前上传的知识文件回填文档 ID：
+// 先为数据库中的旧记录生成文档 ID，再将旧切片按 (user_email, title) 对应到记录，写入 document_id 和 knowledge_id。
+// 旧版本中同一用户的文件名唯一，可以据此确定切片所属的文件。命令可以重复执行
+func runBackfill(ctx context.Context, client *milvusclient.Client, args []string) error {
+	fs := flag.NewFlagSet("backfill", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "only report how many records and chunks need backfilling")
+	fs.Parse(args)
+
+	if err := dao.Init(); err != nil {
+		return err
+	}
+	if err := backfillMetadata(*dryRun); err != nil {
+		return err
+	}
+
+	coll, err := client.DescribeCollection(ctx, milvusclient.NewDescribeCollectionOption(vectorstore.CollectionName))
+	if err != nil {
+		return fmt.Errorf("failed to describe collection: %v", err)
+	}
+
+	updated, orphaned, err := backfillChunks(ctx, client, coll, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Chunks backfilled",
+		"collection", coll.Name,
+		"updated", updated,
+		"orphaned", orphaned,
+		"dry_run", *dryRun,
+	)
+	return nil
+}
+
+// backfillMetadata 为文档 ID 为空的记录逐条生成文档 ID
+func backfillMetadata(dryRun bool) error {
+	legacy, err := dao.GetKnowledgeMetadataWithoutDocumentID()
+	if err != nil {
+		return fmt.Errorf("failed to get knowledge metadata without document id: %v", err)
+	}
+
+	if !dryRun {
+		for _, item := range legacy {
+			if err := dao.BackfillKnowledgeDocumentID(item.ID, uuid.New().String()); err != nil {
+				return fmt.Errorf("failed to backfill document id of knowledge %d: %v", item.ID, err)
+			}
+		}
+	}
+
+	slog.Info("Knowledge metadata backfilled", "records", len(legacy), "dry_run", dryRun)
+	return nil
+}
+
+// backfillChunks 按主键顺序分批读取 document_id 为空的切片，写入补全后的切片并删除原切片。
+// 找不到对应记录的切片保持不变，返回更新和无法对应的切片数量
+func backfillChunks(ctx context.Context, client *milvusclient.Client, coll *entity.Collection, dryRun bool) (int, int, error) {
+	outputFields := sharedScalarFields(coll.Schema, coll.Schema)
+	outputFields = append(outputFields, vectorstore.FieldVector)
+
+	dim, err := vectorDim(coll.Schema)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	type fileKey struct {
+		userEmail string
+		title     string
+	}
+	files := make(map[fileKey]*model.KnowledgeMetadata)
+
+	var lastID int64 = -1
+	updated, orphaned := 0, 0
+	for {
+		rs, err := client.Query(ctx, milvusclient.NewQueryOption(coll.Name).
+			WithFilter(fmt.Sprintf(`%s == "" and %s > %d`, vectorstore.FieldDocumentID, vectorstore.FieldID, lastID)).
+			WithOutputFields(append(outputFields, vectorstore.FieldID)...).
+			WithLimit(migrateBatchSize).
+			WithConsistencyLevel(entity.ClStrong))
+		if err != nil {
+			return updated, orphaned, fmt.Errorf("failed to query legacy chunks: %v", err)
+		}
+		if rs.ResultCount == 0 {
+			break
+		}
+
+		ids, ok1 := rs.GetColumn(vectorstore.FieldID).(*column.ColumnInt64)
+		emails, ok2 := rs.GetColumn(vectorstore.FieldUserEmail).(*column.ColumnVarChar)
+		titles, ok3 := rs.GetColumn(vectorstore.FieldTitle).(*column.ColumnVarChar)
+		versions, ok4 := rs.GetColumn(vectorstore.FieldKnowledgeID).(*column.ColumnInt64)
+		if !ok1 || !ok2 || !ok3 || !ok4 {
+			return updated, orphaned, fmt.Errorf("unexpected column types of legacy chunks")
+		}
+		lastID = ids.Data()[rs.ResultCount-1]
+
+		// 只保留能对应到知识文件的切片
+		var rows []int
+		var documentIDs []string
+		var knowledgeIDs []int64
+		for i := range rs.ResultCount {
+			key := fileKey{emails.Data()[i], titles.Data()[i]}
+			file, cached := files[key]
+			if !cached {
+				file, err = dao.GetKnowledgeMetadataByEmailAndFileName(key.userEmail, key.title)
+				if err != nil {
+					return updated, orphaned, fmt.Errorf("failed to get knowledge metadata: %v", err)
+				}
+				files[key] = file
+			}
+			if file == nil || file.DocumentID == "" {
+				orphaned++
+				continue
+			}
+			// 已记录版本的切片保留原版本，否则归属到文件的最新版本
+			knowledgeID := versions.Data()[i]
+			if knowledgeID == 0 {
+				knowledgeID = int64(file.ID)
+			}
+			rows = append(rows, i)
+			documentIDs = append(documentIDs, file.DocumentID)
+			knowledgeIDs = append(knowledgeIDs, knowledgeID)
+		}
+		if len(rows) == 0 || dryRun {
+			updated += len(rows)
+			continue
+		}
+
+		columns, err := backfilledColumns(rs, outputFields, rows, documentIDs, knowledgeIDs, dim)
+		if err != nil {
+			return updated, orphaned, err
+		}
+		_, err = client.Insert(ctx, milvusclient.NewColumnBasedInsertOption(coll.Name).WithColumns(columns...))
+		if err != nil {
+			return updated, orphaned, fmt.Errorf("failed to insert backfilled chunks: %v", err)
+		}
+
+		replaced := make([]int64, len(rows))
+		for i, row := range rows {
+			replaced[i] = ids.Data()[row]
+		}
+		_, err = client.Delete(ctx, milvusclient.NewDeleteOption(coll.Name).WithInt64IDs(vectorstore.FieldID, replaced))
+		if err != nil {
+			return updated, orphaned, fmt.Errorf("failed to delete legacy chunks: %v", err)
+		}
+
+		updated += len(rows)
+		slog.Info("Backfilled batch", "updated", updated, "orphaned", orphaned, "last_id", lastID)
+	}
+	return updated, orphaned, nil
+}
+
+// backfilledColumns 取出 rows 对应的切片，替换 document_id 和 knowledge_id 列
+func backfilledColumns(rs milvusclient.ResultSet, outputFields []string, rows []int,
+	documentIDs []string, knowledgeIDs []int64, dim int) ([]column.Column, error) {
+	columns := make([]column.Column, 0, len(outputFields))
+	for _, name := range outputFields {
+		switch name {
+		case vectorstore.FieldDocumentID:
+			columns = append(columns, column.NewColumnVarChar(name, documentIDs))
+			continue
+		case vectorstore.FieldKnowledgeID:
+			columns = append(columns, column.NewColumnInt64(name, knowledgeIDs))
+			continue
+		}
+
+		switch col := rs.GetColumn(name).(type) {
+		case *column.ColumnVarChar:
+			columns = append(columns, column.NewColumnVarChar(name, pick(col.Data(), rows)))
+		case *column.ColumnInt64:
+			columns = append(columns, column.NewColumnInt64(name, pick(col.Data(), rows)))
+		case *column.ColumnFloatVector:
+			vectors := make([][]float32, len(rows))
+			for i, vector := range pick(col.Data(), rows) {
+				vectors[i] = vector
+			}
+			columns = append(columns, column.NewColumnFloatVector(name, dim, vectors))
+		default:
+			return nil, fmt.Errorf("unexpected type of field %s", name)
+		}
+	}
+	return columns, nil
+}
+
+func pick[T any](values []T, rows []int) []T {
+	picked := make([]T, len(rows))
+	for i, row := range rows {
+		picked[i] = values[row]
+	}
+	return picked
+}
+
+func vectorDim(schema *entity.Schema) (int, error) {
+	for _, field := range schema.Fields {
+		if field.Name == vectorstore.FieldVector {
+			dim, err := field.GetDim()
+			if err != nil {
+				return 0, fmt.Errorf("failed to get dim of field %s: %v", field.Name, err)
+			}
+			return int(dim), nil
+		}
+	}
+	return 0, fmt.Errorf("field %s not found", vectorstore.FieldVector)
+}
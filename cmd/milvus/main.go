@@ -0,0 +1,345 @@
+package main
+
+import (
+	"context"
+	"diabetes-agent-backend/config"
+	"diabetes-agent-backend/service/embedding"
+	"diabetes-agent-backend/service/knowledge-base/vectorstore"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+
+	"github.com/milvus-io/milvus/client/v2/column"
+	"github.com/milvus-io/milvus/client/v2/entity"
+	"github.com/milvus-io/milvus/client/v2/milvusclient"
+	"github.com/tmc/langchaingo/embeddings"
+)
+
+const (
+	migrateBatchSize = 100
+	usage            = `usage: milvus <command> [flags]
+
+commands:
+  init      create the knowledge collection, indexes and alias if absent, validate otherwise
+  validate  check the knowledge collection against the expected schema
+  migrate   copy (and re-embed if needed) the knowledge collection into a new collection
+  backfill  assign document ids to knowledge files and chunks uploaded before document ids existed`
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err := config.Init(); err != nil {
+		slog.Error("Failed to load config", "err", err)
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	client, err := vectorstore.NewMilvusClient(ctx)
+	if err != nil {
+		slog.Error("Failed to create milvus client", "err", err)
+		os.Exit(1)
+	}
+	defer client.Close(ctx)
+
+	switch os.Args[1] {
+	case "init":
+		err = runInit(ctx, client, os.Args[2:])
+	case "validate":
+		err = runValidate(ctx, client, os.Args[2:])
+	case "migrate":
+		err = runMigrate(ctx, client, os.Args[2:])
+	case "backfill":
+		err = runBackfill(ctx, client, os.Args[2:])
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		slog.Error("Command failed", "command", os.Args[1], "err", err)
+		os.Exit(1)
+	}
+}
+
+// specFlags 注册集合规格相关的命令行参数，默认值取自配置
+func specFlags(fs *flag.FlagSet) *vectorstore.CollectionSpec {
+	spec := vectorstore.DefaultSpec()
+	fs.StringVar(&spec.EmbeddingModel, "model", spec.EmbeddingModel, "embedding model name")
+	fs.Int64Var(&spec.Dim, "dim", spec.Dim, "embedding vector dimension")
+	return &spec
+}
+
+// runInit 集合不存在时创建实际集合并建立别名，集合已存在时校验 schema
+func runInit(ctx context.Context, client *milvusclient.Client, args []string) error {
+	fs := flag.NewFlagSet("init", flag.ExitOnError)
+	spec := specFlags(fs)
+	fs.Parse(args)
+
+	exists, err := client.HasCollection(ctx, milvusclient.NewHasCollectionOption(vectorstore.CollectionName))
+	if err != nil {
+		return fmt.Errorf("failed to check collection: %v", err)
+	}
+	if exists {
+		slog.Info("Collection already exists, validating", "collection", vectorstore.CollectionName)
+		return validate(ctx, client, *spec)
+	}
+
+	target := *spec
+	target.Name = vectorstore.PhysicalCollectionName(vectorstore.SchemaVersion)
+	if err := createCollection(ctx, client, target); err != nil {
+		return err
+	}
+
+	err = client.CreateAlias(ctx, milvusclient.NewCreateAliasOption(target.Name, vectorstore.CollectionName))
+	if err != nil {
+		return fmt.Errorf("failed to create alias %s: %v", vectorstore.CollectionName, err)
+	}
+
+	slog.Info("Collection initialized",
+		"collection", target.Name,
+		"alias", vectorstore.CollectionName,
+		"schema_version", vectorstore.SchemaVersion,
+	)
+	return nil
+}
+
+func runValidate(ctx context.Context, client *milvusclient.Client, args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	spec := specFlags(fs)
+	fs.Parse(args)
+
+	return validate(ctx, client, *spec)
+}
+
+func validate(ctx context.Context, client *milvusclient.Client, spec vectorstore.CollectionSpec) error {
+	coll, err := client.DescribeCollection(ctx, milvusclient.NewDescribeCollectionOption(spec.Name))
+	if err != nil {
+		return fmt.Errorf("failed to describe collection %s: %v", spec.Name, err)
+	}
+
+	problems := vectorstore.Validate(coll, spec)
+	for _, problem := range problems {
+		slog.Warn("Schema mismatch", "collection", coll.Name, "problem", problem)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("collection %s does not match schema version %d, run migrate",
+			coll.Name, vectorstore.SchemaVersion)
+	}
+
+	slog.Info("Collection is up to date", "collection", coll.Name, "schema_version", vectorstore.SchemaVersion)
+	return nil
+}
+
+// runMigrate 将当前集合的数据迁移到新集合，向量化模型或维度变化时重新生成向量，完成后将别名切换到新集合
+func runMigrate(ctx context.Context, client *milvusclient.Client, args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	spec := specFlags(fs)
+	targetName := fs.String("target", vectorstore.PhysicalCollectionName(vectorstore.SchemaVersion), "target collection name")
+	reembed := fs.Bool("reembed", false, "force re-embedding even if model and dim are unchanged")
+	dropSource := fs.Bool("drop-source", false, "drop the source collection after copying if it is not behind the alias")
+	fs.Parse(args)
+
+	source, err := client.DescribeCollection(ctx, milvusclient.NewDescribeCollectionOption(vectorstore.CollectionName))
+	if err != nil {
+		return fmt.Errorf("failed to describe source collection: %v", err)
+	}
+	if source.Name == *targetName {
+		return fmt.Errorf("target collection %s is the current collection, choose another -target", *targetName)
+	}
+
+	// 旧版本直接以 CollectionName 作为实际集合名称，此时需要删除旧集合才能建立同名别名
+	aliases, err := client.ListAliases(ctx, milvusclient.NewListAliasesOption(source.Name))
+	if err != nil {
+		return fmt.Errorf("failed to list aliases of %s: %v", source.Name, err)
+	}
+	aliased := slices.Contains(aliases, vectorstore.CollectionName)
+	if !aliased && !*dropSource {
+		return fmt.Errorf("collection %s is not behind an alias, rerun with -drop-source to replace it", source.Name)
+	}
+
+	target := *spec
+	target.Name = *targetName
+
+	// 模型或维度与源集合记录的不一致时必须重新生成向量
+	if source.Properties[vectorstore.PropertyEmbeddingModel] != target.EmbeddingModel ||
+		source.Properties[vectorstore.PropertyEmbeddingDim] != target.Properties()[vectorstore.PropertyEmbeddingDim] {
+		*reembed = true
+	}
+
+	if err := createCollection(ctx, client, target); err != nil {
+		return err
+	}
+
+	copied, err := copyCollection(ctx, client, source, target, *reembed)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Collection data copied",
+		"source", source.Name,
+		"target", target.Name,
+		"rows", copied,
+		"reembed", *reembed,
+	)
+
+	return switchAlias(ctx, client, source.Name, target.Name, aliased)
+}
+
+func createCollection(ctx context.Context, client *milvusclient.Client, spec vectorstore.CollectionSpec) error {
+	option := milvusclient.NewCreateCollectionOption(spec.Name, vectorstore.NewSchema(spec)).
+		WithIndexOptions(vectorstore.NewIndexOptions(spec.Name)...)
+	for key, value := range spec.Properties() {
+		option = option.WithProperty(key, value)
+	}
+
+	if err := client.CreateCollection(ctx, option); err != nil {
+		return fmt.Errorf("failed to create collection %s: %v", spec.Name, err)
+	}
+
+	loadTask, err := client.LoadCollection(ctx, milvusclient.NewLoadCollectionOption(spec.Name))
+	if err != nil {
+		return fmt.Errorf("failed to load collection %s: %v", spec.Name, err)
+	}
+	if err := loadTask.Await(ctx); err != nil {
+		return fmt.Errorf("failed to wait for collection %s loading: %v", spec.Name, err)
+	}
+
+	slog.Info("Collection created", "collection", spec.Name, "dim", spec.Dim, "model", spec.EmbeddingModel)
+	return nil
+}
+
+// copyCollection 按主键顺序分批读取源集合，写入目标集合，只复制两个集合共有的标量字段
+func copyCollection(ctx context.Context, client *milvusclient.Client, source *entity.Collection,
+	target vectorstore.CollectionSpec, reembed bool) (int, error) {
+	var embedder embeddings.Embedder
+	if reembed {
+		var err error
+		embedder, err = embedding.New(target.EmbeddingModel, target.Dim)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	outputFields := sharedScalarFields(source.Schema, vectorstore.NewSchema(target))
+	if !reembed {
+		outputFields = append(outputFields, vectorstore.FieldVector)
+	}
+
+	var lastID int64 = -1
+	copied := 0
+	for {
+		rs, err := client.Query(ctx, milvusclient.NewQueryOption(source.Name).
+			WithFilter(fmt.Sprintf("%s > %d", vectorstore.FieldID, lastID)).
+			WithOutputFields(outputFields...).
+			WithLimit(migrateBatchSize).
+			WithConsistencyLevel(entity.ClStrong))
+		if err != nil {
+			return copied, fmt.Errorf("failed to query source collection: %v", err)
+		}
+		if rs.ResultCount == 0 {
+			break
+		}
+
+		idColumn := rs.GetColumn(vectorstore.FieldID)
+		if idColumn == nil {
+			idColumn = rs.IDs
+		}
+		for i := range rs.ResultCount {
+			id, err := idColumn.GetAsInt64(i)
+			if err != nil {
+				return copied, fmt.Errorf("failed to read primary key: %v", err)
+			}
+			lastID = max(lastID, id)
+		}
+
+		columns := make([]column.Column, 0, len(outputFields)+1)
+		for _, name := range outputFields {
+			columns = append(columns, rs.GetColumn(name))
+		}
+
+		if reembed {
+			texts, ok := rs.GetColumn(vectorstore.FieldText).(*column.ColumnVarChar)
+			if !ok {
+				return copied, fmt.Errorf("unexpected type of field %s", vectorstore.FieldText)
+			}
+			vectors, err := embedder.EmbedDocuments(ctx, texts.Data())
+			if err != nil {
+				return copied, fmt.Errorf("failed to re-embed texts: %v", err)
+			}
+			columns = append(columns, column.NewColumnFloatVector(vectorstore.FieldVector, int(target.Dim), vectors))
+		}
+
+		_, err = client.Insert(ctx, milvusclient.NewColumnBasedInsertOption(target.Name).WithColumns(columns...))
+		if err != nil {
+			return copied, fmt.Errorf("failed to insert into target collection: %v", err)
+		}
+
+		copied += rs.ResultCount
+		slog.Info("Migrated batch", "rows", copied, "last_id", lastID)
+	}
+
+	flushTask, err := client.Flush(ctx, milvusclient.NewFlushOption(target.Name))
+	if err != nil {
+		return copied, fmt.Errorf("failed to flush target collection: %v", err)
+	}
+	if err := flushTask.Await(ctx); err != nil {
+		return copied, fmt.Errorf("failed to wait for target collection flushing: %v", err)
+	}
+
+	return copied, nil
+}
+
+// sharedScalarFields 返回源集合和目标集合中名称、类型一致的非主键标量字段
+func sharedScalarFields(source, target *entity.Schema) []string {
+	sourceTypes := make(map[string]entity.FieldType)
+	for _, field := range source.Fields {
+		sourceTypes[field.Name] = field.DataType
+	}
+
+	var fields []string
+	for _, field := range target.Fields {
+		if field.PrimaryKey || field.DataType == entity.FieldTypeFloatVector {
+			continue
+		}
+		if dataType, ok := sourceTypes[field.Name]; ok && dataType == field.DataType {
+			fields = append(fields, field.Name)
+		}
+	}
+	return fields
+}
+
+// switchAlias 将业务别名指向新集合，源集合未使用别名时先删除源集合再建立同名别名
+func switchAlias(ctx context.Context, client *milvusclient.Client, sourceName, targetName string, aliased bool) error {
+	if aliased {
+		err := client.AlterAlias(ctx, milvusclient.NewAlterAliasOption(vectorstore.CollectionName, targetName))
+		if err != nil {
+			return fmt.Errorf("failed to alter alias: %v", err)
+		}
+		slog.Info("Alias switched, source collection kept for rollback",
+			"alias", vectorstore.CollectionName,
+			"source", sourceName,
+			"target", targetName,
+		)
+		return nil
+	}
+
+	if err := client.DropCollection(ctx, milvusclient.NewDropCollectionOption(sourceName)); err != nil {
+		return fmt.Errorf("failed to drop source collection: %v", err)
+	}
+	err := client.CreateAlias(ctx, milvusclient.NewCreateAliasOption(targetName, vectorstore.CollectionName))
+	if err != nil {
+		return fmt.Errorf("failed to create alias: %v", err)
+	}
+
+	slog.Info("Source collection replaced by alias",
+		"alias", vectorstore.CollectionName,
+		"target", targetName,
+	)
+	return nil
+}
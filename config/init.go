@@ -35,17 +35,91 @@ type Config struct {
 		RoleARN         string `yaml:"role_arn"`
 	} `yaml:"oss"`
 	MQ struct {
+		// rocketmq（默认）或 memory，memory 为进程内队列，用于本地开发和测试
+		Broker     string   `yaml:"broker"`
 		NameServer []string `yaml:"name_server"`
 	} `yaml:"mq"`
 	Model struct {
-		APIKey string `yaml:"api_key"`
+		APIKey    string `yaml:"api_key"`
+		Embedding struct {
+			Name string `yaml:"name"`
+			Dim  int64  `yaml:"dim"`
+
+			// dashscope（默认）、openai 或 hash，hash 为离线测试使用的确定性向量
+			Provider string `yaml:"provider"`
+
+			// OpenAI 兼容接口地址和密钥，未配置时使用 DashScope 地址和 model.api_key
+			BaseURL string `yaml:"base_url"`
+			APIKey  string `yaml:"api_key"`
+
+			// 每秒请求数上限，0 表示不限流
+			RateLimit float64 `yaml:"rate_limit"`
+
+			// 单次请求的文本数量上限
+			BatchSize int `yaml:"batch_size"`
+
+			// 请求被限流或服务端出错时的最大尝试次数
+			MaxAttempts uint `yaml:"max_attempts"`
+
+			Cache struct {
+				// mysql（默认）、disk 或 none
+				Type string `yaml:"type"`
+
+				// disk 缓存的目录
+				Dir string `yaml:"dir"`
+			} `yaml:"cache"`
+		} `yaml:"embedding"`
+		OCR struct {
+			Provider string `yaml:"provider"`
+			Name     string `yaml:"name"`
+		} `yaml:"ocr"`
 	} `yaml:"model"`
+	Chat struct {
+		Memory struct {
+			// 原文保留的最近对话轮数，更早的对话合并到会话摘要中
+			RecentTurns int `yaml:"recent_turns"`
+
+			// 模型的上下文窗口（token），key 为模型名称
+			ContextWindows map[string]int `yaml:"context_windows"`
+
+			// 未配置的模型使用的上下文窗口
+			DefaultContextWindow int `yaml:"default_context_window"`
+
+			// 为模型输出和 agent 中间步骤预留的 token 数
+			ReservedTokens int `yaml:"reserved_tokens"`
+		} `yaml:"memory"`
+	} `yaml:"chat"`
 	Milvus struct {
 		Endpoint string `yaml:"endpoint"`
 		APIKey   string `yaml:"api_key"`
 		Username string `yaml:"username"`
 		Password string `yaml:"password"`
 	} `yaml:"milvus"`
+	KnowledgeBase struct {
+		// 知识文件大小上限（字节），未配置时使用默认值
+		MaxFileSize int64 `yaml:"max_file_size"`
+
+		// 按文件类型覆盖大小上限，key 为文件类型，如 pdf、xlsx
+		MaxFileSizeByType map[string]int64 `yaml:"max_file_size_by_type"`
+
+		Chunking struct {
+			// 所有文件类型的默认切分配置
+			Default ChunkingConfig `yaml:"default"`
+
+			// 按文件类型覆盖切分配置，key 为文件类型
+			ByFileType map[string]ChunkingConfig `yaml:"by_file_type"`
+		} `yaml:"chunking"`
+	} `yaml:"knowledge_base"`
+}
+
+// ChunkingConfig 切分配置，未配置的字段使用内置默认值
+type ChunkingConfig struct {
+	// recursive、markdown、sentence 或 token
+	Strategy  string `yaml:"strategy"`
+	ChunkSize int    `yaml:"chunk_size"`
+
+	// 未配置时为 nil，配置为 0 表示切片之间不重叠
+	ChunkOverlap *int `yaml:"chunk_overlap"`
 }
 
 type DBConfig struct {
@@ -56,13 +130,15 @@ type DBConfig struct {
 	DBName   string `yaml:"db_name"`
 }
 
-func init() {
+// Init 从工作目录下的 config.yaml 加载配置，需要在使用配置的服务启动前调用
+func Init() error {
 	data, err := os.ReadFile("config.yaml")
 	if err != nil {
-		panic(fmt.Sprintf("Failed to read config: %v", err))
+		return fmt.Errorf("failed to read config: %v", err)
 	}
 
 	if err := yaml.Unmarshal(data, &Cfg); err != nil {
-		panic(fmt.Sprintf("Failed to parse config: %v", err))
+		return fmt.Errorf("failed to parse config: %v", err)
 	}
+	return nil
 }
@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"diabetes-agent-backend/response"
+	"diabetes-agent-backend/service/glucose/agp"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAGPReport 生成动态血糖图谱报告，format 为 pdf（默认）、png、svg 或 json
+func GetAGPReport(c *gin.Context) {
+	email := c.GetString("email")
+	from, to, ok := parseGlucoseRange(c, email, ErrGenerateAGPReport)
+	if !ok {
+		return
+	}
+
+	format := c.DefaultQuery("format", "pdf")
+	if format != "pdf" && format != "png" && format != "svg" && format != "json" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, response.Response{
+			Msg: fmt.Sprintf("unsupported format: %s", format),
+		})
+		return
+	}
+
+	report, err := agp.BuildReport(email, from, to)
+	if err != nil {
+		slog.Error(ErrGenerateAGPReport.Error(), "err", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, response.Response{
+			Msg: ErrGenerateAGPReport.Error(),
+		})
+		return
+	}
+
+	var (
+		data        []byte
+		contentType string
+	)
+	switch format {
+	case "json":
+		c.JSON(http.StatusOK, response.Response{
+			Data: toAGPReportResponse(report),
+		})
+		return
+	case "png":
+		data, err = agp.RenderPNG(report)
+		contentType = "image/png"
+	case "svg":
+		data = agp.RenderSVG(report)
+		contentType = "image/svg+xml"
+	default:
+		data = agp.RenderPDF(report)
+		contentType = "application/pdf"
+	}
+	if err != nil {
+		slog.Error(ErrGenerateAGPReport.Error(), "err", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, response.Response{
+			Msg: ErrGenerateAGPReport.Error(),
+		})
+		return
+	}
+
+	fileName := fmt.Sprintf("agp-%s-%s.%s", from.Format("20060102"), to.Format("20060102"), format)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, fileName))
+	c.Data(http.StatusOK, contentType, data)
+}
+
+func toAGPReportResponse(report *agp.Report) response.AGPReportResponse {
+	resp := response.AGPReportResponse{
+		Metrics:     toGlucoseMetricsResponse(&report.Metrics, report.Unit),
+		GeneratedAt: report.GeneratedAt,
+	}
+	for _, p := range report.Profile {
+		resp.Profile = append(resp.Profile, response.AGPProfilePointResponse{
+			Minute: p.Minute,
+			Count:  p.Count,
+			P5:     roundGlucose(report.Unit, p.P5),
+			P25:    roundGlucose(report.Unit, p.P25),
+			P50:    roundGlucose(report.Unit, p.P50),
+			P75:    roundGlucose(report.Unit, p.P75),
+			P95:    roundGlucose(report.Unit, p.P95),
+		})
+	}
+	return resp
+}
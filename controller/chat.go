@@ -4,9 +4,16 @@ import (
 	"context"
 	"diabetes-agent-backend/request"
 	"diabetes-agent-backend/service/chat"
+	"diabetes-agent-backend/service/glucose"
+	"diabetes-agent-backend/service/glucose/agp"
+	"diabetes-agent-backend/service/knowledge-base/retrieval"
+	"diabetes-agent-backend/service/logbook"
+	patientmemory "diabetes-agent-backend/service/patient-memory"
+	"diabetes-agent-backend/service/profile"
 	"diabetes-agent-backend/service/summarization"
 	"diabetes-agent-backend/utils"
 	"log/slog"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -22,7 +29,23 @@ func AgentChat(c *gin.Context) {
 		return
 	}
 
-	agent, err := chat.NewAgent(req, c)
+	// 本轮对话之前提出的日志操作才能由 Agent 确认写入
+	turnStartedAt := time.Now()
+
+	// 健康档案和长期记忆加载失败时不影响对话
+	email := c.GetString("email")
+	profileText, err := profile.PromptContext(email)
+	if err != nil {
+		slog.Error("Failed to load patient profile", "err", err)
+	}
+	facts, err := patientmemory.PromptContext(email, req.Query)
+	if err != nil {
+		slog.Error("Failed to load patient memory", "err", err)
+	}
+
+	agent, err := chat.NewAgent(req, c, chat.WithUserContext(profileText), chat.WithUserContext(facts),
+		chat.WithTools(glucose.NewTool(email), agp.NewTool(email), retrieval.NewTool(email)),
+		chat.WithTools(logbook.NewTools(email, req.SessionID, turnStartedAt)...))
 	if err != nil {
 		slog.Error(ErrCreateAgent.Error(), "err", err)
 		utils.SendSSEMessage(c, utils.EventError, ErrCreateAgent)
@@ -49,10 +72,22 @@ func AgentChat(c *gin.Context) {
 
 	utils.SendSSEMessage(c, utils.EventDone, "")
 
-	summarization.SummarizerInstance.RegisterSummaryTask(summarization.SummaryTask{
-		MessageIDs: []uint{
-			agent.ChatHistory.UserMessageID,
-			agent.ChatHistory.AgentMessageID,
-		},
+	err = summarization.SummarizerInstance.RegisterSummaryTask(summarization.SummaryTask{
+		UserEmail:     email,
+		SessionID:     req.SessionID,
+		LastMessageID: agent.ChatHistory.AgentMessageID,
 	})
+	if err != nil {
+		slog.Error("Failed to register summary task", "err", err)
+	}
+
+	err = patientmemory.RegisterExtractionTask(patientmemory.ExtractionTask{
+		UserEmail:      email,
+		SessionID:      req.SessionID,
+		UserMessageID:  agent.ChatHistory.UserMessageID,
+		AgentMessageID: agent.ChatHistory.AgentMessageID,
+	})
+	if err != nil {
+		slog.Error("Failed to register patient memory extraction task", "err", err)
+	}
 }
@@ -21,10 +21,45 @@ var (
 	ErrGetAudioFile     = errors.New("failed to get audio file")
 	ErrVoiceRecognition = errors.New("failed to recognize audio")
 
-	ErrGeneratePolicyToken     = errors.New("failed to generate policy token")
-	ErrGetKnowledgeMetadata    = errors.New("failed to get knowledge metadata")
-	ErrUploadKnowledgeMetadata = errors.New("failed to upload knowledge metadata")
-	ErrDeleteKnowledgeMetadata = errors.New("failed to delete knowledge metadata")
-	ErrGetPreSignedURL         = errors.New("failed to get presigned url")
-	ErrSearchKnowledgeMetadata = errors.New("failed to search knowledge metadata")
+	ErrGeneratePolicyToken      = errors.New("failed to generate policy token")
+	ErrGetKnowledgeMetadata     = errors.New("failed to get knowledge metadata")
+	ErrUploadKnowledgeMetadata  = errors.New("failed to upload knowledge metadata")
+	ErrDeleteKnowledgeMetadata  = errors.New("failed to delete knowledge metadata")
+	ErrGetPreSignedURL          = errors.New("failed to get presigned url")
+	ErrSearchKnowledgeMetadata  = errors.New("failed to search knowledge metadata")
+	ErrGetKnowledgeVersions     = errors.New("failed to get knowledge versions")
+	ErrRollbackKnowledgeVersion = errors.New("failed to rollback knowledge version")
+	ErrGetKnowledgeStatus       = errors.New("failed to get knowledge processing status")
+	ErrReprocessKnowledge       = errors.New("failed to reprocess knowledge")
+
+	ErrGetLabResults = errors.New("failed to get lab results")
+
+	ErrGetStuckOutboxMessages = errors.New("failed to get stuck outbox messages")
+
+	ErrGetPatientFacts   = errors.New("failed to get patient facts")
+	ErrUpdatePatientFact = errors.New("failed to update patient fact")
+	ErrDeletePatientFact = errors.New("failed to delete patient fact")
+
+	ErrGetPatientProfile    = errors.New("failed to get patient profile")
+	ErrSavePatientProfile   = errors.New("failed to save patient profile")
+	ErrDeletePatientProfile = errors.New("failed to delete patient profile")
+
+	ErrCreateGlucoseReadings = errors.New("failed to create glucose readings")
+	ErrGetGlucoseReadings    = errors.New("failed to get glucose readings")
+	ErrDeleteGlucoseReading  = errors.New("failed to delete glucose reading")
+	ErrGetGlucoseMetrics     = errors.New("failed to get glucose metrics")
+
+	ErrCreateGlucoseImport   = errors.New("failed to create glucose import")
+	ErrGetGlucoseImports     = errors.New("failed to get glucose imports")
+	ErrGlucoseImportNotFound = errors.New("glucose import not found")
+
+	ErrGenerateAGPReport = errors.New("failed to generate agp report")
+
+	ErrCreateLogEntry          = errors.New("failed to create log entry")
+	ErrGetLogEntries           = errors.New("failed to get log entries")
+	ErrUpdateLogEntry          = errors.New("failed to update log entry")
+	ErrDeleteLogEntry          = errors.New("failed to delete log entry")
+	ErrGetPendingLogActions    = errors.New("failed to get pending log actions")
+	ErrConfirmPendingLogAction = errors.New("failed to confirm pending log action")
+	ErrCancelPendingLogAction  = errors.New("failed to cancel pending log action")
 )
@@ -0,0 +1,230 @@
+package controller
+
+import (
+	"diabetes-agent-backend/model"
+	"diabetes-agent-backend/request"
+	"diabetes-agent-backend/response"
+	"diabetes-agent-backend/service/glucose"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateGlucoseReadings 录入或批量导入血糖记录
+func CreateGlucoseReadings(c *gin.Context) {
+	var req request.CreateGlucoseReadingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Error(ErrParseRequest.Error(), "err", err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, response.Response{
+			Msg: ErrParseRequest.Error(),
+		})
+		return
+	}
+
+	email := c.GetString("email")
+	created, skipped, err := glucose.CreateReadings(email, req)
+	if err != nil {
+		if errors.Is(err, glucose.ErrInvalidRequest) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, response.Response{
+				Msg: err.Error(),
+			})
+			return
+		}
+
+		slog.Error(ErrCreateGlucoseReadings.Error(), "err", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, response.Response{
+			Msg: ErrCreateGlucoseReadings.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Response{
+		Data: response.CreateGlucoseReadingsResponse{
+			Created: created,
+			Skipped: skipped,
+		},
+	})
+}
+
+// GetGlucoseReadings 查询时间范围内的血糖记录，血糖值换算为用户偏好的单位
+func GetGlucoseReadings(c *gin.Context) {
+	email := c.GetString("email")
+	from, to, ok := parseGlucoseRange(c, email, ErrGetGlucoseReadings)
+	if !ok {
+		return
+	}
+
+	readings, err := glucose.GetReadings(email, from, to)
+	if err != nil {
+		slog.Error(ErrGetGlucoseReadings.Error(), "err", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, response.Response{
+			Msg: ErrGetGlucoseReadings.Error(),
+		})
+		return
+	}
+
+	unit, err := glucose.PreferredUnit(email)
+	if err != nil {
+		slog.Error(ErrGetGlucoseReadings.Error(), "err", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, response.Response{
+			Msg: ErrGetGlucoseReadings.Error(),
+		})
+		return
+	}
+
+	var resp response.GetGlucoseReadingsResponse
+	for _, item := range readings {
+		resp.Readings = append(resp.Readings, response.GlucoseReadingResponse{
+			ID:         item.ID,
+			Value:      roundGlucose(unit, item.Value),
+			Unit:       string(unit),
+			MeasuredAt: item.MeasuredAt,
+			Context:    string(item.Context),
+			Source:     string(item.Source),
+			Note:       item.Note,
+		})
+	}
+
+	c.JSON(http.StatusOK, response.Response{
+		Data: resp,
+	})
+}
+
+// DeleteGlucoseReading 删除一条血糖记录
+func DeleteGlucoseReading(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		slog.Error(ErrParseRequest.Error(), "err", err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, response.Response{
+			Msg: ErrParseRequest.Error(),
+		})
+		return
+	}
+
+	email := c.GetString("email")
+	if err := glucose.DeleteReading(email, uint(id)); err != nil {
+		if errors.Is(err, glucose.ErrReadingNotFound) {
+			c.AbortWithStatusJSON(http.StatusNotFound, response.Response{
+				Msg: err.Error(),
+			})
+			return
+		}
+
+		slog.Error(ErrDeleteGlucoseReading.Error(), "err", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, response.Response{
+			Msg: ErrDeleteGlucoseReading.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Response{})
+}
+
+// GetGlucoseMetrics 计算时间范围内的血糖统计指标和每日规律
+func GetGlucoseMetrics(c *gin.Context) {
+	email := c.GetString("email")
+	from, to, ok := parseGlucoseRange(c, email, ErrGetGlucoseMetrics)
+	if !ok {
+		return
+	}
+
+	metrics, err := glucose.GetMetrics(email, from, to)
+	if err != nil {
+		slog.Error(ErrGetGlucoseMetrics.Error(), "err", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, response.Response{
+			Msg: ErrGetGlucoseMetrics.Error(),
+		})
+		return
+	}
+
+	unit, err := glucose.PreferredUnit(email)
+	if err != nil {
+		slog.Error(ErrGetGlucoseMetrics.Error(), "err", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, response.Response{
+			Msg: ErrGetGlucoseMetrics.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Response{
+		Data: toGlucoseMetricsResponse(metrics, unit),
+	})
+}
+
+func toGlucoseMetricsResponse(metrics *glucose.Metrics, unit model.GlucoseUnit) response.GlucoseMetricsResponse {
+	resp := response.GlucoseMetricsResponse{
+		From:         metrics.From,
+		To:           metrics.To,
+		Unit:         string(unit),
+		Count:        metrics.Count,
+		Days:         metrics.Days,
+		Mean:         roundGlucose(unit, metrics.Mean),
+		SD:           roundGlucose(unit, metrics.SD),
+		Min:          roundGlucose(unit, metrics.Min),
+		Max:          roundGlucose(unit, metrics.Max),
+		CV:           roundPercent(metrics.CV),
+		GMI:          roundPercent(metrics.GMI),
+		TargetLow:    roundGlucose(unit, metrics.TargetLow),
+		TargetHigh:   roundGlucose(unit, metrics.TargetHigh),
+		TimeVeryLow:  roundPercent(metrics.TimeVeryLow),
+		TimeLow:      roundPercent(metrics.TimeLow),
+		TimeInRange:  roundPercent(metrics.TimeInRange),
+		TimeHigh:     roundPercent(metrics.TimeHigh),
+		TimeVeryHigh: roundPercent(metrics.TimeVeryHigh),
+	}
+
+	for _, stat := range metrics.Hourly {
+		resp.Hourly = append(resp.Hourly, response.HourlyGlucoseResponse{
+			Hour:   stat.Hour,
+			Count:  stat.Count,
+			Mean:   roundGlucose(unit, stat.Mean),
+			P25:    roundGlucose(unit, stat.P25),
+			Median: roundGlucose(unit, stat.Median),
+			P75:    roundGlucose(unit, stat.P75),
+		})
+	}
+
+	for _, stat := range metrics.ByContext {
+		resp.ByContext = append(resp.ByContext, response.ContextGlucoseResponse{
+			Context: string(stat.Context),
+			Count:   stat.Count,
+			Mean:    roundGlucose(unit, stat.Mean),
+		})
+	}
+	return resp
+}
+
+// roundGlucose 将 mmol/L 的值换算为 unit 单位，保留一位小数
+func roundGlucose(unit model.GlucoseUnit, value float64) float64 {
+	return math.Round(unit.FromMmolL(value)*10) / 10
+}
+
+func roundPercent(value float64) float64 {
+	return math.Round(value*10) / 10
+}
+
+// parseGlucoseRange 按用户所在的时区解析查询的时间范围，参数有误返回 400，查询时区失败返回 500
+func parseGlucoseRange(c *gin.Context, email string, fallback error) (time.Time, time.Time, bool) {
+	loc, err := glucose.Location(email)
+	if err != nil {
+		slog.Error(fallback.Error(), "err", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, response.Response{
+			Msg: fallback.Error(),
+		})
+		return time.Time{}, time.Time{}, false
+	}
+
+	from, to, err := glucose.ParseRange(c.Query("from"), c.Query("to"), loc)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, response.Response{
+			Msg: err.Error(),
+		})
+		return time.Time{}, time.Time{}, false
+	}
+	return from, to, true
+}
@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"diabetes-agent-backend/model"
+	"diabetes-agent-backend/request"
+	"diabetes-agent-backend/response"
+	"diabetes-agent-backend/service/glucose"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateGlucoseImport 导入已上传的 CGM 导出文件，文件由 MQ 异步处理
+func CreateGlucoseImport(c *gin.Context) {
+	var req request.CreateGlucoseImportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Error(ErrParseRequest.Error(), "err", err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, response.Response{
+			Msg: ErrParseRequest.Error(),
+		})
+		return
+	}
+
+	email := c.GetString("email")
+	glucoseImport, err := glucose.CreateImport(email, req)
+	if err != nil {
+		if errors.Is(err, glucose.ErrInvalidRequest) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, response.Response{
+				Msg: err.Error(),
+			})
+			return
+		}
+
+		slog.Error(ErrCreateGlucoseImport.Error(), "err", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, response.Response{
+			Msg: ErrCreateGlucoseImport.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Response{
+		Data: toGlucoseImportResponse(glucoseImport),
+	})
+}
+
+// GetGlucoseImports 查询最近的导入任务
+func GetGlucoseImports(c *gin.Context) {
+	email := c.GetString("email")
+
+	imports, err := glucose.GetImports(email)
+	if err != nil {
+		slog.Error(ErrGetGlucoseImports.Error(), "err", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, response.Response{
+			Msg: ErrGetGlucoseImports.Error(),
+		})
+		return
+	}
+
+	var resp response.GetGlucoseImportsResponse
+	for _, item := range imports {
+		resp.Imports = append(resp.Imports, toGlucoseImportResponse(&item))
+	}
+
+	c.JSON(http.StatusOK, response.Response{
+		Data: resp,
+	})
+}
+
+// GetGlucoseImport 查询导入任务的处理状态和结果
+func GetGlucoseImport(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		slog.Error(ErrParseRequest.Error(), "err", err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, response.Response{
+			Msg: ErrParseRequest.Error(),
+		})
+		return
+	}
+
+	email := c.GetString("email")
+	glucoseImport, err := glucose.GetImport(email, uint(id))
+	if err != nil {
+		slog.Error(ErrGetGlucoseImports.Error(), "err", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, response.Response{
+			Msg: ErrGetGlucoseImports.Error(),
+		})
+		return
+	}
+	if glucoseImport == nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, response.Response{
+			Msg: ErrGlucoseImportNotFound.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Response{
+		Data: toGlucoseImportResponse(glucoseImport),
+	})
+}
+
+func toGlucoseImportResponse(glucoseImport *model.GlucoseImport) response.GlucoseImportResponse {
+	return response.GlucoseImportResponse{
+		ID:         glucoseImport.ID,
+		FileName:   glucoseImport.FileName,
+		Format:     glucoseImport.Format,
+		Timezone:   glucoseImport.Timezone,
+		Status:     string(glucoseImport.Status),
+		Created:    glucoseImport.Created,
+		Skipped:    glucoseImport.Skipped,
+		Invalid:    glucoseImport.Invalid,
+		Error:      glucoseImport.Error,
+		CreatedAt:  glucoseImport.CreatedAt,
+		FinishedAt: glucoseImport.FinishedAt,
+	}
+}
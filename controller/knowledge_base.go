@@ -6,12 +6,12 @@ import (
 	"diabetes-agent-backend/request"
 	"diabetes-agent-backend/response"
 	knowledgebase "diabetes-agent-backend/service/knowledge-base"
-	"diabetes-agent-backend/service/knowledge-base/etl"
-	"diabetes-agent-backend/service/mq"
+	"diabetes-agent-backend/utils"
+	"errors"
 	"log/slog"
 	"net/http"
-	"path/filepath"
-	"strings"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -29,11 +29,7 @@ func GetKnowledgeMetadata(c *gin.Context) {
 
 	var resp response.GetKnowledgeMetadataResponse
 	for _, item := range metadata {
-		resp.Metadata = append(resp.Metadata, response.MetadataResponse{
-			FileName: item.FileName,
-			FileType: string(item.FileType),
-			FileSize: item.FileSize,
-		})
+		resp.Metadata = append(resp.Metadata, toMetadataResponse(&item))
 	}
 
 	c.JSON(http.StatusOK, response.Response{
@@ -41,8 +37,11 @@ func GetKnowledgeMetadata(c *gin.Context) {
 	})
 }
 
+// 推送知识文件处理状态时查询数据库的间隔
+const statusPollInterval = time.Second
+
 // UploadKnowledgeMetadata 在前端将文件成功传输到OSS后调用
-// 存储知识文件元数据，向MQ发送向量化任务
+// 存储知识文件元数据，向量化任务随元数据一起写入 outbox，由 relay 投递到MQ
 func UploadKnowledgeMetadata(c *gin.Context) {
 	var req request.UploadKnowledgeMetadataRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -54,53 +53,24 @@ func UploadKnowledgeMetadata(c *gin.Context) {
 	}
 
 	email := c.GetString("email")
-	err := knowledgebase.UploadKnowledgeMetadata(req, email)
-	if err != nil {
-		slog.Error(ErrUploadKnowledgeMetadata.Error(), "err", err)
-		c.AbortWithStatusJSON(http.StatusInternalServerError, response.Response{
-			Msg: ErrUploadKnowledgeMetadata.Error(),
-		})
+	if _, err := knowledgebase.UploadKnowledgeMetadata(req, email); err != nil {
+		abortKnowledgeError(c, ErrUploadKnowledgeMetadata, err)
 		return
 	}
 
-	mq.SendMessage(c.Request.Context(), &mq.Message{
-		Topic: mq.TopicKnowledgeBase,
-		Tag:   mq.TagETL,
-		Payload: etl.ETLMessage{
-			FileType:   model.FileType(req.FileType),
-			ObjectName: req.ObjectName,
-		},
-	})
-
 	c.JSON(http.StatusOK, response.Response{})
 }
 
-// DeleteKnowledgeMetadata 删除知识文件元数据和OSS上的文件，向MQ发送删除任务
+// DeleteKnowledgeMetadata 删除知识文件元数据，删除OSS文件和向量存储的任务随删除操作一起写入 outbox
 func DeleteKnowledgeMetadata(c *gin.Context) {
 	email := c.GetString("email")
 	fileName := c.Query("file-name")
-	err := dao.DeleteKnowledgeMetadataByEmailAndFileName(email, fileName)
-	if err != nil {
-		slog.Error(ErrDeleteKnowledgeMetadata.Error(), "err", err)
-		c.AbortWithStatusJSON(http.StatusInternalServerError, response.Response{
-			Msg: ErrDeleteKnowledgeMetadata.Error(),
-		})
+
+	if err := knowledgebase.DeleteKnowledgeMetadata(email, fileName); err != nil {
+		abortKnowledgeError(c, ErrDeleteKnowledgeMetadata, err)
 		return
 	}
 
-	// 获取文件扩展名（包含.）
-	extension := filepath.Ext(fileName)
-	fileType := strings.TrimPrefix(extension, ".")
-
-	mq.SendMessage(c.Request.Context(), &mq.Message{
-		Topic: mq.TopicKnowledgeBase,
-		Tag:   mq.TagDelete,
-		Payload: etl.DeleteMessage{
-			FileType:   model.FileType(fileType),
-			ObjectName: email + "/" + fileName,
-		},
-	})
-
 	c.JSON(http.StatusOK, response.Response{})
 }
 
@@ -126,10 +96,34 @@ func SearchKnowledgeMetadata(c *gin.Context) {
 
 	var resp response.SearchKnowledgeMetadataResponse
 	for _, item := range metadata {
-		resp.Metadata = append(resp.Metadata, response.MetadataResponse{
-			FileName: item.FileName,
-			FileType: string(item.FileType),
-			FileSize: item.FileSize,
+		resp.Metadata = append(resp.Metadata, toMetadataResponse(&item))
+	}
+
+	c.JSON(http.StatusOK, response.Response{
+		Data: resp,
+	})
+}
+
+// GetKnowledgeVersions 获取知识文件的所有版本
+func GetKnowledgeVersions(c *gin.Context) {
+	email := c.GetString("email")
+	documentID := c.Param("id")
+
+	versions, err := knowledgebase.GetKnowledgeVersions(email, documentID)
+	if err != nil {
+		abortKnowledgeError(c, ErrGetKnowledgeVersions, err)
+		return
+	}
+
+	var resp response.GetKnowledgeVersionsResponse
+	for _, item := range versions {
+		resp.Versions = append(resp.Versions, response.KnowledgeVersionResponse{
+			Version:    item.Version,
+			FileName:   item.FileName,
+			FileSize:   item.FileSize,
+			Status:     string(item.Status),
+			Superseded: item.Superseded,
+			UploadedAt: item.UploadedAt,
 		})
 	}
 
@@ -137,3 +131,152 @@ func SearchKnowledgeMetadata(c *gin.Context) {
 		Data: resp,
 	})
 }
+
+// RollbackKnowledgeVersion 回滚知识文件到指定版本，重新执行向量化
+func RollbackKnowledgeVersion(c *gin.Context) {
+	email := c.GetString("email")
+	documentID := c.Param("id")
+
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		slog.Error(ErrParseRequest.Error(), "err", err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, response.Response{
+			Msg: ErrParseRequest.Error(),
+		})
+		return
+	}
+
+	if _, err := knowledgebase.RollbackKnowledgeVersion(email, documentID, version); err != nil {
+		abortKnowledgeError(c, ErrRollbackKnowledgeVersion, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Response{})
+}
+
+// ReprocessKnowledge 手动重新处理知识文件，重新执行向量化
+func ReprocessKnowledge(c *gin.Context) {
+	email := c.GetString("email")
+	documentID := c.Param("id")
+
+	if _, err := knowledgebase.ReprocessKnowledge(email, documentID, queryVersion(c)); err != nil {
+		abortKnowledgeError(c, ErrReprocessKnowledge, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Response{})
+}
+
+// GetKnowledgeStatus 轮询知识文件的处理状态
+func GetKnowledgeStatus(c *gin.Context) {
+	email := c.GetString("email")
+	documentID := c.Param("id")
+
+	metadata, err := knowledgebase.GetKnowledgeStatus(email, documentID, queryVersion(c))
+	if err != nil {
+		abortKnowledgeError(c, ErrGetKnowledgeStatus, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Response{
+		Data: toKnowledgeStatusResponse(metadata),
+	})
+}
+
+// StreamKnowledgeStatus 使用 SSE 推送知识文件的处理状态，处理完成或失败后结束
+func StreamKnowledgeStatus(c *gin.Context) {
+	email := c.GetString("email")
+	documentID := c.Param("id")
+	version := queryVersion(c)
+
+	// 开始推送前先查询一次，知识文件不存在时直接返回 404
+	metadata, err := knowledgebase.GetKnowledgeStatus(email, documentID, version)
+	if err != nil {
+		abortKnowledgeError(c, ErrGetKnowledgeStatus, err)
+		return
+	}
+
+	utils.SetSSEHeaders(c)
+
+	ticker := time.NewTicker(statusPollInterval)
+	defer ticker.Stop()
+
+	var last response.KnowledgeStatusResponse
+	for {
+		// 只在状态变化时推送
+		status := toKnowledgeStatusResponse(metadata)
+		if status != last {
+			utils.SendSSEMessage(c, utils.EventStatus, status)
+			last = status
+		}
+
+		if metadata.Status == model.StatusProcessed || metadata.Status == model.StatusProcessedFailed {
+			utils.SendSSEMessage(c, utils.EventDone, "")
+			return
+		}
+
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-ticker.C:
+		}
+
+		metadata, err = knowledgebase.GetKnowledgeStatus(email, documentID, version)
+		if err != nil {
+			slog.Error(ErrGetKnowledgeStatus.Error(), "err", err)
+			utils.SendSSEMessage(c, utils.EventError, ErrGetKnowledgeStatus)
+			utils.SendSSEMessage(c, utils.EventDone, "")
+			return
+		}
+	}
+}
+
+// abortKnowledgeError 上传无效返回 400，知识文件不存在返回 404，版本状态冲突返回 409，其他错误返回 500
+func abortKnowledgeError(c *gin.Context, fallback error, err error) {
+	switch {
+	case errors.Is(err, knowledgebase.ErrInvalidUpload):
+		c.AbortWithStatusJSON(http.StatusBadRequest, response.Response{
+			Msg: err.Error(),
+		})
+	case errors.Is(err, knowledgebase.ErrKnowledgeNotFound):
+		c.AbortWithStatusJSON(http.StatusNotFound, response.Response{
+			Msg: err.Error(),
+		})
+	case errors.Is(err, knowledgebase.ErrKnowledgeConflict):
+		c.AbortWithStatusJSON(http.StatusConflict, response.Response{
+			Msg: err.Error(),
+		})
+	default:
+		slog.Error(fallback.Error(), "err", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, response.Response{
+			Msg: fallback.Error(),
+		})
+	}
+}
+
+func toMetadataResponse(metadata *model.KnowledgeMetadata) response.MetadataResponse {
+	return response.MetadataResponse{
+		DocumentID:              metadata.DocumentID,
+		FileName:                metadata.FileName,
+		FileType:                string(metadata.FileType),
+		FileSize:                metadata.FileSize,
+		KnowledgeStatusResponse: toKnowledgeStatusResponse(metadata),
+		Chunking: response.ChunkingResponse{
+			Strategy:     string(metadata.Chunking.Strategy),
+			ChunkSize:    metadata.Chunking.ChunkSize,
+			ChunkOverlap: metadata.Chunking.ChunkOverlap,
+		},
+	}
+}
+
+func toKnowledgeStatusResponse(metadata *model.KnowledgeMetadata) response.KnowledgeStatusResponse {
+	return response.KnowledgeStatusResponse{
+		Version:           metadata.Version,
+		Status:            string(metadata.Status),
+		Stage:             string(metadata.Stage),
+		Progress:          metadata.Progress,
+		ChunkCount:        metadata.ChunkCount,
+		ErrorMessage:      metadata.ErrorMessage,
+		ExtractionQuality: string(metadata.ExtractionQuality),
+	}
+}
@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"diabetes-agent-backend/response"
+	"diabetes-agent-backend/service/knowledge-base/labreport"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetLabResults 查询从化验单中提取的检验结果，可按检验项目和知识文件过滤
+func GetLabResults(c *gin.Context) {
+	email := c.GetString("email")
+	analyte := c.Query("analyte")
+	documentID := c.Query("document-id")
+
+	results, err := labreport.GetLabResults(email, analyte, documentID)
+	if err != nil {
+		slog.Error(ErrGetLabResults.Error(), "err", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, response.Response{
+			Msg: ErrGetLabResults.Error(),
+		})
+		return
+	}
+
+	var resp response.GetLabResultsResponse
+	for _, item := range results {
+		resp.Results = append(resp.Results, response.LabResultResponse{
+			DocumentID:     item.DocumentID,
+			Analyte:        item.Analyte,
+			Name:           item.Name,
+			Value:          item.Value,
+			Unit:           item.Unit,
+			RawValue:       item.RawValue,
+			RawUnit:        item.RawUnit,
+			ReferenceLow:   item.ReferenceLow,
+			ReferenceHigh:  item.ReferenceHigh,
+			ReferenceRange: item.ReferenceRange,
+			SampledAt:      item.SampledAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, response.Response{
+		Data: resp,
+	})
+}
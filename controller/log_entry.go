@@ -0,0 +1,227 @@
+package controller
+
+import (
+	"diabetes-agent-backend/model"
+	"diabetes-agent-backend/request"
+	"diabetes-agent-backend/response"
+	"diabetes-agent-backend/service/logbook"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateLogEntry 录入一条用药、胰岛素注射或饮食记录
+func CreateLogEntry(c *gin.Context) {
+	var req request.LogEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Error(ErrParseRequest.Error(), "err", err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, response.Response{
+			Msg: ErrParseRequest.Error(),
+		})
+		return
+	}
+
+	email := c.GetString("email")
+	entry, err := logbook.CreateEntry(email, req)
+	if err != nil {
+		abortLogbookError(c, ErrCreateLogEntry, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Response{
+		Data: toLogEntryResponse(entry),
+	})
+}
+
+// GetLogEntries 查询时间范围内的日志记录，可以按 kind 过滤
+func GetLogEntries(c *gin.Context) {
+	email := c.GetString("email")
+	from, to, ok := parseGlucoseRange(c, email, ErrGetLogEntries)
+	if !ok {
+		return
+	}
+
+	entries, err := logbook.GetEntries(email, c.Query("kind"), from, to)
+	if err != nil {
+		abortLogbookError(c, ErrGetLogEntries, err)
+		return
+	}
+
+	var resp response.GetLogEntriesResponse
+	for i := range entries {
+		resp.Entries = append(resp.Entries, toLogEntryResponse(&entries[i]))
+	}
+
+	c.JSON(http.StatusOK, response.Response{
+		Data: resp,
+	})
+}
+
+// UpdateLogEntry 整体替换一条日志记录的内容
+func UpdateLogEntry(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		slog.Error(ErrParseRequest.Error(), "err", err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, response.Response{
+			Msg: ErrParseRequest.Error(),
+		})
+		return
+	}
+
+	var req request.LogEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Error(ErrParseRequest.Error(), "err", err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, response.Response{
+			Msg: ErrParseRequest.Error(),
+		})
+		return
+	}
+
+	email := c.GetString("email")
+	entry, err := logbook.UpdateEntry(email, uint(id), req)
+	if err != nil {
+		abortLogbookError(c, ErrUpdateLogEntry, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Response{
+		Data: toLogEntryResponse(entry),
+	})
+}
+
+// DeleteLogEntry 删除一条日志记录
+func DeleteLogEntry(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		slog.Error(ErrParseRequest.Error(), "err", err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, response.Response{
+			Msg: ErrParseRequest.Error(),
+		})
+		return
+	}
+
+	email := c.GetString("email")
+	if err := logbook.DeleteEntry(email, uint(id)); err != nil {
+		abortLogbookError(c, ErrDeleteLogEntry, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Response{})
+}
+
+// GetPendingLogActions 查询 Agent 提出的、等待用户确认的操作
+func GetPendingLogActions(c *gin.Context) {
+	email := c.GetString("email")
+
+	actions, err := logbook.GetPendingActions(email)
+	if err != nil {
+		slog.Error(ErrGetPendingLogActions.Error(), "err", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, response.Response{
+			Msg: ErrGetPendingLogActions.Error(),
+		})
+		return
+	}
+
+	var resp response.GetPendingLogActionsResponse
+	for _, item := range actions {
+		resp.Actions = append(resp.Actions, response.PendingLogActionResponse{
+			ID:        item.ID,
+			SessionID: item.SessionID,
+			Action:    string(item.Action),
+			EntryID:   item.EntryID,
+			Summary:   item.Summary,
+			CreatedAt: item.CreatedAt,
+			ExpiresAt: item.ExpiresAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, response.Response{
+		Data: resp,
+	})
+}
+
+// ConfirmPendingLogAction 用户在界面上确认 Agent 提出的操作，返回写入或修改后的记录
+func ConfirmPendingLogAction(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		slog.Error(ErrParseRequest.Error(), "err", err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, response.Response{
+			Msg: ErrParseRequest.Error(),
+		})
+		return
+	}
+
+	email := c.GetString("email")
+	entry, err := logbook.ConfirmAction(email, uint(id))
+	if err != nil {
+		abortLogbookError(c, ErrConfirmPendingLogAction, err)
+		return
+	}
+
+	if entry == nil {
+		c.JSON(http.StatusOK, response.Response{})
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Response{
+		Data: toLogEntryResponse(entry),
+	})
+}
+
+// CancelPendingLogAction 用户放弃 Agent 提出的操作
+func CancelPendingLogAction(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		slog.Error(ErrParseRequest.Error(), "err", err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, response.Response{
+			Msg: ErrParseRequest.Error(),
+		})
+		return
+	}
+
+	email := c.GetString("email")
+	if err := logbook.CancelAction(email, uint(id)); err != nil {
+		abortLogbookError(c, ErrCancelPendingLogAction, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Response{})
+}
+
+// abortLogbookError 校验失败返回 400，记录或操作不存在返回 404，其他错误返回 500
+func abortLogbookError(c *gin.Context, fallback error, err error) {
+	switch {
+	case errors.Is(err, logbook.ErrInvalidEntry):
+		c.AbortWithStatusJSON(http.StatusBadRequest, response.Response{
+			Msg: err.Error(),
+		})
+	case errors.Is(err, logbook.ErrEntryNotFound), errors.Is(err, logbook.ErrActionNotFound):
+		c.AbortWithStatusJSON(http.StatusNotFound, response.Response{
+			Msg: err.Error(),
+		})
+	default:
+		slog.Error(fallback.Error(), "err", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, response.Response{
+			Msg: fallback.Error(),
+		})
+	}
+}
+
+func toLogEntryResponse(entry *model.LogEntry) response.LogEntryResponse {
+	return response.LogEntryResponse{
+		ID:          entry.ID,
+		Kind:        string(entry.Kind),
+		Name:        entry.Name,
+		Dose:        entry.Dose,
+		InsulinType: string(entry.InsulinType),
+		Units:       entry.Units,
+		Carbs:       entry.Carbs,
+		MealType:    string(entry.MealType),
+		OccurredAt:  entry.OccurredAt,
+		Note:        entry.Note,
+		Source:      string(entry.Source),
+	}
+}
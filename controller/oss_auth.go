@@ -6,6 +6,7 @@ import (
 	ossauth "diabetes-agent-backend/service/oss-auth"
 	"log/slog"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -16,6 +17,7 @@ func GetPolicyToken(c *gin.Context) {
 		Email:     c.GetString("email"),
 		SessionID: c.Query("session-id"),
 		FileName:  c.Query("file-name"),
+		Version:   queryVersion(c),
 	})
 	if err != nil {
 		slog.Error(ErrGeneratePolicyToken.Error(), "err", err)
@@ -36,6 +38,7 @@ func GetPresignedURL(c *gin.Context) {
 		Email:     c.GetString("email"),
 		SessionID: c.Query("session-id"),
 		FileName:  c.Query("file-name"),
+		Version:   queryVersion(c),
 	})
 	if err != nil {
 		slog.Error(ErrGetPreSignedURL.Error(), "err", err)
@@ -51,3 +54,12 @@ func GetPresignedURL(c *gin.Context) {
 		},
 	})
 }
+
+// queryVersion 解析知识文件版本号，未指定时返回 0
+func queryVersion(c *gin.Context) int {
+	version, err := strconv.Atoi(c.Query("version"))
+	if err != nil {
+		return 0
+	}
+	return version
+}
@@ -0,0 +1,42 @@
+package controller
+
+import (
+	"diabetes-agent-backend/response"
+	"diabetes-agent-backend/service/outbox"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetStuckOutboxMessages 查询用户超时未投递到MQ的后台任务
+func GetStuckOutboxMessages(c *gin.Context) {
+	email := c.GetString("email")
+
+	messages, err := outbox.GetStuckMessages(email)
+	if err != nil {
+		slog.Error(ErrGetStuckOutboxMessages.Error(), "err", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, response.Response{
+			Msg: ErrGetStuckOutboxMessages.Error(),
+		})
+		return
+	}
+
+	var resp response.GetStuckOutboxMessagesResponse
+	for _, item := range messages {
+		resp.Messages = append(resp.Messages, response.OutboxMessageResponse{
+			ID:            item.ID,
+			DocumentID:    item.DocumentID,
+			Topic:         item.Topic,
+			Tag:           item.Tag,
+			Attempts:      item.Attempts,
+			LastError:     item.LastError,
+			NextAttemptAt: item.NextAttemptAt,
+			CreatedAt:     item.CreatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, response.Response{
+		Data: resp,
+	})
+}
@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"diabetes-agent-backend/model"
+	"diabetes-agent-backend/request"
+	"diabetes-agent-backend/response"
+	patientmemory "diabetes-agent-backend/service/patient-memory"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetPatientFacts 查询跨会话保留的长期记忆
+func GetPatientFacts(c *gin.Context) {
+	email := c.GetString("email")
+
+	facts, err := patientmemory.GetFacts(email)
+	if err != nil {
+		slog.Error(ErrGetPatientFacts.Error(), "err", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, response.Response{
+			Msg: ErrGetPatientFacts.Error(),
+		})
+		return
+	}
+
+	var resp response.GetPatientFactsResponse
+	for _, item := range facts {
+		resp.Facts = append(resp.Facts, response.PatientFactResponse{
+			ID:              item.ID,
+			Category:        string(item.Category),
+			Content:         item.Content,
+			Confidence:      item.Confidence,
+			Source:          string(item.Source),
+			SourceSessionID: item.SourceSessionID,
+			SourceMessageID: item.SourceMessageID,
+			ObservedAt:      item.ObservedAt,
+			CreatedAt:       item.CreatedAt,
+			UpdatedAt:       item.UpdatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, response.Response{
+		Data: resp,
+	})
+}
+
+// UpdatePatientFact 用户编辑长期记忆
+func UpdatePatientFact(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		slog.Error(ErrParseRequest.Error(), "err", err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, response.Response{
+			Msg: ErrParseRequest.Error(),
+		})
+		return
+	}
+
+	var req request.UpdatePatientFactRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Error(ErrParseRequest.Error(), "err", err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, response.Response{
+			Msg: ErrParseRequest.Error(),
+		})
+		return
+	}
+
+	email := c.GetString("email")
+	err = patientmemory.UpdateFact(email, uint(id), model.FactCategory(req.Category), req.Content)
+	if err != nil {
+		abortPatientFactError(c, ErrUpdatePatientFact, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Response{})
+}
+
+// DeletePatientFact 删除长期记忆，之后的对话中不会再提取相同的记忆
+func DeletePatientFact(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		slog.Error(ErrParseRequest.Error(), "err", err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, response.Response{
+			Msg: ErrParseRequest.Error(),
+		})
+		return
+	}
+
+	email := c.GetString("email")
+	if err := patientmemory.DeleteFact(email, uint(id)); err != nil {
+		abortPatientFactError(c, ErrDeletePatientFact, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Response{})
+}
+
+// abortPatientFactError 校验失败返回 400，记忆不存在或不属于该用户返回 404，其他错误返回 500
+func abortPatientFactError(c *gin.Context, fallback error, err error) {
+	switch {
+	case errors.Is(err, patientmemory.ErrInvalidFact):
+		c.AbortWithStatusJSON(http.StatusBadRequest, response.Response{
+			Msg: err.Error(),
+		})
+	case errors.Is(err, patientmemory.ErrFactNotFound):
+		c.AbortWithStatusJSON(http.StatusNotFound, response.Response{
+			Msg: err.Error(),
+		})
+	default:
+		slog.Error(fallback.Error(), "err", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, response.Response{
+			Msg: fallback.Error(),
+		})
+	}
+}
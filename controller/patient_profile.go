@@ -0,0 +1,146 @@
+package controller
+
+import (
+	"diabetes-agent-backend/model"
+	"diabetes-agent-backend/request"
+	"diabetes-agent-backend/response"
+	"diabetes-agent-backend/service/profile"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetPatientProfile 查询健康档案，未填写时 Data 为空
+func GetPatientProfile(c *gin.Context) {
+	email := c.GetString("email")
+
+	patientProfile, err := profile.GetProfile(email)
+	if err != nil {
+		slog.Error(ErrGetPatientProfile.Error(), "err", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, response.Response{
+			Msg: ErrGetPatientProfile.Error(),
+		})
+		return
+	}
+
+	if patientProfile == nil {
+		c.JSON(http.StatusOK, response.Response{})
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Response{
+		Data: toPatientProfileResponse(patientProfile),
+	})
+}
+
+// SavePatientProfile 创建或整体替换健康档案
+func SavePatientProfile(c *gin.Context) {
+	var req request.SavePatientProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Error(ErrParseRequest.Error(), "err", err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, response.Response{
+			Msg: ErrParseRequest.Error(),
+		})
+		return
+	}
+
+	email := c.GetString("email")
+	patientProfile, err := profile.SaveProfile(email, req)
+	if err != nil {
+		if errors.Is(err, profile.ErrInvalidProfile) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, response.Response{
+				Msg: err.Error(),
+			})
+			return
+		}
+
+		slog.Error(ErrSavePatientProfile.Error(), "err", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, response.Response{
+			Msg: ErrSavePatientProfile.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Response{
+		Data: toPatientProfileResponse(patientProfile),
+	})
+}
+
+// DeletePatientProfile 删除健康档案
+func DeletePatientProfile(c *gin.Context) {
+	email := c.GetString("email")
+
+	if err := profile.DeleteProfile(email); err != nil {
+		slog.Error(ErrDeletePatientProfile.Error(), "err", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, response.Response{
+			Msg: ErrDeletePatientProfile.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Response{})
+}
+
+// toPatientProfileResponse 血糖相关的值换算为用户偏好的单位返回
+func toPatientProfileResponse(patientProfile *model.PatientProfile) response.PatientProfileResponse {
+	unit := patientProfile.GlucoseUnit
+	resp := response.PatientProfileResponse{
+		DiabetesType:  string(patientProfile.DiabetesType),
+		HeightCM:      patientProfile.HeightCM,
+		WeightKG:      patientProfile.WeightKG,
+		BMI:           profile.BMI(patientProfile),
+		Allergies:     patientProfile.Allergies,
+		Comorbidities: patientProfile.Comorbidities,
+		TargetLow:     convertGlucose(unit, patientProfile.TargetLow),
+		TargetHigh:    convertGlucose(unit, patientProfile.TargetHigh),
+		GlucoseUnit:   string(unit),
+		Timezone:      patientProfile.Timezone,
+		UpdatedAt:     patientProfile.UpdatedAt,
+	}
+
+	if patientProfile.DiagnosisDate != nil {
+		resp.DiagnosisDate = patientProfile.DiagnosisDate.Format("2006-01-02")
+	}
+
+	for _, item := range patientProfile.Medications {
+		resp.Medications = append(resp.Medications, response.MedicationResponse{
+			Name:      item.Name,
+			Dose:      item.Dose,
+			Frequency: item.Frequency,
+		})
+	}
+
+	if regimen := patientProfile.InsulinRegimen; regimen != nil {
+		resp.InsulinRegimen = &response.InsulinRegimenResponse{
+			Type:             string(regimen.Type),
+			Basal:            toInsulinDoseResponses(regimen.Basal),
+			Bolus:            toInsulinDoseResponses(regimen.Bolus),
+			CarbRatio:        regimen.CarbRatio,
+			CorrectionFactor: convertGlucose(unit, regimen.CorrectionFactor),
+		}
+	}
+	return resp
+}
+
+func toInsulinDoseResponses(doses []model.InsulinDose) []response.InsulinDoseResponse {
+	var result []response.InsulinDoseResponse
+	for _, item := range doses {
+		result = append(result, response.InsulinDoseResponse{
+			Name:  item.Name,
+			Units: item.Units,
+			Time:  item.Time,
+		})
+	}
+	return result
+}
+
+// convertGlucose 将 mmol/L 的值换算为 unit 单位，未设置时返回 nil
+func convertGlucose(unit model.GlucoseUnit, value *float64) *float64 {
+	if value == nil {
+		return nil
+	}
+	converted := roundGlucose(unit, *value)
+	return &converted
+}
@@ -0,0 +1,24 @@
+package dao
+
+import (
+	"diabetes-agent-backend/model"
+
+	"gorm.io/gorm/clause"
+)
+
+func GetEmbeddingCache(modelKey string, hashes []string) ([]model.EmbeddingCache, error) {
+	var entries []model.EmbeddingCache
+	if err := DB.Where("model_key = ? AND content_hash IN ?", modelKey, hashes).
+		Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// SaveEmbeddingCache 写入向量缓存，已存在的记录保持不变
+func SaveEmbeddingCache(entries []model.EmbeddingCache) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&entries).Error
+}
@@ -0,0 +1,42 @@
+package dao
+
+import (
+	"diabetes-agent-backend/model"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+func CreateGlucoseImport(tx *gorm.DB, glucoseImport *model.GlucoseImport) error {
+	return tx.Create(glucoseImport).Error
+}
+
+// GetGlucoseImport 返回导入任务，不存在时返回 nil
+func GetGlucoseImport(id uint) (*model.GlucoseImport, error) {
+	var glucoseImport model.GlucoseImport
+	if err := DB.First(&glucoseImport, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &glucoseImport, nil
+}
+
+// GetGlucoseImports 按创建时间倒序返回用户最近的导入任务
+func GetGlucoseImports(email string, limit int) ([]model.GlucoseImport, error) {
+	var imports []model.GlucoseImport
+	if err := DB.Where("user_email = ?", email).
+		Order("id DESC").
+		Limit(limit).
+		Find(&imports).Error; err != nil {
+		return nil, err
+	}
+	return imports, nil
+}
+
+func UpdateGlucoseImport(id uint, updates map[string]any) error {
+	return DB.Model(&model.GlucoseImport{}).
+		Where("id = ?", id).
+		Updates(updates).Error
+}
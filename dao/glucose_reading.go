@@ -0,0 +1,41 @@
+package dao
+
+import (
+	"diabetes-agent-backend/model"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// 批量写入血糖记录时每批的条数
+const glucoseBatchSize = 500
+
+// CreateGlucoseReadings 批量写入血糖记录，已存在的记录被忽略，返回新写入的条数
+func CreateGlucoseReadings(readings []model.GlucoseReading) (int64, error) {
+	if len(readings) == 0 {
+		return 0, nil
+	}
+
+	result := DB.Clauses(clause.OnConflict{DoNothing: true}).
+		CreateInBatches(&readings, glucoseBatchSize)
+	return result.RowsAffected, result.Error
+}
+
+// GetGlucoseReadings 按测量时间升序返回 [from, to) 内的血糖记录
+func GetGlucoseReadings(email string, from, to time.Time) ([]model.GlucoseReading, error) {
+	var readings []model.GlucoseReading
+	if err := DB.Where("user_email = ? AND measured_at >= ? AND measured_at < ?", email, from, to).
+		Order("measured_at").
+		Order("id").
+		Find(&readings).Error; err != nil {
+		return nil, err
+	}
+	return readings, nil
+}
+
+// DeleteGlucoseReading 删除用户的一条血糖记录，记录不存在时返回 false
+func DeleteGlucoseReading(email string, id uint) (bool, error) {
+	result := DB.Where("user_email = ? AND id = ?", email, id).
+		Delete(&model.GlucoseReading{})
+	return result.RowsAffected > 0, result.Error
+}
@@ -10,7 +10,8 @@ import (
 
 var DB *gorm.DB
 
-func init() {
+// Init 连接数据库，需要在 config.Init 之后调用
+func Init() error {
 	dbConfig := config.Cfg.DB.MySQL
 
 	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
@@ -24,6 +25,7 @@ func init() {
 	var err error
 	DB, err = gorm.Open(mysql.Open(dsn))
 	if err != nil {
-		panic(fmt.Sprintf("Failed to connect database: %v", err))
+		return fmt.Errorf("failed to connect database: %v", err)
 	}
+	return nil
 }
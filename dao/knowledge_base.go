@@ -4,25 +4,48 @@ import (
 	"diabetes-agent-backend/model"
 	"diabetes-agent-backend/request"
 	"errors"
+	"time"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
-func SaveKnowledgeMetadata(req request.UploadKnowledgeMetadataRequest, email string) error {
+// SaveKnowledgeMetadata 在事务中保存知识文件的一个版本，documentID 为空时生成新的文档 ID
+func SaveKnowledgeMetadata(tx *gorm.DB, req request.UploadKnowledgeMetadataRequest, email, documentID string, version int, chunking model.Chunking) (*model.KnowledgeMetadata, error) {
+	if documentID == "" {
+		documentID = uuid.New().String()
+	}
+
 	fileMetadata := model.KnowledgeMetadata{
 		UserEmail:  email,
+		DocumentID: documentID,
+		Version:    version,
+		UploadedAt: time.Now(),
 		FileName:   req.FileName,
 		FileType:   model.FileType(req.FileType),
 		FileSize:   req.FileSize,
 		ObjectName: req.ObjectName,
+		Chunking:   chunking,
 		Status:     model.StatusUploaded,
 	}
-	return DB.Create(&fileMetadata).Error
+	if err := tx.Create(&fileMetadata).Error; err != nil {
+		return nil, err
+	}
+	return &fileMetadata, nil
 }
 
+// latestVersionCondition 每个知识文件只保留一个版本：优先取处理完成且未被替换的最新版本，
+// 没有处理完成的版本时取最新上传的版本，参数为 model.StatusProcessed
+const latestVersionCondition = `version = COALESCE(
+	(SELECT MAX(p.version) FROM knowledge_metadata p
+	WHERE p.document_id = knowledge_metadata.document_id AND p.status = ? AND p.superseded = false),
+	(SELECT MAX(v.version) FROM knowledge_metadata v
+	WHERE v.document_id = knowledge_metadata.document_id))`
+
 func GetKnowledgeMetadataByEmail(email string) ([]model.KnowledgeMetadata, error) {
 	var fileMetadata []model.KnowledgeMetadata
 	if err := DB.Where("user_email = ?", email).
+		Where(latestVersionCondition, model.StatusProcessed).
 		Order("created_at DESC").
 		Find(&fileMetadata).Error; err != nil {
 		return nil, err
@@ -30,9 +53,11 @@ func GetKnowledgeMetadataByEmail(email string) ([]model.KnowledgeMetadata, error
 	return fileMetadata, nil
 }
 
+// GetKnowledgeMetadataByEmailAndFileName 返回同名知识文件的最新版本
 func GetKnowledgeMetadataByEmailAndFileName(email, fileName string) (*model.KnowledgeMetadata, error) {
 	var fileMetadata model.KnowledgeMetadata
 	if err := DB.Where("user_email = ? AND file_name = ?", email, fileName).
+		Order("version DESC").
 		First(&fileMetadata).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
@@ -42,22 +67,175 @@ func GetKnowledgeMetadataByEmailAndFileName(email, fileName string) (*model.Know
 	return &fileMetadata, nil
 }
 
-func DeleteKnowledgeMetadataByEmailAndFileName(email, fileName string) error {
-	return DB.Where("user_email = ? AND file_name = ?", email, fileName).
+func GetKnowledgeMetadataVersions(email, documentID string) ([]model.KnowledgeMetadata, error) {
+	var versions []model.KnowledgeMetadata
+	if err := DB.Where("user_email = ? AND document_id = ?", email, documentID).
+		Order("version DESC").
+		Find(&versions).Error; err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+func GetKnowledgeMetadataVersion(email, documentID string, version int) (*model.KnowledgeMetadata, error) {
+	var fileMetadata model.KnowledgeMetadata
+	if err := DB.Where("user_email = ? AND document_id = ? AND version = ?", email, documentID, version).
+		First(&fileMetadata).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &fileMetadata, nil
+}
+
+func GetKnowledgeMetadataByID(id uint) (*model.KnowledgeMetadata, error) {
+	var fileMetadata model.KnowledgeMetadata
+	if err := DB.Where("id = ?", id).First(&fileMetadata).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &fileMetadata, nil
+}
+
+// DeleteKnowledgeMetadataByDocumentID 在事务中删除知识文件的所有版本
+func DeleteKnowledgeMetadataByDocumentID(tx *gorm.DB, documentID string) error {
+	return tx.Where("document_id = ?", documentID).
 		Delete(&model.KnowledgeMetadata{}).Error
 }
 
-func UpdateKnowledgeMetadataStatus(email, fileName string, status model.Status) error {
+func UpdateKnowledgeMetadataStatus(id uint, status model.Status) error {
 	return DB.Model(&model.KnowledgeMetadata{}).
-		Where("user_email = ? AND file_name = ?", email, fileName).
+		Where("id = ?", id).
 		Update("status", status).Error
 }
 
+// UpdateKnowledgeMetadataProgress 更新处理阶段和进度，chunkCount 为 0 时不更新切片数量
+func UpdateKnowledgeMetadataProgress(id uint, stage model.Stage, progress, chunkCount int) error {
+	updates := map[string]any{
+		"stage":    stage,
+		"progress": progress,
+	}
+	if chunkCount > 0 {
+		updates["chunk_count"] = chunkCount
+	}
+
+	return DB.Model(&model.KnowledgeMetadata{}).
+		Where("id = ?", id).
+		Updates(updates).Error
+}
+
+func UpdateKnowledgeMetadataError(id uint, errorMessage string) error {
+	return DB.Model(&model.KnowledgeMetadata{}).
+		Where("id = ?", id).
+		Update("error_message", errorMessage).Error
+}
+
+// UpdateKnowledgeMetadataCheckpoint 记录已写入向量存储的切片数量
+func UpdateKnowledgeMetadataCheckpoint(id uint, fingerprint string, checkpoint int) error {
+	return DB.Model(&model.KnowledgeMetadata{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"chunk_fingerprint": fingerprint,
+			"checkpoint_chunks": checkpoint,
+		}).Error
+}
+
+func UpdateKnowledgeMetadataQuality(id uint, quality model.ExtractionQuality) error {
+	return DB.Model(&model.KnowledgeMetadata{}).
+		Where("id = ?", id).
+		Update("extraction_quality", quality).Error
+}
+
+// MarkKnowledgeMetadataFailed 标记处理失败，记录失败原因
+func MarkKnowledgeMetadataFailed(id uint, errorMessage string) error {
+	return DB.Model(&model.KnowledgeMetadata{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"status":        model.StatusProcessedFailed,
+			"error_message": errorMessage,
+		}).Error
+}
+
+// ResetKnowledgeMetadataProgress 在事务中清空处理状态，用于重新处理
+func ResetKnowledgeMetadataProgress(tx *gorm.DB, id uint) error {
+	return tx.Model(&model.KnowledgeMetadata{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"status":             model.StatusUploaded,
+			"stage":              "",
+			"progress":           0,
+			"chunk_count":        0,
+			"error_message":      "",
+			"extraction_quality": "",
+			"checkpoint_chunks":  0,
+			"chunk_fingerprint":  "",
+		}).Error
+}
+
+// GetLatestUpdatedKnowledgeMetadata 返回知识文件最近更新的版本，即正在处理或最近处理完成的版本
+func GetLatestUpdatedKnowledgeMetadata(email, documentID string) (*model.KnowledgeMetadata, error) {
+	var fileMetadata model.KnowledgeMetadata
+	if err := DB.Where("user_email = ? AND document_id = ?", email, documentID).
+		Order("updated_at DESC").
+		First(&fileMetadata).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &fileMetadata, nil
+}
+
+// GetActiveKnowledgeMetadataIDs 返回用户知识文件当前生效版本的 ID，即处理完成且未被替换的版本，documentIDs 为空时返回全部知识文件
+func GetActiveKnowledgeMetadataIDs(email string, documentIDs []string) ([]uint, error) {
+	query := DB.Model(&model.KnowledgeMetadata{}).
+		Where("user_email = ? AND status = ? AND superseded = false", email, model.StatusProcessed)
+	if len(documentIDs) > 0 {
+		query = query.Where("document_id IN ?", documentIDs)
+	}
+
+	var ids []uint
+	if err := query.Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// ActivateKnowledgeMetadataVersion 将指定版本标记为处理完成的当前版本，同一知识文件的其他版本标记为已替换
+func ActivateKnowledgeMetadataVersion(id uint) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		var fileMetadata model.KnowledgeMetadata
+		if err := tx.Where("id = ?", id).First(&fileMetadata).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&model.KnowledgeMetadata{}).
+			Where("document_id = ? AND id <> ?", fileMetadata.DocumentID, id).
+			Update("superseded", true).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&model.KnowledgeMetadata{}).
+			Where("id = ?", id).
+			Updates(map[string]any{
+				"superseded":    false,
+				"status":        model.StatusProcessed,
+				"stage":         model.StageCompleted,
+				"progress":      100,
+				"error_message": "",
+			}).Error
+	})
+}
+
 func SearchKnowledgeMetadataByFullText(email, query string) ([]model.KnowledgeMetadata, error) {
 	var fileMetadata []model.KnowledgeMetadata
 
 	// 使用全文索引做左右模糊匹配
 	err := DB.Where("user_email = ? AND MATCH(file_name) AGAINST(? IN BOOLEAN MODE)", email, "*"+query+"*").
+		Where(latestVersionCondition, model.StatusProcessed).
 		Order("created_at DESC").
 		Find(&fileMetadata).Error
 
@@ -67,3 +245,21 @@ func SearchKnowledgeMetadataByFullText(email, query string) ([]model.KnowledgeMe
 
 	return fileMetadata, nil
 }
+
+// GetKnowledgeMetadataWithoutDocumentID 返回引入文档 ID 之前上传、尚未回填文档 ID 的记录
+func GetKnowledgeMetadataWithoutDocumentID() ([]model.KnowledgeMetadata, error) {
+	var fileMetadata []model.KnowledgeMetadata
+	if err := DB.Where("document_id = ''").
+		Order("id").
+		Find(&fileMetadata).Error; err != nil {
+		return nil, err
+	}
+	return fileMetadata, nil
+}
+
+// BackfillKnowledgeDocumentID 为旧记录写入文档 ID，已有文档 ID 的记录不会被覆盖
+func BackfillKnowledgeDocumentID(id uint, documentID string) error {
+	return DB.Model(&model.KnowledgeMetadata{}).
+		Where("id = ? AND document_id = ''", id).
+		Update("document_id", documentID).Error
+}
@@ -0,0 +1,46 @@
+package dao
+
+import (
+	"diabetes-agent-backend/model"
+
+	"gorm.io/gorm"
+)
+
+// ReplaceLabResults 替换知识文件的化验结果，只保留最新处理版本的提取结果
+func ReplaceLabResults(documentID string, results []model.LabResult) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("document_id = ?", documentID).
+			Delete(&model.LabResult{}).Error; err != nil {
+			return err
+		}
+
+		if len(results) == 0 {
+			return nil
+		}
+		return tx.Create(&results).Error
+	})
+}
+
+func DeleteLabResultsByDocumentID(tx *gorm.DB, documentID string) error {
+	return tx.Where("document_id = ?", documentID).
+		Delete(&model.LabResult{}).Error
+}
+
+// GetLabResults 查询用户的化验结果，analyte 和 documentID 为空时不过滤
+func GetLabResults(email, analyte, documentID string) ([]model.LabResult, error) {
+	query := DB.Where("user_email = ?", email)
+	if analyte != "" {
+		query = query.Where("analyte = ?", analyte)
+	}
+	if documentID != "" {
+		query = query.Where("document_id = ?", documentID)
+	}
+
+	var results []model.LabResult
+	if err := query.Order("sampled_at DESC").
+		Order("id ASC").
+		Find(&results).Error; err != nil {
+		return nil, err
+	}
+	return results, nil
+}
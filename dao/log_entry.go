@@ -0,0 +1,94 @@
+package dao
+
+import (
+	"diabetes-agent-backend/model"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func CreateLogEntry(tx *gorm.DB, entry *model.LogEntry) error {
+	return tx.Create(entry).Error
+}
+
+// GetLogEntries 按发生时间升序返回 [from, to) 内的日志记录，kind 为空时返回所有类型
+func GetLogEntries(email string, kind model.LogKind, from, to time.Time) ([]model.LogEntry, error) {
+	query := DB.Where("user_email = ? AND occurred_at >= ? AND occurred_at < ?", email, from, to)
+	if kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+
+	var entries []model.LogEntry
+	if err := query.Order("occurred_at").Order("id").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// GetLogEntry 查询用户的一条日志记录，不存在时返回 nil
+func GetLogEntry(email string, id uint) (*model.LogEntry, error) {
+	var entry model.LogEntry
+	if err := DB.Where("user_email = ? AND id = ?", email, id).
+		First(&entry).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// UpdateLogEntry 更新用户的一条日志记录，记录不存在时返回 false
+func UpdateLogEntry(tx *gorm.DB, email string, id uint, updates map[string]any) (bool, error) {
+	result := tx.Model(&model.LogEntry{}).
+		Where("user_email = ? AND id = ?", email, id).
+		Updates(updates)
+	return result.RowsAffected > 0, result.Error
+}
+
+// DeleteLogEntry 软删除用户的一条日志记录，记录不存在时返回 false
+func DeleteLogEntry(tx *gorm.DB, email string, id uint) (bool, error) {
+	result := tx.Where("user_email = ? AND id = ?", email, id).
+		Delete(&model.LogEntry{})
+	return result.RowsAffected > 0, result.Error
+}
+
+func CreatePendingLogAction(action *model.PendingLogAction) error {
+	return DB.Create(action).Error
+}
+
+// GetPendingLogAction 查询用户的一条待确认操作，不存在时返回 nil
+func GetPendingLogAction(email string, id uint) (*model.PendingLogAction, error) {
+	var action model.PendingLogAction
+	if err := DB.Where("user_email = ? AND id = ?", email, id).
+		First(&action).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &action, nil
+}
+
+// GetPendingLogActions 返回用户尚未确认且未过期的操作
+func GetPendingLogActions(email string, now time.Time) ([]model.PendingLogAction, error) {
+	var actions []model.PendingLogAction
+	if err := DB.Where("user_email = ? AND status = ? AND expires_at > ?",
+		email, model.PendingStatusPending, now).
+		Order("id").
+		Find(&actions).Error; err != nil {
+		return nil, err
+	}
+	return actions, nil
+}
+
+// ResolvePendingLogAction 将未过期的待确认操作更新为 status，
+// 只有一个请求能够成功，操作已处理或已过期时返回 false
+func ResolvePendingLogAction(tx *gorm.DB, email string, id uint, status model.PendingStatus, now time.Time) (bool, error) {
+	result := tx.Model(&model.PendingLogAction{}).
+		Where("user_email = ? AND id = ? AND status = ? AND expires_at > ?",
+			email, id, model.PendingStatusPending, now).
+		Update("status", status)
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,105 @@
+package dao
+
+import (
+	"diabetes-agent-backend/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Transaction 在同一事务中执行多个数据库操作
+func Transaction(fn func(tx *gorm.DB) error) error {
+	return DB.Transaction(fn)
+}
+
+// CreateOutboxMessage 在业务事务中写入待投递的消息
+func CreateOutboxMessage(tx *gorm.DB, message *model.OutboxMessage) error {
+	return tx.Create(message).Error
+}
+
+// ClaimOutboxMessages 领取到期的待投递消息，并将其下次投递时间推迟 lease
+// 使用 SKIP LOCKED，多个实例同时领取时不会拿到相同的消息
+func ClaimOutboxMessages(limit int, lease time.Duration) ([]model.OutboxMessage, error) {
+	var messages []model.OutboxMessage
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", model.OutboxStatusPending, now).
+			Order("next_attempt_at").
+			Limit(limit).
+			Find(&messages).Error; err != nil {
+			return err
+		}
+		if len(messages) == 0 {
+			return nil
+		}
+
+		ids := make([]uint, 0, len(messages))
+		for _, message := range messages {
+			ids = append(ids, message.ID)
+		}
+		return tx.Model(&model.OutboxMessage{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(lease)).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+func MarkOutboxMessageSent(id uint) error {
+	return DB.Model(&model.OutboxMessage{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"status":     model.OutboxStatusSent,
+			"attempts":   gorm.Expr("attempts + 1"),
+			"last_error": "",
+			"sent_at":    time.Now(),
+		}).Error
+}
+
+// MarkOutboxMessageFailed 记录投递失败，nextAttemptAt 后重新投递
+func MarkOutboxMessageFailed(id uint, errorMessage string, nextAttemptAt time.Time) error {
+	return DB.Model(&model.OutboxMessage{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"attempts":        gorm.Expr("attempts + 1"),
+			"last_error":      errorMessage,
+			"next_attempt_at": nextAttemptAt,
+		}).Error
+}
+
+// GetStuckOutboxMessages 返回在 before 之前创建且仍未投递的消息，email 为空时不按用户过滤
+func GetStuckOutboxMessages(email string, before time.Time, limit int) ([]model.OutboxMessage, error) {
+	query := DB.Where("status = ? AND created_at < ?", model.OutboxStatusPending, before)
+	if email != "" {
+		query = query.Where("user_email = ?", email)
+	}
+
+	var messages []model.OutboxMessage
+	if err := query.Order("created_at").
+		Limit(limit).
+		Find(&messages).Error; err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+func CountStuckOutboxMessages(before time.Time) (int64, error) {
+	var count int64
+	if err := DB.Model(&model.OutboxMessage{}).
+		Where("status = ? AND created_at < ?", model.OutboxStatusPending, before).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// DeleteSentOutboxMessages 清理 before 之前已投递的消息
+func DeleteSentOutboxMessages(before time.Time) (int64, error) {
+	result := DB.Where("status = ? AND sent_at < ?", model.OutboxStatusSent, before).
+		Delete(&model.OutboxMessage{})
+	return result.RowsAffected, result.Error
+}
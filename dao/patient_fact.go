@@ -0,0 +1,62 @@
+package dao
+
+import (
+	"diabetes-agent-backend/model"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+func GetPatientFacts(email string) ([]model.PatientFact, error) {
+	var facts []model.PatientFact
+	if err := DB.Where("user_email = ?", email).
+		Order("category").
+		Order("observed_at DESC").
+		Find(&facts).Error; err != nil {
+		return nil, err
+	}
+	return facts, nil
+}
+
+// GetPatientFact 返回用户的一条记忆，不存在时返回 nil
+func GetPatientFact(email string, id uint) (*model.PatientFact, error) {
+	var fact model.PatientFact
+	if err := DB.Where("user_email = ? AND id = ?", email, id).
+		First(&fact).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &fact, nil
+}
+
+// GetDeletedPatientFacts 返回用户删除的记忆
+func GetDeletedPatientFacts(email string) ([]model.PatientFact, error) {
+	var facts []model.PatientFact
+	if err := DB.Unscoped().
+		Where("user_email = ? AND deleted_at IS NOT NULL", email).
+		Find(&facts).Error; err != nil {
+		return nil, err
+	}
+	return facts, nil
+}
+
+func CreatePatientFact(fact *model.PatientFact) error {
+	return DB.Create(fact).Error
+}
+
+// UpdatePatientFact 更新用户的一条记忆，返回记忆是否存在
+func UpdatePatientFact(email string, id uint, updates map[string]any) (bool, error) {
+	result := DB.Model(&model.PatientFact{}).
+		Where("user_email = ? AND id = ?", email, id).
+		Updates(updates)
+	return result.RowsAffected > 0, result.Error
+}
+
+// DeletePatientFact 软删除用户的一条记忆，返回记忆是否存在
+func DeletePatientFact(email string, id uint) (bool, error) {
+	result := DB.Where("user_email = ? AND id = ?", email, id).
+		Delete(&model.PatientFact{})
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,42 @@
+package dao
+
+import (
+	"diabetes-agent-backend/model"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetPatientProfile 返回用户的健康档案，不存在时返回 nil
+func GetPatientProfile(email string) (*model.PatientProfile, error) {
+	var profile model.PatientProfile
+	if err := DB.Where("user_email = ?", email).
+		First(&profile).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// SavePatientProfile 创建或整体替换用户的健康档案
+func SavePatientProfile(profile *model.PatientProfile) error {
+	return DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_email"}},
+		UpdateAll: true,
+	}).Create(profile).Error
+}
+
+// FillPatientProfileTimezone 在健康档案未设置时区时写入时区，没有健康档案时不创建
+func FillPatientProfileTimezone(email, timezone string) error {
+	return DB.Model(&model.PatientProfile{}).
+		Where("user_email = ? AND timezone = ''", email).
+		Update("timezone", timezone).Error
+}
+
+func DeletePatientProfile(email string) error {
+	return DB.Where("user_email = ?", email).
+		Delete(&model.PatientProfile{}).Error
+}
@@ -0,0 +1,64 @@
+package dao
+
+import (
+	"diabetes-agent-backend/model"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// ClaimProcessedMessage 领取消息的处理权
+// 返回 claimed 表示可以处理该消息，completed 表示该消息已处理完成，两者都为 false 表示其他消费者正在处理
+func ClaimProcessedMessage(message *model.ProcessedMessage, lease time.Duration) (claimed, completed bool, err error) {
+	now := time.Now()
+	message.Status = model.ProcessedStatusProcessing
+	message.LockedUntil = now.Add(lease)
+
+	result := DB.Clauses(clause.OnConflict{DoNothing: true}).Create(message)
+	if result.Error != nil {
+		return false, false, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return true, false, nil
+	}
+
+	var existing model.ProcessedMessage
+	if err := DB.Where("idempotency_key = ?", message.IdempotencyKey).
+		First(&existing).Error; err != nil {
+		return false, false, err
+	}
+	if existing.Status == model.ProcessedStatusCompleted {
+		return false, true, nil
+	}
+
+	// 租约过期说明上一个消费者没有完成处理，接管该消息
+	result = DB.Model(&model.ProcessedMessage{}).
+		Where("idempotency_key = ? AND status = ? AND locked_until < ?",
+			message.IdempotencyKey, model.ProcessedStatusProcessing, now).
+		Updates(map[string]any{
+			"msg_id":       message.MsgID,
+			"locked_until": message.LockedUntil,
+		})
+	if result.Error != nil {
+		return false, false, result.Error
+	}
+	return result.RowsAffected == 1, false, nil
+}
+
+func CompleteProcessedMessage(key string) error {
+	return DB.Model(&model.ProcessedMessage{}).
+		Where("idempotency_key = ?", key).
+		Update("status", model.ProcessedStatusCompleted).Error
+}
+
+// ReleaseProcessedMessage 处理失败后释放处理权，重试时可以立即重新领取
+func ReleaseProcessedMessage(key string) error {
+	return DB.Where("idempotency_key = ? AND status = ?", key, model.ProcessedStatusProcessing).
+		Delete(&model.ProcessedMessage{}).Error
+}
+
+// DeleteProcessedMessages 清理 before 之前创建的处理记录
+func DeleteProcessedMessages(before time.Time) (int64, error) {
+	result := DB.Where("created_at < ?", before).Delete(&model.ProcessedMessage{})
+	return result.RowsAffected, result.Error
+}
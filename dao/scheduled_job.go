@@ -0,0 +1,79 @@
+package dao
+
+import (
+	"diabetes-agent-backend/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SyncScheduledJob 写入代码中注册的周期任务，已存在时更新任务定义
+// cron 表达式变化时使用新的下次执行时间，否则保留已计算的执行时间
+func SyncScheduledJob(job *model.ScheduledJob) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(job)
+		if result.Error != nil || result.RowsAffected == 1 {
+			return result.Error
+		}
+
+		var existing model.ScheduledJob
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("name = ?", job.Name).
+			First(&existing).Error; err != nil {
+			return err
+		}
+
+		updates := map[string]any{
+			"topic":   job.Topic,
+			"tag":     job.Tag,
+			"payload": job.Payload,
+			"enabled": true,
+		}
+		if existing.CronSpec != job.CronSpec || !existing.Enabled {
+			updates["cron_spec"] = job.CronSpec
+			updates["next_run_at"] = job.NextRunAt
+		}
+		return tx.Model(&model.ScheduledJob{}).
+			Where("id = ?", existing.ID).
+			Updates(updates).Error
+	})
+}
+
+func CreateScheduledJob(job *model.ScheduledJob) error {
+	return DB.Create(job).Error
+}
+
+// ClaimDueScheduledJobs 在事务中锁定到期的任务，需要在同一事务中更新或删除这些任务
+// 使用 SKIP LOCKED，多个实例同时调度时不会拿到相同的任务
+func ClaimDueScheduledJobs(tx *gorm.DB, now time.Time, limit int) ([]model.ScheduledJob, error) {
+	var jobs []model.ScheduledJob
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("enabled = ? AND next_run_at <= ?", true, now).
+		Order("next_run_at").
+		Limit(limit).
+		Find(&jobs).Error; err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// AdvanceScheduledJob 记录周期任务的执行时间和下次执行时间
+func AdvanceScheduledJob(tx *gorm.DB, id uint, lastRunAt, nextRunAt time.Time) error {
+	return tx.Model(&model.ScheduledJob{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"last_run_at": lastRunAt,
+			"next_run_at": nextRunAt,
+		}).Error
+}
+
+func DisableScheduledJob(tx *gorm.DB, id uint) error {
+	return tx.Model(&model.ScheduledJob{}).
+		Where("id = ?", id).
+		Update("enabled", false).Error
+}
+
+func DeleteScheduledJob(tx *gorm.DB, id uint) error {
+	return tx.Delete(&model.ScheduledJob{}, id).Error
+}
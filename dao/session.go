@@ -2,6 +2,11 @@ package dao
 
 import (
 	"diabetes-agent-backend/model"
+	"errors"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
 )
 
 func GetSessionsByEmail(email string) ([]model.Session, error) {
@@ -51,6 +56,79 @@ func GetMessageByID(messageID uint) (*model.Message, error) {
 	return &message, nil
 }
 
+// GetSession 返回会话，不存在时返回 nil
+func GetSession(sessionID string) (*model.Session, error) {
+	var session model.Session
+	if err := DB.Where("session_id = ?", sessionID).
+		First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetRecentMessages 按时间顺序返回 ID 大于 afterID 的最近 limit 条聊天记录
+func GetRecentMessages(sessionID string, afterID uint, limit int) ([]model.Message, error) {
+	var messages []model.Message
+	if err := DB.Where("session_id = ? AND id > ?", sessionID, afterID).
+		Order("id DESC").
+		Limit(limit).
+		Find(&messages).Error; err != nil {
+		return nil, err
+	}
+	slices.Reverse(messages)
+	return messages, nil
+}
+
+// GetMessagesAfter 按时间顺序返回 ID 大于 afterID 的前 limit 条聊天记录
+func GetMessagesAfter(sessionID string, afterID uint, limit int) ([]model.Message, error) {
+	var messages []model.Message
+	if err := DB.Where("session_id = ? AND id > ?", sessionID, afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&messages).Error; err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// UpdateSessionSummary 更新会话的滚动摘要
+// 仅在摘要仍停留在 prevMessageID 时更新，并发生成的摘要只有一个生效，返回是否更新成功
+func UpdateSessionSummary(sessionID string, prevMessageID, messageID uint, summary string) (bool, error) {
+	result := DB.Model(&model.Session{}).
+		Where("session_id = ? AND summary_message_id = ?", sessionID, prevMessageID).
+		Updates(map[string]any{
+			"summary":            summary,
+			"summary_message_id": messageID,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// SessionBacklog 会话摘要之后尚未合并的聊天记录
+type SessionBacklog struct {
+	SessionID     string
+	Count         int
+	LastMessageID uint
+}
+
+// GetSessionBacklogs 返回在 [after, before) 之间有新消息、且摘要之后超过 minCount 条聊天记录的会话
+func GetSessionBacklogs(minCount int, after, before time.Time, limit int) ([]SessionBacklog, error) {
+	var backlogs []SessionBacklog
+	if err := DB.Table("chat_message AS m").
+		Select("m.session_id, COUNT(*) AS count, MAX(m.id) AS last_message_id").
+		Joins("JOIN chat_session AS s ON s.session_id = m.session_id AND m.id > s.summary_message_id").
+		Where("m.created_at >= ? AND m.created_at < ?", after, before).
+		Group("m.session_id").
+		Having("COUNT(*) > ?", minCount).
+		Limit(limit).
+		Scan(&backlogs).Error; err != nil {
+		return nil, err
+	}
+	return backlogs, nil
+}
+
 func UpdateSessionTitle(email, sessionID, title string) error {
 	err := DB.Model(&model.Session{}).
 		Where("user_email = ? AND session_id = ?", email, sessionID).
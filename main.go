@@ -1,32 +1,100 @@
 package main
 
 import (
+	"context"
 	"diabetes-agent-backend/config"
+	"diabetes-agent-backend/dao"
 	"diabetes-agent-backend/router"
+	"diabetes-agent-backend/service/glucose"
+	"diabetes-agent-backend/service/knowledge-base/etl"
+	"diabetes-agent-backend/service/maintenance"
 	"diabetes-agent-backend/service/mq"
+	"diabetes-agent-backend/service/outbox"
+	patientmemory "diabetes-agent-backend/service/patient-memory"
 	"diabetes-agent-backend/service/summarization"
+	voicerecognition "diabetes-agent-backend/service/voice-recognition"
+	"errors"
 	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
+// 收到退出信号后等待正在处理的请求完成的最长时间
+const shutdownTimeout = 30 * time.Second
+
 func main() {
-	// 设置日志
+	// 加载配置并设置日志
+	if err := config.Init(); err != nil {
+		slog.Error("Failed to load config", "err", err)
+		return
+	}
 	setSysLog()
 
-	// 启动对话摘要生成服务
-	summarization.SummarizerInstance.Run()
+	// 连接数据库并创建依赖配置的服务
+	if err := dao.Init(); err != nil {
+		slog.Error("Failed to initialize database", "err", err)
+		return
+	}
+	if err := summarization.Init(); err != nil {
+		slog.Error("Failed to create summarizer", "err", err)
+		return
+	}
+	voicerecognition.Init()
 
-	// 启动 MQ 服务
+	// 注册消息处理器并启动 MQ 服务
+	if err := etl.RegisterHandlers(); err != nil {
+		slog.Error("Failed to create knowledge base consumer", "err", err)
+		return
+	}
+	maintenance.RegisterJobs()
+	summarization.SummarizerInstance.RegisterHandlers()
+	patientmemory.RegisterHandlers()
+	glucose.RegisterHandlers()
 	if err := mq.Run(); err != nil {
 		slog.Error("Failed to start MQ service", "err", err)
 		return
 	}
 	defer mq.Shutdown()
 
-	// 启动 HTTP 服务
-	r := router.Register()
-	if err := r.Run(":" + config.Cfg.Server.Port); err != nil {
-		slog.Error("Failed to start HTTP server", "err", err)
+	// 启动 outbox 消息投递服务
+	outbox.RelayInstance.Run()
+	defer outbox.RelayInstance.Shutdown()
+
+	// 启动定时任务调度，到期的任务写入 outbox 由 relay 投递
+	if err := mq.SchedulerInstance.Run(); err != nil {
+		slog.Error("Failed to start job scheduler", "err", err)
+		return
+	}
+	defer mq.SchedulerInstance.Shutdown()
+
+	// 关闭 MQ 消费者之前等待正在生成的对话摘要完成
+	defer summarization.SummarizerInstance.Shutdown()
+
+	// 启动 HTTP 服务，收到退出信号后依次关闭各服务
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	server := &http.Server{
+		Addr:    ":" + config.Cfg.Server.Port,
+		Handler: router.Register(),
+	}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Failed to start HTTP server", "err", err)
+			stop()
+		}
+	}()
+
+	<-ctx.Done()
+	slog.Info("Shutting down server")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Failed to shutdown HTTP server", "err", err)
 	}
 }
 
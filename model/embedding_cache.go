@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// EmbeddingCache 缓存文本的向量，相同内容的切片不再重复调用向量化模型
+// 建立唯一索引 (model_key, content_hash)
+type EmbeddingCache struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `gorm:"not null" json:"created_at"`
+
+	// 向量化模型和维度，形如 "text-embedding-v4@1024"
+	ModelKey string `gorm:"not null;size:128;uniqueIndex:idx_model_hash" json:"model_key"`
+
+	// 文本内容的 SHA-256 摘要
+	ContentHash string `gorm:"not null;size:64;uniqueIndex:idx_model_hash" json:"content_hash"`
+
+	// 按小端序编码的 float32 向量
+	Vector []byte `gorm:"type:blob;not null" json:"-"`
+}
+
+func (EmbeddingCache) TableName() string {
+	return "embedding_cache"
+}
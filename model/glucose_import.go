@@ -0,0 +1,47 @@
+package model
+
+import "time"
+
+type ImportStatus string
+
+const (
+	// 已创建导入任务，等待处理
+	ImportStatusPending ImportStatus = "PENDING"
+
+	ImportStatusProcessing ImportStatus = "PROCESSING"
+	ImportStatusSucceeded  ImportStatus = "SUCCEEDED"
+	ImportStatusFailed     ImportStatus = "FAILED"
+)
+
+// GlucoseImport 从 CGM 导出文件导入血糖数据的任务
+type GlucoseImport struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `gorm:"not null" json:"created_at"`
+	UpdatedAt time.Time `gorm:"not null" json:"updated_at"`
+	UserEmail string    `gorm:"not null;index" json:"user_email"`
+
+	FileName   string `gorm:"not null" json:"file_name"`
+	ObjectName string `gorm:"not null" json:"object_name"`
+
+	// 导出文件的格式，创建时为空表示自动识别，处理后为识别出的格式
+	Format string `gorm:"not null;default:''" json:"format"`
+
+	// 解析导出文件中不带时区的时间使用的 IANA 时区，创建时未指定则取健康档案中的时区，仍为空时使用服务器时区
+	Timezone string `gorm:"not null;default:''" json:"timezone"`
+
+	Status ImportStatus `gorm:"not null;default:PENDING" json:"status"`
+
+	// 新导入、与已有数据重复、无法解析的读数条数
+	Created int64 `gorm:"not null;default:0" json:"created"`
+	Skipped int64 `gorm:"not null;default:0" json:"skipped"`
+	Invalid int64 `gorm:"not null;default:0" json:"invalid"`
+
+	// 处理失败的原因
+	Error string `gorm:"type:text" json:"error"`
+
+	FinishedAt *time.Time `json:"finished_at"`
+}
+
+func (GlucoseImport) TableName() string {
+	return "glucose_import"
+}
@@ -0,0 +1,66 @@
+package model
+
+import "time"
+
+// GlucoseContext 测量血糖时的场景
+type GlucoseContext string
+
+const (
+	GlucoseFasting   GlucoseContext = "fasting"
+	GlucosePreMeal   GlucoseContext = "pre_meal"
+	GlucosePostMeal  GlucoseContext = "post_meal"
+	GlucoseBedtime   GlucoseContext = "bedtime"
+	GlucoseOvernight GlucoseContext = "overnight"
+	GlucoseRandom    GlucoseContext = "random"
+)
+
+func (c GlucoseContext) IsValid() bool {
+	switch c {
+	case GlucoseFasting, GlucosePreMeal, GlucosePostMeal, GlucoseBedtime, GlucoseOvernight, GlucoseRandom:
+		return true
+	}
+	return false
+}
+
+// GlucoseSource 血糖数据的来源
+type GlucoseSource string
+
+const (
+	GlucoseSourceManual GlucoseSource = "manual"
+	GlucoseSourceMeter  GlucoseSource = "meter"
+	GlucoseSourceCGM    GlucoseSource = "cgm"
+	GlucoseSourceImport GlucoseSource = "import"
+)
+
+func (s GlucoseSource) IsValid() bool {
+	switch s {
+	case GlucoseSourceManual, GlucoseSourceMeter, GlucoseSourceCGM, GlucoseSourceImport:
+		return true
+	}
+	return false
+}
+
+// GlucoseReading 一次血糖测量记录
+// 在 (user_email, source, measured_at) 上建立唯一索引，重复导入同一时刻的数据时忽略
+type GlucoseReading struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `gorm:"not null" json:"created_at"`
+	UpdatedAt time.Time `gorm:"not null" json:"updated_at"`
+	UserEmail string    `gorm:"not null;uniqueIndex:idx_email_source_measured,priority:1;index:idx_email_measured,priority:1" json:"user_email"`
+
+	// 血糖值（mmol/L）
+	Value float64 `gorm:"not null" json:"value"`
+
+	// 用户录入时使用的单位，用于按原始单位展示
+	Unit GlucoseUnit `gorm:"not null;default:'mmol/L'" json:"unit"`
+
+	MeasuredAt time.Time `gorm:"not null;uniqueIndex:idx_email_source_measured,priority:3;index:idx_email_measured,priority:2" json:"measured_at"`
+
+	Context GlucoseContext `gorm:"not null;default:'random'" json:"context"`
+	Source  GlucoseSource  `gorm:"not null;size:16;uniqueIndex:idx_email_source_measured,priority:2" json:"source"`
+	Note    string         `gorm:"not null;default:''" json:"note"`
+}
+
+func (GlucoseReading) TableName() string {
+	return "glucose_reading"
+}
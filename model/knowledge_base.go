@@ -8,8 +8,26 @@ const (
 	FileTypePDF      FileType = "pdf"
 	FileTypeMarkdown FileType = "md"
 	FileTypeText     FileType = "txt"
+	FileTypeDocx     FileType = "docx"
+	FileTypeHTML     FileType = "html"
+	FileTypeCSV      FileType = "csv"
+	FileTypeXLSX     FileType = "xlsx"
+	FileTypeJPG      FileType = "jpg"
+	FileTypeJPEG     FileType = "jpeg"
+	FileTypePNG      FileType = "png"
 )
 
+// IsSupported 判断知识库是否支持处理该文件类型
+func (t FileType) IsSupported() bool {
+	switch t {
+	case FileTypePDF, FileTypeMarkdown, FileTypeText,
+		FileTypeDocx, FileTypeHTML, FileTypeCSV, FileTypeXLSX,
+		FileTypeJPG, FileTypeJPEG, FileTypePNG:
+		return true
+	}
+	return false
+}
+
 type Status string
 
 const (
@@ -23,22 +41,154 @@ const (
 	StatusProcessedFailed Status = "PROCESSED_FAILED"
 )
 
-// KnowledgeMetadata 存储知识文件元数据
-// 建立联合索引 (user_email, created_at)，在 file_name 上建立全文索引
+type Stage string
+
+const (
+	// 从OSS下载文件
+	StageDownloading Stage = "DOWNLOADING"
+
+	// 加载并切分文档
+	StageSplitting Stage = "SPLITTING"
+
+	// OCR 识别扫描页和图片中的文字
+	StageRecognizing Stage = "RECOGNIZING"
+
+	// 生成切片向量
+	StageEmbedding Stage = "EMBEDDING"
+
+	// 写入向量存储
+	StageIndexing Stage = "INDEXING"
+
+	// 提取化验单中的检验结果
+	StageExtracting Stage = "EXTRACTING"
+
+	// 处理完成
+	StageCompleted Stage = "COMPLETED"
+)
+
+// ChunkingStrategy 知识文件切分策略
+type ChunkingStrategy string
+
+const (
+	// 按分隔符优先级递归切分
+	ChunkingRecursive ChunkingStrategy = "recursive"
+
+	// 按 Markdown 标题和表格结构切分
+	ChunkingMarkdown ChunkingStrategy = "markdown"
+
+	// 按完整句子组成窗口切分，相邻切片以句子重叠
+	ChunkingSentence ChunkingStrategy = "sentence"
+
+	// 按 tiktoken 分词结果切分，切片大小以 token 计
+	ChunkingToken ChunkingStrategy = "token"
+)
+
+const (
+	DefaultChunkSize    = 4000
+	DefaultChunkOverlap = 400
+)
+
+// IsValid 判断是否为支持的切分策略
+func (s ChunkingStrategy) IsValid() bool {
+	switch s {
+	case ChunkingRecursive, ChunkingMarkdown, ChunkingSentence, ChunkingToken:
+		return true
+	}
+	return false
+}
+
+// Chunking 知识文件的切分配置，记录在每个版本上，保证重新处理时切分结果可复现
+type Chunking struct {
+	Strategy     ChunkingStrategy `gorm:"column:chunking_strategy;not null;default:''" json:"strategy"`
+	ChunkSize    int              `gorm:"column:chunk_size;not null;default:0" json:"chunk_size"`
+	ChunkOverlap int              `gorm:"column:chunk_overlap;not null;default:0" json:"chunk_overlap"`
+}
+
+// DefaultChunking 返回文件类型的内置切分配置
+// PDF 按分隔符递归切分，其他格式转换为 Markdown 后按结构切分
+func DefaultChunking(fileType FileType) Chunking {
+	strategy := ChunkingMarkdown
+	if fileType == FileTypePDF {
+		strategy = ChunkingRecursive
+	}
+	return Chunking{
+		Strategy:     strategy,
+		ChunkSize:    DefaultChunkSize,
+		ChunkOverlap: DefaultChunkOverlap,
+	}
+}
+
+// ExtractionQuality 文本提取质量
+type ExtractionQuality string
+
+const (
+	// 直接从文件中提取到文本
+	QualityGood ExtractionQuality = "GOOD"
+
+	// 部分或全部文本来自 OCR 识别
+	QualityOCR ExtractionQuality = "OCR"
+
+	// 提取到的文本过少，检索结果可能不完整
+	QualityPoor ExtractionQuality = "POOR"
+)
+
+// KnowledgeMetadata 存储知识文件元数据，每条记录对应知识文件的一个版本
+// 建立联合索引 (user_email, created_at)，唯一索引 (document_id, version)，在 file_name 上建立全文索引
 type KnowledgeMetadata struct {
 	ID        uint      `gorm:"primarykey" json:"id"`
 	CreatedAt time.Time `gorm:"not null;index:idx_email_created" json:"created_at"`
 	UpdatedAt time.Time `gorm:"not null" json:"updated_at"`
 	UserEmail string    `gorm:"not null;index:idx_email_created" json:"user_email"`
-	FileName  string    `gorm:"not null;index:idx_fulltext_file_name,class:FULLTEXT,option:WITH PARSER ngram" json:"file_name"`
-	FileType  FileType  `gorm:"not null" json:"file_type"`
-	FileSize  int64     `gorm:"not null" json:"file_size"`
+
+	// 知识文件的稳定标识，贯穿 MQ 消息、milvus 切片和数据库记录
+	// 引入该字段之前的记录为空串，创建唯一索引前需要先回填：
+	//   UPDATE knowledge_metadata SET document_id = UUID() WHERE document_id = '';
+	//   CREATE UNIQUE INDEX idx_document_version ON knowledge_metadata (document_id, version);
+	// 然后执行 milvus backfill 为旧切片写入对应的文档 ID，否则旧文件无法删除
+	DocumentID string `gorm:"not null;size:36;uniqueIndex:idx_document_version" json:"document_id"`
+
+	// 版本号，从 1 开始递增
+	Version int `gorm:"not null;default:1;uniqueIndex:idx_document_version" json:"version"`
+
+	// 该版本的上传时间
+	UploadedAt time.Time `gorm:"not null" json:"uploaded_at"`
+
+	// 是否已被其他版本替换，被替换的版本不再保留向量存储
+	Superseded bool `gorm:"not null;default:false" json:"superseded"`
+
+	FileName string   `gorm:"not null;index:idx_fulltext_file_name,class:FULLTEXT,option:WITH PARSER ngram" json:"file_name"`
+	FileType FileType `gorm:"not null" json:"file_type"`
+	FileSize int64    `gorm:"not null" json:"file_size"`
 
 	// 文件在OSS上的完整路径，不包含bucket名称
 	ObjectName string `gorm:"not null" json:"object_name"`
 
+	// 切分配置
+	Chunking Chunking `gorm:"embedded" json:"chunking"`
+
 	// 文件处理状态
 	Status Status `gorm:"not null;default:UPLOADED" json:"status"`
+
+	// 文件处理阶段
+	Stage Stage `gorm:"not null;default:''" json:"stage"`
+
+	// 文件处理进度百分比
+	Progress int `gorm:"not null;default:0" json:"progress"`
+
+	// 文件切片数量
+	ChunkCount int `gorm:"not null;default:0" json:"chunk_count"`
+
+	// 最近一次处理失败的原因
+	ErrorMessage string `gorm:"type:text" json:"error_message"`
+
+	// 已写入向量存储的切片数量，重试时从此处继续
+	CheckpointChunks int `gorm:"not null;default:0" json:"checkpoint_chunks"`
+
+	// 切片集合的指纹，切片结果变化时检查点失效
+	ChunkFingerprint string `gorm:"not null;size:64;default:''" json:"chunk_fingerprint"`
+
+	// 文本提取质量，处理完成前为空
+	ExtractionQuality ExtractionQuality `gorm:"not null;default:''" json:"extraction_quality"`
 }
 
 func (KnowledgeMetadata) TableName() string {
@@ -0,0 +1,44 @@
+package model
+
+import "time"
+
+// LabResult 从化验单中提取的单项检验结果
+// 建立联合索引 (user_email, analyte, sampled_at)，在 document_id 上建立索引
+type LabResult struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	UserEmail string    `gorm:"not null;index:idx_email_analyte_sampled" json:"user_email"`
+
+	// 来源知识文件及其版本
+	DocumentID  string `gorm:"not null;size:36;index" json:"document_id"`
+	KnowledgeID uint   `gorm:"not null" json:"knowledge_id"`
+
+	// 标准化后的检验项目代码，如 HBA1C、FPG，无法识别的项目为空
+	Analyte string `gorm:"not null;default:'';index:idx_email_analyte_sampled" json:"analyte"`
+
+	// 化验单上的项目名称
+	Name string `gorm:"not null" json:"name"`
+
+	// 转换为标准单位后的结果，非数值结果（如 "阴性"）为空
+	Value *float64 `json:"value"`
+	Unit  string   `gorm:"not null;default:''" json:"unit"`
+
+	// 化验单上的原始结果和单位
+	RawValue string `gorm:"not null" json:"raw_value"`
+	RawUnit  string `gorm:"not null;default:''" json:"raw_unit"`
+
+	// 转换为标准单位后的参考范围，单侧范围只有一端有值
+	ReferenceLow  *float64 `json:"reference_low"`
+	ReferenceHigh *float64 `json:"reference_high"`
+
+	// 化验单上的原始参考范围
+	ReferenceRange string `gorm:"not null;default:''" json:"reference_range"`
+
+	// 采样或报告日期，化验单未注明时为空
+	SampledAt *time.Time `gorm:"index:idx_email_analyte_sampled" json:"sampled_at"`
+}
+
+func (LabResult) TableName() string {
+	return "lab_result"
+}
@@ -0,0 +1,160 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LogKind 日志记录的类型
+type LogKind string
+
+const (
+	LogKindMedication LogKind = "medication"
+	LogKindInsulin    LogKind = "insulin"
+	LogKindMeal       LogKind = "meal"
+)
+
+func (k LogKind) IsValid() bool {
+	switch k {
+	case LogKindMedication, LogKindInsulin, LogKindMeal:
+		return true
+	}
+	return false
+}
+
+// InsulinType 胰岛素的作用类型
+type InsulinType string
+
+const (
+	InsulinTypeRapid        InsulinType = "rapid"
+	InsulinTypeShort        InsulinType = "short"
+	InsulinTypeIntermediate InsulinType = "intermediate"
+	InsulinTypeLong         InsulinType = "long"
+	InsulinTypePremixed     InsulinType = "premixed"
+	InsulinTypeOther        InsulinType = "other"
+)
+
+func (t InsulinType) IsValid() bool {
+	switch t {
+	case InsulinTypeRapid, InsulinTypeShort, InsulinTypeIntermediate, InsulinTypeLong, InsulinTypePremixed, InsulinTypeOther:
+		return true
+	}
+	return false
+}
+
+// MealType 餐次
+type MealType string
+
+const (
+	MealBreakfast MealType = "breakfast"
+	MealLunch     MealType = "lunch"
+	MealDinner    MealType = "dinner"
+	MealSnack     MealType = "snack"
+)
+
+func (t MealType) IsValid() bool {
+	switch t {
+	case MealBreakfast, MealLunch, MealDinner, MealSnack:
+		return true
+	}
+	return false
+}
+
+// LogSource 日志记录的来源
+type LogSource string
+
+const (
+	// 用户通过接口录入
+	LogSourceManual LogSource = "manual"
+
+	// 用户在对话中确认后由 Agent 写入
+	LogSourceAgent LogSource = "agent"
+)
+
+// LogEntry 一条用药、胰岛素注射或饮食记录，不同类型使用的字段不同
+type LogEntry struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `gorm:"not null" json:"created_at"`
+	UpdatedAt time.Time      `gorm:"not null" json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	UserEmail string         `gorm:"not null;index:idx_email_occurred,priority:1" json:"user_email"`
+
+	Kind LogKind `gorm:"not null;size:16" json:"kind"`
+
+	// 药品名称、胰岛素名称或饮食内容
+	Name string `gorm:"not null;size:200" json:"name"`
+
+	// 药品剂量，如 "500 mg"
+	Dose string `gorm:"not null;size:50;default:''" json:"dose"`
+
+	InsulinType InsulinType `gorm:"not null;size:16;default:''" json:"insulin_type"`
+	Units       *float64    `json:"units"`
+
+	// 碳水化合物（克）
+	Carbs    *float64 `json:"carbs"`
+	MealType MealType `gorm:"not null;size:16;default:''" json:"meal_type"`
+
+	OccurredAt time.Time `gorm:"not null;index:idx_email_occurred,priority:2" json:"occurred_at"`
+	Note       string    `gorm:"not null;size:200;default:''" json:"note"`
+	Source     LogSource `gorm:"not null;size:16" json:"source"`
+}
+
+func (LogEntry) TableName() string {
+	return "log_entry"
+}
+
+// LogAction Agent 对日志记录的操作
+type LogAction string
+
+const (
+	LogActionCreate LogAction = "create"
+	LogActionUpdate LogAction = "update"
+	LogActionDelete LogAction = "delete"
+)
+
+func (a LogAction) IsValid() bool {
+	switch a {
+	case LogActionCreate, LogActionUpdate, LogActionDelete:
+		return true
+	}
+	return false
+}
+
+// PendingStatus 待确认操作的状态
+type PendingStatus string
+
+const (
+	PendingStatusPending   PendingStatus = "PENDING"
+	PendingStatusConfirmed PendingStatus = "CONFIRMED"
+	PendingStatusCancelled PendingStatus = "CANCELLED"
+)
+
+// PendingLogAction Agent 提出的待用户确认的日志操作，确认后才写入 log_entry
+type PendingLogAction struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `gorm:"not null" json:"created_at"`
+	UpdatedAt time.Time `gorm:"not null" json:"updated_at"`
+	UserEmail string    `gorm:"not null;index:idx_email_status,priority:1" json:"user_email"`
+
+	// 提出操作的会话
+	SessionID string `gorm:"not null;size:36;default:''" json:"session_id"`
+
+	Action LogAction `gorm:"not null;size:16" json:"action"`
+
+	// 修改或删除的记录，新建时为 0
+	EntryID uint `gorm:"not null;default:0" json:"entry_id"`
+
+	// 校验后的记录内容（JSON），删除时为空
+	Payload string `gorm:"type:text;not null" json:"payload"`
+
+	// 展示给用户确认的操作描述
+	Summary string `gorm:"type:text;not null" json:"summary"`
+
+	Status    PendingStatus `gorm:"not null;size:16;index:idx_email_status,priority:2" json:"status"`
+	ExpiresAt time.Time     `gorm:"not null" json:"expires_at"`
+}
+
+func (PendingLogAction) TableName() string {
+	return "pending_log_action"
+}
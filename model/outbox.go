@@ -0,0 +1,51 @@
+package model
+
+import "time"
+
+type OutboxStatus string
+
+const (
+	// 等待投递到 MQ
+	OutboxStatusPending OutboxStatus = "PENDING"
+
+	// 已投递到 MQ
+	OutboxStatusSent OutboxStatus = "SENT"
+)
+
+// OutboxMessage 待投递的 MQ 消息，与触发它的业务数据在同一事务中写入，由 relay 异步投递
+// 建立联合索引 (status, next_attempt_at)，(user_email, status)
+type OutboxMessage struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `gorm:"not null" json:"created_at"`
+	UpdatedAt time.Time `gorm:"not null" json:"updated_at"`
+
+	// 触发消息的用户和知识文件，用于排查未投递的消息
+	UserEmail  string `gorm:"not null;index:idx_email_status" json:"user_email"`
+	DocumentID string `gorm:"not null;size:36;default:''" json:"document_id"`
+
+	Topic string `gorm:"not null" json:"topic"`
+	Tag   string `gorm:"not null;default:''" json:"tag"`
+
+	// 业务幂等键，随消息投递，消费者据此去重
+	IdempotencyKey string `gorm:"not null;size:191;default:''" json:"idempotency_key"`
+
+	// JSON 编码的消息体
+	Payload string `gorm:"type:json;not null" json:"payload"`
+
+	Status OutboxStatus `gorm:"not null;default:PENDING;index:idx_status_next_attempt;index:idx_email_status" json:"status"`
+
+	// 已尝试投递的次数
+	Attempts int `gorm:"not null;default:0" json:"attempts"`
+
+	// 下次尝试投递的时间，relay 领取消息后推迟该时间，避免多个实例重复投递
+	NextAttemptAt time.Time `gorm:"not null;index:idx_status_next_attempt" json:"next_attempt_at"`
+
+	// 最近一次投递失败的原因
+	LastError string `gorm:"type:text" json:"last_error"`
+
+	SentAt *time.Time `json:"sent_at"`
+}
+
+func (OutboxMessage) TableName() string {
+	return "outbox_message"
+}
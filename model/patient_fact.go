@@ -0,0 +1,70 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// FactCategory 长期记忆的分类
+type FactCategory string
+
+const (
+	FactDiabetesType FactCategory = "diabetes_type"
+	FactMedication   FactCategory = "medication"
+	FactComplication FactCategory = "complication"
+	FactCondition    FactCategory = "condition"
+	FactAllergy      FactCategory = "allergy"
+	FactLifestyle    FactCategory = "lifestyle"
+	FactGoal         FactCategory = "goal"
+	FactOther        FactCategory = "other"
+)
+
+func (c FactCategory) IsValid() bool {
+	switch c {
+	case FactDiabetesType, FactMedication, FactComplication, FactCondition,
+		FactAllergy, FactLifestyle, FactGoal, FactOther:
+		return true
+	}
+	return false
+}
+
+// FactSource 长期记忆的来源
+type FactSource string
+
+const (
+	// 从对话中自动提取
+	FactSourceExtracted FactSource = "EXTRACTED"
+
+	// 用户手动编辑，自动提取不会覆盖
+	FactSourceUser FactSource = "USER"
+)
+
+// PatientFact 跨会话保留的用户长期记忆，如糖尿病类型、用药和并发症
+// 用户删除的记忆保留软删除记录，避免再次从对话中提取
+// 建立联合索引 (user_email, category)
+type PatientFact struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	UserEmail string         `gorm:"not null;index:idx_email_category" json:"user_email"`
+
+	Category FactCategory `gorm:"not null;index:idx_email_category" json:"category"`
+	Content  string       `gorm:"type:text;not null" json:"content"`
+
+	// 提取时模型给出的置信度，用户编辑的记忆为 1
+	Confidence float64    `gorm:"not null" json:"confidence"`
+	Source     FactSource `gorm:"not null" json:"source"`
+
+	// 最近一次提到该记忆的会话和聊天记录
+	SourceSessionID string `gorm:"not null;size:36;default:''" json:"source_session_id"`
+	SourceMessageID uint   `gorm:"not null;default:0" json:"source_message_id"`
+
+	// 最近一次在对话中提到或被用户确认的时间
+	ObservedAt time.Time `gorm:"not null" json:"observed_at"`
+}
+
+func (PatientFact) TableName() string {
+	return "patient_fact"
+}
@@ -0,0 +1,131 @@
+package model
+
+import "time"
+
+type DiabetesType string
+
+const (
+	DiabetesType1           DiabetesType = "type1"
+	DiabetesType2           DiabetesType = "type2"
+	DiabetesTypeGestational DiabetesType = "gestational"
+	DiabetesTypePrediabetes DiabetesType = "prediabetes"
+	DiabetesTypeLADA        DiabetesType = "lada"
+	DiabetesTypeMODY        DiabetesType = "mody"
+	DiabetesTypeOther       DiabetesType = "other"
+)
+
+func (t DiabetesType) IsValid() bool {
+	switch t {
+	case DiabetesType1, DiabetesType2, DiabetesTypeGestational, DiabetesTypePrediabetes,
+		DiabetesTypeLADA, DiabetesTypeMODY, DiabetesTypeOther:
+		return true
+	}
+	return false
+}
+
+// GlucoseUnit 血糖单位，数据库中的血糖值统一使用 mmol/L
+type GlucoseUnit string
+
+const (
+	GlucoseUnitMmolL GlucoseUnit = "mmol/L"
+	GlucoseUnitMgDL  GlucoseUnit = "mg/dL"
+
+	// 葡萄糖 1 mmol/L = 18.016 mg/dL
+	MgDLPerMmolL = 18.016
+)
+
+func (u GlucoseUnit) IsValid() bool {
+	return u == GlucoseUnitMmolL || u == GlucoseUnitMgDL
+}
+
+// ToMmolL 将 unit 单位的血糖值换算为 mmol/L
+func (u GlucoseUnit) ToMmolL(value float64) float64 {
+	if u == GlucoseUnitMgDL {
+		return value / MgDLPerMmolL
+	}
+	return value
+}
+
+// FromMmolL 将 mmol/L 的血糖值换算为 unit 单位
+func (u GlucoseUnit) FromMmolL(value float64) float64 {
+	if u == GlucoseUnitMgDL {
+		return value * MgDLPerMmolL
+	}
+	return value
+}
+
+type InsulinRegimenType string
+
+const (
+	InsulinNone       InsulinRegimenType = "none"
+	InsulinBasal      InsulinRegimenType = "basal"
+	InsulinBasalBolus InsulinRegimenType = "basal_bolus"
+	InsulinPremixed   InsulinRegimenType = "premixed"
+	InsulinPump       InsulinRegimenType = "pump"
+)
+
+func (t InsulinRegimenType) IsValid() bool {
+	switch t {
+	case InsulinNone, InsulinBasal, InsulinBasalBolus, InsulinPremixed, InsulinPump:
+		return true
+	}
+	return false
+}
+
+// Medication 长期使用的口服药或注射药物
+type Medication struct {
+	Name      string `json:"name"`
+	Dose      string `json:"dose"`
+	Frequency string `json:"frequency"`
+}
+
+// InsulinDose 胰岛素的固定剂量，Time 为 HH:MM 或用餐时机，如 "22:00"、"早餐前"
+type InsulinDose struct {
+	Name  string  `json:"name"`
+	Units float64 `json:"units"`
+	Time  string  `json:"time"`
+}
+
+// InsulinRegimen 胰岛素治疗方案
+type InsulinRegimen struct {
+	Type  InsulinRegimenType `json:"type"`
+	Basal []InsulinDose      `json:"basal"`
+	Bolus []InsulinDose      `json:"bolus"`
+
+	// 碳水系数（g/U）和校正系数（mmol/L/U），未设置时为空
+	CarbRatio        *float64 `json:"carb_ratio"`
+	CorrectionFactor *float64 `json:"correction_factor"`
+}
+
+// PatientProfile 用户填写的结构化健康档案，作为 Agent 的上下文
+type PatientProfile struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `gorm:"not null" json:"created_at"`
+	UpdatedAt time.Time `gorm:"not null" json:"updated_at"`
+	UserEmail string    `gorm:"not null;uniqueIndex" json:"user_email"`
+
+	DiabetesType  DiabetesType `gorm:"not null;default:''" json:"diabetes_type"`
+	DiagnosisDate *time.Time   `gorm:"type:date" json:"diagnosis_date"`
+
+	HeightCM *float64 `json:"height_cm"`
+	WeightKG *float64 `json:"weight_kg"`
+
+	Medications    []Medication    `gorm:"serializer:json;type:json" json:"medications"`
+	InsulinRegimen *InsulinRegimen `gorm:"serializer:json;type:json" json:"insulin_regimen"`
+	Allergies      []string        `gorm:"serializer:json;type:json" json:"allergies"`
+	Comorbidities  []string        `gorm:"serializer:json;type:json" json:"comorbidities"`
+
+	// 目标血糖范围（mmol/L），未设置时为空
+	TargetLow  *float64 `json:"target_low"`
+	TargetHigh *float64 `json:"target_high"`
+
+	// 用户偏好的血糖单位，Agent 回答和接口返回使用该单位
+	GlucoseUnit GlucoseUnit `gorm:"not null;default:'mmol/L'" json:"glucose_unit"`
+
+	// 用户所在的 IANA 时区，如 Asia/Shanghai，日期的解析和按天、按小时的统计使用该时区，为空时使用服务器时区
+	Timezone string `gorm:"not null;default:''" json:"timezone"`
+}
+
+func (PatientProfile) TableName() string {
+	return "patient_profile"
+}
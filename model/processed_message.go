@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+type ProcessedStatus string
+
+const (
+	// 消息正在处理，LockedUntil 之前其他消费者不能处理同一消息
+	ProcessedStatusProcessing ProcessedStatus = "PROCESSING"
+
+	// 消息已处理完成，重复投递时直接确认
+	ProcessedStatusCompleted ProcessedStatus = "COMPLETED"
+)
+
+// ProcessedMessage 消息处理记录，按业务幂等键去重，保证重复投递的消息只处理一次
+type ProcessedMessage struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `gorm:"not null;index" json:"created_at"`
+	UpdatedAt time.Time `gorm:"not null" json:"updated_at"`
+
+	// 业务幂等键，由消息生产者生成
+	IdempotencyKey string `gorm:"not null;size:191;uniqueIndex" json:"idempotency_key"`
+
+	Topic string `gorm:"not null" json:"topic"`
+	Tag   string `gorm:"not null;default:''" json:"tag"`
+
+	// 最近一次处理该消息的 MQ 消息 ID
+	MsgID string `gorm:"not null;default:''" json:"msg_id"`
+
+	Status ProcessedStatus `gorm:"not null" json:"status"`
+
+	// 处理中状态的租约到期时间，消费者异常退出后其他消费者可以在到期后接管
+	LockedUntil time.Time `gorm:"not null" json:"locked_until"`
+}
+
+func (ProcessedMessage) TableName() string {
+	return "processed_message"
+}
@@ -0,0 +1,37 @@
+package model
+
+import "time"
+
+// ScheduledJob 定时任务，到期时由调度器写入 outbox，经 relay 投递到 MQ
+// CronSpec 为空的任务是一次性的延迟消息，触发后删除
+// 建立联合索引 (enabled, next_run_at)
+type ScheduledJob struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `gorm:"not null" json:"created_at"`
+	UpdatedAt time.Time `gorm:"not null" json:"updated_at"`
+
+	// 周期任务的名称，延迟消息使用随机名称
+	Name string `gorm:"not null;size:191;uniqueIndex" json:"name"`
+
+	// 标准 5 段 cron 表达式
+	CronSpec string `gorm:"not null;default:''" json:"cron_spec"`
+
+	Topic string `gorm:"not null" json:"topic"`
+	Tag   string `gorm:"not null;default:''" json:"tag"`
+
+	// 延迟消息的业务幂等键，为空时按任务名称和触发时间生成
+	IdempotencyKey string `gorm:"not null;size:191;default:''" json:"idempotency_key"`
+
+	// JSON 编码的消息体
+	Payload string `gorm:"type:json;not null" json:"payload"`
+
+	// cron 表达式无法解析的任务会被停用
+	Enabled bool `gorm:"not null;default:true;index:idx_enabled_next_run" json:"enabled"`
+
+	NextRunAt time.Time  `gorm:"not null;index:idx_enabled_next_run" json:"next_run_at"`
+	LastRunAt *time.Time `json:"last_run_at"`
+}
+
+func (ScheduledJob) TableName() string {
+	return "scheduled_job"
+}
@@ -12,8 +12,12 @@ type Session struct {
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 	UserEmail string    `gorm:"not null;index" json:"user_email"`
-	SessionID string    `gorm:"not null" json:"session_id"`
+	SessionID string    `gorm:"not null;index" json:"session_id"`
 	Title     string    `json:"title"`
+
+	// 会话的滚动摘要，概括 ID 不大于 SummaryMessageID 的所有聊天记录
+	Summary          string `gorm:"type:text" json:"-"`
+	SummaryMessageID uint   `gorm:"not null;default:0" json:"-"`
 }
 
 func (Session) TableName() string {
@@ -31,7 +35,9 @@ type Message struct {
 	Content         string          `gorm:"type:text" json:"content"`
 	ImmediateSteps  string          `gorm:"type:text" json:"immediate_steps"`
 	ToolCallResults json.RawMessage `gorm:"type:json" json:"tool_call_results"`
-	Summary         string          `gorm:"type:text" json:"summary"`
+
+	// 旧版按单条消息生成的摘要，已改为会话级滚动摘要，不再写入
+	Summary string `gorm:"type:text" json:"summary"`
 }
 
 type ToolCallResult struct {
@@ -0,0 +1,25 @@
+package request
+
+import "time"
+
+// CreateGlucoseReadingsRequest 录入一条或批量导入多条血糖记录
+type CreateGlucoseReadingsRequest struct {
+	Readings []GlucoseReadingRequest `json:"readings"`
+}
+
+type GlucoseReadingRequest struct {
+	Value float64 `json:"value"`
+
+	// mmol/L 或 mg/dL，为空时使用健康档案中的偏好单位
+	Unit string `json:"unit"`
+
+	MeasuredAt time.Time `json:"measured_at"`
+
+	// fasting、pre_meal、post_meal、bedtime、overnight 或 random（默认）
+	Context string `json:"context"`
+
+	// manual（默认）、meter、cgm 或 import
+	Source string `json:"source"`
+
+	Note string `json:"note"`
+}
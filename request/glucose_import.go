@@ -0,0 +1,13 @@
+package request
+
+// CreateGlucoseImportRequest 导入已上传到 upload 命名空间的 CGM 导出文件
+type CreateGlucoseImportRequest struct {
+	SessionID string `json:"session_id"`
+	FileName  string `json:"file_name"`
+
+	// dexcom、libreview 或 nightscout，为空时自动识别
+	Format string `json:"format"`
+
+	// 导出文件所在的 IANA 时区，如 Asia/Shanghai，为空时使用健康档案中的时区，档案未设置时区时会记录该时区
+	Timezone string `json:"timezone"`
+}
@@ -1,8 +1,29 @@
 package request
 
+const (
+	// 新建知识文件，同名文件已存在时拒绝上传
+	UploadModeCreate = "create"
+
+	// 上传同名文件的新版本，新版本处理成功后替换当前版本
+	UploadModeReplace = "replace"
+)
+
 type UploadKnowledgeMetadataRequest struct {
 	FileName   string `json:"file_name"`
 	FileType   string `json:"file_type"`
 	FileSize   int64  `json:"file_size"`
 	ObjectName string `json:"object_name"`
+	UploadMode string `json:"upload_mode"`
+
+	// 可选，覆盖文件类型的默认切分配置
+	Chunking *ChunkingOptions `json:"chunking"`
+}
+
+// ChunkingOptions 切分配置，未设置的字段使用默认值
+type ChunkingOptions struct {
+	Strategy  string `json:"strategy"`
+	ChunkSize int    `json:"chunk_size"`
+
+	// 为 nil 时使用默认值，0 表示切片之间不重叠
+	ChunkOverlap *int `json:"chunk_overlap"`
 }
@@ -0,0 +1,26 @@
+package request
+
+import "time"
+
+// LogEntryRequest 录入或修改一条用药、胰岛素注射或饮食记录
+type LogEntryRequest struct {
+	// medication、insulin 或 meal，修改记录时忽略
+	Kind string `json:"kind"`
+
+	// 药品名称、胰岛素名称或饮食内容
+	Name string `json:"name"`
+
+	// 用药剂量，如 "500 mg"
+	Dose string `json:"dose"`
+
+	// 胰岛素：rapid、short、intermediate、long、premixed 或 other
+	InsulinType string   `json:"insulin_type"`
+	Units       *float64 `json:"units"`
+
+	// 饮食：碳水化合物（克），breakfast、lunch、dinner 或 snack
+	Carbs    *float64 `json:"carbs"`
+	MealType string   `json:"meal_type"`
+
+	OccurredAt time.Time `json:"occurred_at"`
+	Note       string    `json:"note"`
+}
@@ -5,4 +5,7 @@ type OSSAuthRequest struct {
 	Email     string `json:"email"`
 	SessionID string `json:"session_id"`
 	FileName  string `json:"file_name"`
+
+	// 知识文件版本号，大于 1 时对象路径包含版本目录
+	Version int `json:"version"`
 }
@@ -0,0 +1,6 @@
+package request
+
+type UpdatePatientFactRequest struct {
+	Category string `json:"category" binding:"required"`
+	Content  string `json:"content" binding:"required"`
+}
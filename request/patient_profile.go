@@ -0,0 +1,52 @@
+package request
+
+// SavePatientProfileRequest 创建或整体替换健康档案，未填写的字段会被清空
+type SavePatientProfileRequest struct {
+	DiabetesType string `json:"diabetes_type"`
+
+	// 确诊日期，格式为 YYYY-MM-DD
+	DiagnosisDate string `json:"diagnosis_date"`
+
+	HeightCM *float64 `json:"height_cm"`
+	WeightKG *float64 `json:"weight_kg"`
+
+	Medications    []MedicationRequest    `json:"medications"`
+	InsulinRegimen *InsulinRegimenRequest `json:"insulin_regimen"`
+	Allergies      []string               `json:"allergies"`
+	Comorbidities  []string               `json:"comorbidities"`
+
+	// 目标血糖范围，单位为 GlucoseUnit
+	TargetLow  *float64 `json:"target_low"`
+	TargetHigh *float64 `json:"target_high"`
+
+	// mmol/L（默认）或 mg/dL
+	GlucoseUnit string `json:"glucose_unit"`
+
+	// IANA 时区，如 Asia/Shanghai，为空时使用服务器时区
+	Timezone string `json:"timezone"`
+}
+
+type MedicationRequest struct {
+	Name      string `json:"name"`
+	Dose      string `json:"dose"`
+	Frequency string `json:"frequency"`
+}
+
+type InsulinDoseRequest struct {
+	Name  string  `json:"name"`
+	Units float64 `json:"units"`
+	Time  string  `json:"time"`
+}
+
+type InsulinRegimenRequest struct {
+	// none、basal、basal_bolus、premixed 或 pump
+	Type  string               `json:"type"`
+	Basal []InsulinDoseRequest `json:"basal"`
+	Bolus []InsulinDoseRequest `json:"bolus"`
+
+	// 碳水系数（g/U）
+	CarbRatio *float64 `json:"carb_ratio"`
+
+	// 校正系数，单位为 GlucoseUnit/U
+	CorrectionFactor *float64 `json:"correction_factor"`
+}
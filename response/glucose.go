@@ -0,0 +1,92 @@
+package response
+
+import "time"
+
+type GlucoseReadingResponse struct {
+	ID         uint      `json:"id"`
+	Value      float64   `json:"value"`
+	Unit       string    `json:"unit"`
+	MeasuredAt time.Time `json:"measured_at"`
+	Context    string    `json:"context"`
+	Source     string    `json:"source"`
+	Note       string    `json:"note"`
+}
+
+type GetGlucoseReadingsResponse struct {
+	Readings []GlucoseReadingResponse `json:"readings"`
+}
+
+type CreateGlucoseReadingsResponse struct {
+	// 新写入的条数，与已有记录重复的条数
+	Created int64 `json:"created"`
+	Skipped int64 `json:"skipped"`
+}
+
+// GlucoseMetricsResponse 血糖统计指标，血糖值的单位为 Unit
+type GlucoseMetricsResponse struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+	Unit string    `json:"unit"`
+
+	Count int `json:"count"`
+
+	// 有血糖记录的天数
+	Days int `json:"days"`
+
+	Mean float64 `json:"mean"`
+	SD   float64 `json:"sd"`
+	Min  float64 `json:"min"`
+	Max  float64 `json:"max"`
+
+	// 变异系数（%）和血糖管理指标 GMI（%）
+	CV  float64 `json:"cv"`
+	GMI float64 `json:"gmi"`
+
+	TargetLow  float64 `json:"target_low"`
+	TargetHigh float64 `json:"target_high"`
+
+	// 各血糖区间的时间占比（%）
+	TimeVeryLow  float64 `json:"time_very_low"`
+	TimeLow      float64 `json:"time_low"`
+	TimeInRange  float64 `json:"time_in_range"`
+	TimeHigh     float64 `json:"time_high"`
+	TimeVeryHigh float64 `json:"time_very_high"`
+
+	Hourly    []HourlyGlucoseResponse  `json:"hourly"`
+	ByContext []ContextGlucoseResponse `json:"by_context"`
+}
+
+// HourlyGlucoseResponse 一天中某个小时的血糖分布
+type HourlyGlucoseResponse struct {
+	Hour   int     `json:"hour"`
+	Count  int     `json:"count"`
+	Mean   float64 `json:"mean"`
+	P25    float64 `json:"p25"`
+	Median float64 `json:"median"`
+	P75    float64 `json:"p75"`
+}
+
+// ContextGlucoseResponse 某个测量场景的血糖均值
+type ContextGlucoseResponse struct {
+	Context string  `json:"context"`
+	Count   int     `json:"count"`
+	Mean    float64 `json:"mean"`
+}
+
+// AGPReportResponse 动态血糖图谱的数据，血糖值的单位为 Metrics.Unit
+type AGPReportResponse struct {
+	Metrics     GlucoseMetricsResponse    `json:"metrics"`
+	Profile     []AGPProfilePointResponse `json:"profile"`
+	GeneratedAt time.Time                 `json:"generated_at"`
+}
+
+// AGPProfilePointResponse 一天中某个时刻的血糖百分位，Count 为 0 时表示数据不足
+type AGPProfilePointResponse struct {
+	Minute int     `json:"minute"`
+	Count  int     `json:"count"`
+	P5     float64 `json:"p5"`
+	P25    float64 `json:"p25"`
+	P50    float64 `json:"p50"`
+	P75    float64 `json:"p75"`
+	P95    float64 `json:"p95"`
+}
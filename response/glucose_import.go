@@ -0,0 +1,21 @@
+package response
+
+import "time"
+
+type GlucoseImportResponse struct {
+	ID         uint       `json:"id"`
+	FileName   string     `json:"file_name"`
+	Format     string     `json:"format"`
+	Timezone   string     `json:"timezone"`
+	Status     string     `json:"status"`
+	Created    int64      `json:"created"`
+	Skipped    int64      `json:"skipped"`
+	Invalid    int64      `json:"invalid"`
+	Error      string     `json:"error"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+}
+
+type GetGlucoseImportsResponse struct {
+	Imports []GlucoseImportResponse `json:"imports"`
+}
@@ -1,5 +1,7 @@
 package response
 
+import "time"
+
 // GetPolicyTokenResponse 前端直传文件至OSS的凭证
 type GetPolicyTokenResponse struct {
 	Policy           string `json:"policy"`
@@ -13,9 +15,31 @@ type GetPolicyTokenResponse struct {
 }
 
 type MetadataResponse struct {
-	FileName string `json:"file_name"`
-	FileType string `json:"file_type"`
-	FileSize int64  `json:"file_size"`
+	DocumentID string `json:"document_id"`
+	FileName   string `json:"file_name"`
+	FileType   string `json:"file_type"`
+	FileSize   int64  `json:"file_size"`
+	KnowledgeStatusResponse
+
+	// 该版本使用的切分配置
+	Chunking ChunkingResponse `json:"chunking"`
+}
+
+type ChunkingResponse struct {
+	Strategy     string `json:"strategy"`
+	ChunkSize    int    `json:"chunk_size"`
+	ChunkOverlap int    `json:"chunk_overlap"`
+}
+
+// KnowledgeStatusResponse 知识文件处理状态
+type KnowledgeStatusResponse struct {
+	Version           int    `json:"version"`
+	Status            string `json:"status"`
+	Stage             string `json:"stage"`
+	Progress          int    `json:"progress"`
+	ChunkCount        int    `json:"chunk_count"`
+	ErrorMessage      string `json:"error_message"`
+	ExtractionQuality string `json:"extraction_quality"`
 }
 
 type GetKnowledgeMetadataResponse struct {
@@ -29,3 +53,16 @@ type GetPreSignedURLResponse struct {
 type SearchKnowledgeMetadataResponse struct {
 	Metadata []MetadataResponse `json:"metadata"`
 }
+
+type KnowledgeVersionResponse struct {
+	Version    int       `json:"version"`
+	FileName   string    `json:"file_name"`
+	FileSize   int64     `json:"file_size"`
+	Status     string    `json:"status"`
+	Superseded bool      `json:"superseded"`
+	UploadedAt time.Time `json:"uploaded_at"`
+}
+
+type GetKnowledgeVersionsResponse struct {
+	Versions []KnowledgeVersionResponse `json:"versions"`
+}
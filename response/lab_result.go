@@ -0,0 +1,21 @@
+package response
+
+import "time"
+
+type LabResultResponse struct {
+	DocumentID     string     `json:"document_id"`
+	Analyte        string     `json:"analyte"`
+	Name           string     `json:"name"`
+	Value          *float64   `json:"value"`
+	Unit           string     `json:"unit"`
+	RawValue       string     `json:"raw_value"`
+	RawUnit        string     `json:"raw_unit"`
+	ReferenceLow   *float64   `json:"reference_low"`
+	ReferenceHigh  *float64   `json:"reference_high"`
+	ReferenceRange string     `json:"reference_range"`
+	SampledAt      *time.Time `json:"sampled_at"`
+}
+
+type GetLabResultsResponse struct {
+	Results []LabResultResponse `json:"results"`
+}
@@ -0,0 +1,36 @@
+package response
+
+import "time"
+
+type LogEntryResponse struct {
+	ID          uint      `json:"id"`
+	Kind        string    `json:"kind"`
+	Name        string    `json:"name"`
+	Dose        string    `json:"dose,omitempty"`
+	InsulinType string    `json:"insulin_type,omitempty"`
+	Units       *float64  `json:"units,omitempty"`
+	Carbs       *float64  `json:"carbs,omitempty"`
+	MealType    string    `json:"meal_type,omitempty"`
+	OccurredAt  time.Time `json:"occurred_at"`
+	Note        string    `json:"note"`
+	Source      string    `json:"source"`
+}
+
+type GetLogEntriesResponse struct {
+	Entries []LogEntryResponse `json:"entries"`
+}
+
+// PendingLogActionResponse Agent 提出的待用户确认的操作
+type PendingLogActionResponse struct {
+	ID        uint      `json:"id"`
+	SessionID string    `json:"session_id"`
+	Action    string    `json:"action"`
+	EntryID   uint      `json:"entry_id"`
+	Summary   string    `json:"summary"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type GetPendingLogActionsResponse struct {
+	Actions []PendingLogActionResponse `json:"actions"`
+}
@@ -0,0 +1,19 @@
+package response
+
+import "time"
+
+// OutboxMessageResponse 超时未投递的后台任务
+type OutboxMessageResponse struct {
+	ID            uint      `json:"id"`
+	DocumentID    string    `json:"document_id"`
+	Topic         string    `json:"topic"`
+	Tag           string    `json:"tag"`
+	Attempts      int       `json:"attempts"`
+	LastError     string    `json:"last_error"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type GetStuckOutboxMessagesResponse struct {
+	Messages []OutboxMessageResponse `json:"messages"`
+}
@@ -0,0 +1,20 @@
+package response
+
+import "time"
+
+type PatientFactResponse struct {
+	ID              uint      `json:"id"`
+	Category        string    `json:"category"`
+	Content         string    `json:"content"`
+	Confidence      float64   `json:"confidence"`
+	Source          string    `json:"source"`
+	SourceSessionID string    `json:"source_session_id"`
+	SourceMessageID uint      `json:"source_message_id"`
+	ObservedAt      time.Time `json:"observed_at"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+type GetPatientFactsResponse struct {
+	Facts []PatientFactResponse `json:"facts"`
+}
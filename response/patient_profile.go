@@ -0,0 +1,45 @@
+package response
+
+import "time"
+
+type PatientProfileResponse struct {
+	DiabetesType  string `json:"diabetes_type"`
+	DiagnosisDate string `json:"diagnosis_date"`
+
+	HeightCM *float64 `json:"height_cm"`
+	WeightKG *float64 `json:"weight_kg"`
+	BMI      *float64 `json:"bmi"`
+
+	Medications    []MedicationResponse    `json:"medications"`
+	InsulinRegimen *InsulinRegimenResponse `json:"insulin_regimen"`
+	Allergies      []string                `json:"allergies"`
+	Comorbidities  []string                `json:"comorbidities"`
+
+	// 目标血糖范围，单位为 GlucoseUnit
+	TargetLow   *float64 `json:"target_low"`
+	TargetHigh  *float64 `json:"target_high"`
+	GlucoseUnit string   `json:"glucose_unit"`
+	Timezone    string   `json:"timezone"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type MedicationResponse struct {
+	Name      string `json:"name"`
+	Dose      string `json:"dose"`
+	Frequency string `json:"frequency"`
+}
+
+type InsulinDoseResponse struct {
+	Name  string  `json:"name"`
+	Units float64 `json:"units"`
+	Time  string  `json:"time"`
+}
+
+type InsulinRegimenResponse struct {
+	Type             string                `json:"type"`
+	Basal            []InsulinDoseResponse `json:"basal"`
+	Bolus            []InsulinDoseResponse `json:"bolus"`
+	CarbRatio        *float64              `json:"carb_ratio"`
+	CorrectionFactor *float64              `json:"correction_factor"`
+}
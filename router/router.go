@@ -39,6 +39,39 @@ func Register() *gin.Engine {
 			protected.POST("/kb/metadata", controller.UploadKnowledgeMetadata)
 			protected.DELETE("/kb/metadata", controller.DeleteKnowledgeMetadata)
 			protected.GET("/kb/metadata/search", controller.SearchKnowledgeMetadata)
+			protected.GET("/kb/:id/versions", controller.GetKnowledgeVersions)
+			protected.POST("/kb/:id/versions/:version/rollback", controller.RollbackKnowledgeVersion)
+			protected.GET("/kb/:id/status", controller.GetKnowledgeStatus)
+			protected.GET("/kb/:id/status/stream", controller.StreamKnowledgeStatus)
+			protected.POST("/kb/:id/reprocess", controller.ReprocessKnowledge)
+			protected.GET("/kb/outbox/stuck", controller.GetStuckOutboxMessages)
+
+			protected.GET("/lab-results", controller.GetLabResults)
+
+			protected.GET("/memory/facts", controller.GetPatientFacts)
+			protected.PUT("/memory/facts/:id", controller.UpdatePatientFact)
+			protected.DELETE("/memory/facts/:id", controller.DeletePatientFact)
+
+			protected.GET("/profile", controller.GetPatientProfile)
+			protected.PUT("/profile", controller.SavePatientProfile)
+			protected.DELETE("/profile", controller.DeletePatientProfile)
+
+			protected.POST("/glucose/readings", controller.CreateGlucoseReadings)
+			protected.GET("/glucose/readings", controller.GetGlucoseReadings)
+			protected.DELETE("/glucose/readings/:id", controller.DeleteGlucoseReading)
+			protected.GET("/glucose/metrics", controller.GetGlucoseMetrics)
+			protected.GET("/glucose/agp", controller.GetAGPReport)
+			protected.POST("/glucose/imports", controller.CreateGlucoseImport)
+			protected.GET("/glucose/imports", controller.GetGlucoseImports)
+			protected.GET("/glucose/imports/:id", controller.GetGlucoseImport)
+
+			protected.POST("/logs", controller.CreateLogEntry)
+			protected.GET("/logs", controller.GetLogEntries)
+			protected.PUT("/logs/:id", controller.UpdateLogEntry)
+			protected.DELETE("/logs/:id", controller.DeleteLogEntry)
+			protected.GET("/logs/pending", controller.GetPendingLogActions)
+			protected.POST("/logs/pending/:id/confirm", controller.ConfirmPendingLogAction)
+			protected.POST("/logs/pending/:id/cancel", controller.CancelPendingLogAction)
 		}
 	}
 
@@ -22,7 +22,6 @@ import (
 	"github.com/tmc/langchaingo/agents"
 	"github.com/tmc/langchaingo/chains"
 	"github.com/tmc/langchaingo/llms/openai"
-	"github.com/tmc/langchaingo/memory"
 	"github.com/tmc/langchaingo/tools"
 )
 
@@ -52,6 +51,32 @@ var (
 	conversationalSuffix string
 )
 
+// 提示词前缀中注入用户上下文的位置，创建 Agent 时替换
+const userContextPlaceholder = "{{.user_context}}"
+
+type agentOptions struct {
+	userContext []string
+	tools       []tools.Tool
+}
+
+type AgentOption func(*agentOptions)
+
+// WithUserContext 在提示词前缀中注入关于用户的上下文，如长期记忆
+func WithUserContext(text string) AgentOption {
+	return func(o *agentOptions) {
+		if text != "" {
+			o.userContext = append(o.userContext, text)
+		}
+	}
+}
+
+// WithTools 添加进程内实现的工具，如查询用户血糖数据，不受 AgentConfig.Tools 的筛选
+func WithTools(agentTools ...tools.Tool) AgentOption {
+	return func(o *agentOptions) {
+		o.tools = append(o.tools, agentTools...)
+	}
+}
+
 type Agent struct {
 	// Agent 执行器
 	Executor *agents.Executor
@@ -69,7 +94,12 @@ type Agent struct {
 	SSEHandler *GinSSEHandler
 }
 
-func NewAgent(req request.ChatRequest, c *gin.Context) (*Agent, error) {
+func NewAgent(req request.ChatRequest, c *gin.Context, opts ...AgentOption) (*Agent, error) {
+	var options agentOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	llm, err := openai.New(
 		openai.WithModel(req.AgentConfig.Model),
 		openai.WithToken(config.Cfg.Model.APIKey),
@@ -98,17 +128,22 @@ func NewAgent(req request.ChatRequest, c *gin.Context) (*Agent, error) {
 	sseHandler := NewGinSSEHandler(c, req.SessionID)
 	registerMCPNotificationHandler(ctx, mcpClient, sseHandler)
 
-	a := agents.NewConversationalAgent(llm, mcpTools,
+	agentTools := mcpTools
+	for _, tool := range options.tools {
+		agentTools = append(agentTools, &localTool{Tool: tool, sseHandler: sseHandler})
+	}
+
+	prefix := buildPromptPrefix(options.userContext)
+	a := agents.NewConversationalAgent(llm, agentTools,
 		agents.WithCallbacksHandler(sseHandler),
-		agents.WithPromptPrefix(conversationalPrefix),
+		agents.WithPromptPrefix(prefix),
 		agents.WithPromptFormatInstructions(conversationalFormatInstructions),
 		agents.WithPromptSuffix(conversationalSuffix),
 	)
 
+	// 会话记忆按所选模型的上下文窗口控制长度
 	chatHistory := NewMySQLChatMessageHistory(req.SessionID)
-	memory := memory.NewConversationBuffer(
-		memory.WithChatHistory(chatHistory),
-	)
+	memory := NewSummaryBufferMemory(chatHistory, historyTokenBudget(req.AgentConfig.Model, prefix, agentTools))
 
 	executor := agents.NewExecutor(
 		a,
@@ -125,6 +160,17 @@ func NewAgent(req request.ChatRequest, c *gin.Context) (*Agent, error) {
 	}, nil
 }
 
+// buildPromptPrefix 将用户上下文写入提示词前缀
+// 前缀会作为 Go 模板解析，需要转义用户上下文中的模板分隔符
+func buildPromptPrefix(userContext []string) string {
+	var section string
+	if len(userContext) > 0 {
+		escaper := strings.NewReplacer("{{", "{ {", "}}", "} }")
+		section = "\n" + escaper.Replace(strings.Join(userContext, "\n\n")) + "\n"
+	}
+	return strings.Replace(conversationalPrefix, userContextPlaceholder, section, 1)
+}
+
 func (a *Agent) Call(ctx context.Context, req request.ChatRequest, c *gin.Context) error {
 	// TODO: 若用户传入图片URL，调用视觉理解模型生成图片摘要，与 query 拼接
 
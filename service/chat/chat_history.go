@@ -40,7 +40,8 @@ func NewMySQLChatMessageHistory(session string) *MySQLChatMessageHistory {
 	}
 }
 
-// Messages 加载记忆时优先选取消息摘要，若为空选取全量消息
+// Messages 按时间顺序返回最近 Limit 条聊天记录
+// Agent 的记忆由 SummaryBufferMemory 按 token 预算组装，不使用该方法
 func (h *MySQLChatMessageHistory) Messages(ctx context.Context) ([]llms.ChatMessage, error) {
 	if ctx == nil {
 		ctx = context.Background()
@@ -48,15 +49,14 @@ func (h *MySQLChatMessageHistory) Messages(ctx context.Context) ([]llms.ChatMess
 
 	var messages []struct {
 		Content string
-		Summary string
 		Role    string
 	}
 
 	result := h.DB.WithContext(ctx).
 		Table(h.TableName).
-		Select("content, summary, role").
+		Select("content, role").
 		Where("session_id = ?", h.Session).
-		Order("created_at ASC").
+		Order("id DESC").
 		Limit(h.Limit).
 		Find(&messages)
 
@@ -64,22 +64,10 @@ func (h *MySQLChatMessageHistory) Messages(ctx context.Context) ([]llms.ChatMess
 		return nil, result.Error
 	}
 
-	var msgs []llms.ChatMessage
-	for _, msg := range messages {
-		var content string
-		if msg.Summary != "" {
-			content = msg.Summary
-		} else {
-			content = msg.Content
-		}
-
-		switch msg.Role {
-		case string(llms.ChatMessageTypeAI):
-			msgs = append(msgs, llms.AIChatMessage{Content: content})
-		case string(llms.ChatMessageTypeHuman):
-			msgs = append(msgs, llms.HumanChatMessage{Content: content})
-		case string(llms.ChatMessageTypeSystem):
-			msgs = append(msgs, llms.SystemChatMessage{Content: content})
+	msgs := make([]llms.ChatMessage, 0, len(messages))
+	for i := len(messages) - 1; i >= 0; i-- {
+		if msg := toChatMessage(messages[i].Role, messages[i].Content); msg != nil {
+			msgs = append(msgs, msg)
 		}
 	}
 
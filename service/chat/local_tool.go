@@ -0,0 +1,28 @@
+package chat
+
+import (
+	"context"
+	"diabetes-agent-backend/model"
+
+	"github.com/tmc/langchaingo/tools"
+)
+
+// localTool 包装进程内工具，与 MCP 工具一样推送并保存工具调用结果
+type localTool struct {
+	tools.Tool
+
+	sseHandler *GinSSEHandler
+}
+
+func (t *localTool) Call(ctx context.Context, input string) (string, error) {
+	output, err := t.Tool.Call(ctx, input)
+	if err != nil {
+		return "", err
+	}
+
+	t.sseHandler.HandleToolCallResult(ctx, model.ToolCallResult{
+		Name:   t.Name(),
+		Result: []string{output},
+	})
+	return output, nil
+}
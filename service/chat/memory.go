@@ -0,0 +1,172 @@
+package chat
+
+import (
+	"context"
+	"diabetes-agent-backend/config"
+	"diabetes-agent-backend/dao"
+	"diabetes-agent-backend/model"
+	"diabetes-agent-backend/utils"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/memory"
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/tools"
+)
+
+const (
+	defaultRecentTurns    = 4
+	defaultContextWindow  = 32768
+	defaultReservedTokens = 8192
+
+	// 加载摘要之后聊天记录的数量上限，摘要生成滞后时更早的记录由 token 预算截断
+	maxHistoryMessages = 200
+
+	// 会话摘要最多占用记忆预算的比例
+	summaryBudgetRatio = 0.5
+)
+
+// RecentTurns 原文保留的最近对话轮数
+func RecentTurns() int {
+	if turns := config.Cfg.Chat.Memory.RecentTurns; turns > 0 {
+		return turns
+	}
+	return defaultRecentTurns
+}
+
+// ContextWindow 返回模型的上下文窗口（token）
+func ContextWindow(modelName string) int {
+	memoryConfig := config.Cfg.Chat.Memory
+	if window := memoryConfig.ContextWindows[modelName]; window > 0 {
+		return window
+	}
+	if memoryConfig.DefaultContextWindow > 0 {
+		return memoryConfig.DefaultContextWindow
+	}
+	return defaultContextWindow
+}
+
+func reservedTokens() int {
+	if tokens := config.Cfg.Chat.Memory.ReservedTokens; tokens > 0 {
+		return tokens
+	}
+	return defaultReservedTokens
+}
+
+// historyTokenBudget 计算记忆和本轮输入可以使用的 token 数
+// 从模型上下文窗口中扣除预留的输出、提示词模板和工具描述占用的 token
+func historyTokenBudget(modelName, prefix string, agentTools []tools.Tool) int {
+	prompt := prefix + conversationalFormatInstructions + conversationalSuffix
+	for _, tool := range agentTools {
+		prompt += fmt.Sprintf("- %s: %s\n", tool.Name(), tool.Description())
+	}
+
+	budget := ContextWindow(modelName) - reservedTokens() - utils.CountTokens(prompt)
+	return max(budget, 0)
+}
+
+// SummaryBufferMemory 会话记忆，由会话的滚动摘要和摘要之后的最近聊天记录原文组成
+// 按 token 预算从最新的聊天记录开始保留，超出预算的更早记录只以摘要的形式出现
+type SummaryBufferMemory struct {
+	*memory.ConversationBuffer
+
+	ChatHistory *MySQLChatMessageHistory
+
+	// 记忆和本轮输入可以使用的 token 数
+	TokenBudget int
+}
+
+var _ schema.Memory = &SummaryBufferMemory{}
+
+func NewSummaryBufferMemory(chatHistory *MySQLChatMessageHistory, tokenBudget int) *SummaryBufferMemory {
+	return &SummaryBufferMemory{
+		ConversationBuffer: memory.NewConversationBuffer(memory.WithChatHistory(chatHistory)),
+		ChatHistory:        chatHistory,
+		TokenBudget:        tokenBudget,
+	}
+}
+
+func (m *SummaryBufferMemory) LoadMemoryVariables(ctx context.Context, inputs map[string]any) (map[string]any, error) {
+	budget := m.TokenBudget
+	if input, err := memory.GetInputValue(inputs, m.InputKey); err == nil {
+		budget -= utils.CountTokens(input)
+	}
+
+	session, err := dao.GetSession(m.ChatHistory.Session)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %v", err)
+	}
+
+	var (
+		summary          string
+		summaryMessageID uint
+	)
+	if session != nil {
+		summary = session.Summary
+		summaryMessageID = session.SummaryMessageID
+	}
+
+	messages, err := dao.GetRecentMessages(m.ChatHistory.Session, summaryMessageID, maxHistoryMessages)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recent messages: %v", err)
+	}
+
+	history, err := m.buildHistory(summary, messages, budget)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]any{
+		m.MemoryKey: history,
+	}, nil
+}
+
+// buildHistory 拼接会话摘要和最近的聊天记录，总长度不超过 budget 个 token
+func (m *SummaryBufferMemory) buildHistory(summary string, messages []model.Message, budget int) (string, error) {
+	var summaryLine string
+	if summary = utils.TruncateTokens(summary, int(float64(budget)*summaryBudgetRatio)); summary != "" {
+		summaryLine = "Summary of earlier conversation: " + summary
+		budget -= utils.CountTokens(summaryLine)
+	}
+
+	// 从最新的聊天记录开始保留，直到超出预算
+	var lines []string
+	for i := len(messages) - 1; i >= 0; i-- {
+		message := toChatMessage(messages[i].Role, messages[i].Content)
+		if message == nil {
+			continue
+		}
+
+		line, err := llms.GetBufferString([]llms.ChatMessage{message}, m.HumanPrefix, m.AIPrefix)
+		if err != nil {
+			return "", err
+		}
+
+		tokens := utils.CountTokens(line)
+		if tokens > budget {
+			break
+		}
+		budget -= tokens
+		lines = append(lines, line)
+	}
+
+	if summaryLine != "" {
+		lines = append(lines, summaryLine)
+	}
+	slices.Reverse(lines)
+	return strings.Join(lines, "\n"), nil
+}
+
+func toChatMessage(role, content string) llms.ChatMessage {
+	switch role {
+	case string(llms.ChatMessageTypeAI):
+		return llms.AIChatMessage{Content: content}
+	case string(llms.ChatMessageTypeHuman):
+		return llms.HumanChatMessage{Content: content}
+	case string(llms.ChatMessageTypeSystem):
+		return llms.SystemChatMessage{Content: content}
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package embedding
+
+import (
+	"context"
+	"diabetes-agent-backend/dao"
+	"diabetes-agent-backend/model"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	CacheMySQL = "mysql"
+	CacheDisk  = "disk"
+	CacheNone  = "none"
+
+	defaultCacheDir = "embedding-cache"
+)
+
+// Cache 按模型和文本摘要缓存向量
+type Cache interface {
+	// Get 返回命中缓存的向量，key 为文本摘要
+	Get(ctx context.Context, modelKey string, hashes []string) (map[string][]float32, error)
+
+	Set(ctx context.Context, modelKey string, vectors map[string][]float32) error
+}
+
+func newCache(cacheType, dir string) (Cache, error) {
+	switch cacheType {
+	case "", CacheMySQL:
+		return MySQLCache{}, nil
+	case CacheDisk:
+		if dir == "" {
+			dir = defaultCacheDir
+		}
+		return NewDiskCache(dir), nil
+	case CacheNone:
+		return NoopCache{}, nil
+	}
+	return nil, fmt.Errorf("unsupported embedding cache type: %s", cacheType)
+}
+
+// NoopCache 不缓存向量
+type NoopCache struct{}
+
+func (NoopCache) Get(ctx context.Context, modelKey string, hashes []string) (map[string][]float32, error) {
+	return nil, nil
+}
+
+func (NoopCache) Set(ctx context.Context, modelKey string, vectors map[string][]float32) error {
+	return nil
+}
+
+// MySQLCache 将向量缓存在 embedding_cache 表中，多个实例共享
+type MySQLCache struct{}
+
+func (MySQLCache) Get(ctx context.Context, modelKey string, hashes []string) (map[string][]float32, error) {
+	entries, err := dao.GetEmbeddingCache(modelKey, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	vectors := make(map[string][]float32, len(entries))
+	for _, entry := range entries {
+		vectors[entry.ContentHash] = decodeVector(entry.Vector)
+	}
+	return vectors, nil
+}
+
+func (MySQLCache) Set(ctx context.Context, modelKey string, vectors map[string][]float32) error {
+	entries := make([]model.EmbeddingCache, 0, len(vectors))
+	for hash, vector := range vectors {
+		entries = append(entries, model.EmbeddingCache{
+			ModelKey:    modelKey,
+			ContentHash: hash,
+			Vector:      encodeVector(vector),
+		})
+	}
+	return dao.SaveEmbeddingCache(entries)
+}
+
+// DiskCache 将向量缓存在本地目录，每个向量一个文件，适合单机和离线测试
+type DiskCache struct {
+	Dir string
+}
+
+func NewDiskCache(dir string) DiskCache {
+	return DiskCache{Dir: dir}
+}
+
+func (c DiskCache) Get(ctx context.Context, modelKey string, hashes []string) (map[string][]float32, error) {
+	vectors := make(map[string][]float32)
+	for _, hash := range hashes {
+		data, err := os.ReadFile(c.path(modelKey, hash))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		vectors[hash] = decodeVector(data)
+	}
+	return vectors, nil
+}
+
+// Set 先写入临时文件再重命名，并发写入同一向量时不会读到不完整的文件
+func (c DiskCache) Set(ctx context.Context, modelKey string, vectors map[string][]float32) error {
+	for hash, vector := range vectors {
+		path := c.path(modelKey, hash)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+
+		tmp, err := os.CreateTemp(filepath.Dir(path), hash+".tmp*")
+		if err != nil {
+			return err
+		}
+		_, err = tmp.Write(encodeVector(vector))
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Rename(tmp.Name(), path)
+		}
+		if err != nil {
+			os.Remove(tmp.Name())
+			return err
+		}
+	}
+	return nil
+}
+
+// path 按摘要前两位分目录，避免单个目录下文件过多
+func (c DiskCache) path(modelKey, hash string) string {
+	modelDir := strings.NewReplacer("/", "_", "\\", "_", ":", "_").Replace(modelKey)
+	return filepath.Join(c.Dir, modelDir, hash[:2], hash)
+}
+
+func encodeVector(vector []float32) []byte {
+	data := make([]byte, len(vector)*4)
+	for i, value := range vector {
+		binary.LittleEndian.PutUint32(data[i*4:], math.Float32bits(value))
+	}
+	return data
+}
+
+func decodeVector(data []byte) []float32 {
+	vector := make([]float32, len(data)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+	}
+	return vector
+}
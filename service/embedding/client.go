@@ -0,0 +1,53 @@
+package embedding
+
+import (
+	"diabetes-agent-backend/config"
+	"diabetes-agent-backend/service/chat"
+	"diabetes-agent-backend/utils"
+	"fmt"
+
+	"github.com/tmc/langchaingo/llms/openai"
+)
+
+const (
+	ProviderDashScope = "dashscope"
+	ProviderOpenAI    = "openai"
+	ProviderHash      = "hash"
+)
+
+// newClient 按服务商创建向量化模型客户端，未配置时使用 DashScope
+func newClient(provider, modelName string, dim int64) (Client, error) {
+	cfg := config.Cfg.Model.Embedding
+
+	switch provider {
+	case ProviderHash:
+		return NewHashClient(int(dim)), nil
+	case "", ProviderDashScope, ProviderOpenAI:
+		baseURL := cfg.BaseURL
+		if baseURL == "" && provider != ProviderOpenAI {
+			baseURL = chat.BaseURL
+		}
+		apiKey := cfg.APIKey
+		if apiKey == "" {
+			apiKey = config.Cfg.Model.APIKey
+		}
+
+		opts := []openai.Option{
+			openai.WithEmbeddingModel(modelName),
+			// 请求与集合一致的维度，支持多种维度的模型默认返回的维度可能与集合不同
+			openai.WithEmbeddingDimensions(int(dim)),
+			openai.WithToken(apiKey),
+			openai.WithHTTPClient(utils.DefaultHTTPClient()),
+		}
+		if baseURL != "" {
+			opts = append(opts, openai.WithBaseURL(baseURL))
+		}
+
+		client, err := openai.New(opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create embedding client: %v", err)
+		}
+		return client, nil
+	}
+	return nil, fmt.Errorf("unsupported embedding provider: %s", provider)
+}
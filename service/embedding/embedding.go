@@ -0,0 +1,229 @@
+package embedding
+
+import (
+	"context"
+	"diabetes-agent-backend/config"
+	"diabetes-agent-backend/service/knowledge-base/vectorstore"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/avast/retry-go/v4"
+	"github.com/tmc/langchaingo/embeddings"
+	"golang.org/x/time/rate"
+)
+
+const (
+	defaultBatchSize   = 10
+	defaultMaxAttempts = 5
+	retryDelay         = time.Second
+	maxRetryDelay      = 30 * time.Second
+)
+
+var (
+	defaultService     *Service
+	defaultServiceErr  error
+	defaultServiceOnce sync.Once
+)
+
+// Client 向量化模型客户端
+type Client interface {
+	CreateEmbedding(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// Service 向量化服务，按内容摘要缓存向量，并对模型请求限流和重试
+type Service struct {
+	client      Client
+	cache       Cache
+	modelKey    string
+	dim         int
+	limiter     *rate.Limiter
+	batchSize   int
+	maxAttempts uint
+}
+
+var _ embeddings.Embedder = &Service{}
+
+// Default 返回配置的向量化模型对应的共享服务，所有处理器复用同一个客户端和缓存
+func Default() (*Service, error) {
+	defaultServiceOnce.Do(func() {
+		spec := vectorstore.DefaultSpec()
+		defaultService, defaultServiceErr = New(spec.EmbeddingModel, spec.Dim)
+	})
+	return defaultService, defaultServiceErr
+}
+
+// New 创建指定模型和维度的向量化服务，服务商、限流和缓存使用配置
+func New(modelName string, dim int64) (*Service, error) {
+	cfg := config.Cfg.Model.Embedding
+
+	client, err := newClient(cfg.Provider, modelName, dim)
+	if err != nil {
+		return nil, err
+	}
+
+	cache, err := newCache(cfg.Cache.Type, cfg.Cache.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	limiter := rate.NewLimiter(rate.Inf, 0)
+	if cfg.RateLimit > 0 {
+		limiter = rate.NewLimiter(rate.Limit(cfg.RateLimit), 1)
+	}
+
+	batchSize := cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+	maxAttempts := cfg.MaxAttempts
+	if maxAttempts == 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+
+	return NewService(client, cache, fmt.Sprintf("%s@%d", modelName, dim), int(dim), limiter, batchSize, maxAttempts), nil
+}
+
+// NewService 使用给定的客户端和缓存创建向量化服务，modelKey 区分不同模型的缓存，dim 为模型返回向量的维度
+func NewService(client Client, cache Cache, modelKey string, dim int, limiter *rate.Limiter, batchSize int, maxAttempts uint) *Service {
+	return &Service{
+		client:      client,
+		cache:       cache,
+		modelKey:    modelKey,
+		dim:         dim,
+		limiter:     limiter,
+		batchSize:   batchSize,
+		maxAttempts: maxAttempts,
+	}
+}
+
+func (s *Service) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	vectors, err := s.EmbedDocuments(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+// EmbedDocuments 返回与 texts 一一对应的向量，命中缓存的文本不再请求模型
+func (s *Service) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	hashes := make([]string, len(texts))
+	for i, text := range texts {
+		hashes[i] = vectorstore.ContentHash(text)
+	}
+
+	cached, err := s.cache.Get(ctx, s.modelKey, hashes)
+	if err != nil {
+		// 缓存不可用时直接请求模型
+		slog.Warn("failed to read embedding cache", "model", s.modelKey, "err", err)
+		cached = nil
+	}
+
+	vectors := make([][]float32, len(texts))
+	var missing []int
+	pending := make(map[string]bool)
+	for i, hash := range hashes {
+		if vector, ok := cached[hash]; ok {
+			vectors[i] = vector
+			continue
+		}
+		// 同一批中内容相同的文本只请求一次
+		if !pending[hash] {
+			pending[hash] = true
+			missing = append(missing, i)
+		}
+	}
+
+	created := make(map[string][]float32, len(missing))
+	for start := 0; start < len(missing); start += s.batchSize {
+		end := min(start+s.batchSize, len(missing))
+
+		batch := make([]string, 0, end-start)
+		for _, i := range missing[start:end] {
+			batch = append(batch, texts[i])
+		}
+
+		embedded, err := s.createEmbedding(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		if len(embedded) != len(batch) {
+			return nil, fmt.Errorf("embedding count mismatch: expected %d, got %d", len(batch), len(embedded))
+		}
+
+		for j, i := range missing[start:end] {
+			// 维度不一致的向量无法写入集合，也不能写入缓存
+			if len(embedded[j]) != s.dim {
+				return nil, fmt.Errorf("embedding dimension mismatch: expected %d, got %d", s.dim, len(embedded[j]))
+			}
+			created[hashes[i]] = embedded[j]
+		}
+	}
+
+	for i, hash := range hashes {
+		if vectors[i] == nil {
+			vectors[i] = created[hash]
+		}
+	}
+
+	if len(created) > 0 {
+		if err := s.cache.Set(ctx, s.modelKey, created); err != nil {
+			slog.Warn("failed to write embedding cache", "model", s.modelKey, "err", err)
+		}
+	}
+
+	slog.Debug("embedded documents",
+		"model", s.modelKey,
+		"texts", len(texts),
+		"cache_hits", len(texts)-len(missing),
+	)
+
+	return vectors, nil
+}
+
+// createEmbedding 限流后请求模型，被限流或服务端出错时退避重试
+func (s *Service) createEmbedding(ctx context.Context, texts []string) ([][]float32, error) {
+	var vectors [][]float32
+	err := retry.Do(
+		func() error {
+			if err := s.limiter.Wait(ctx); err != nil {
+				return retry.Unrecoverable(err)
+			}
+
+			var err error
+			vectors, err = s.client.CreateEmbedding(ctx, texts)
+			return err
+		},
+		retry.Context(ctx),
+		retry.Attempts(s.maxAttempts),
+		retry.Delay(retryDelay),
+		retry.MaxDelay(maxRetryDelay),
+		retry.DelayType(retry.BackOffDelay),
+		retry.LastErrorOnly(true),
+		retry.RetryIf(isRetryable),
+		retry.OnRetry(func(n uint, err error) {
+			slog.Warn("retrying embedding request",
+				"model", s.modelKey,
+				"attempt", n+1,
+				"err", err,
+			)
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create embedding: %v", err)
+	}
+	return vectors, nil
+}
+
+// isRetryable 判断是否为限流或服务端错误，OpenAI 兼容客户端只在错误信息中携带状态码
+func isRetryable(err error) bool {
+	msg := err.Error()
+	for _, code := range []string{"429", "500", "502", "503", "504"} {
+		if strings.Contains(msg, "status code: "+code) {
+			return true
+		}
+	}
+	return false
+}
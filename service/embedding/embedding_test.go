@@ -0,0 +1,147 @@
+package embedding
+
+import (
+	"context"
+	"diabetes-agent-backend/service/knowledge-base/vectorstore"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const testDim = 16
+
+// countingClient 记录请求次数和请求的文本，前 failures 次请求返回 err
+type countingClient struct {
+	mu       sync.Mutex
+	client   Client
+	failures int
+	err      error
+	calls    int
+	texts    []string
+}
+
+func (c *countingClient) CreateEmbedding(ctx context.Context, texts []string) ([][]float32, error) {
+	c.mu.Lock()
+	c.calls++
+	c.texts = append(c.texts, texts...)
+	fail := c.calls <= c.failures
+	c.mu.Unlock()
+
+	if fail {
+		return nil, c.err
+	}
+	return c.client.CreateEmbedding(ctx, texts)
+}
+
+func newTestService(client Client, cache Cache, maxAttempts uint) *Service {
+	return NewService(client, cache, "hash@16", testDim, rate.NewLimiter(rate.Inf, 0), 2, maxAttempts)
+}
+
+func TestEmbedDocumentsUsesContentHashCache(t *testing.T) {
+	ctx := context.Background()
+	client := &countingClient{client: NewHashClient(testDim)}
+	s := newTestService(client, NewDiskCache(t.TempDir()), 1)
+
+	first, err := s.EmbedDocuments(ctx, []string{"空腹血糖", "餐后血糖", "空腹血糖"})
+	if err != nil {
+		t.Fatalf("EmbedDocuments() error = %v", err)
+	}
+	// 同一批中重复的文本只请求一次
+	if len(client.texts) != 2 {
+		t.Fatalf("requested texts = %q, want 2 distinct texts", client.texts)
+	}
+	if !slices.Equal(first[0], first[2]) {
+		t.Error("duplicate texts got different vectors")
+	}
+
+	second, err := s.EmbedDocuments(ctx, []string{"餐后血糖", "糖化血红蛋白", "空腹血糖"})
+	if err != nil {
+		t.Fatalf("EmbedDocuments() error = %v", err)
+	}
+	if got := client.texts[2:]; len(got) != 1 || got[0] != "糖化血红蛋白" {
+		t.Errorf("requested texts on second call = %q, want only the uncached text", got)
+	}
+	if !slices.Equal(second[0], first[1]) || !slices.Equal(second[2], first[0]) {
+		t.Error("cached vectors differ from the vectors created by the model")
+	}
+
+	// 不同模型不共享缓存
+	other := NewService(client, s.cache, "other@16", testDim, rate.NewLimiter(rate.Inf, 0), 2, 1)
+	if _, err := other.EmbedQuery(ctx, "空腹血糖"); err != nil {
+		t.Fatalf("EmbedQuery() error = %v", err)
+	}
+	if got := client.texts[len(client.texts)-1]; len(client.texts) != 4 || got != "空腹血糖" {
+		t.Errorf("requested texts = %q, want a cache miss for another model", client.texts)
+	}
+}
+
+func TestEmbedDocumentsRetriesRateLimitedRequests(t *testing.T) {
+	client := &countingClient{
+		client:   NewHashClient(testDim),
+		failures: 1,
+		err:      errors.New("API returned unexpected status code: 429: Requests rate limit exceeded"),
+	}
+	s := newTestService(client, NoopCache{}, 2)
+
+	start := time.Now()
+	vectors, err := s.EmbedDocuments(context.Background(), []string{"空腹血糖"})
+	if err != nil {
+		t.Fatalf("EmbedDocuments() error = %v", err)
+	}
+	if client.calls != 2 || len(vectors) != 1 {
+		t.Errorf("calls = %d, vectors = %d, want a retry after the 429", client.calls, len(vectors))
+	}
+	if elapsed := time.Since(start); elapsed < retryDelay {
+		t.Errorf("retried after %v, want backoff of at least %v", elapsed, retryDelay)
+	}
+}
+
+func TestEmbedDocumentsGivesUpAfterMaxAttempts(t *testing.T) {
+	client := &countingClient{
+		client:   NewHashClient(testDim),
+		failures: 2,
+		err:      errors.New("API returned unexpected status code: 429"),
+	}
+	s := newTestService(client, NoopCache{}, 2)
+
+	if _, err := s.EmbedDocuments(context.Background(), []string{"空腹血糖"}); err == nil {
+		t.Fatal("EmbedDocuments() error = nil, want rate limit error")
+	}
+	if client.calls != 2 {
+		t.Errorf("calls = %d, want %d", client.calls, 2)
+	}
+}
+
+func TestEmbedDocumentsDoesNotRetryClientErrors(t *testing.T) {
+	client := &countingClient{
+		client:   NewHashClient(testDim),
+		failures: 1,
+		err:      errors.New("API returned unexpected status code: 400: invalid input"),
+	}
+	s := newTestService(client, NoopCache{}, 5)
+
+	if _, err := s.EmbedDocuments(context.Background(), []string{"空腹血糖"}); err == nil {
+		t.Fatal("EmbedDocuments() error = nil, want invalid input error")
+	}
+	if client.calls != 1 {
+		t.Errorf("calls = %d, want 1", client.calls)
+	}
+}
+
+func TestEmbedDocumentsRejectsDimensionMismatch(t *testing.T) {
+	cache := NewDiskCache(t.TempDir())
+	s := newTestService(NewHashClient(testDim*2), cache, 1)
+
+	_, err := s.EmbedDocuments(context.Background(), []string{"空腹血糖"})
+	if err == nil || !strings.Contains(err.Error(), "dimension mismatch") {
+		t.Fatalf("EmbedDocuments() error = %v, want dimension mismatch", err)
+	}
+	if cached, _ := cache.Get(context.Background(), "hash@16", []string{vectorstore.ContentHash("空腹血糖")}); len(cached) != 0 {
+		t.Error("vectors with the wrong dimension were cached")
+	}
+}
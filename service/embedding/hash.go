@@ -0,0 +1,57 @@
+package embedding
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+)
+
+// HashClient 根据文本摘要生成确定性向量，不依赖外部服务，用于离线测试
+// 相同文本得到相同向量，不同文本的向量近似正交，不具备语义相似性
+type HashClient struct {
+	Dim int
+}
+
+var _ Client = HashClient{}
+
+func NewHashClient(dim int) HashClient {
+	return HashClient{Dim: dim}
+}
+
+func (c HashClient) CreateEmbedding(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = c.embed(text)
+	}
+	return vectors, nil
+}
+
+// embed 以计数器模式扩展文本摘要填充各维度，再归一化为单位向量
+func (c HashClient) embed(text string) []float32 {
+	vector := make([]float32, c.Dim)
+	seed := sha256.Sum256([]byte(text))
+
+	var block [sha256.Size]byte
+	var norm float64
+	for i := range vector {
+		if i%8 == 0 {
+			var input [sha256.Size + 8]byte
+			copy(input[:], seed[:])
+			binary.LittleEndian.PutUint64(input[sha256.Size:], uint64(i/8))
+			block = sha256.Sum256(input[:])
+		}
+		n := binary.LittleEndian.Uint32(block[(i%8)*4:])
+		value := float64(n)/math.MaxUint32*2 - 1
+		vector[i] = float32(value)
+		norm += value * value
+	}
+
+	if norm > 0 {
+		scale := float32(1 / math.Sqrt(norm))
+		for i := range vector {
+			vector[i] *= scale
+		}
+	}
+	return vector
+}
@@ -0,0 +1,121 @@
+package agp
+
+import (
+	"diabetes-agent-backend/model"
+	"diabetes-agent-backend/service/glucose"
+	"slices"
+	"time"
+)
+
+const (
+	// 百分位曲线的时间分箱（分钟），一天 96 个点
+	binMinutes = 15
+
+	// 每个点合并前后 30 分钟内的读数，使曲线平滑
+	smoothingBins = 2
+
+	// 读数少于该数量的点不绘制
+	minReadingsPerPoint = 5
+
+	// AGP 建议至少使用 14 天的数据
+	recommendedDays = 14
+)
+
+// ProfilePoint 一天中某个时刻的血糖百分位（mmol/L），Count 为 0 时表示数据不足
+type ProfilePoint struct {
+	// 距离零点的分钟数，取时间分箱的中点
+	Minute int
+
+	Count int
+	P5    float64
+	P25   float64
+	P50   float64
+	P75   float64
+	P95   float64
+}
+
+// Report 动态血糖图谱报告
+type Report struct {
+	From time.Time
+	To   time.Time
+
+	// 报告中血糖值的展示单位，计算均使用 mmol/L
+	Unit model.GlucoseUnit
+
+	Metrics glucose.Metrics
+	Profile []ProfilePoint
+
+	GeneratedAt time.Time
+}
+
+// BuildReport 使用 [from, to) 内的血糖记录生成报告
+func BuildReport(email string, from, to time.Time) (*Report, error) {
+	readings, err := glucose.GetReadings(email, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	low, high, err := glucose.TargetRange(email)
+	if err != nil {
+		return nil, err
+	}
+
+	unit, err := glucose.PreferredUnit(email)
+	if err != nil {
+		return nil, err
+	}
+
+	loc, err := glucose.Location(email)
+	if err != nil {
+		return nil, err
+	}
+
+	// 报告中的日期和时刻都使用用户所在的时区
+	from, to = from.In(loc), to.In(loc)
+	metrics := glucose.ComputeMetrics(readings, low, high, loc)
+	metrics.From = from
+	metrics.To = to
+
+	return &Report{
+		From:        from,
+		To:          to,
+		Unit:        unit,
+		Metrics:     metrics,
+		Profile:     ComputeProfile(readings, loc),
+		GeneratedAt: time.Now().In(loc),
+	}, nil
+}
+
+// ComputeProfile 按 loc 时区中一天的时刻计算 5/25/50/75/95 百分位曲线，不区分日期
+func ComputeProfile(readings []model.GlucoseReading, loc *time.Location) []ProfilePoint {
+	binCount := 24 * 60 / binMinutes
+	bins := make([][]float64, binCount)
+	for _, reading := range readings {
+		measuredAt := reading.MeasuredAt.In(loc)
+		bin := (measuredAt.Hour()*60 + measuredAt.Minute()) / binMinutes
+		bins[bin] = append(bins[bin], reading.Value)
+	}
+
+	profile := make([]ProfilePoint, 0, binCount)
+	for i := range binCount {
+		point := ProfilePoint{Minute: i*binMinutes + binMinutes/2}
+
+		// 跨零点的时刻合并前一天末尾和后一天开头的读数
+		var values []float64
+		for offset := -smoothingBins; offset <= smoothingBins; offset++ {
+			values = append(values, bins[(i+offset+binCount)%binCount]...)
+		}
+
+		if len(values) >= minReadingsPerPoint {
+			slices.Sort(values)
+			point.Count = len(values)
+			point.P5 = glucose.Percentile(values, 5)
+			point.P25 = glucose.Percentile(values, 25)
+			point.P50 = glucose.Percentile(values, 50)
+			point.P75 = glucose.Percentile(values, 75)
+			point.P95 = glucose.Percentile(values, 95)
+		}
+		profile = append(profile, point)
+	}
+	return profile
+}
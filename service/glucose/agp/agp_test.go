@@ -0,0 +1,42 @@
+package agp
+
+import (
+	"diabetes-agent-backend/model"
+	"testing"
+	"time"
+)
+
+func TestComputeProfileBucketsByUserTimezone(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skipf("tzdata not available: %v", err)
+	}
+
+	// 连续 5 天 UTC 零点的读数，在上海为早上 8 点
+	var readings []model.GlucoseReading
+	for day := range minReadingsPerPoint {
+		readings = append(readings, model.GlucoseReading{
+			Value:      float64(5 + day),
+			MeasuredAt: time.Date(2026, 3, 1+day, 0, 5, 0, 0, time.UTC),
+		})
+	}
+
+	countAt := func(profile []ProfilePoint, hour int) int {
+		return profile[hour*60/binMinutes].Count
+	}
+
+	utc := ComputeProfile(readings, time.UTC)
+	if countAt(utc, 0) != minReadingsPerPoint || countAt(utc, 8) != 0 {
+		t.Errorf("UTC counts at 00:00 and 08:00 = %d, %d, want %d, 0",
+			countAt(utc, 0), countAt(utc, 8), minReadingsPerPoint)
+	}
+
+	local := ComputeProfile(readings, shanghai)
+	if countAt(local, 8) != minReadingsPerPoint || countAt(local, 0) != 0 {
+		t.Errorf("Asia/Shanghai counts at 08:00 and 00:00 = %d, %d, want %d, 0",
+			countAt(local, 8), countAt(local, 0), minReadingsPerPoint)
+	}
+	if p50 := local[8*60/binMinutes].P50; p50 != 7 {
+		t.Errorf("P50 at 08:00 = %v, want 7", p50)
+	}
+}
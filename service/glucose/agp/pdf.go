@@ -0,0 +1,136 @@
+package agp
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"strings"
+)
+
+// Helvetica 中 ASCII 32-126 字符的宽度（1/1000 字号），用于计算居中和右对齐文本的位置
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+// pdfCanvas 生成单页 PDF 的内容流，使用内置的 Helvetica 字体，不嵌入字体文件
+// PDF 坐标原点在左下角，绘制时翻转纵坐标
+type pdfCanvas struct {
+	content bytes.Buffer
+}
+
+var _ canvas = &pdfCanvas{}
+
+// RenderPDF 将报告渲染为单页 A4 PDF
+func RenderPDF(report *Report) []byte {
+	c := &pdfCanvas{}
+	drawReport(c, report)
+	return c.document()
+}
+
+func (c *pdfCanvas) rect(x, y, w, h float64, fill color.RGBA) {
+	if w <= 0 || h <= 0 {
+		return
+	}
+	fmt.Fprintf(&c.content, "%s rg %.2f %.2f %.2f %.2f re f\n", pdfColor(fill), x, pageHeight-y-h, w, h)
+}
+
+func (c *pdfCanvas) polygon(points []point, fill color.RGBA) {
+	if len(points) < 3 {
+		return
+	}
+	fmt.Fprintf(&c.content, "%s rg ", pdfColor(fill))
+	c.path(points)
+	c.content.WriteString("h f\n")
+}
+
+func (c *pdfCanvas) polyline(points []point, stroke color.RGBA, width float64) {
+	if len(points) < 2 {
+		return
+	}
+	fmt.Fprintf(&c.content, "%s RG %.2f w 1 J 1 j ", pdfColor(stroke), width)
+	c.path(points)
+	c.content.WriteString("S\n")
+}
+
+func (c *pdfCanvas) path(points []point) {
+	for i, p := range points {
+		op := "l"
+		if i == 0 {
+			op = "m"
+		}
+		fmt.Fprintf(&c.content, "%.2f %.2f %s ", p.x, pageHeight-p.y, op)
+	}
+}
+
+func (c *pdfCanvas) text(x, y float64, s string, size float64, fill color.RGBA, a anchor) {
+	s = pdfASCII(s)
+	switch a {
+	case anchorMiddle:
+		x -= textWidth(s, size) / 2
+	case anchorEnd:
+		x -= textWidth(s, size)
+	}
+
+	fmt.Fprintf(&c.content, "BT %s rg /F1 %g Tf %.2f %.2f Td (%s) Tj ET\n",
+		pdfColor(fill), size, x, pageHeight-y, pdfEscape(s))
+}
+
+// document 组装 PDF 文件：目录、页面树、页面、字体和内容流，最后写入交叉引用表
+func (c *pdfCanvas) document() []byte {
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %g %g] /Resources << /Font << /F1 4 0 R >> >> /Contents 5 0 R >>",
+			pageWidth, pageHeight),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", c.content.Len(), c.content.String()),
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+func textWidth(s string, size float64) float64 {
+	var width int
+	for _, r := range s {
+		width += helveticaWidths[r-32]
+	}
+	return float64(width) * size / 1000
+}
+
+// pdfASCII 将不可打印或非 ASCII 字符替换为问号
+func pdfASCII(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 32 || r > 126 {
+			return '?'
+		}
+		return r
+	}, s)
+}
+
+func pdfEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`).Replace(s)
+}
+
+func pdfColor(c color.RGBA) string {
+	return fmt.Sprintf("%.3f %.3f %.3f", float64(c.R)/255, float64(c.G)/255, float64(c.B)/255)
+}
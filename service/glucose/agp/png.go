@@ -0,0 +1,138 @@
+package agp
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"slices"
+)
+
+// PNG 相对页面尺寸的缩放倍数
+const pngScale = 2.0
+
+type pngCanvas struct {
+	img *image.RGBA
+}
+
+var _ canvas = &pngCanvas{}
+
+// RenderPNG 将报告渲染为 PNG
+func RenderPNG(report *Report) ([]byte, error) {
+	c := &pngCanvas{
+		img: image.NewRGBA(image.Rect(0, 0, int(pageWidth*pngScale), int(pageHeight*pngScale))),
+	}
+	drawReport(c, report)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, c.img); err != nil {
+		return nil, fmt.Errorf("failed to encode png: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (c *pngCanvas) rect(x, y, w, h float64, fill color.RGBA) {
+	r := image.Rect(
+		int(math.Round(x*pngScale)), int(math.Round(y*pngScale)),
+		int(math.Round((x+w)*pngScale)), int(math.Round((y+h)*pngScale)),
+	)
+	draw.Draw(c.img, r, &image.Uniform{C: fill}, image.Point{}, draw.Src)
+}
+
+// polygon 扫描线填充，使用奇偶规则
+func (c *pngCanvas) polygon(points []point, fill color.RGBA) {
+	if len(points) < 3 {
+		return
+	}
+
+	minY, maxY := math.Inf(1), math.Inf(-1)
+	scaled := make([]point, len(points))
+	for i, p := range points {
+		scaled[i] = point{p.x * pngScale, p.y * pngScale}
+		minY = min(minY, scaled[i].y)
+		maxY = max(maxY, scaled[i].y)
+	}
+
+	bounds := c.img.Bounds()
+	var xs []float64
+	for py := max(int(minY), bounds.Min.Y); py <= min(int(maxY), bounds.Max.Y-1); py++ {
+		sy := float64(py) + 0.5
+		xs = xs[:0]
+		for i := range scaled {
+			a, b := scaled[i], scaled[(i+1)%len(scaled)]
+			if (a.y <= sy) == (b.y <= sy) {
+				continue
+			}
+			xs = append(xs, a.x+(sy-a.y)*(b.x-a.x)/(b.y-a.y))
+		}
+		slices.Sort(xs)
+
+		for i := 0; i+1 < len(xs); i += 2 {
+			for px := max(int(math.Round(xs[i])), bounds.Min.X); px < min(int(math.Round(xs[i+1])), bounds.Max.X); px++ {
+				c.img.SetRGBA(px, py, fill)
+			}
+		}
+	}
+}
+
+// polyline 沿线段按半个像素的步长绘制圆点，得到带宽度的折线
+func (c *pngCanvas) polyline(points []point, stroke color.RGBA, width float64) {
+	radius := max(width*pngScale/2, 0.5)
+	for i := 0; i+1 < len(points); i++ {
+		a := point{points[i].x * pngScale, points[i].y * pngScale}
+		b := point{points[i+1].x * pngScale, points[i+1].y * pngScale}
+
+		steps := int(math.Hypot(b.x-a.x, b.y-a.y)*2) + 1
+		for s := 0; s <= steps; s++ {
+			t := float64(s) / float64(steps)
+			c.dot(a.x+(b.x-a.x)*t, a.y+(b.y-a.y)*t, radius, stroke)
+		}
+	}
+}
+
+func (c *pngCanvas) dot(cx, cy, radius float64, fill color.RGBA) {
+	for py := int(math.Floor(cy - radius)); py <= int(math.Ceil(cy+radius)); py++ {
+		for px := int(math.Floor(cx - radius)); px <= int(math.Ceil(cx+radius)); px++ {
+			dx, dy := float64(px)+0.5-cx, float64(py)+0.5-cy
+			if dx*dx+dy*dy <= radius*radius && image.Pt(px, py).In(c.img.Bounds()) {
+				c.img.SetRGBA(px, py, fill)
+			}
+		}
+	}
+}
+
+// text 使用点阵字体绘制，字号换算为整数倍的像素大小
+func (c *pngCanvas) text(x, y float64, s string, size float64, fill color.RGBA, a anchor) {
+	pixel := max(1, int(math.Round(size*pngScale*0.7/glyphHeight)))
+	runes := []rune(s)
+	width := len(runes)*(glyphWidth+1)*pixel - pixel
+
+	left := int(math.Round(x * pngScale))
+	switch a {
+	case anchorMiddle:
+		left -= width / 2
+	case anchorEnd:
+		left -= width
+	}
+	top := int(math.Round(y*pngScale)) - glyphHeight*pixel
+
+	for i, r := range runes {
+		g, ok := glyph(r)
+		if !ok {
+			continue
+		}
+		originX := left + i*(glyphWidth+1)*pixel
+		for row := range glyphHeight {
+			for col := range glyphWidth {
+				if g[row]&(1<<(glyphWidth-1-col)) == 0 {
+					continue
+				}
+				r := image.Rect(originX+col*pixel, top+row*pixel, originX+(col+1)*pixel, top+(row+1)*pixel)
+				draw.Draw(c.img, r, &image.Uniform{C: fill}, image.Point{}, draw.Src)
+			}
+		}
+	}
+}
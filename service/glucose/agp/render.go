@@ -0,0 +1,251 @@
+package agp
+
+import (
+	"diabetes-agent-backend/service/glucose"
+	"fmt"
+	"image/color"
+	"time"
+)
+
+// 报告页面为 A4 尺寸，单位为 pt，原点在左上角
+const (
+	pageWidth  = 595.0
+	pageHeight = 842.0
+	margin     = 40.0
+
+	// 图谱纵轴的上限（mmol/L），对应 400 mg/dL
+	chartMaxMmolL = 22.2
+)
+
+type point struct {
+	x, y float64
+}
+
+type anchor int
+
+const (
+	anchorStart anchor = iota
+	anchorMiddle
+	anchorEnd
+)
+
+// canvas 报告的绘图接口，分别由 SVG、PNG 和 PDF 实现
+// 文本的 y 坐标为基线位置，文本只使用 ASCII 字符
+type canvas interface {
+	rect(x, y, w, h float64, fill color.RGBA)
+	polygon(points []point, fill color.RGBA)
+	polyline(points []point, stroke color.RGBA, width float64)
+	text(x, y float64, s string, size float64, fill color.RGBA, a anchor)
+}
+
+var (
+	colorText      = color.RGBA{0x22, 0x22, 0x22, 0xff}
+	colorMuted     = color.RGBA{0x77, 0x77, 0x77, 0xff}
+	colorGrid      = color.RGBA{0xdd, 0xdd, 0xdd, 0xff}
+	colorTarget    = color.RGBA{0x2e, 0x9e, 0x5b, 0xff}
+	colorVeryLow   = color.RGBA{0x9e, 0x1b, 0x1b, 0xff}
+	colorLow       = color.RGBA{0xe8, 0x46, 0x3b, 0xff}
+	colorInRange   = color.RGBA{0x3b, 0xb2, 0x73, 0xff}
+	colorHigh      = color.RGBA{0xf8, 0xc1, 0x3a, 0xff}
+	colorVeryHigh  = color.RGBA{0xe6, 0x6e, 0x1f, 0xff}
+	colorOuterBand = color.RGBA{0xc6, 0xdb, 0xef, 0xff}
+	colorInnerBand = color.RGBA{0x6b, 0xae, 0xd6, 0xff}
+	colorMedian    = color.RGBA{0x08, 0x51, 0x9c, 0xff}
+)
+
+// drawReport 绘制报告页面：统计指标、各血糖区间的时间占比和 AGP 百分位曲线
+func drawReport(c canvas, report *Report) {
+	c.rect(0, 0, pageWidth, pageHeight, color.RGBA{0xff, 0xff, 0xff, 0xff})
+
+	days := int(report.To.Sub(report.From).Hours()/24 + 0.5)
+	c.text(margin, 55, "AGP Report: Continuous Glucose Monitoring", 18, colorText, anchorStart)
+	c.text(margin, 75, fmt.Sprintf("%s - %s (%d days)  |  Glucose unit: %s",
+		report.From.Format("2006-01-02"), report.To.Add(-time.Second).Format("2006-01-02"), days, report.Unit),
+		10, colorMuted, anchorStart)
+	c.polyline([]point{{margin, 90}, {pageWidth - margin, 90}}, colorGrid, 1)
+
+	drawStatistics(c, report)
+	drawTimeInRanges(c, report)
+	c.polyline([]point{{margin, 310}, {pageWidth - margin, 310}}, colorGrid, 1)
+	drawProfile(c, report)
+
+	c.text(margin, pageHeight-42, "Generated "+report.GeneratedAt.Format("2006-01-02 15:04"), 8, colorMuted, anchorStart)
+	c.text(margin, pageHeight-30, "For reference only; discuss treatment changes with your care team.", 8, colorMuted, anchorStart)
+}
+
+func drawStatistics(c canvas, report *Report) {
+	metrics := report.Metrics
+	format := func(value float64) string {
+		return glucose.FormatValue(report.Unit, value) + " " + string(report.Unit)
+	}
+
+	c.text(margin, 115, "GLUCOSE STATISTICS AND TARGETS", 11, colorText, anchorStart)
+
+	rows := [][2]string{
+		{"Readings", fmt.Sprintf("%d", metrics.Count)},
+		{"Days with data", fmt.Sprintf("%d", metrics.Days)},
+		{"Target range", glucose.FormatValue(report.Unit, metrics.TargetLow) + "-" + format(metrics.TargetHigh)},
+	}
+	if metrics.Count > 0 {
+		rows = append(rows,
+			[2]string{"Mean glucose", format(metrics.Mean)},
+			[2]string{"Glucose Management Indicator (GMI)", fmt.Sprintf("%.1f%%", metrics.GMI)},
+			[2]string{"Glucose variability (CV)", fmt.Sprintf("%.1f%%", metrics.CV)},
+			[2]string{"Standard deviation", format(metrics.SD)},
+		)
+	}
+
+	y := 140.0
+	for _, row := range rows {
+		c.text(margin, y, row[0], 10, colorText, anchorStart)
+		c.text(300, y, row[1], 10, colorText, anchorEnd)
+		y += 20
+	}
+
+	if metrics.Days < recommendedDays {
+		c.text(margin, y+5, fmt.Sprintf("Fewer than %d days of data;", recommendedDays), 8, colorLow, anchorStart)
+		c.text(margin, y+17, "the profile may not be representative.", 8, colorLow, anchorStart)
+	}
+}
+
+func drawTimeInRanges(c canvas, report *Report) {
+	metrics := report.Metrics
+	format := func(value float64) string {
+		return glucose.FormatValue(report.Unit, value)
+	}
+
+	c.text(340, 115, "TIME IN RANGES", 11, colorText, anchorStart)
+
+	ranges := []struct {
+		label   string
+		percent float64
+		color   color.RGBA
+	}{
+		{"Very High >" + format(glucose.VeryHighThreshold), metrics.TimeVeryHigh, colorVeryHigh},
+		{"High " + format(metrics.TargetHigh) + "-" + format(glucose.VeryHighThreshold), metrics.TimeHigh, colorHigh},
+		{"Target " + format(metrics.TargetLow) + "-" + format(metrics.TargetHigh), metrics.TimeInRange, colorInRange},
+		{"Low " + format(glucose.VeryLowThreshold) + "-" + format(metrics.TargetLow), metrics.TimeLow, colorLow},
+		{"Very Low <" + format(glucose.VeryLowThreshold), metrics.TimeVeryLow, colorVeryLow},
+	}
+
+	// 从上到下依次为极高、偏高、目标范围、偏低、极低
+	const barTop, barHeight = 130.0, 160.0
+	y := barTop
+	for _, item := range ranges {
+		h := barHeight * item.percent / 100
+		c.rect(340, y, 28, h, item.color)
+		y += h
+	}
+	if metrics.Count == 0 {
+		c.rect(340, barTop, 28, barHeight, colorGrid)
+	}
+
+	for i, item := range ranges {
+		rowY := barTop + 18 + float64(i)*32
+		c.rect(385, rowY-8, 8, 8, item.color)
+		c.text(400, rowY, item.label, 9, colorText, anchorStart)
+		c.text(pageWidth-margin, rowY, fmt.Sprintf("%.0f%%", item.percent), 11, colorText, anchorEnd)
+	}
+}
+
+func drawProfile(c canvas, report *Report) {
+	const (
+		left   = 80.0
+		right  = pageWidth - margin
+		top    = 385.0
+		bottom = 700.0
+	)
+
+	c.text(margin, 335, "AMBULATORY GLUCOSE PROFILE (AGP)", 11, colorText, anchorStart)
+	c.text(margin, 349, "Median (50%) and other percentiles of glucose by time of day, all days overlaid", 8, colorMuted, anchorStart)
+
+	x := func(minute float64) float64 {
+		return left + (right-left)*minute/(24*60)
+	}
+	y := func(value float64) float64 {
+		value = min(max(value, 0), chartMaxMmolL)
+		return bottom - (bottom-top)*value/chartMaxMmolL
+	}
+
+	// 横轴每 3 小时一个刻度
+	for hour := 0; hour <= 24; hour += 3 {
+		px := x(float64(hour * 60))
+		c.polyline([]point{{px, top}, {px, bottom}}, colorGrid, 0.5)
+		c.text(px, bottom+15, fmt.Sprintf("%02d:00", hour), 8, colorMuted, anchorMiddle)
+	}
+
+	metrics := report.Metrics
+	for _, value := range []float64{glucose.VeryLowThreshold, metrics.TargetLow, metrics.TargetHigh, glucose.VeryHighThreshold, chartMaxMmolL} {
+		c.polyline([]point{{left, y(value)}, {right, y(value)}}, colorGrid, 0.5)
+		c.text(left-6, y(value)+3, glucose.FormatValue(report.Unit, value), 8, colorMuted, anchorEnd)
+	}
+	c.polyline([]point{{left, bottom}, {right, bottom}}, colorMuted, 1)
+	c.text(left-6, top-8, string(report.Unit), 8, colorMuted, anchorEnd)
+
+	hasData := false
+	for _, run := range profileRuns(report.Profile) {
+		hasData = true
+
+		var outer, inner, median []point
+		for _, p := range run {
+			outer = append(outer, point{x(float64(p.Minute)), y(p.P95)})
+			inner = append(inner, point{x(float64(p.Minute)), y(p.P75)})
+			median = append(median, point{x(float64(p.Minute)), y(p.P50)})
+		}
+		for i := len(run) - 1; i >= 0; i-- {
+			outer = append(outer, point{x(float64(run[i].Minute)), y(run[i].P5)})
+			inner = append(inner, point{x(float64(run[i].Minute)), y(run[i].P25)})
+		}
+
+		c.polygon(outer, colorOuterBand)
+		c.polygon(inner, colorInnerBand)
+		c.polyline(median, colorMedian, 2)
+	}
+
+	// 目标范围画在曲线之上，便于对照
+	c.polyline([]point{{left, y(metrics.TargetLow)}, {right, y(metrics.TargetLow)}}, colorTarget, 1.2)
+	c.polyline([]point{{left, y(metrics.TargetHigh)}, {right, y(metrics.TargetHigh)}}, colorTarget, 1.2)
+
+	if !hasData {
+		c.text((left+right)/2, (top+bottom)/2, "Not enough glucose data to build the profile", 11, colorMuted, anchorMiddle)
+	}
+
+	legendY := bottom + 45
+	legend := []struct {
+		label string
+		color color.RGBA
+	}{
+		{"5%-95%", colorOuterBand},
+		{"25%-75%", colorInnerBand},
+		{"Median", colorMedian},
+		{"Target range", colorTarget},
+	}
+	legendX := left
+	for _, item := range legend {
+		c.rect(legendX, legendY-8, 14, 8, item.color)
+		c.text(legendX+20, legendY, item.label, 9, colorText, anchorStart)
+		legendX += 110
+	}
+}
+
+// profileRuns 将百分位曲线按数据不足的点拆分为连续的片段
+func profileRuns(profile []ProfilePoint) [][]ProfilePoint {
+	var (
+		runs    [][]ProfilePoint
+		current []ProfilePoint
+	)
+	for _, p := range profile {
+		if p.Count == 0 {
+			if len(current) > 1 {
+				runs = append(runs, current)
+			}
+			current = nil
+			continue
+		}
+		current = append(current, p)
+	}
+	if len(current) > 1 {
+		runs = append(runs, current)
+	}
+	return runs
+}
@@ -0,0 +1,68 @@
+package agp
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"strings"
+)
+
+type svgCanvas struct {
+	buf bytes.Buffer
+}
+
+var _ canvas = &svgCanvas{}
+
+// RenderSVG 将报告渲染为 SVG
+func RenderSVG(report *Report) []byte {
+	c := &svgCanvas{}
+	fmt.Fprintf(&c.buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%g" height="%g" viewBox="0 0 %g %g" font-family="Helvetica, Arial, sans-serif">`,
+		pageWidth, pageHeight, pageWidth, pageHeight)
+	c.buf.WriteString("\n")
+	drawReport(c, report)
+	c.buf.WriteString("</svg>\n")
+	return c.buf.Bytes()
+}
+
+func (c *svgCanvas) rect(x, y, w, h float64, fill color.RGBA) {
+	if w <= 0 || h <= 0 {
+		return
+	}
+	fmt.Fprintf(&c.buf, `<rect x="%.2f" y="%.2f" width="%.2f" height="%.2f" fill="%s"/>`+"\n", x, y, w, h, svgColor(fill))
+}
+
+func (c *svgCanvas) polygon(points []point, fill color.RGBA) {
+	fmt.Fprintf(&c.buf, `<polygon points="%s" fill="%s"/>`+"\n", svgPoints(points), svgColor(fill))
+}
+
+func (c *svgCanvas) polyline(points []point, stroke color.RGBA, width float64) {
+	fmt.Fprintf(&c.buf, `<polyline points="%s" fill="none" stroke="%s" stroke-width="%g" stroke-linejoin="round" stroke-linecap="round"/>`+"\n",
+		svgPoints(points), svgColor(stroke), width)
+}
+
+func (c *svgCanvas) text(x, y float64, s string, size float64, fill color.RGBA, a anchor) {
+	textAnchor := "start"
+	switch a {
+	case anchorMiddle:
+		textAnchor = "middle"
+	case anchorEnd:
+		textAnchor = "end"
+	}
+
+	fmt.Fprintf(&c.buf, `<text x="%.2f" y="%.2f" font-size="%g" fill="%s" text-anchor="%s">`, x, y, size, svgColor(fill), textAnchor)
+	xml.EscapeText(&c.buf, []byte(s))
+	c.buf.WriteString("</text>\n")
+}
+
+func svgPoints(points []point) string {
+	parts := make([]string, 0, len(points))
+	for _, p := range points {
+		parts = append(parts, fmt.Sprintf("%.2f,%.2f", p.x, p.y))
+	}
+	return strings.Join(parts, " ")
+}
+
+func svgColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
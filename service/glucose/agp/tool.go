@@ -0,0 +1,103 @@
+package agp
+
+import (
+	"bytes"
+	"context"
+	"diabetes-agent-backend/request"
+	"diabetes-agent-backend/service/glucose"
+	ossauth "diabetes-agent-backend/service/oss-auth"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tmc/langchaingo/tools"
+)
+
+// 报告图片下载链接的有效期，链接会保存在聊天记录中，取预签名 URL 允许的最大值
+const imageURLExpires = 7 * 24 * time.Hour
+
+// Tool 供 Agent 生成 AGP 报告图片的进程内工具
+type Tool struct {
+	Email string
+}
+
+var _ tools.Tool = &Tool{}
+
+func NewTool(email string) *Tool {
+	return &Tool{Email: email}
+}
+
+func (t *Tool) Name() string {
+	return "agp_report"
+}
+
+func (t *Tool) Description() string {
+	return `Generate an Ambulatory Glucose Profile (AGP) report image from the user's glucose readings. ` +
+		`Input is a JSON object: {"days": 14} for the most recent days, or {"from": "YYYY-MM-DD", "to": "YYYY-MM-DD"}. ` +
+		`Empty input means the last 14 days. Returns an image URL to include in the answer as markdown, ` +
+		`and the key metrics shown in the report.`
+}
+
+// Call 输入有误时将错误信息作为结果返回，由 Agent 修正输入后重试
+func (t *Tool) Call(ctx context.Context, input string) (string, error) {
+	loc, err := glucose.Location(t.Email)
+	if err != nil {
+		return "", err
+	}
+
+	from, to, err := glucose.ParseToolRange(input, loc)
+	if err != nil {
+		return "Invalid input: " + err.Error(), nil
+	}
+
+	report, err := BuildReport(t.Email, from, to)
+	if err != nil {
+		return "", err
+	}
+	if report.Metrics.Count == 0 {
+		return fmt.Sprintf("No glucose readings recorded between %s and %s, the AGP report was not generated.",
+			from.Format(time.DateOnly), to.Format(time.DateOnly)), nil
+	}
+
+	image, err := RenderPNG(report)
+	if err != nil {
+		return "", err
+	}
+
+	url, err := UploadImage(ctx, t.Email, report, image)
+	if err != nil {
+		return "", err
+	}
+
+	metrics := report.Metrics
+	var b strings.Builder
+	fmt.Fprintf(&b, "AGP report image URL: %s\n", url)
+	fmt.Fprintf(&b, "Include it in the answer as ![AGP report](%s)\n", url)
+	fmt.Fprintf(&b, "Period %s to %s, %d readings over %d days. ",
+		from.Format(time.DateOnly), to.Format(time.DateOnly), metrics.Count, metrics.Days)
+	fmt.Fprintf(&b, "Time in range %.0f%%, below range %.0f%%, above range %.0f%%, GMI %.1f%%, CV %.1f%%.",
+		metrics.TimeInRange, metrics.TimeVeryLow+metrics.TimeLow, metrics.TimeHigh+metrics.TimeVeryHigh,
+		metrics.GMI, metrics.CV)
+	return b.String(), nil
+}
+
+// UploadImage 将报告图片上传到 OSS 的 report 命名空间，返回预签名的下载链接
+func UploadImage(ctx context.Context, email string, report *Report, image []byte) (string, error) {
+	req := request.OSSAuthRequest{
+		Namespace: ossauth.OSSKeyPrefixReport,
+		Email:     email,
+		FileName: fmt.Sprintf("agp-%s-%s-%s.png",
+			report.From.Format("20060102"), report.To.Format("20060102"), uuid.New().String()[:8]),
+	}
+
+	if err := ossauth.UploadObject(ctx, req, bytes.NewReader(image), "image/png"); err != nil {
+		return "", fmt.Errorf("failed to upload agp report: %v", err)
+	}
+
+	url, err := ossauth.PresignObject(req, imageURLExpires)
+	if err != nil {
+		return "", fmt.Errorf("failed to presign agp report: %v", err)
+	}
+	return url, nil
+}
@@ -0,0 +1,145 @@
+package cgm
+
+import (
+	"bufio"
+	"bytes"
+	"diabetes-agent-backend/model"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Format CGM 导出文件的格式
+type Format string
+
+const (
+	FormatDexcom     Format = "dexcom"
+	FormatLibreView  Format = "libreview"
+	FormatNightscout Format = "nightscout"
+)
+
+func (f Format) IsValid() bool {
+	return f == FormatDexcom || f == FormatLibreView || f == FormatNightscout
+}
+
+// ErrInvalidFile 文件内容无法识别或解析，重试也无法处理成功
+var ErrInvalidFile = errors.New("invalid cgm export file")
+
+const (
+	// 血糖值的有效范围（mmol/L）
+	minGlucoseMmolL = 1.0
+	maxGlucoseMmolL = 33.3
+
+	// 识别文件格式时读取的字节数
+	sniffSize = 4096
+)
+
+// Reading 从导出文件中解析出的一条读数，Value 已换算为 mmol/L
+type Reading struct {
+	Value      float64
+	Unit       model.GlucoseUnit
+	MeasuredAt time.Time
+	Source     model.GlucoseSource
+}
+
+// Result 文件的解析结果，Invalid 为无法解析或超出范围而跳过的行数
+type Result struct {
+	Format   Format
+	Readings []Reading
+	Invalid  int
+}
+
+// Parse 解析 CGM 导出文件，format 为空时自动识别
+// 导出文件中不带时区的时间按 loc 解析
+func Parse(r io.Reader, format Format, loc *time.Location) (*Result, error) {
+	reader := bufio.NewReaderSize(r, sniffSize)
+	if format == "" {
+		head, _ := reader.Peek(sniffSize)
+		format = Detect(head)
+		if format == "" {
+			return nil, fmt.Errorf("%w: unrecognized file format", ErrInvalidFile)
+		}
+	}
+
+	var (
+		result *Result
+		err    error
+	)
+	switch format {
+	case FormatDexcom:
+		result, err = parseDexcom(reader, loc)
+	case FormatLibreView:
+		result, err = parseLibreView(reader, loc)
+	case FormatNightscout:
+		result, err = parseNightscout(reader)
+	default:
+		return nil, fmt.Errorf("%w: unsupported format %s", ErrInvalidFile, format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result.Format = format
+	return result, nil
+}
+
+// Detect 根据文件开头的内容识别格式，无法识别时返回空字符串
+func Detect(head []byte) Format {
+	head = bytes.TrimPrefix(head, []byte("\xef\xbb\xbf"))
+	trimmed := bytes.TrimSpace(head)
+	if bytes.HasPrefix(trimmed, []byte("[")) || bytes.HasPrefix(trimmed, []byte("{")) {
+		return FormatNightscout
+	}
+
+	text := string(head)
+	switch {
+	case strings.Contains(text, "Glucose Value") && strings.Contains(text, "Event Type"):
+		return FormatDexcom
+	case strings.Contains(text, "Historic Glucose") || strings.Contains(text, "Record Type"):
+		return FormatLibreView
+	}
+	return ""
+}
+
+// newReading 换算并校验读数，超出有效范围时返回 false
+func newReading(value float64, unit model.GlucoseUnit, measuredAt time.Time, source model.GlucoseSource) (Reading, bool) {
+	mmol := unit.ToMmolL(value)
+	if mmol < minGlucoseMmolL || mmol > maxGlucoseMmolL || measuredAt.IsZero() {
+		return Reading{}, false
+	}
+
+	// 精确到秒，重复导入同一文件时时间一致
+	return Reading{
+		Value:      mmol,
+		Unit:       unit,
+		MeasuredAt: measuredAt.Truncate(time.Second),
+		Source:     source,
+	}, true
+}
+
+// headerUnit 从列名中识别血糖单位，如 "Glucose Value (mg/dL)"
+func headerUnit(header string) model.GlucoseUnit {
+	if strings.Contains(strings.ToLower(header), "mmol") {
+		return model.GlucoseUnitMmolL
+	}
+	return model.GlucoseUnitMgDL
+}
+
+// findColumn 返回第一个以 prefix 开头的列的下标，不存在时返回 -1
+func findColumn(header []string, prefix string) int {
+	for i, name := range header {
+		if strings.HasPrefix(strings.TrimSpace(name), prefix) {
+			return i
+		}
+	}
+	return -1
+}
+
+func field(record []string, index int) string {
+	if index < 0 || index >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[index])
+}
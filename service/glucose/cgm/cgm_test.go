@@ -0,0 +1,188 @@
+package cgm
+
+import (
+	"diabetes-agent-backend/model"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const dexcomHeader = "Index,Timestamp (YYYY-MM-DDThh:mm:ss),Event Type,Event Subtype,Patient Info,Device Info," +
+	"Source Device ID,Glucose Value (mg/dL),Insulin Value (u),Carb Value (grams),Duration (hh:mm:ss)," +
+	"Glucose Rate of Change (mg/dL/min),Transmitter Time (Long Integer),Transmitter ID\n"
+
+const libreHeader = "Glucose Data,Generated on,03-20-2026 10:00 AM UTC,Generated by,Test Patient\n" +
+	"Device,Serial Number,Device Timestamp,Record Type,Historic Glucose mg/dL,Scan Glucose mg/dL," +
+	"Non-numeric Rapid-Acting Insulin,Rapid-Acting Insulin (units),Notes,Strip Glucose mg/dL\n"
+
+func mgdl(value float64) float64 {
+	return model.GlucoseUnitMgDL.ToMmolL(value)
+}
+
+func loadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("tzdata not available: %v", err)
+	}
+	return loc
+}
+
+func TestParse(t *testing.T) {
+	shanghai := loadLocation(t, "Asia/Shanghai")
+	newYork := loadLocation(t, "America/New_York")
+
+	tests := []struct {
+		name        string
+		format      Format
+		loc         *time.Location
+		input       string
+		wantFormat  Format
+		want        []Reading
+		wantInvalid int
+		wantErr     bool
+	}{
+		{
+			name: "dexcom egv with low and high",
+			loc:  shanghai,
+			input: dexcomHeader +
+				"1,,FirstName,,Test,,,,,,,,,\n" +
+				"2,,Device,,,G6,,,,,,,,\n" +
+				"3,2026-03-01T08:00:00,EGV,,,,iOS G6,120,,,,,,\n" +
+				"4,2026-03-01T08:05:00,EGV,,,,iOS G6,Low,,,,,,\n" +
+				"5,2026-03-01T08:10:00,EGV,,,,iOS G6,High,,,,,,\n" +
+				"6,2026-03-01T08:12:00,Calibration,,,,iOS G6,130,,,,,,\n" +
+				"7,2026-03-01T08:15:00,Insulin,Fast-Acting,,,,,4,,,,,\n" +
+				"8,2026-03-01T08:20:00,EGV,,,,iOS G6,,,,,,,\n",
+			wantFormat: FormatDexcom,
+			want: []Reading{
+				{Value: mgdl(120), Unit: model.GlucoseUnitMgDL, MeasuredAt: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), Source: model.GlucoseSourceCGM},
+				{Value: mgdl(dexcomLowMgDL), Unit: model.GlucoseUnitMgDL, MeasuredAt: time.Date(2026, 3, 1, 0, 5, 0, 0, time.UTC), Source: model.GlucoseSourceCGM},
+				{Value: mgdl(dexcomHighMgDL), Unit: model.GlucoseUnitMgDL, MeasuredAt: time.Date(2026, 3, 1, 0, 10, 0, 0, time.UTC), Source: model.GlucoseSourceCGM},
+			},
+			wantInvalid: 1,
+		},
+		{
+			// 夏令时开始时 02:00 跳到 03:00，两条读数实际相隔 10 分钟
+			name:   "dexcom across dst start",
+			format: FormatDexcom,
+			loc:    newYork,
+			input: dexcomHeader +
+				"1,2026-03-08T01:55:00,EGV,,,,iOS G6,100,,,,,,\n" +
+				"2,2026-03-08T03:05:00,EGV,,,,iOS G6,105,,,,,,\n",
+			wantFormat: FormatDexcom,
+			want: []Reading{
+				{Value: mgdl(100), Unit: model.GlucoseUnitMgDL, MeasuredAt: time.Date(2026, 3, 8, 6, 55, 0, 0, time.UTC), Source: model.GlucoseSourceCGM},
+				{Value: mgdl(105), Unit: model.GlucoseUnitMgDL, MeasuredAt: time.Date(2026, 3, 8, 7, 5, 0, 0, time.UTC), Source: model.GlucoseSourceCGM},
+			},
+		},
+		{
+			name: "libreview record types after preamble",
+			loc:  shanghai,
+			input: libreHeader +
+				"FreeStyle LibreLink,ABC,03-13-2026 08:00 AM,0,110,,,,,\n" +
+				"FreeStyle LibreLink,ABC,03-13-2026 08:07 AM,1,,125,,,,\n" +
+				"FreeStyle LibreLink,ABC,03-13-2026 08:30 AM,2,,,,,,98\n" +
+				"FreeStyle LibreLink,ABC,03-13-2026 09:00 AM,6,,,,,breakfast,\n" +
+				"FreeStyle LibreLink,ABC,03-13-2026 09:15 AM,0,,,,,,\n",
+			wantFormat: FormatLibreView,
+			want: []Reading{
+				{Value: mgdl(110), Unit: model.GlucoseUnitMgDL, MeasuredAt: time.Date(2026, 3, 13, 0, 0, 0, 0, time.UTC), Source: model.GlucoseSourceCGM},
+				{Value: mgdl(125), Unit: model.GlucoseUnitMgDL, MeasuredAt: time.Date(2026, 3, 13, 0, 7, 0, 0, time.UTC), Source: model.GlucoseSourceCGM},
+				{Value: mgdl(98), Unit: model.GlucoseUnitMgDL, MeasuredAt: time.Date(2026, 3, 13, 0, 30, 0, 0, time.UTC), Source: model.GlucoseSourceMeter},
+			},
+			wantInvalid: 1,
+		},
+		{
+			// 一条日期大于 12 的记录决定整个文件为日在前
+			name:   "libreview day first",
+			format: FormatLibreView,
+			loc:    shanghai,
+			input: libreHeader +
+				"FreeStyle LibreLink,ABC,03-04-2026 08:00,0,110,,,,,\n" +
+				"FreeStyle LibreLink,ABC,13-04-2026 08:00,0,120,,,,,\n",
+			wantFormat: FormatLibreView,
+			want: []Reading{
+				{Value: mgdl(110), Unit: model.GlucoseUnitMgDL, MeasuredAt: time.Date(2026, 4, 3, 0, 0, 0, 0, time.UTC), Source: model.GlucoseSourceCGM},
+				{Value: mgdl(120), Unit: model.GlucoseUnitMgDL, MeasuredAt: time.Date(2026, 4, 13, 0, 0, 0, 0, time.UTC), Source: model.GlucoseSourceCGM},
+			},
+		},
+		{
+			name:   "libreview ambiguous day and month",
+			format: FormatLibreView,
+			loc:    shanghai,
+			input: libreHeader +
+				"FreeStyle LibreLink,ABC,03-04-2026 08:00,0,110,,,,,\n" +
+				"FreeStyle LibreLink,ABC,04-04-2026 08:00,0,120,,,,,\n",
+			wantErr: true,
+		},
+		{
+			name:   "libreview iso dates",
+			format: FormatLibreView,
+			loc:    shanghai,
+			input: libreHeader +
+				"FreeStyle LibreLink,ABC,2026-04-03 08:00,0,110,,,,,\n",
+			wantFormat: FormatLibreView,
+			want: []Reading{
+				{Value: mgdl(110), Unit: model.GlucoseUnitMgDL, MeasuredAt: time.Date(2026, 4, 3, 0, 0, 0, 0, time.UTC), Source: model.GlucoseSourceCGM},
+			},
+		},
+		{
+			// date 优先于 dateString，date 缺失时使用 dateString，时间不受 loc 影响
+			name: "nightscout sgv and mbg",
+			loc:  shanghai,
+			input: `[
+				{"type": "sgv", "sgv": 120, "date": 1772352000000, "dateString": "2026-03-01T09:00:00Z"},
+				{"type": "mbg", "mbg": 100, "dateString": "2026-03-01T08:30:00.000Z"},
+				{"type": "cal", "slope": 1000, "date": 1772352000000},
+				{"type": "sgv", "sgv": 130},
+				{"type": "sgv", "sgv": "high", "date": 1772352000000}
+			]`,
+			wantFormat: FormatNightscout,
+			want: []Reading{
+				{Value: mgdl(120), Unit: model.GlucoseUnitMgDL, MeasuredAt: time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC), Source: model.GlucoseSourceCGM},
+				{Value: mgdl(100), Unit: model.GlucoseUnitMgDL, MeasuredAt: time.Date(2026, 3, 1, 8, 30, 0, 0, time.UTC), Source: model.GlucoseSourceMeter},
+			},
+			wantInvalid: 2,
+		},
+		{
+			name:    "unrecognized file",
+			loc:     shanghai,
+			input:   "time,value\n2026-03-01 08:00,120\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Parse(strings.NewReader(tt.input), tt.format, tt.loc)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidFile) {
+					t.Fatalf("Parse() error = %v, want %v", err, ErrInvalidFile)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+
+			if result.Format != tt.wantFormat {
+				t.Errorf("Format = %s, want %s", result.Format, tt.wantFormat)
+			}
+			if result.Invalid != tt.wantInvalid {
+				t.Errorf("Invalid = %d, want %d", result.Invalid, tt.wantInvalid)
+			}
+			if len(result.Readings) != len(tt.want) {
+				t.Fatalf("got %d readings, want %d: %+v", len(result.Readings), len(tt.want), result.Readings)
+			}
+			for i, got := range result.Readings {
+				want := tt.want[i]
+				if got.Value != want.Value || got.Unit != want.Unit || got.Source != want.Source || !got.MeasuredAt.Equal(want.MeasuredAt) {
+					t.Errorf("reading %d = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
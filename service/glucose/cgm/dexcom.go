@@ -0,0 +1,102 @@
+package cgm
+
+import (
+	"diabetes-agent-backend/model"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Dexcom Clarity 将超出传感器量程的读数导出为 Low 和 High，分别记为 40 和 400 mg/dL
+const (
+	dexcomLowMgDL  = 40
+	dexcomHighMgDL = 400
+)
+
+var dexcomTimeLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+}
+
+// parseDexcom 解析 Dexcom Clarity 导出的 CSV
+// 只导入 Event Type 为 EGV 的传感器读数，校准和其他事件被忽略
+func parseDexcom(r io.Reader, loc *time.Location) (*Result, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read header: %v", ErrInvalidFile, err)
+	}
+
+	timeColumn := findColumn(header, "Timestamp")
+	typeColumn := findColumn(header, "Event Type")
+	valueColumn := findColumn(header, "Glucose Value")
+	if timeColumn < 0 || typeColumn < 0 || valueColumn < 0 {
+		return nil, fmt.Errorf("%w: missing Dexcom columns", ErrInvalidFile)
+	}
+	unit := headerUnit(header[valueColumn])
+
+	result := &Result{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+		}
+
+		if !strings.EqualFold(field(record, typeColumn), "EGV") {
+			continue
+		}
+
+		measuredAt, err := parseTime(field(record, timeColumn), dexcomTimeLayouts, loc)
+		if err != nil {
+			result.Invalid++
+			continue
+		}
+
+		value, unit, ok := dexcomValue(field(record, valueColumn), unit)
+		if !ok {
+			result.Invalid++
+			continue
+		}
+
+		reading, ok := newReading(value, unit, measuredAt, model.GlucoseSourceCGM)
+		if !ok {
+			result.Invalid++
+			continue
+		}
+		result.Readings = append(result.Readings, reading)
+	}
+	return result, nil
+}
+
+func dexcomValue(raw string, unit model.GlucoseUnit) (float64, model.GlucoseUnit, bool) {
+	switch strings.ToLower(raw) {
+	case "low":
+		return dexcomLowMgDL, model.GlucoseUnitMgDL, true
+	case "high":
+		return dexcomHighMgDL, model.GlucoseUnitMgDL, true
+	}
+
+	value, err := strconv.ParseFloat(raw, 64)
+	return value, unit, err == nil
+}
+
+func parseTime(value string, layouts []string, loc *time.Location) (time.Time, error) {
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time: %s", value)
+}
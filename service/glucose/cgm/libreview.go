@@ -0,0 +1,176 @@
+package cgm
+
+import (
+	"diabetes-agent-backend/model"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LibreView 的记录类型：0 为每 15 分钟自动记录，1 为扫描，2 为指尖血
+const (
+	libreRecordHistoric = "0"
+	libreRecordScan     = "1"
+	libreRecordStrip    = "2"
+)
+
+// LibreView 的时间格式随导出地区变化，月在前还是日在前按整个文件判断
+var (
+	libreMonthFirstLayouts = []string{
+		"01-02-2006 03:04 PM",
+		"01-02-2006 15:04",
+		"01/02/2006 03:04 PM",
+		"01/02/2006 15:04",
+	}
+	libreDayFirstLayouts = []string{
+		"02-01-2006 15:04",
+		"02/01/2006 15:04",
+		"02.01.2006 15:04",
+	}
+	libreISOLayouts = []string{
+		"2006-01-02 15:04",
+		"2006-01-02T15:04:05",
+		"2006-01-02 15:04:05",
+	}
+)
+
+type libreRecord struct {
+	timestamp string
+	value     float64
+	unit      model.GlucoseUnit
+	source    model.GlucoseSource
+}
+
+// parseLibreView 解析 FreeStyle Libre 通过 LibreView 导出的 CSV
+// 文件第一行为报告信息，第二行为列名
+func parseLibreView(r io.Reader, loc *time.Location) (*Result, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := readLibreHeader(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	timeColumn := findColumn(header, "Device Timestamp")
+	typeColumn := findColumn(header, "Record Type")
+	historicColumn := findColumn(header, "Historic Glucose")
+	scanColumn := findColumn(header, "Scan Glucose")
+	stripColumn := findColumn(header, "Strip Glucose")
+	if timeColumn < 0 || typeColumn < 0 || historicColumn < 0 {
+		return nil, fmt.Errorf("%w: missing LibreView columns", ErrInvalidFile)
+	}
+
+	result := &Result{}
+	var records []libreRecord
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+		}
+
+		var (
+			column int
+			source = model.GlucoseSourceCGM
+		)
+		switch field(record, typeColumn) {
+		case libreRecordHistoric:
+			column = historicColumn
+		case libreRecordScan:
+			column = scanColumn
+		case libreRecordStrip:
+			column = stripColumn
+			source = model.GlucoseSourceMeter
+		default:
+			// 胰岛素、饮食、备注等记录
+			continue
+		}
+
+		value, err := strconv.ParseFloat(strings.ReplaceAll(field(record, column), ",", "."), 64)
+		if err != nil {
+			result.Invalid++
+			continue
+		}
+		records = append(records, libreRecord{
+			timestamp: field(record, timeColumn),
+			value:     value,
+			unit:      headerUnit(header[column]),
+			source:    source,
+		})
+	}
+
+	layouts, err := libreLayouts(records)
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		measuredAt, err := parseTime(record.timestamp, layouts, loc)
+		if err != nil {
+			result.Invalid++
+			continue
+		}
+
+		reading, ok := newReading(record.value, record.unit, measuredAt, record.source)
+		if !ok {
+			result.Invalid++
+			continue
+		}
+		result.Readings = append(result.Readings, reading)
+	}
+	return result, nil
+}
+
+// readLibreHeader 跳过报告信息行，返回列名
+func readLibreHeader(reader *csv.Reader) ([]string, error) {
+	for range 3 {
+		record, err := reader.Read()
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to read header: %v", ErrInvalidFile, err)
+		}
+		if findColumn(record, "Device Timestamp") >= 0 {
+			return record, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: missing LibreView header", ErrInvalidFile)
+}
+
+// libreLayouts 按只能用一种顺序解析的时间判断整个文件是月在前还是日在前
+// 所有时间都能按两种顺序解析且结果不同时无法判断，返回错误而不是猜测
+func libreLayouts(records []libreRecord) ([]string, error) {
+	monthFirst := slices.Concat(libreMonthFirstLayouts, libreISOLayouts)
+	dayFirst := slices.Concat(libreDayFirstLayouts, libreISOLayouts)
+
+	var monthOnly, dayOnly int
+	ambiguous := false
+	for _, record := range records {
+		monthTime, monthErr := parseTime(record.timestamp, monthFirst, time.UTC)
+		dayTime, dayErr := parseTime(record.timestamp, dayFirst, time.UTC)
+		switch {
+		case monthErr == nil && dayErr != nil:
+			monthOnly++
+		case monthErr != nil && dayErr == nil:
+			dayOnly++
+		case monthErr == nil && dayErr == nil && !monthTime.Equal(dayTime):
+			ambiguous = true
+		}
+	}
+
+	switch {
+	case monthOnly > dayOnly:
+		return monthFirst, nil
+	case dayOnly > monthOnly:
+		return dayFirst, nil
+	case ambiguous || monthOnly > 0:
+		return nil, fmt.Errorf("%w: cannot tell whether dates are month-first or day-first, export with ISO dates or a longer date range", ErrInvalidFile)
+	}
+	return monthFirst, nil
+}
@@ -0,0 +1,83 @@
+package cgm
+
+import (
+	"diabetes-agent-backend/model"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// nightscoutEntry Nightscout entries 接口返回的一条记录，血糖值固定为 mg/dL
+type nightscoutEntry struct {
+	Type       string  `json:"type"`
+	SGV        float64 `json:"sgv"`
+	MBG        float64 `json:"mbg"`
+	Date       int64   `json:"date"`
+	DateString string  `json:"dateString"`
+}
+
+// parseNightscout 解析 Nightscout 导出的 entries JSON 数组
+// sgv 为传感器读数，mbg 为指尖血读数，时间使用记录中的 UTC 毫秒时间戳
+func parseNightscout(r io.Reader) (*Result, error) {
+	decoder := json.NewDecoder(r)
+
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return nil, fmt.Errorf("%w: expected a JSON array of entries", ErrInvalidFile)
+	}
+
+	result := &Result{}
+	for decoder.More() {
+		var entry nightscoutEntry
+		if err := decoder.Decode(&entry); err != nil {
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+			}
+			result.Invalid++
+			continue
+		}
+
+		var (
+			value  float64
+			source model.GlucoseSource
+		)
+		switch entry.Type {
+		case "sgv":
+			value, source = entry.SGV, model.GlucoseSourceCGM
+		case "mbg":
+			value, source = entry.MBG, model.GlucoseSourceMeter
+		default:
+			continue
+		}
+
+		measuredAt, ok := nightscoutTime(entry)
+		if !ok {
+			result.Invalid++
+			continue
+		}
+
+		reading, ok := newReading(value, model.GlucoseUnitMgDL, measuredAt, source)
+		if !ok {
+			result.Invalid++
+			continue
+		}
+		result.Readings = append(result.Readings, reading)
+	}
+	return result, nil
+}
+
+func nightscoutTime(entry nightscoutEntry) (time.Time, bool) {
+	if entry.Date > 0 {
+		return time.UnixMilli(entry.Date), true
+	}
+	if t, err := time.Parse(time.RFC3339, entry.DateString); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,232 @@
+package glucose
+
+import (
+	"diabetes-agent-backend/dao"
+	"diabetes-agent-backend/model"
+	"diabetes-agent-backend/request"
+	"diabetes-agent-backend/service/profile"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// ErrInvalidRequest 血糖记录或查询参数校验失败，错误信息可以直接返回给用户
+var ErrInvalidRequest = errors.New("invalid glucose request")
+
+// ErrReadingNotFound 血糖记录不存在或不属于该用户
+var ErrReadingNotFound = errors.New("glucose reading not found")
+
+const (
+	// 血糖值的有效范围（mmol/L），对应 18-600 mg/dL
+	minGlucoseMmolL = 1.0
+	maxGlucoseMmolL = 33.3
+
+	// 允许的测量时间误差，避免客户端时钟偏差导致拒绝
+	futureTolerance = 5 * time.Minute
+
+	maxReadingsPerRequest = 5000
+	maxNoteLength         = 200
+
+	// 查询默认的天数和最大天数
+	defaultRangeDays = 14
+	maxRangeDays     = 90
+
+	// 未设置目标范围时使用国际共识的 3.9-10.0 mmol/L
+	defaultTargetLow  = 3.9
+	defaultTargetHigh = 10.0
+)
+
+// CreateReadings 校验并写入血糖记录，与已有记录重复的条目被忽略，返回写入和忽略的条数
+func CreateReadings(email string, req request.CreateGlucoseReadingsRequest) (int64, int64, error) {
+	if len(req.Readings) == 0 {
+		return 0, 0, invalid("readings is empty")
+	}
+	if len(req.Readings) > maxReadingsPerRequest {
+		return 0, 0, invalid("at most %d readings per request", maxReadingsPerRequest)
+	}
+
+	unit, err := PreferredUnit(email)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	readings := make([]model.GlucoseReading, 0, len(req.Readings))
+	for i, item := range req.Readings {
+		reading, err := toReading(email, item, unit)
+		if err != nil {
+			return 0, 0, fmt.Errorf("reading %d: %w", i, err)
+		}
+		readings = append(readings, *reading)
+	}
+
+	created, err := dao.CreateGlucoseReadings(readings)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to create glucose readings: %v", err)
+	}
+	return created, int64(len(readings)) - created, nil
+}
+
+func toReading(email string, req request.GlucoseReadingRequest, defaultUnit model.GlucoseUnit) (*model.GlucoseReading, error) {
+	reading := &model.GlucoseReading{
+		UserEmail:  email,
+		Unit:       model.GlucoseUnit(req.Unit),
+		MeasuredAt: req.MeasuredAt,
+		Context:    model.GlucoseContext(req.Context),
+		Source:     model.GlucoseSource(req.Source),
+		Note:       strings.TrimSpace(req.Note),
+	}
+
+	if reading.Unit == "" {
+		reading.Unit = defaultUnit
+	}
+	if !reading.Unit.IsValid() {
+		return nil, invalid("unsupported glucose unit: %s", req.Unit)
+	}
+	if reading.Context == "" {
+		reading.Context = model.GlucoseRandom
+	}
+	if !reading.Context.IsValid() {
+		return nil, invalid("unsupported context: %s", req.Context)
+	}
+	if reading.Source == "" {
+		reading.Source = model.GlucoseSourceManual
+	}
+	if !reading.Source.IsValid() {
+		return nil, invalid("unsupported source: %s", req.Source)
+	}
+
+	reading.Value = reading.Unit.ToMmolL(req.Value)
+	if math.IsNaN(reading.Value) || reading.Value < minGlucoseMmolL || reading.Value > maxGlucoseMmolL {
+		return nil, invalid("value is out of range")
+	}
+	if reading.MeasuredAt.IsZero() || reading.MeasuredAt.After(time.Now().Add(futureTolerance)) {
+		return nil, invalid("measured_at is missing or in the future")
+	}
+	if len([]rune(reading.Note)) > maxNoteLength {
+		return nil, invalid("note is too long")
+	}
+	return reading, nil
+}
+
+// GetReadings 返回 [from, to) 内的血糖记录
+func GetReadings(email string, from, to time.Time) ([]model.GlucoseReading, error) {
+	readings, err := dao.GetGlucoseReadings(email, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get glucose readings: %v", err)
+	}
+	return readings, nil
+}
+
+func DeleteReading(email string, id uint) error {
+	found, err := dao.DeleteGlucoseReading(email, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete glucose reading: %v", err)
+	}
+	if !found {
+		return fmt.Errorf("%w: %d", ErrReadingNotFound, id)
+	}
+	return nil
+}
+
+// GetMetrics 计算 [from, to) 内的血糖统计指标，目标范围和时区使用健康档案中的设置
+func GetMetrics(email string, from, to time.Time) (*Metrics, error) {
+	readings, err := GetReadings(email, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	low, high, err := TargetRange(email)
+	if err != nil {
+		return nil, err
+	}
+
+	loc, err := Location(email)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := ComputeMetrics(readings, low, high, loc)
+	metrics.From = from.In(loc)
+	metrics.To = to.In(loc)
+	return &metrics, nil
+}
+
+// PreferredUnit 返回用户偏好的血糖单位，未填写健康档案时为 mmol/L
+func PreferredUnit(email string) (model.GlucoseUnit, error) {
+	patientProfile, err := profile.GetProfile(email)
+	if err != nil {
+		return "", err
+	}
+	if patientProfile == nil || !patientProfile.GlucoseUnit.IsValid() {
+		return model.GlucoseUnitMmolL, nil
+	}
+	return patientProfile.GlucoseUnit, nil
+}
+
+// TargetRange 返回用户的目标血糖范围（mmol/L），未设置时使用 3.9-10.0
+func TargetRange(email string) (float64, float64, error) {
+	patientProfile, err := profile.GetProfile(email)
+	if err != nil {
+		return 0, 0, err
+	}
+	if patientProfile == nil || patientProfile.TargetLow == nil || patientProfile.TargetHigh == nil {
+		return defaultTargetLow, defaultTargetHigh, nil
+	}
+	return *patientProfile.TargetLow, *patientProfile.TargetHigh, nil
+}
+
+// Location 返回用户所在的时区，未设置时使用服务器时区
+func Location(email string) (*time.Location, error) {
+	patientProfile, err := profile.GetProfile(email)
+	if err != nil {
+		return nil, err
+	}
+	return profile.Location(patientProfile), nil
+}
+
+// ParseRange 解析查询的时间范围，支持 RFC3339 和 YYYY-MM-DD 格式，日期按 loc 时区解析
+// 日期格式的 to 包含当天，未指定时查询最近 14 天
+func ParseRange(fromStr, toStr string, loc *time.Location) (time.Time, time.Time, error) {
+	to := time.Now().In(loc)
+	if toStr != "" {
+		t, dateOnly, err := parseTime(toStr, loc)
+		if err != nil {
+			return time.Time{}, time.Time{}, invalid("to must be RFC3339 or YYYY-MM-DD")
+		}
+		if dateOnly {
+			t = t.AddDate(0, 0, 1)
+		}
+		to = t
+	}
+
+	from := to.AddDate(0, 0, -defaultRangeDays)
+	if fromStr != "" {
+		t, _, err := parseTime(fromStr, loc)
+		if err != nil {
+			return time.Time{}, time.Time{}, invalid("from must be RFC3339 or YYYY-MM-DD")
+		}
+		from = t
+	}
+
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, invalid("from must be before to")
+	}
+	if to.Sub(from) > maxRangeDays*24*time.Hour {
+		return time.Time{}, time.Time{}, invalid("range must not exceed %d days", maxRangeDays)
+	}
+	return from, to, nil
+}
+
+func parseTime(value string, loc *time.Location) (time.Time, bool, error) {
+	if t, err := time.ParseInLocation(time.DateOnly, value, loc); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	return t, false, err
+}
+
+func invalid(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalidRequest, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,331 @@
+package glucose
+
+import (
+	"context"
+	"diabetes-agent-backend/config"
+	"diabetes-agent-backend/dao"
+	"diabetes-agent-backend/model"
+	"diabetes-agent-backend/request"
+	"diabetes-agent-backend/service/glucose/cgm"
+	"diabetes-agent-backend/service/mq"
+	ossauth "diabetes-agent-backend/service/oss-auth"
+	"diabetes-agent-backend/service/profile"
+	"diabetes-agent-backend/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss"
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/credentials"
+	"gorm.io/gorm"
+)
+
+// 血糖数据导入 MQ 消息的 topic 和 tag
+const (
+	TopicGlucose = "topic_glucose"
+	TagImport    = "tag_import"
+)
+
+const (
+	// CGM 导出文件的大小上限，约为两年的 5 分钟间隔读数
+	maxImportFileSize = 50 << 20
+
+	// 查询导入任务时返回的最近任务数量
+	maxImportsListed = 50
+)
+
+// errImportTooLarge 导出文件超过大小上限，重试也无法处理成功
+var errImportTooLarge = errors.New("import file too large")
+
+// 全局 HTTP 客户端，访问 OSS 时复用
+var httpClient *http.Client = utils.DefaultHTTPClient()
+
+// ImportMessage 导入 CGM 导出文件的任务
+type ImportMessage struct {
+	ImportID uint `json:"import_id"`
+}
+
+// RegisterHandlers 注册血糖数据导入的消息处理器
+func RegisterHandlers() {
+	mq.Register(TopicGlucose, TagImport, mq.Handler{
+		Handle:      HandleImportMessage,
+		OnExhausted: HandleImportExhausted,
+	})
+}
+
+// CreateImport 创建导入任务，与导入消息在同一事务中写入 outbox，由 MQ 异步处理
+func CreateImport(email string, req request.CreateGlucoseImportRequest) (*model.GlucoseImport, error) {
+	req.FileName = strings.TrimSpace(req.FileName)
+	if req.FileName == "" || strings.Contains(req.FileName, "/") {
+		return nil, invalid("invalid file name")
+	}
+
+	format := cgm.Format(req.Format)
+	if format != "" && !format.IsValid() {
+		return nil, invalid("unsupported format: %s", req.Format)
+	}
+	if req.Timezone != "" {
+		if _, err := time.LoadLocation(req.Timezone); err != nil {
+			return nil, invalid("unknown timezone: %s", req.Timezone)
+		}
+	} else {
+		// 未指定时按健康档案中的时区解析
+		patientProfile, err := profile.GetProfile(email)
+		if err != nil {
+			return nil, err
+		}
+		if patientProfile != nil {
+			req.Timezone = patientProfile.Timezone
+		}
+	}
+
+	objectName, err := ossauth.ObjectName(request.OSSAuthRequest{
+		Namespace: ossauth.OSSKeyPrefixUpload,
+		Email:     email,
+		SessionID: req.SessionID,
+		FileName:  req.FileName,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate object name: %v", err)
+	}
+
+	glucoseImport := &model.GlucoseImport{
+		UserEmail:  email,
+		FileName:   req.FileName,
+		ObjectName: objectName,
+		Format:     string(format),
+		Timezone:   req.Timezone,
+		Status:     model.ImportStatusPending,
+	}
+
+	err = dao.Transaction(func(tx *gorm.DB) error {
+		if err := dao.CreateGlucoseImport(tx, glucoseImport); err != nil {
+			return fmt.Errorf("failed to create glucose import: %v", err)
+		}
+		return enqueueImportMessage(tx, glucoseImport)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 导出文件的时区通常就是用户所在的时区，健康档案未设置时区时记录下来，之后的统计按该时区分天
+	if glucoseImport.Timezone != "" {
+		if err := profile.FillTimezone(email, glucoseImport.Timezone); err != nil {
+			slog.Warn("failed to fill patient timezone", "email", email, "err", err)
+		}
+	}
+	return glucoseImport, nil
+}
+
+func enqueueImportMessage(tx *gorm.DB, glucoseImport *model.GlucoseImport) error {
+	data, err := json.Marshal(ImportMessage{ImportID: glucoseImport.ID})
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %v", err)
+	}
+
+	err = dao.CreateOutboxMessage(tx, &model.OutboxMessage{
+		UserEmail:      glucoseImport.UserEmail,
+		Topic:          TopicGlucose,
+		Tag:            TagImport,
+		IdempotencyKey: fmt.Sprintf("%s:%d", TagImport, glucoseImport.ID),
+		Payload:        string(data),
+		Status:         model.OutboxStatusPending,
+		NextAttemptAt:  time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create outbox message: %v", err)
+	}
+	return nil
+}
+
+// GetImport 返回用户的导入任务，不存在时返回 nil
+func GetImport(email string, id uint) (*model.GlucoseImport, error) {
+	glucoseImport, err := dao.GetGlucoseImport(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get glucose import: %v", err)
+	}
+	if glucoseImport == nil || glucoseImport.UserEmail != email {
+		return nil, nil
+	}
+	return glucoseImport, nil
+}
+
+func GetImports(email string) ([]model.GlucoseImport, error) {
+	imports, err := dao.GetGlucoseImports(email, maxImportsListed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get glucose imports: %v", err)
+	}
+	return imports, nil
+}
+
+// HandleImportMessage 下载并解析导出文件，写入血糖记录
+// 文件无法解析或过大时直接标记失败并确认消息，其他错误由 MQ 重试
+func HandleImportMessage(ctx context.Context, msg *mq.Delivery) error {
+	var message ImportMessage
+	if err := json.Unmarshal(msg.Body, &message); err != nil {
+		return fmt.Errorf("failed to unmarshal message body: %v", err)
+	}
+
+	glucoseImport, err := dao.GetGlucoseImport(message.ImportID)
+	if err != nil {
+		return fmt.Errorf("failed to get glucose import %d: %v", message.ImportID, err)
+	}
+	if glucoseImport == nil || glucoseImport.Status == model.ImportStatusSucceeded {
+		return nil
+	}
+
+	if err := dao.UpdateGlucoseImport(glucoseImport.ID, map[string]any{
+		"status": model.ImportStatusProcessing,
+	}); err != nil {
+		return fmt.Errorf("failed to update glucose import: %v", err)
+	}
+
+	result, err := executeImport(ctx, glucoseImport)
+	if err != nil {
+		if errors.Is(err, cgm.ErrInvalidFile) || errors.Is(err, errImportTooLarge) {
+			slog.Warn("Glucose import rejected",
+				"msg_id", msg.MsgID,
+				"import_id", glucoseImport.ID,
+				"err", err)
+			return markImportFailed(glucoseImport.ID, err.Error())
+		}
+
+		dao.UpdateGlucoseImport(glucoseImport.ID, map[string]any{"error": err.Error()})
+		return err
+	}
+
+	slog.Info("Glucose import executed successfully",
+		"msg_id", msg.MsgID,
+		"import_id", glucoseImport.ID,
+		"format", result.Format,
+		"readings", len(result.Readings))
+	return nil
+}
+
+// HandleImportExhausted 在导入消息重试次数耗尽后调用，将导入任务标记为失败
+func HandleImportExhausted(ctx context.Context, msg *mq.Delivery, cause error) error {
+	var message ImportMessage
+	if err := json.Unmarshal(msg.Body, &message); err != nil {
+		return fmt.Errorf("failed to unmarshal message body: %v", err)
+	}
+	return markImportFailed(message.ImportID, cause.Error())
+}
+
+func executeImport(ctx context.Context, glucoseImport *model.GlucoseImport) (*cgm.Result, error) {
+	loc := time.Local
+	if glucoseImport.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(glucoseImport.Timezone); err != nil {
+			return nil, fmt.Errorf("%w: unknown timezone %s", cgm.ErrInvalidFile, glucoseImport.Timezone)
+		}
+	}
+
+	body, err := downloadObject(ctx, glucoseImport.ObjectName)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	// 超过上限时文件被截断，解析错误没有意义
+	result, err := cgm.Parse(body, cgm.Format(glucoseImport.Format), loc)
+	if body.exceeded() {
+		return nil, fmt.Errorf("%w: exceeds limit of %d bytes", errImportTooLarge, maxImportFileSize)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	readings := make([]model.GlucoseReading, 0, len(result.Readings))
+	for _, item := range result.Readings {
+		readings = append(readings, model.GlucoseReading{
+			UserEmail:  glucoseImport.UserEmail,
+			Value:      item.Value,
+			Unit:       item.Unit,
+			MeasuredAt: item.MeasuredAt,
+			Context:    model.GlucoseRandom,
+			Source:     item.Source,
+		})
+	}
+
+	// 重复处理同一文件时已写入的读数被忽略
+	created, err := dao.CreateGlucoseReadings(readings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create glucose readings: %v", err)
+	}
+
+	now := time.Now()
+	if err := dao.UpdateGlucoseImport(glucoseImport.ID, map[string]any{
+		"status":      model.ImportStatusSucceeded,
+		"format":      string(result.Format),
+		"created":     created,
+		"skipped":     int64(len(readings)) - created,
+		"invalid":     result.Invalid,
+		"error":       "",
+		"finished_at": now,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to update glucose import: %v", err)
+	}
+	return result, nil
+}
+
+func markImportFailed(id uint, reason string) error {
+	now := time.Now()
+	if err := dao.UpdateGlucoseImport(id, map[string]any{
+		"status":      model.ImportStatusFailed,
+		"error":       reason,
+		"finished_at": now,
+	}); err != nil {
+		return fmt.Errorf("failed to mark glucose import failed: %v", err)
+	}
+	return nil
+}
+
+// limitedBody 限制读取的字节数，多读取一个字节用于判断文件是否超过上限
+type limitedBody struct {
+	io.Reader
+	closer io.Closer
+	limit  *io.LimitedReader
+}
+
+func (b *limitedBody) Close() error {
+	return b.closer.Close()
+}
+
+func (b *limitedBody) exceeded() bool {
+	return b.limit.N <= 0
+}
+
+// downloadObject 流式读取 OSS 上的导出文件，调用方负责关闭
+func downloadObject(ctx context.Context, objectName string) (*limitedBody, error) {
+	cfg := &oss.Config{
+		Region: oss.Ptr(config.Cfg.OSS.Region),
+		CredentialsProvider: credentials.NewStaticCredentialsProvider(
+			config.Cfg.OSS.AccessKeyID,
+			config.Cfg.OSS.AccessKeySecret,
+		),
+		HttpClient: httpClient,
+	}
+	client := oss.NewClient(cfg)
+
+	result, err := client.GetObject(ctx, &oss.GetObjectRequest{
+		Bucket: oss.Ptr(config.Cfg.OSS.BucketName),
+		Key:    oss.Ptr(objectName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download object from oss: %v", err)
+	}
+
+	if result.ContentLength > maxImportFileSize {
+		result.Body.Close()
+		return nil, fmt.Errorf("%w: %d bytes exceeds limit of %d bytes",
+			errImportTooLarge, result.ContentLength, maxImportFileSize)
+	}
+
+	limit := &io.LimitedReader{R: result.Body, N: maxImportFileSize + 1}
+	return &limitedBody{Reader: limit, closer: result.Body, limit: limit}, nil
+}
@@ -0,0 +1,190 @@
+package glucose
+
+import (
+	"diabetes-agent-backend/model"
+	"math"
+	"slices"
+	"time"
+)
+
+// 国际共识中的极低和极高血糖阈值（mmol/L）
+const (
+	VeryLowThreshold  = 3.0
+	VeryHighThreshold = 13.9
+)
+
+// Metrics 血糖统计指标，血糖值的单位为 mmol/L
+type Metrics struct {
+	From time.Time
+	To   time.Time
+
+	Count int
+	Days  int
+
+	Mean float64
+	SD   float64
+	Min  float64
+	Max  float64
+
+	// 变异系数（%）
+	CV float64
+
+	// 血糖管理指标（%），由平均血糖估算的糖化血红蛋白
+	GMI float64
+
+	TargetLow  float64
+	TargetHigh float64
+
+	// 各血糖区间的读数占比（%），CGM 数据等间隔采样，近似为时间占比
+	TimeVeryLow  float64
+	TimeLow      float64
+	TimeInRange  float64
+	TimeHigh     float64
+	TimeVeryHigh float64
+
+	Hourly    []HourlyStat
+	ByContext []ContextStat
+}
+
+// HourlyStat 一天中某个小时的血糖分布，用于观察每日规律
+type HourlyStat struct {
+	Hour   int
+	Count  int
+	Mean   float64
+	P25    float64
+	Median float64
+	P75    float64
+}
+
+// ContextStat 某个测量场景的血糖均值
+type ContextStat struct {
+	Context model.GlucoseContext
+	Count   int
+	Mean    float64
+}
+
+// ComputeMetrics 计算血糖统计指标，low 和 high 为目标范围（mmol/L），按天和按小时的统计使用 loc 时区
+// 低于 3.0 为极低，3.0 到 low 为偏低，高于 13.9 为极高，high 到 13.9 为偏高
+func ComputeMetrics(readings []model.GlucoseReading, low, high float64, loc *time.Location) Metrics {
+	metrics := Metrics{
+		Count:      len(readings),
+		TargetLow:  low,
+		TargetHigh: high,
+	}
+	if len(readings) == 0 {
+		return metrics
+	}
+
+	values := make([]float64, 0, len(readings))
+	days := make(map[string]bool)
+	hourly := make(map[int][]float64)
+	byContext := make(map[model.GlucoseContext][]float64)
+
+	var veryLow, lowCount, inRange, highCount, veryHigh int
+	for _, reading := range readings {
+		value := reading.Value
+		values = append(values, value)
+
+		measuredAt := reading.MeasuredAt.In(loc)
+		days[measuredAt.Format(time.DateOnly)] = true
+		hourly[measuredAt.Hour()] = append(hourly[measuredAt.Hour()], value)
+		byContext[reading.Context] = append(byContext[reading.Context], value)
+
+		switch {
+		case value < VeryLowThreshold:
+			veryLow++
+		case value < low:
+			lowCount++
+		case value <= high:
+			inRange++
+		case value <= VeryHighThreshold:
+			highCount++
+		default:
+			veryHigh++
+		}
+	}
+
+	metrics.Days = len(days)
+	metrics.Mean = mean(values)
+	metrics.SD = standardDeviation(values, metrics.Mean)
+	metrics.Min = slices.Min(values)
+	metrics.Max = slices.Max(values)
+	if metrics.Mean > 0 {
+		metrics.CV = metrics.SD / metrics.Mean * 100
+	}
+	metrics.GMI = gmi(metrics.Mean)
+
+	total := float64(len(readings))
+	metrics.TimeVeryLow = float64(veryLow) / total * 100
+	metrics.TimeLow = float64(lowCount) / total * 100
+	metrics.TimeInRange = float64(inRange) / total * 100
+	metrics.TimeHigh = float64(highCount) / total * 100
+	metrics.TimeVeryHigh = float64(veryHigh) / total * 100
+
+	for hour := 0; hour < 24; hour++ {
+		hourValues := hourly[hour]
+		if len(hourValues) == 0 {
+			continue
+		}
+		slices.Sort(hourValues)
+		metrics.Hourly = append(metrics.Hourly, HourlyStat{
+			Hour:   hour,
+			Count:  len(hourValues),
+			Mean:   mean(hourValues),
+			P25:    Percentile(hourValues, 25),
+			Median: Percentile(hourValues, 50),
+			P75:    Percentile(hourValues, 75),
+		})
+	}
+
+	for context, contextValues := range byContext {
+		metrics.ByContext = append(metrics.ByContext, ContextStat{
+			Context: context,
+			Count:   len(contextValues),
+			Mean:    mean(contextValues),
+		})
+	}
+	slices.SortFunc(metrics.ByContext, func(a, b ContextStat) int {
+		return b.Count - a.Count
+	})
+
+	return metrics
+}
+
+// gmi 按 GMI(%) = 3.31 + 0.02392 × 平均血糖(mg/dL) 计算
+func gmi(meanMmolL float64) float64 {
+	return 3.31 + 0.02392*model.GlucoseUnitMgDL.FromMmolL(meanMmolL)
+}
+
+func mean(values []float64) float64 {
+	var sum float64
+	for _, value := range values {
+		sum += value
+	}
+	return sum / float64(len(values))
+}
+
+// standardDeviation 样本标准差，少于两个值时为 0
+func standardDeviation(values []float64, mean float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+
+	var sum float64
+	for _, value := range values {
+		sum += (value - mean) * (value - mean)
+	}
+	return math.Sqrt(sum / float64(len(values)-1))
+}
+
+// Percentile 返回已排序数据的第 p 百分位数，相邻值之间线性插值
+func Percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
@@ -0,0 +1,58 @@
+package glucose
+
+import (
+	"diabetes-agent-backend/model"
+	"testing"
+	"time"
+)
+
+func TestComputeMetricsBucketsByUserTimezone(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skipf("tzdata not available: %v", err)
+	}
+
+	// UTC 23:30 和次日 00:30 在上海都是次日上午
+	readings := []model.GlucoseReading{
+		{Value: 6.0, MeasuredAt: time.Date(2026, 3, 1, 23, 30, 0, 0, time.UTC)},
+		{Value: 7.0, MeasuredAt: time.Date(2026, 3, 2, 0, 30, 0, 0, time.UTC)},
+	}
+
+	utc := ComputeMetrics(readings, defaultTargetLow, defaultTargetHigh, time.UTC)
+	if utc.Days != 2 || len(utc.Hourly) != 2 || utc.Hourly[0].Hour != 0 || utc.Hourly[1].Hour != 23 {
+		t.Errorf("UTC days = %d, hourly = %+v, want 2 days at hours 0 and 23", utc.Days, utc.Hourly)
+	}
+
+	local := ComputeMetrics(readings, defaultTargetLow, defaultTargetHigh, shanghai)
+	if local.Days != 1 || len(local.Hourly) != 2 || local.Hourly[0].Hour != 7 || local.Hourly[1].Hour != 8 {
+		t.Errorf("Asia/Shanghai days = %d, hourly = %+v, want 1 day at hours 7 and 8", local.Days, local.Hourly)
+	}
+}
+
+func TestParseRangeUsesUserTimezone(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skipf("tzdata not available: %v", err)
+	}
+
+	from, to, err := ParseRange("2026-03-01", "2026-03-07", shanghai)
+	if err != nil {
+		t.Fatalf("ParseRange() error = %v", err)
+	}
+	if want := time.Date(2026, 2, 28, 16, 0, 0, 0, time.UTC); !from.Equal(want) {
+		t.Errorf("from = %v, want %v", from, want)
+	}
+	// 日期格式的 to 包含当天
+	if want := time.Date(2026, 3, 7, 16, 0, 0, 0, time.UTC); !to.Equal(want) {
+		t.Errorf("to = %v, want %v", to, want)
+	}
+
+	// RFC3339 自带时区，不受用户时区影响
+	from, _, err = ParseRange("2026-03-01T00:00:00Z", "2026-03-07", shanghai)
+	if err != nil {
+		t.Fatalf("ParseRange() error = %v", err)
+	}
+	if want := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC); !from.Equal(want) {
+		t.Errorf("from = %v, want %v", from, want)
+	}
+}
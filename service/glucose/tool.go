@@ -0,0 +1,160 @@
+package glucose
+
+import (
+	"context"
+	"diabetes-agent-backend/model"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tmc/langchaingo/tools"
+)
+
+// 工具结果中附带的最近读数条数
+const toolRecentReadings = 10
+
+// Tool 供 Agent 查询当前用户血糖数据的进程内工具
+type Tool struct {
+	Email string
+}
+
+var _ tools.Tool = &Tool{}
+
+func NewTool(email string) *Tool {
+	return &Tool{Email: email}
+}
+
+func (t *Tool) Name() string {
+	return "glucose_data"
+}
+
+func (t *Tool) Description() string {
+	return `Query the user's recorded blood glucose readings and statistics. ` +
+		`Input is a JSON object: {"days": 14} for the most recent days, or {"from": "YYYY-MM-DD", "to": "YYYY-MM-DD"}. ` +
+		`Empty input means the last 14 days. Returns mean, SD, CV, GMI, time in/above/below range, ` +
+		`hourly pattern, per-context averages and the latest readings, in the user's preferred unit.`
+}
+
+type toolInput struct {
+	Days int    `json:"days"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Call 输入有误时将错误信息作为结果返回，由 Agent 修正输入后重试
+func (t *Tool) Call(ctx context.Context, input string) (string, error) {
+	loc, err := Location(t.Email)
+	if err != nil {
+		return "", err
+	}
+
+	from, to, err := ParseToolRange(input, loc)
+	if err != nil {
+		return "Invalid input: " + err.Error(), nil
+	}
+
+	metrics, err := GetMetrics(t.Email, from, to)
+	if err != nil {
+		return "", err
+	}
+	if metrics.Count == 0 {
+		return fmt.Sprintf("No glucose readings recorded between %s and %s.",
+			from.Format(time.DateOnly), to.Format(time.DateOnly)), nil
+	}
+
+	unit, err := PreferredUnit(t.Email)
+	if err != nil {
+		return "", err
+	}
+
+	readings, err := GetReadings(t.Email, from, to)
+	if err != nil {
+		return "", err
+	}
+	if len(readings) > toolRecentReadings {
+		readings = readings[len(readings)-toolRecentReadings:]
+	}
+
+	return formatMetrics(metrics, readings, unit, loc), nil
+}
+
+// ParseToolRange 解析工具输入的时间范围，支持天数或起止日期，日期按 loc 时区解析
+func ParseToolRange(input string, loc *time.Location) (time.Time, time.Time, error) {
+	input = strings.TrimSpace(input)
+	input = strings.TrimPrefix(input, "```json")
+	input = strings.Trim(input, "`\n ")
+	if input == "" {
+		return ParseRange("", "", loc)
+	}
+
+	// 兼容直接输入天数
+	if days, err := strconv.Atoi(input); err == nil {
+		return rangeOfDays(days, loc)
+	}
+
+	var parsed toolInput
+	if err := json.Unmarshal([]byte(input), &parsed); err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("input must be a JSON object")
+	}
+	if parsed.Days > 0 {
+		return rangeOfDays(parsed.Days, loc)
+	}
+	return ParseRange(parsed.From, parsed.To, loc)
+}
+
+func rangeOfDays(days int, loc *time.Location) (time.Time, time.Time, error) {
+	if days <= 0 || days > maxRangeDays {
+		return time.Time{}, time.Time{}, fmt.Errorf("days must be between 1 and %d", maxRangeDays)
+	}
+	to := time.Now().In(loc)
+	return to.AddDate(0, 0, -days), to, nil
+}
+
+func formatMetrics(metrics *Metrics, readings []model.GlucoseReading, unit model.GlucoseUnit, loc *time.Location) string {
+	var b strings.Builder
+	format := func(value float64) string {
+		return FormatValue(unit, value)
+	}
+
+	fmt.Fprintf(&b, "Glucose data from %s to %s (%s): %d readings over %d days.\n",
+		metrics.From.Format(time.DateOnly), metrics.To.Format(time.DateOnly), unit, metrics.Count, metrics.Days)
+	fmt.Fprintf(&b, "Mean %s, SD %s, CV %.1f%%, GMI %.1f%%, min %s, max %s.\n",
+		format(metrics.Mean), format(metrics.SD), metrics.CV, metrics.GMI, format(metrics.Min), format(metrics.Max))
+	fmt.Fprintf(&b, "Target range %s-%s. Time very low (<%s) %.1f%%, low %.1f%%, in range %.1f%%, high %.1f%%, very high (>%s) %.1f%%.\n",
+		format(metrics.TargetLow), format(metrics.TargetHigh),
+		format(VeryLowThreshold), metrics.TimeVeryLow, metrics.TimeLow, metrics.TimeInRange,
+		metrics.TimeHigh, format(VeryHighThreshold), metrics.TimeVeryHigh)
+
+	if len(metrics.ByContext) > 0 {
+		var parts []string
+		for _, stat := range metrics.ByContext {
+			parts = append(parts, fmt.Sprintf("%s %s (n=%d)", stat.Context, format(stat.Mean), stat.Count))
+		}
+		fmt.Fprintf(&b, "Mean by context: %s.\n", strings.Join(parts, ", "))
+	}
+
+	if len(metrics.Hourly) > 0 {
+		var parts []string
+		for _, stat := range metrics.Hourly {
+			parts = append(parts, fmt.Sprintf("%02d:00 %s", stat.Hour, format(stat.Median)))
+		}
+		fmt.Fprintf(&b, "Hourly median: %s.\n", strings.Join(parts, ", "))
+	}
+
+	b.WriteString("Latest readings:")
+	for _, reading := range readings {
+		fmt.Fprintf(&b, "\n- %s %s %s",
+			reading.MeasuredAt.In(loc).Format("2006-01-02 15:04"), format(reading.Value), reading.Context)
+	}
+	return b.String()
+}
+
+// FormatValue 将 mmol/L 的血糖值换算为 unit 单位并格式化，mg/dL 取整
+func FormatValue(unit model.GlucoseUnit, value float64) string {
+	if unit == model.GlucoseUnitMgDL {
+		return strconv.FormatFloat(unit.FromMmolL(value), 'f', 0, 64)
+	}
+	return strconv.FormatFloat(value, 'f', 1, 64)
+}
@@ -0,0 +1,67 @@
+package knowledgebase
+
+import (
+	"diabetes-agent-backend/config"
+	"diabetes-agent-backend/model"
+	"diabetes-agent-backend/request"
+	"fmt"
+)
+
+const (
+	minChunkSize = 100
+	maxChunkSize = 8000
+)
+
+// ResolveChunking 确定知识文件版本的切分配置
+// 优先级：上传时指定 > 上一版本记录的配置 > 按文件类型配置 > 默认配置 > 内置默认值
+func ResolveChunking(fileType model.FileType, override *request.ChunkingOptions, previous *model.Chunking) (model.Chunking, error) {
+	chunking := model.DefaultChunking(fileType)
+	cfg := config.Cfg.KnowledgeBase.Chunking
+	mergeChunkingConfig(&chunking, cfg.Default)
+	if typed, ok := cfg.ByFileType[string(fileType)]; ok {
+		mergeChunkingConfig(&chunking, typed)
+	}
+
+	// 替换上传沿用上一版本的配置，保证版本间切分方式一致
+	if previous != nil && previous.Strategy != "" {
+		chunking = *previous
+	}
+
+	if override != nil {
+		mergeChunkingConfig(&chunking, config.ChunkingConfig{
+			Strategy:     override.Strategy,
+			ChunkSize:    override.ChunkSize,
+			ChunkOverlap: override.ChunkOverlap,
+		})
+	}
+
+	if err := validateChunking(chunking); err != nil {
+		return model.Chunking{}, err
+	}
+	return chunking, nil
+}
+
+func mergeChunkingConfig(chunking *model.Chunking, cfg config.ChunkingConfig) {
+	if cfg.Strategy != "" {
+		chunking.Strategy = model.ChunkingStrategy(cfg.Strategy)
+	}
+	if cfg.ChunkSize > 0 {
+		chunking.ChunkSize = cfg.ChunkSize
+	}
+	if cfg.ChunkOverlap != nil {
+		chunking.ChunkOverlap = *cfg.ChunkOverlap
+	}
+}
+
+func validateChunking(chunking model.Chunking) error {
+	if !chunking.Strategy.IsValid() {
+		return fmt.Errorf("%w: unsupported chunking strategy: %s", ErrInvalidUpload, chunking.Strategy)
+	}
+	if chunking.ChunkSize < minChunkSize || chunking.ChunkSize > maxChunkSize {
+		return fmt.Errorf("%w: chunk size must be between %d and %d", ErrInvalidUpload, minChunkSize, maxChunkSize)
+	}
+	if chunking.ChunkOverlap < 0 || chunking.ChunkOverlap > chunking.ChunkSize/2 {
+		return fmt.Errorf("%w: chunk overlap must be between 0 and %d", ErrInvalidUpload, chunking.ChunkSize/2)
+	}
+	return nil
+}
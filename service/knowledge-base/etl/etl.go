@@ -4,117 +4,289 @@ import (
 	"context"
 	"diabetes-agent-backend/config"
 	"diabetes-agent-backend/model"
+	knowledgebase "diabetes-agent-backend/service/knowledge-base"
 	"diabetes-agent-backend/service/knowledge-base/etl/processor"
+	"diabetes-agent-backend/service/mq"
 	"diabetes-agent-backend/utils"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"os"
+	"slices"
 
 	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss"
 	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/credentials"
-	"github.com/apache/rocketmq-client-go/v2/primitive"
 )
 
-var (
-	// 知识文件 ETL 处理器注册表
-	etlProcessorRegistry []processor.ETLProcessor
+// 全局 HTTP 客户端，访问 OSS 时复用
+var httpClient *http.Client = utils.DefaultHTTPClient()
 
-	// 全局 HTTP 客户端，访问 OSS 时复用
-	httpClient *http.Client = utils.DefaultHTTPClient()
-)
+// ObjectStorage 知识文件的对象存储
+type ObjectStorage interface {
+	// Download 将知识文件下载到临时文件，调用方负责关闭并删除临时文件
+	Download(ctx context.Context, etlMessage *knowledgebase.ETLMessage) (*os.File, int64, error)
 
-type ETLMessage struct {
-	FileType   model.FileType `json:"file_type"`
-	ObjectName string         `json:"object_name"`
+	Delete(ctx context.Context, objectName string) error
 }
 
-type DeleteMessage struct {
-	FileType   model.FileType `json:"file_type"`
-	ObjectName string         `json:"object_name"`
+// Consumer 消费知识库消息，执行 ETL 流程或删除知识文件的向量存储
+type Consumer struct {
+	// 按顺序匹配文件类型的 ETL 处理器
+	Processors []processor.ETLProcessor
+
+	Knowledge knowledgebase.ProcessingStore
+	Objects   ObjectStorage
 }
 
-func init() {
+// NewConsumer 创建使用 OSS、数据库和 Milvus 的消费者
+func NewConsumer() (*Consumer, error) {
 	pdfProcessor, err := processor.NewPDFETLProcessor()
 	if err != nil {
-		panic(fmt.Sprintf("error creating PDFETLProcessor: %v", err))
+		return nil, fmt.Errorf("error creating PDFETLProcessor: %v", err)
 	}
 
 	markdownProcessor, err := processor.NewMarkdownETLProcessor()
 	if err != nil {
-		panic(fmt.Sprintf("error creating MarkdownETLProcessor: %v", err))
+		return nil, fmt.Errorf("error creating MarkdownETLProcessor: %v", err)
+	}
+
+	docxProcessor, err := processor.NewDocxETLProcessor()
+	if err != nil {
+		return nil, fmt.Errorf("error creating DocxETLProcessor: %v", err)
+	}
+
+	htmlProcessor, err := processor.NewHTMLETLProcessor()
+	if err != nil {
+		return nil, fmt.Errorf("error creating HTMLETLProcessor: %v", err)
+	}
+
+	csvProcessor, err := processor.NewCSVETLProcessor()
+	if err != nil {
+		return nil, fmt.Errorf("error creating CSVETLProcessor: %v", err)
+	}
+
+	xlsxProcessor, err := processor.NewXLSXETLProcessor()
+	if err != nil {
+		return nil, fmt.Errorf("error creating XLSXETLProcessor: %v", err)
+	}
+
+	imageProcessor, err := processor.NewImageETLProcessor()
+	if err != nil {
+		return nil, fmt.Errorf("error creating ImageETLProcessor: %v", err)
+	}
+
+	return &Consumer{
+		Processors: []processor.ETLProcessor{
+			pdfProcessor,
+			markdownProcessor,
+			docxProcessor,
+			htmlProcessor,
+			csvProcessor,
+			xlsxProcessor,
+			imageProcessor,
+		},
+		Knowledge: knowledgebase.DBProcessingStore{},
+		Objects:   OSSStorage{},
+	}, nil
+}
+
+// RegisterHandlers 创建消费者并注册知识库消息的处理器
+func RegisterHandlers() error {
+	consumer, err := NewConsumer()
+	if err != nil {
+		return err
+	}
+	consumer.Register(mq.Register)
+	return nil
+}
+
+// Register 通过 register 注册知识库消息的处理器，register 可以是 mq.Register 或 Registry.Register
+func (c *Consumer) Register(register func(topic, tag string, handler mq.Handler)) {
+	register(knowledgebase.TopicKnowledgeBase, knowledgebase.TagETL, mq.Handler{
+		Handle:       c.HandleETLMessage,
+		OnExhausted:  c.HandleETLExhausted,
+		OnDeadLetter: c.HandleETLDeadLetter,
+	})
+	register(knowledgebase.TopicKnowledgeBase, knowledgebase.TagDelete, mq.Handler{
+		Handle: c.HandleDeleteMessage,
+	})
+}
+
+func (c *Consumer) HandleETLMessage(ctx context.Context, msg *mq.Delivery) error {
+	var etlMessage knowledgebase.ETLMessage
+	if err := json.Unmarshal(msg.Body, &etlMessage); err != nil {
+		return fmt.Errorf("failed to unmarshal message body: %v", err)
+	}
+
+	if err := c.executeETL(ctx, &etlMessage); err != nil {
+		// 文件过大时重试无意义，直接标记处理失败并确认消息
+		if errors.Is(err, knowledgebase.ErrFileTooLarge) {
+			slog.Warn("knowledge file rejected",
+				"msg_id", msg.MsgID,
+				"document_id", etlMessage.DocumentID,
+				"err", err,
+			)
+			return c.Knowledge.MarkFailed(etlMessage.KnowledgeID, err.Error())
+		}
+
+		// 记录最近一次失败原因，重试次数耗尽后由 HandleETLExhausted 标记处理失败
+		c.Knowledge.RecordError(etlMessage.KnowledgeID, err.Error())
+		return err
+	}
+
+	slog.Info("ETL pipeline executed successfully",
+		"msg_id", msg.MsgID,
+		"document_id", etlMessage.DocumentID,
+	)
+	return nil
+}
+
+// HandleETLExhausted 在 ETL 消息重试次数耗尽后调用，将知识文件标记为处理失败并删除该版本已写入的切片
+func (c *Consumer) HandleETLExhausted(ctx context.Context, msg *mq.Delivery, cause error) error {
+	var etlMessage knowledgebase.ETLMessage
+	if err := json.Unmarshal(msg.Body, &etlMessage); err != nil {
+		return fmt.Errorf("failed to unmarshal message body: %v", err)
 	}
 
-	etlProcessorRegistry = []processor.ETLProcessor{
-		pdfProcessor,
-		markdownProcessor,
+	slog.Warn("ETL retries exhausted",
+		"msg_id", msg.MsgID,
+		"document_id", etlMessage.DocumentID,
+		"err", cause,
+	)
+
+	if err := c.Knowledge.MarkFailed(etlMessage.KnowledgeID, cause.Error()); err != nil {
+		return err
 	}
+	return c.deleteFailedVersion(ctx, &etlMessage)
 }
 
-func HandleETLMessage(ctx context.Context, msg *primitive.MessageExt) error {
-	var etlMessage ETLMessage
+// HandleETLDeadLetter 消费死信队列中的 ETL 消息，将知识文件标记为处理失败并删除该版本已写入的切片
+func (c *Consumer) HandleETLDeadLetter(ctx context.Context, msg *mq.Delivery) error {
+	var etlMessage knowledgebase.ETLMessage
 	if err := json.Unmarshal(msg.Body, &etlMessage); err != nil {
 		return fmt.Errorf("failed to unmarshal message body: %v", err)
 	}
 
-	object, err := getObjectFromOSS(ctx, &etlMessage)
+	slog.Warn("ETL message dead-lettered",
+		"msg_id", msg.MsgID,
+		"document_id", etlMessage.DocumentID,
+		"knowledge_id", etlMessage.KnowledgeID,
+	)
+
+	if err := c.Knowledge.MarkDeadLettered(etlMessage.KnowledgeID); err != nil {
+		return err
+	}
+	return c.deleteFailedVersion(ctx, &etlMessage)
+}
+
+// deleteFailedVersion 删除处理失败的版本中断前写入的切片，当前生效的版本不受影响
+func (c *Consumer) deleteFailedVersion(ctx context.Context, etlMessage *knowledgebase.ETLMessage) error {
+	active, err := c.Knowledge.ActiveVersionIDs(etlMessage.UserEmail, []string{etlMessage.DocumentID})
+	if err != nil {
+		return err
+	}
+	if slices.Contains(active, etlMessage.KnowledgeID) {
+		return nil
+	}
+
+	for _, p := range c.Processors {
+		if p.CanProcess(etlMessage.FileType) {
+			if err := p.DeleteVersionVectorStore(ctx, etlMessage.DocumentID, etlMessage.KnowledgeID); err != nil {
+				return fmt.Errorf("failed to delete chunks of failed version: %v", err)
+			}
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (c *Consumer) executeETL(ctx context.Context, etlMessage *knowledgebase.ETLMessage) error {
+	c.Knowledge.UpdateProgress(etlMessage.KnowledgeID, model.StageDownloading, 0, 0)
+
+	file, size, err := c.Objects.Download(ctx, etlMessage)
 	if err != nil {
-		return fmt.Errorf("failed to get object from oss: %v", err)
+		return fmt.Errorf("failed to download object from oss: %w", err)
 	}
+	defer func() {
+		file.Close()
+		os.Remove(file.Name())
+	}()
 
-	slog.Debug("get object from oss successfully", "object_name", etlMessage.ObjectName)
+	slog.Debug("download object from oss successfully",
+		"object_name", etlMessage.ObjectName,
+		"size", size,
+	)
+
+	object := &processor.Object{ReaderAt: file, Size: size}
 
 	// 查找匹配文件类型的处理器，执行 ETL 流程
-	foundProcessor := false
-	for _, processor := range etlProcessorRegistry {
-		if processor.CanProcess(etlMessage.FileType) {
-			foundProcessor = true
-			if err := processor.ExecuteETLPipeline(ctx, object, etlMessage.ObjectName); err != nil {
+	for _, p := range c.Processors {
+		if p.CanProcess(etlMessage.FileType) {
+			if err := p.ExecuteETLPipeline(ctx, object, &processor.Metadata{
+				ObjectName:  etlMessage.ObjectName,
+				KnowledgeID: etlMessage.KnowledgeID,
+				DocumentID:  etlMessage.DocumentID,
+				UserEmail:   etlMessage.UserEmail,
+				FileName:    etlMessage.FileName,
+				Chunking:    etlMessage.Chunking,
+			}); err != nil {
 				return fmt.Errorf("failed to execute ETL pipeline: %v", err)
 			}
-			slog.Info("ETL pipeline executed successfully", "msg_id", msg.MsgId)
 			return nil
 		}
 	}
 
-	if !foundProcessor {
-		return fmt.Errorf("no processor found for file type: %s", etlMessage.FileType)
-	}
-
-	return nil
+	return fmt.Errorf("no processor found for file type: %s", etlMessage.FileType)
 }
 
-func HandleDeleteMessage(ctx context.Context, msg *primitive.MessageExt) error {
-	var deleteMessage DeleteMessage
+func (c *Consumer) HandleDeleteMessage(ctx context.Context, msg *mq.Delivery) error {
+	var deleteMessage knowledgebase.DeleteMessage
 	if err := json.Unmarshal(msg.Body, &deleteMessage); err != nil {
 		return fmt.Errorf("failed to unmarshal message body: %v", err)
 	}
 
-	if err := deleteObjectFromOSS(ctx, &deleteMessage); err != nil {
-		return fmt.Errorf("failed to delete object from oss: %v", err)
+	for _, objectName := range deleteMessage.ObjectNames {
+		if err := c.Objects.Delete(ctx, objectName); err != nil {
+			return fmt.Errorf("failed to delete object %s from oss: %v", objectName, err)
+		}
 	}
 
-	foundProcessor := false
-	for _, processor := range etlProcessorRegistry {
+	for _, processor := range c.Processors {
 		if processor.CanProcess(deleteMessage.FileType) {
-			foundProcessor = true
-			if err := processor.DeleteVectorStore(ctx, deleteMessage.ObjectName); err != nil {
+			if err := processor.DeleteVectorStore(ctx, deleteMessage.DocumentID); err != nil {
 				return fmt.Errorf("failed to delete vector store: %v", err)
 			}
-			slog.Info("vector store deleted successfully", "msg_id", msg.MsgId)
+			slog.Info("vector store deleted successfully",
+				"msg_id", msg.MsgID,
+				"document_id", deleteMessage.DocumentID,
+			)
 			return nil
 		}
 	}
 
-	if !foundProcessor {
-		return fmt.Errorf("no processor found for file type: %s", deleteMessage.FileType)
-	}
+	return fmt.Errorf("no processor found for file type: %s", deleteMessage.FileType)
+}
 
-	return nil
+// OSSStorage 基于阿里云 OSS 的对象存储
+type OSSStorage struct{}
+
+var _ ObjectStorage = OSSStorage{}
+
+func (OSSStorage) Download(ctx context.Context, etlMessage *knowledgebase.ETLMessage) (*os.File, int64, error) {
+	return downloadObjectFromOSS(ctx, etlMessage)
 }
 
-func getObjectFromOSS(ctx context.Context, etlMessage *ETLMessage) ([]byte, error) {
+func (OSSStorage) Delete(ctx context.Context, objectName string) error {
+	return deleteObjectFromOSS(ctx, objectName)
+}
+
+// downloadObjectFromOSS 将OSS上的知识文件流式下载到临时文件，避免将整个文件读入内存
+// 文件超过大小上限时返回包装 ErrFileTooLarge 的错误，调用方负责关闭并删除临时文件
+func downloadObjectFromOSS(ctx context.Context, etlMessage *knowledgebase.ETLMessage) (*os.File, int64, error) {
 	cfg := &oss.Config{
 		Region: oss.Ptr(config.Cfg.OSS.Region),
 		CredentialsProvider: credentials.NewStaticCredentialsProvider(
@@ -130,19 +302,35 @@ func getObjectFromOSS(ctx context.Context, etlMessage *ETLMessage) ([]byte, erro
 		Key:    oss.Ptr(etlMessage.ObjectName),
 	})
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer result.Body.Close()
 
-	data, err := io.ReadAll(result.Body)
+	if err := knowledgebase.CheckFileSize(etlMessage.FileType, result.ContentLength); err != nil {
+		return nil, 0, err
+	}
+
+	file, err := os.CreateTemp("", "knowledge-*")
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create temp file: %v", err)
+	}
+
+	// 多读取一个字节，用于判断实际内容是否超过上限
+	limit := knowledgebase.MaxFileSize(etlMessage.FileType)
+	size, err := io.Copy(file, io.LimitReader(result.Body, limit+1))
+	if err == nil {
+		err = knowledgebase.CheckFileSize(etlMessage.FileType, size)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to read object: %v", err)
+		file.Close()
+		os.Remove(file.Name())
+		return nil, 0, err
 	}
 
-	return data, nil
+	return file, size, nil
 }
 
-func deleteObjectFromOSS(ctx context.Context, deleteMessage *DeleteMessage) error {
+func deleteObjectFromOSS(ctx context.Context, objectName string) error {
 	cfg := &oss.Config{
 		Region: oss.Ptr(config.Cfg.OSS.Region),
 		CredentialsProvider: credentials.NewStaticCredentialsProvider(
@@ -155,7 +343,7 @@ func deleteObjectFromOSS(ctx context.Context, deleteMessage *DeleteMessage) erro
 
 	_, err := client.DeleteObject(ctx, &oss.DeleteObjectRequest{
 		Bucket: oss.Ptr(config.Cfg.OSS.BucketName),
-		Key:    oss.Ptr(deleteMessage.ObjectName),
+		Key:    oss.Ptr(objectName),
 	})
 	if err != nil {
 		return err
@@ -0,0 +1,522 @@
+package etl
+
+import (
+	"context"
+	"diabetes-agent-backend/model"
+	"diabetes-agent-backend/service/embedding"
+	knowledgebase "diabetes-agent-backend/service/knowledge-base"
+	"diabetes-agent-backend/service/knowledge-base/etl/processor"
+	"diabetes-agent-backend/service/knowledge-base/labreport"
+	"diabetes-agent-backend/service/knowledge-base/retrieval"
+	"diabetes-agent-backend/service/knowledge-base/vectorstore"
+	"diabetes-agent-backend/service/mq"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"golang.org/x/time/rate"
+)
+
+const (
+	testDim   = 32
+	testEmail = "patient@example.com"
+)
+
+const testMarkdown = `# 饮食指南
+
+## 主食
+
+每餐主食控制在一拳大小，优先选择全谷物和杂豆，减少精米白面。
+
+## 蔬菜
+
+每天摄入五百克蔬菜，深色蔬菜占一半以上，先吃蔬菜再吃主食有助于平稳餐后血糖。
+
+## 运动
+
+餐后一小时散步三十分钟，避免空腹剧烈运动。
+`
+
+// memoryObjects 内存中的对象存储
+type memoryObjects struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	deleted []string
+}
+
+func (s *memoryObjects) Download(ctx context.Context, etlMessage *knowledgebase.ETLMessage) (*os.File, int64, error) {
+	s.mu.Lock()
+	data, ok := s.objects[etlMessage.ObjectName]
+	s.mu.Unlock()
+	if !ok {
+		return nil, 0, fmt.Errorf("object not found: %s", etlMessage.ObjectName)
+	}
+
+	file, err := os.CreateTemp("", "knowledge-test-*")
+	if err != nil {
+		return nil, 0, err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, 0, err
+	}
+	return file, int64(len(data)), nil
+}
+
+func (s *memoryObjects) Delete(ctx context.Context, objectName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deleted = append(s.deleted, objectName)
+	return nil
+}
+
+// stubExtractor 返回固定的化验结果
+type stubExtractor struct {
+	results []labreport.ExtractedResult
+	err     error
+}
+
+func (e *stubExtractor) Extract(ctx context.Context, text string) ([]labreport.ExtractedResult, error) {
+	return e.results, e.err
+}
+
+type testEnv struct {
+	consumer   *Consumer
+	vectors    *vectorstore.MemoryStore
+	knowledge  *knowledgebase.MemoryProcessingStore
+	objects    *memoryObjects
+	embedder   *embedding.Service
+	extractor  *stubExtractor
+	labResults *labreport.MemoryResultStore
+}
+
+// newTestEnv 创建使用内存存储和 HashClient 的消费者，只注册 Markdown 处理器
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	env := &testEnv{
+		vectors:   vectorstore.NewMemoryStore(testDim),
+		knowledge: knowledgebase.NewMemoryProcessingStore(),
+		objects:   &memoryObjects{objects: make(map[string][]byte)},
+		embedder: embedding.NewService(embedding.NewHashClient(testDim), embedding.NoopCache{},
+			"hash", testDim, rate.NewLimiter(rate.Inf, 0), 10, 1),
+		extractor:  &stubExtractor{},
+		labResults: labreport.NewMemoryResultStore(),
+	}
+
+	textSplitter, err := processor.NewTextSplitter(model.DefaultChunking(model.FileTypeMarkdown))
+	if err != nil {
+		t.Fatalf("failed to create text splitter: %v", err)
+	}
+	markdownProcessor := &processor.MarkdownETLProcessor{
+		BaseETLProcessor: processor.BaseETLProcessor{
+			TextSplitter: textSplitter,
+			Embedder:     env.embedder,
+			VectorStore:  env.vectors,
+			LabExtractor: env.extractor,
+			LabResults:   env.labResults,
+			Knowledge:    env.knowledge,
+		},
+	}
+
+	env.consumer = &Consumer{
+		Processors: []processor.ETLProcessor{markdownProcessor},
+		Knowledge:  env.knowledge,
+		Objects:    env.objects,
+	}
+	return env
+}
+
+func (env *testEnv) retriever() *retrieval.Retriever {
+	return &retrieval.Retriever{Embedder: env.embedder, VectorStore: env.vectors, Versions: env.knowledge}
+}
+
+// addFile 上传一个 Markdown 知识文件的版本，返回对应的 ETL 消息
+func (env *testEnv) addFile(documentID string, knowledgeID uint, version int, content string) knowledgebase.ETLMessage {
+	objectName := fmt.Sprintf("%s/%s/v%d/guide.md", testEmail, documentID, version)
+	env.objects.objects[objectName] = []byte(content)
+	env.knowledge.Add(model.KnowledgeMetadata{
+		ID:         knowledgeID,
+		UserEmail:  testEmail,
+		DocumentID: documentID,
+		Version:    version,
+		FileName:   "guide.md",
+		FileType:   model.FileTypeMarkdown,
+		ObjectName: objectName,
+		Status:     model.StatusUploaded,
+	})
+
+	return knowledgebase.ETLMessage{
+		DocumentID:  documentID,
+		KnowledgeID: knowledgeID,
+		UserEmail:   testEmail,
+		FileName:    "guide.md",
+		FileType:    model.FileTypeMarkdown,
+		ObjectName:  objectName,
+	}
+}
+
+func newDelivery(t *testing.T, tag string, payload any) *mq.Delivery {
+	t.Helper()
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("failed to marshal payload: %v", err)
+	}
+	return &mq.Delivery{
+		Topic: knowledgebase.TopicKnowledgeBase,
+		Tag:   tag,
+		MsgID: uuid.New().String(),
+		Body:  body,
+	}
+}
+
+func TestETLThenSearchReturnsCitedChunks(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	documentID := uuid.New().String()
+	message := env.addFile(documentID, 1, 1, testMarkdown)
+	if err := env.consumer.HandleETLMessage(ctx, newDelivery(t, knowledgebase.TagETL, message)); err != nil {
+		t.Fatalf("HandleETLMessage() error = %v", err)
+	}
+
+	record, _ := env.knowledge.Get(1)
+	if record.Status != model.StatusProcessed || record.Progress != 100 {
+		t.Fatalf("status = %s, progress = %d, want PROCESSED and 100", record.Status, record.Progress)
+	}
+
+	chunks := env.vectors.Chunks()
+	if len(chunks) == 0 || record.ChunkCount != len(chunks) {
+		t.Fatalf("stored %d chunks, recorded chunk count %d", len(chunks), record.ChunkCount)
+	}
+
+	// HashClient 的向量只对相同文本相似，以切片原文检索时该切片排在第一位
+	var target vectorstore.Chunk
+	for _, chunk := range chunks {
+		if strings.Contains(chunk.Text, "深色蔬菜") {
+			target = chunk
+		}
+	}
+	if target.Text == "" {
+		t.Fatalf("no chunk contains the vegetable section: %+v", chunks)
+	}
+
+	retriever := env.retriever()
+	results, err := retriever.Search(ctx, testEmail, target.Text, 3)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(results) == 0 || results[0].Text != target.Text {
+		t.Fatalf("top result = %+v, want chunk %q", results, target.Text)
+	}
+
+	citation := results[0].Citation()
+	if !strings.HasPrefix(citation, "guide.md") || !strings.Contains(citation, "蔬菜") {
+		t.Errorf("Citation() = %q, want file name and heading path", citation)
+	}
+	if formatted := retrieval.FormatResults(results[:1]); !strings.HasPrefix(formatted, "[1] "+citation+"\n") {
+		t.Errorf("FormatResults() = %q, want numbered citation", formatted)
+	}
+
+	// 其他用户检索不到该文件
+	others, err := retriever.Search(ctx, "other@example.com", target.Text, 3)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(others) != 0 {
+		t.Errorf("other user got %d results, want 0", len(others))
+	}
+}
+
+func TestHandleDeleteMessageDeletesOnlyThatDocument(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	deletedID := uuid.New().String()
+	keptID := uuid.New().String()
+	deleted := env.addFile(deletedID, 1, 1, testMarkdown)
+	kept := env.addFile(keptID, 2, 1, testMarkdown)
+	for _, message := range []knowledgebase.ETLMessage{deleted, kept} {
+		if err := env.consumer.HandleETLMessage(ctx, newDelivery(t, knowledgebase.TagETL, message)); err != nil {
+			t.Fatalf("HandleETLMessage() error = %v", err)
+		}
+	}
+
+	err := env.consumer.HandleDeleteMessage(ctx, newDelivery(t, knowledgebase.TagDelete, knowledgebase.DeleteMessage{
+		DocumentID:  deletedID,
+		FileType:    model.FileTypeMarkdown,
+		ObjectNames: []string{deleted.ObjectName},
+	}))
+	if err != nil {
+		t.Fatalf("HandleDeleteMessage() error = %v", err)
+	}
+
+	chunks := env.vectors.Chunks()
+	if len(chunks) == 0 {
+		t.Fatal("chunks of the other document were deleted")
+	}
+	for _, chunk := range chunks {
+		if chunk.DocumentID != keptID {
+			t.Errorf("chunk %d of document %s was not deleted", chunk.ChunkIndex, chunk.DocumentID)
+		}
+	}
+	if len(env.objects.deleted) != 1 || env.objects.deleted[0] != deleted.ObjectName {
+		t.Errorf("deleted objects = %v, want [%s]", env.objects.deleted, deleted.ObjectName)
+	}
+}
+
+func TestHandleDeleteMessageRejectsMissingDocumentID(t *testing.T) {
+	env := newTestEnv(t)
+
+	// 未回填文档 ID 的旧文件不能按空文档 ID 删除，否则会匹配到其他旧文件的切片
+	err := env.consumer.HandleDeleteMessage(context.Background(), newDelivery(t, knowledgebase.TagDelete, knowledgebase.DeleteMessage{
+		FileType: model.FileTypeMarkdown,
+	}))
+	if err == nil {
+		t.Fatal("HandleDeleteMessage() error = nil, want invalid document id")
+	}
+}
+
+func TestETLStatusIsKeyedByVersion(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	documentID := uuid.New().String()
+	first := env.addFile(documentID, 1, 1, testMarkdown)
+	if err := env.consumer.HandleETLMessage(ctx, newDelivery(t, knowledgebase.TagETL, first)); err != nil {
+		t.Fatalf("HandleETLMessage() error = %v", err)
+	}
+
+	// 新版本的文件在 OSS 上不存在，处理失败只记录在新版本上
+	second := env.addFile(documentID, 2, 2, testMarkdown)
+	delete(env.objects.objects, second.ObjectName)
+	delivery := newDelivery(t, knowledgebase.TagETL, second)
+	if err := env.consumer.HandleETLMessage(ctx, delivery); err == nil {
+		t.Fatal("HandleETLMessage() error = nil, want download error")
+	}
+
+	record, _ := env.knowledge.Get(2)
+	if record.Status != model.StatusUploaded || record.Stage != model.StageDownloading || record.ErrorMessage == "" {
+		t.Errorf("failed version = %+v, want UPLOADED with stage DOWNLOADING and an error message", record)
+	}
+
+	if err := env.consumer.HandleETLDeadLetter(ctx, delivery); err != nil {
+		t.Fatalf("HandleETLDeadLetter() error = %v", err)
+	}
+	record, _ = env.knowledge.Get(2)
+	if record.Status != model.StatusProcessedFailed {
+		t.Errorf("dead-lettered version status = %s, want %s", record.Status, model.StatusProcessedFailed)
+	}
+
+	previous, _ := env.knowledge.Get(1)
+	if previous.Status != model.StatusProcessed || previous.Superseded || previous.ErrorMessage != "" {
+		t.Errorf("previous version = %+v, want PROCESSED and current", previous)
+	}
+	for _, chunk := range env.vectors.Chunks() {
+		if chunk.KnowledgeID != 1 {
+			t.Errorf("chunk %d belongs to version %d, want 1", chunk.ChunkIndex, chunk.KnowledgeID)
+		}
+	}
+
+	// 重新上传成功后新版本替换旧版本的切片
+	env.objects.objects[second.ObjectName] = []byte(testMarkdown + "\n## 复查\n\n每三个月复查一次糖化血红蛋白。\n")
+	if err := env.consumer.HandleETLMessage(ctx, newDelivery(t, knowledgebase.TagETL, second)); err != nil {
+		t.Fatalf("HandleETLMessage() error = %v", err)
+	}
+	previous, _ = env.knowledge.Get(1)
+	if !previous.Superseded {
+		t.Error("previous version is not superseded")
+	}
+	for _, chunk := range env.vectors.Chunks() {
+		if chunk.KnowledgeID != 2 {
+			t.Errorf("chunk %d of superseded version %d was kept", chunk.ChunkIndex, chunk.KnowledgeID)
+		}
+	}
+}
+
+func TestFailedVersionChunksAreNotSearchable(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	documentID := uuid.New().String()
+	first := env.addFile(documentID, 1, 1, testMarkdown)
+	if err := env.consumer.HandleETLMessage(ctx, newDelivery(t, knowledgebase.TagETL, first)); err != nil {
+		t.Fatalf("HandleETLMessage() error = %v", err)
+	}
+
+	// 模拟新版本写入部分切片后中断
+	second := env.addFile(documentID, 2, 2, testMarkdown)
+	var partial []vectorstore.Chunk
+	for _, chunk := range env.vectors.Chunks() {
+		chunk.KnowledgeID = 2
+		partial = append(partial, chunk)
+	}
+	if err := env.vectors.Upsert(ctx, partial); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+
+	results, err := env.retriever().Search(ctx, testEmail, partial[0].Text, 10)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(results) == 0 {
+		t.Fatal("Search() returned no results, want chunks of version 1")
+	}
+	for _, result := range results {
+		if result.KnowledgeID != 1 {
+			t.Errorf("result %d belongs to version %d, want only the active version 1", result.ChunkIndex, result.KnowledgeID)
+		}
+	}
+
+	delivery := newDelivery(t, knowledgebase.TagETL, second)
+	if err := env.consumer.HandleETLExhausted(ctx, delivery, errors.New("embedding timeout")); err != nil {
+		t.Fatalf("HandleETLExhausted() error = %v", err)
+	}
+	if record, _ := env.knowledge.Get(2); record.Status != model.StatusProcessedFailed {
+		t.Errorf("status = %s, want %s", record.Status, model.StatusProcessedFailed)
+	}
+
+	chunks := env.vectors.Chunks()
+	if len(chunks) != len(partial) {
+		t.Errorf("stored %d chunks, want the %d chunks of version 1", len(chunks), len(partial))
+	}
+	for _, chunk := range chunks {
+		if chunk.KnowledgeID != 1 {
+			t.Errorf("chunk %d of failed version %d was kept", chunk.ChunkIndex, chunk.KnowledgeID)
+		}
+	}
+
+	// 死信到达时当前生效版本的切片不会被删除
+	if err := env.consumer.HandleETLDeadLetter(ctx, newDelivery(t, knowledgebase.TagETL, first)); err != nil {
+		t.Fatalf("HandleETLDeadLetter() error = %v", err)
+	}
+	if got := len(env.vectors.Chunks()); got != len(partial) {
+		t.Errorf("stored %d chunks after dead letter of the active version, want %d", got, len(partial))
+	}
+}
+
+func TestLabExtractionFailureKeepsPreviousResults(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	labMarkdown := testMarkdown + "\n## 化验\n\n糖化血红蛋白 6.8%，参考范围 4.0-6.0。\n"
+	documentID := uuid.New().String()
+	env.extractor.results = []labreport.ExtractedResult{
+		{Name: "糖化血红蛋白", Value: "6.8", Unit: "%", ReferenceRange: "4.0-6.0", SampledAt: "2026-09-01"},
+	}
+	first := env.addFile(documentID, 1, 1, labMarkdown)
+	if err := env.consumer.HandleETLMessage(ctx, newDelivery(t, knowledgebase.TagETL, first)); err != nil {
+		t.Fatalf("HandleETLMessage() error = %v", err)
+	}
+	if results := env.labResults.Results(documentID); len(results) != 1 || results[0].KnowledgeID != 1 {
+		t.Fatalf("lab results = %+v, want one result of version 1", results)
+	}
+
+	// 新版本提取失败时不影响处理结果，也不清空之前版本的化验结果
+	env.extractor.results, env.extractor.err = nil, errors.New("status code: 500")
+	second := env.addFile(documentID, 2, 2, labMarkdown)
+	if err := env.consumer.HandleETLMessage(ctx, newDelivery(t, knowledgebase.TagETL, second)); err != nil {
+		t.Fatalf("HandleETLMessage() error = %v", err)
+	}
+	if record, _ := env.knowledge.Get(2); record.Status != model.StatusProcessed {
+		t.Errorf("status = %s, want %s", record.Status, model.StatusProcessed)
+	}
+	if results := env.labResults.Results(documentID); len(results) != 1 || results[0].KnowledgeID != 1 {
+		t.Errorf("lab results = %+v, want the results of version 1 to be kept", results)
+	}
+}
+
+// startBroker 启动注册了 ETL 处理器的内存消息中间件，使用内存中的消息处理记录
+func (env *testEnv) startBroker(t *testing.T) *mq.MemoryBroker {
+	t.Helper()
+
+	mq.SetLedger(mq.NewMemoryLedger())
+	t.Cleanup(func() { mq.SetLedger(mq.DBLedger{}) })
+
+	registry := mq.NewRegistry()
+	env.consumer.Register(registry.Register)
+
+	broker := mq.NewMemoryBroker()
+	if err := broker.Start(registry); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(broker.Shutdown)
+	return broker
+}
+
+// assertSingleChunkSet 检查版本的切片只写入了一份
+func (env *testEnv) assertSingleChunkSet(t *testing.T, knowledgeID uint) {
+	t.Helper()
+
+	record, _ := env.knowledge.Get(knowledgeID)
+	if record.Status != model.StatusProcessed {
+		t.Fatalf("status = %s, want %s", record.Status, model.StatusProcessed)
+	}
+
+	chunks := env.vectors.Chunks()
+	seen := make(map[int64]bool)
+	for _, chunk := range chunks {
+		if seen[chunk.ChunkIndex] {
+			t.Errorf("chunk %d was stored more than once", chunk.ChunkIndex)
+		}
+		seen[chunk.ChunkIndex] = true
+	}
+	if len(chunks) == 0 || len(chunks) != record.ChunkCount {
+		t.Errorf("stored %d chunks, want chunk count %d", len(chunks), record.ChunkCount)
+	}
+}
+
+func TestReplayedETLMessageIsProcessedOnce(t *testing.T) {
+	const replays = 5
+
+	env := newTestEnv(t)
+	broker := env.startBroker(t)
+	ctx := context.Background()
+
+	message := env.addFile(uuid.New().String(), 1, 1, testMarkdown)
+	key := fmt.Sprintf("%s:%d:%s", knowledgebase.TagETL, message.KnowledgeID, uuid.New().String())
+	for range replays {
+		err := broker.Send(ctx, &mq.Message{
+			Topic:   knowledgebase.TopicKnowledgeBase,
+			Tag:     knowledgebase.TagETL,
+			Payload: message,
+			Key:     key,
+		})
+		if err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+	broker.Wait()
+
+	env.assertSingleChunkSet(t, message.KnowledgeID)
+}
+
+func TestReprocessedVersionKeepsOneChunkSet(t *testing.T) {
+	env := newTestEnv(t)
+	broker := env.startBroker(t)
+	ctx := context.Background()
+
+	// 手动重新处理时使用新的幂等键，不会被去重，同一版本处理多次也只保留一份切片
+	message := env.addFile(uuid.New().String(), 1, 1, testMarkdown)
+	for range 3 {
+		err := broker.Send(ctx, &mq.Message{
+			Topic:   knowledgebase.TopicKnowledgeBase,
+			Tag:     knowledgebase.TagETL,
+			Payload: message,
+			Key:     fmt.Sprintf("%s:%d:%s", knowledgebase.TagETL, message.KnowledgeID, uuid.New().String()),
+		})
+		if err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+		broker.Wait()
+	}
+
+	env.assertSingleChunkSet(t, message.KnowledgeID)
+}
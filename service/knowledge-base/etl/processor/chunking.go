@@ -0,0 +1,177 @@
+package processor
+
+import (
+	"diabetes-agent-backend/model"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/tmc/langchaingo/textsplitter"
+)
+
+// token 切分使用的编码，与 tiktoken 的 cl100k_base 一致
+// 首次使用时 tiktoken 会下载编码文件，可通过 TIKTOKEN_CACHE_DIR 指定本地缓存目录
+const tokenEncoding = "cl100k_base"
+
+// 中文文档的分隔符，按段落、句子、短语的优先级切分
+var chineseSeparators = []string{"\n\n", "\n", "。", "！", "？", "；", "，", " ", ""}
+
+// 句末标点，句子窗口切分时在其后断句
+var sentenceTerminators = map[rune]bool{
+	'。': true, '！': true, '？': true, '；': true,
+	'!': true, '?': true, ';': true,
+}
+
+// 紧跟在句末标点后的引号和括号归入同一句
+var sentenceClosers = map[rune]bool{
+	'”': true, '’': true, '」': true, '』': true, '）': true,
+	'"': true, '\'': true, ')': true,
+}
+
+// NewTextSplitter 按切分配置创建切分器
+func NewTextSplitter(chunking model.Chunking) (textsplitter.TextSplitter, error) {
+	switch chunking.Strategy {
+	case model.ChunkingRecursive:
+		return textsplitter.NewRecursiveCharacter(
+			textsplitter.WithSeparators(chineseSeparators),
+			textsplitter.WithChunkSize(chunking.ChunkSize),
+			textsplitter.WithChunkOverlap(chunking.ChunkOverlap),
+		), nil
+	case model.ChunkingMarkdown:
+		return newMarkdownTextSplitter(chunking.ChunkSize, chunking.ChunkOverlap), nil
+	case model.ChunkingSentence:
+		return SentenceWindowSplitter{
+			ChunkSize:    chunking.ChunkSize,
+			ChunkOverlap: chunking.ChunkOverlap,
+		}, nil
+	case model.ChunkingToken:
+		return textsplitter.NewTokenSplitter(
+			textsplitter.WithEncodingName(tokenEncoding),
+			textsplitter.WithChunkSize(chunking.ChunkSize),
+			textsplitter.WithChunkOverlap(chunking.ChunkOverlap),
+		), nil
+	}
+	return nil, fmt.Errorf("unsupported chunking strategy: %s", chunking.Strategy)
+}
+
+// textSplitter 返回知识文件版本记录的切分器，未记录切分配置的旧消息使用处理器默认切分器
+func (p *BaseETLProcessor) textSplitter(metadata *Metadata) (textsplitter.TextSplitter, error) {
+	if metadata.Chunking.Strategy == "" {
+		return p.TextSplitter, nil
+	}
+	return NewTextSplitter(metadata.Chunking)
+}
+
+// SentenceWindowSplitter 以完整句子组成切片，相邻切片重叠末尾的若干句子
+// 单个句子超过切片大小时按字符截断
+type SentenceWindowSplitter struct {
+	ChunkSize    int
+	ChunkOverlap int
+}
+
+var _ textsplitter.TextSplitter = SentenceWindowSplitter{}
+
+func (s SentenceWindowSplitter) SplitText(text string) ([]string, error) {
+	var (
+		chunks []string
+		window []string
+		length int
+
+		// 窗口中上一个切片之后新加入的句子数
+		fresh int
+	)
+
+	flush := func() {
+		chunk := strings.TrimSpace(strings.Join(window, ""))
+		if chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+	}
+
+	for _, sentence := range s.splitSentences(text) {
+		n := utf8.RuneCountInString(sentence)
+		if fresh > 0 && length+n > s.ChunkSize {
+			flush()
+			window, length = overlapTail(window, s.ChunkOverlap)
+			fresh = 0
+		}
+		// 重叠部分放不下新句子时丢弃重叠
+		if length+n > s.ChunkSize {
+			window, length = nil, 0
+		}
+
+		window = append(window, sentence)
+		length += n
+		fresh++
+	}
+	if fresh > 0 {
+		flush()
+	}
+
+	return chunks, nil
+}
+
+// splitSentences 按句末标点和换行断句，保留原有标点和空白
+func (s SentenceWindowSplitter) splitSentences(text string) []string {
+	var (
+		sentences []string
+		current   []rune
+	)
+	runes := []rune(text)
+
+	cut := func() {
+		if len(current) == 0 {
+			return
+		}
+		sentences = append(sentences, s.truncate(current)...)
+		current = nil
+	}
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		current = append(current, r)
+
+		switch {
+		case r == '\n':
+			cut()
+		case sentenceTerminators[r] || (r == '.' && (i+1 == len(runes) || unicode.IsSpace(runes[i+1]))):
+			for i+1 < len(runes) && sentenceClosers[runes[i+1]] {
+				i++
+				current = append(current, runes[i])
+			}
+			cut()
+		}
+	}
+	cut()
+
+	return sentences
+}
+
+// truncate 将超过切片大小的句子按字符截断
+func (s SentenceWindowSplitter) truncate(sentence []rune) []string {
+	var parts []string
+	for len(sentence) > s.ChunkSize {
+		parts = append(parts, string(sentence[:s.ChunkSize]))
+		sentence = sentence[s.ChunkSize:]
+	}
+	return append(parts, string(sentence))
+}
+
+// overlapTail 从窗口末尾取不超过 overlap 个字符的完整句子
+func overlapTail(window []string, overlap int) ([]string, int) {
+	length := 0
+	start := len(window)
+	for start > 0 {
+		n := utf8.RuneCountInString(window[start-1])
+		if length+n > overlap {
+			break
+		}
+		length += n
+		start--
+	}
+
+	tail := make([]string, len(window)-start)
+	copy(tail, window[start:])
+	return tail, length
+}
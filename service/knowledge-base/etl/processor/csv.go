@@ -0,0 +1,63 @@
+package processor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"diabetes-agent-backend/model"
+	"encoding/csv"
+	"fmt"
+	"io"
+)
+
+// CSVETLProcessor CSV文件ETL处理器，按表格切分，每个切片保留表头
+type CSVETLProcessor struct {
+	BaseETLProcessor
+}
+
+var _ ETLProcessor = &CSVETLProcessor{}
+
+func NewCSVETLProcessor() (*CSVETLProcessor, error) {
+	baseETLProcessor, err := NewBaseETLProcessor(newMarkdownTextSplitter(model.DefaultChunkSize, model.DefaultChunkOverlap))
+	if err != nil {
+		return nil, fmt.Errorf("error creating BaseETLProcessor: %v", err)
+	}
+
+	return &CSVETLProcessor{
+		BaseETLProcessor: *baseETLProcessor,
+	}, nil
+}
+
+func (p *CSVETLProcessor) CanProcess(fileType model.FileType) bool {
+	return fileType == model.FileTypeCSV
+}
+
+func (p *CSVETLProcessor) ExecuteETLPipeline(ctx context.Context, object *Object, metadata *Metadata) error {
+	p.reportProgress(metadata, model.StageSplitting, progressSplitting, 0)
+
+	content, err := csvToMarkdown(object.NewReader())
+	if err != nil {
+		return fmt.Errorf("error parsing csv: %v", err)
+	}
+
+	return p.storeMarkdown(ctx, content, metadata)
+}
+
+// csvToMarkdown 将CSV转换为Markdown表格，兼容带BOM和列数不一致的导出文件
+func csvToMarkdown(r io.Reader) (string, error) {
+	br := bufio.NewReader(r)
+	if bom, err := br.Peek(3); err == nil && bytes.Equal(bom, []byte("\xef\xbb\xbf")) {
+		br.Discard(3)
+	}
+
+	reader := csv.NewReader(br)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return "", err
+	}
+
+	return renderMarkdownTable(rows), nil
+}
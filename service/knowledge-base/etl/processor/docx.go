@@ -0,0 +1,219 @@
+package processor
+
+import (
+	"archive/zip"
+	"context"
+	"diabetes-agent-backend/model"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// DocxETLProcessor Word文件ETL处理器，标题、列表和表格转换为Markdown后切分
+type DocxETLProcessor struct {
+	BaseETLProcessor
+}
+
+var _ ETLProcessor = &DocxETLProcessor{}
+
+func NewDocxETLProcessor() (*DocxETLProcessor, error) {
+	baseETLProcessor, err := NewBaseETLProcessor(newMarkdownTextSplitter(model.DefaultChunkSize, model.DefaultChunkOverlap))
+	if err != nil {
+		return nil, fmt.Errorf("error creating BaseETLProcessor: %v", err)
+	}
+
+	return &DocxETLProcessor{
+		BaseETLProcessor: *baseETLProcessor,
+	}, nil
+}
+
+func (p *DocxETLProcessor) CanProcess(fileType model.FileType) bool {
+	return fileType == model.FileTypeDocx
+}
+
+func (p *DocxETLProcessor) ExecuteETLPipeline(ctx context.Context, object *Object, metadata *Metadata) error {
+	p.reportProgress(metadata, model.StageSplitting, progressSplitting, 0)
+
+	content, err := docxToMarkdown(object)
+	if err != nil {
+		return fmt.Errorf("error parsing docx: %v", err)
+	}
+
+	return p.storeMarkdown(ctx, content, metadata)
+}
+
+type docxStyles struct {
+	Styles []struct {
+		ID   string `xml:"styleId,attr"`
+		Name struct {
+			Val string `xml:"val,attr"`
+		} `xml:"name"`
+		OutlineLevel *struct {
+			Val int `xml:"val,attr"`
+		} `xml:"pPr>outlineLvl"`
+	} `xml:"style"`
+}
+
+// docxHeadingLevels 返回标题样式 ID 到标题级别的映射
+// 中文版 Word 的标题样式 ID 为 "1"、"2" 等，因此按样式名称和大纲级别识别
+func docxHeadingLevels(styles *docxStyles) map[string]int {
+	levels := make(map[string]int)
+	for _, style := range styles.Styles {
+		name := strings.ToLower(style.Name.Val)
+		switch {
+		case name == "title":
+			levels[style.ID] = 1
+		case strings.HasPrefix(name, "heading "):
+			if level, err := strconv.Atoi(strings.TrimPrefix(name, "heading ")); err == nil {
+				levels[style.ID] = level
+			}
+		case style.OutlineLevel != nil && style.OutlineLevel.Val < 9:
+			levels[style.ID] = style.OutlineLevel.Val + 1
+		}
+	}
+	return levels
+}
+
+// docxToMarkdown 解析 word/document.xml，将段落、标题、列表和表格转换为 Markdown
+func docxToMarkdown(object *Object) (string, error) {
+	reader, err := zip.NewReader(object, object.Size)
+	if err != nil {
+		return "", fmt.Errorf("invalid docx archive: %v", err)
+	}
+
+	files := make(map[string]*zip.File, len(reader.File))
+	for _, f := range reader.File {
+		files[f.Name] = f
+	}
+
+	var styles docxStyles
+	if _, ok := files["word/styles.xml"]; ok {
+		if err := decodeZipXML(files, "word/styles.xml", &styles); err != nil {
+			return "", err
+		}
+	}
+	headingLevels := docxHeadingLevels(&styles)
+
+	f, ok := files["word/document.xml"]
+	if !ok {
+		return "", fmt.Errorf("missing word/document.xml")
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return "", fmt.Errorf("error opening word/document.xml: %v", err)
+	}
+	defer rc.Close()
+
+	var (
+		sb         strings.Builder
+		para       strings.Builder
+		paraStyle  string
+		isListItem bool
+		inText     bool
+
+		// 嵌套表格的内容合并到最外层表格的单元格中
+		tableDepth int
+		rows       [][]string
+		row        []string
+		cell       []string
+	)
+
+	decoder := xml.NewDecoder(io.LimitReader(rc, maxZipEntrySize))
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("error decoding word/document.xml: %v", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "tbl":
+				tableDepth++
+				if tableDepth == 1 {
+					rows = nil
+				}
+			case "tr":
+				if tableDepth == 1 {
+					row = nil
+				}
+			case "tc":
+				if tableDepth == 1 {
+					cell = nil
+				}
+			case "p":
+				para.Reset()
+				paraStyle = ""
+				isListItem = false
+			case "pStyle":
+				paraStyle = xmlAttr(t, "val")
+			case "numPr":
+				isListItem = true
+			case "t":
+				inText = true
+			case "tab":
+				para.WriteString(" ")
+			case "br", "cr":
+				para.WriteString("\n")
+			}
+		case xml.CharData:
+			if inText {
+				para.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				text := strings.TrimSpace(para.String())
+				if text == "" {
+					continue
+				}
+				if tableDepth > 0 {
+					cell = append(cell, text)
+					continue
+				}
+
+				if level, ok := headingLevels[paraStyle]; ok {
+					fmt.Fprintf(&sb, "%s %s\n\n", strings.Repeat("#", min(level, 6)), strings.ReplaceAll(text, "\n", " "))
+				} else if isListItem {
+					fmt.Fprintf(&sb, "- %s\n\n", text)
+				} else {
+					fmt.Fprintf(&sb, "%s\n\n", text)
+				}
+			case "tc":
+				if tableDepth == 1 {
+					row = append(row, strings.Join(cell, " "))
+				}
+			case "tr":
+				if tableDepth == 1 {
+					rows = append(rows, row)
+				}
+			case "tbl":
+				tableDepth--
+				if tableDepth == 0 {
+					if table := renderMarkdownTable(rows); table != "" {
+						fmt.Fprintf(&sb, "\n%s\n", table)
+					}
+				}
+			}
+		}
+	}
+
+	return sb.String(), nil
+}
+
+func xmlAttr(element xml.StartElement, name string) string {
+	for _, attr := range element.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,185 @@
+package processor
+
+import (
+	"context"
+	"diabetes-agent-backend/model"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// HTMLETLProcessor HTML文件ETL处理器，保留标题层级和表格结构
+type HTMLETLProcessor struct {
+	BaseETLProcessor
+}
+
+var _ ETLProcessor = &HTMLETLProcessor{}
+
+func NewHTMLETLProcessor() (*HTMLETLProcessor, error) {
+	baseETLProcessor, err := NewBaseETLProcessor(newMarkdownTextSplitter(model.DefaultChunkSize, model.DefaultChunkOverlap))
+	if err != nil {
+		return nil, fmt.Errorf("error creating BaseETLProcessor: %v", err)
+	}
+
+	return &HTMLETLProcessor{
+		BaseETLProcessor: *baseETLProcessor,
+	}, nil
+}
+
+func (p *HTMLETLProcessor) CanProcess(fileType model.FileType) bool {
+	return fileType == model.FileTypeHTML
+}
+
+func (p *HTMLETLProcessor) ExecuteETLPipeline(ctx context.Context, object *Object, metadata *Metadata) error {
+	p.reportProgress(metadata, model.StageSplitting, progressSplitting, 0)
+
+	content, err := htmlToMarkdown(object.NewReader())
+	if err != nil {
+		return fmt.Errorf("error parsing html: %v", err)
+	}
+
+	return p.storeMarkdown(ctx, content, metadata)
+}
+
+var (
+	whitespaceRegex   = regexp.MustCompile(`\s+`)
+	blankLinesRegex   = regexp.MustCompile(`\n{3,}`)
+	htmlHeadingLevels = map[atom.Atom]int{
+		atom.H1: 1, atom.H2: 2, atom.H3: 3, atom.H4: 4, atom.H5: 5, atom.H6: 6,
+	}
+)
+
+// 不包含正文内容的元素
+var htmlSkippedElements = map[atom.Atom]bool{
+	atom.Head:     true,
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Template: true,
+	atom.Svg:      true,
+	atom.Iframe:   true,
+}
+
+// 渲染时前后需要换行的块级元素
+var htmlBlockElements = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Section: true, atom.Article: true,
+	atom.Header: true, atom.Footer: true, atom.Main: true, atom.Aside: true,
+	atom.Ul: true, atom.Ol: true, atom.Blockquote: true, atom.Pre: true,
+	atom.Dl: true, atom.Dt: true, atom.Dd: true, atom.Figure: true, atom.Hr: true,
+}
+
+// htmlToMarkdown 将HTML转换为Markdown，只保留标题、段落、列表和表格结构
+func htmlToMarkdown(r io.Reader) (string, error) {
+	root, err := html.Parse(r)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	renderHTMLNode(&sb, root)
+
+	lines := strings.Split(sb.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	content := blankLinesRegex.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+
+	return strings.TrimSpace(content) + "\n", nil
+}
+
+func renderHTMLNode(sb *strings.Builder, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		sb.WriteString(whitespaceRegex.ReplaceAllString(n.Data, " "))
+		return
+	case html.ElementNode:
+		if htmlSkippedElements[n.DataAtom] {
+			return
+		}
+
+		if level, ok := htmlHeadingLevels[n.DataAtom]; ok {
+			if text := htmlNodeText(n); text != "" {
+				fmt.Fprintf(sb, "\n\n%s %s\n\n", strings.Repeat("#", level), text)
+			}
+			return
+		}
+
+		switch n.DataAtom {
+		case atom.Table:
+			if table := renderMarkdownTable(htmlTableRows(n)); table != "" {
+				fmt.Fprintf(sb, "\n\n%s\n", table)
+			}
+			return
+		case atom.Br:
+			sb.WriteString("\n")
+			return
+		case atom.Li:
+			sb.WriteString("\n- ")
+		}
+
+		if htmlBlockElements[n.DataAtom] {
+			sb.WriteString("\n\n")
+			defer sb.WriteString("\n\n")
+		}
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		renderHTMLNode(sb, c)
+	}
+}
+
+// htmlTableRows 提取表格各行单元格的文本，嵌套表格的内容合并到外层单元格
+func htmlTableRows(table *html.Node) [][]string {
+	var rows [][]string
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			if c.DataAtom != atom.Tr {
+				walk(c)
+				continue
+			}
+
+			var row []string
+			for cell := c.FirstChild; cell != nil; cell = cell.NextSibling {
+				if cell.DataAtom == atom.Td || cell.DataAtom == atom.Th {
+					row = append(row, htmlNodeText(cell))
+				}
+			}
+			rows = append(rows, row)
+		}
+	}
+	walk(table)
+
+	return rows
+}
+
+// htmlNodeText 返回节点下所有文本，空白字符合并为一个空格
+func htmlNodeText(n *html.Node) string {
+	var sb strings.Builder
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+			sb.WriteString(" ")
+			return
+		}
+		if n.Type == html.ElementNode && htmlSkippedElements[n.DataAtom] {
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+
+	return strings.TrimSpace(whitespaceRegex.ReplaceAllString(sb.String(), " "))
+}
@@ -0,0 +1,67 @@
+package processor
+
+import (
+	"context"
+	"diabetes-agent-backend/model"
+	"diabetes-agent-backend/service/knowledge-base/ocr"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ImageETLProcessor 图片文件ETL处理器，OCR 识别为 Markdown 后切分
+type ImageETLProcessor struct {
+	BaseETLProcessor
+	OCR ocr.Engine
+}
+
+var _ ETLProcessor = &ImageETLProcessor{}
+
+func NewImageETLProcessor() (*ImageETLProcessor, error) {
+	baseETLProcessor, err := NewBaseETLProcessor(newMarkdownTextSplitter(model.DefaultChunkSize, model.DefaultChunkOverlap))
+	if err != nil {
+		return nil, fmt.Errorf("error creating BaseETLProcessor: %v", err)
+	}
+
+	ocrEngine, err := ocr.NewEngine()
+	if err != nil {
+		return nil, fmt.Errorf("error creating ocr engine: %v", err)
+	}
+
+	return &ImageETLProcessor{
+		BaseETLProcessor: *baseETLProcessor,
+		OCR:              ocrEngine,
+	}, nil
+}
+
+func (p *ImageETLProcessor) CanProcess(fileType model.FileType) bool {
+	return fileType == model.FileTypeJPG || fileType == model.FileTypeJPEG || fileType == model.FileTypePNG
+}
+
+func (p *ImageETLProcessor) ExecuteETLPipeline(ctx context.Context, object *Object, metadata *Metadata) error {
+	p.reportProgress(metadata, model.StageRecognizing, progressSplitting, 0)
+
+	data, err := object.Bytes()
+	if err != nil {
+		return fmt.Errorf("error reading image: %v", err)
+	}
+
+	// 以文件内容判断图片格式，不依赖文件扩展名
+	mimeType := http.DetectContentType(data)
+	if mimeType != "image/jpeg" && mimeType != "image/png" {
+		return fmt.Errorf("unsupported image content type: %s", mimeType)
+	}
+
+	text, err := p.OCR.Recognize(ctx, ocr.Image{Data: data, MIMEType: mimeType})
+	if err != nil {
+		return fmt.Errorf("error recognizing image: %v", err)
+	}
+
+	quality := model.QualityOCR
+	if textLength(text) < minPageTextLength {
+		quality = model.QualityPoor
+	}
+	p.reportQuality(metadata, quality)
+
+	return p.storeMarkdown(ctx, strings.TrimSpace(text), metadata)
+}
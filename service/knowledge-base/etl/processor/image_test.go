@@ -0,0 +1,60 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"diabetes-agent-backend/model"
+	"diabetes-agent-backend/service/knowledge-base/ocr"
+	"testing"
+)
+
+// fakePNG 返回带 PNG 文件头的图片数据，用于内容类型检测
+func fakePNG(name string) []byte {
+	return []byte("\x89PNG\r\n\x1a\n" + name)
+}
+
+func TestImageQuality(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want model.ExtractionQuality
+	}{
+		{"readable photo", ocrText, model.QualityOCR},
+		{"blurry photo", "血糖 7.2", model.QualityPoor},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := fakePNG(tt.name)
+			engine := ocr.NewFakeEngine()
+			engine.Texts[imageHash(data)] = tt.text
+
+			base, stores := newTestBase(t, model.FileTypePNG)
+			p := &ImageETLProcessor{BaseETLProcessor: base, OCR: engine}
+			metadata := stores.newTestMetadata("photo.png", model.FileTypePNG)
+
+			object := &Object{ReaderAt: bytes.NewReader(data), Size: int64(len(data))}
+			if err := p.ExecuteETLPipeline(context.Background(), object, metadata); err != nil {
+				t.Fatalf("ExecuteETLPipeline() error = %v", err)
+			}
+
+			if quality := stores.quality(t, metadata); quality != tt.want {
+				t.Errorf("quality = %s, want %s", quality, tt.want)
+			}
+			if chunks := stores.vectors.Chunks(); len(chunks) == 0 {
+				t.Error("recognized text was not stored")
+			}
+		})
+	}
+}
+
+func TestImageRejectsUnsupportedContent(t *testing.T) {
+	base, stores := newTestBase(t, model.FileTypePNG)
+	p := &ImageETLProcessor{BaseETLProcessor: base, OCR: ocr.NewFakeEngine()}
+	metadata := stores.newTestMetadata("photo.png", model.FileTypePNG)
+
+	data := []byte("%PDF-1.4 renamed to png")
+	object := &Object{ReaderAt: bytes.NewReader(data), Size: int64(len(data))}
+	if err := p.ExecuteETLPipeline(context.Background(), object, metadata); err == nil {
+		t.Fatal("ExecuteETLPipeline() error = nil, want unsupported content type")
+	}
+}
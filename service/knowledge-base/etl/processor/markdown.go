@@ -1,22 +1,21 @@
 package processor
 
 import (
-	"bytes"
 	"context"
 	"diabetes-agent-backend/model"
-	knowledgebase "diabetes-agent-backend/service/knowledge-base"
 	"fmt"
 	"log/slog"
 	"regexp"
 	"strings"
 
-	"github.com/milvus-io/milvus/client/v2/column"
-	client "github.com/milvus-io/milvus/client/v2/milvusclient"
 	"github.com/tmc/langchaingo/documentloaders"
 	"github.com/tmc/langchaingo/schema"
 	"github.com/tmc/langchaingo/textsplitter"
 )
 
+// 匹配形如 "## xxx" 的标题行
+var headingLineRegex = regexp.MustCompile(`^#{1,6}\s+(.+)$`)
+
 // MarkdownETLProcessor Markdown文件ETL处理器，兼容Text文件
 type MarkdownETLProcessor struct {
 	BaseETLProcessor
@@ -25,98 +24,108 @@ type MarkdownETLProcessor struct {
 var _ ETLProcessor = &MarkdownETLProcessor{}
 
 func NewMarkdownETLProcessor() (*MarkdownETLProcessor, error) {
-	separators := []string{"\n\n", "\n", "。", "！", "？", "；", "，", " ", ""}
-	textSplitter := textsplitter.NewMarkdownTextSplitter(
+	baseETLProcessor, err := NewBaseETLProcessor(newMarkdownTextSplitter(model.DefaultChunkSize, model.DefaultChunkOverlap))
+	if err != nil {
+		return nil, err
+	}
+
+	return &MarkdownETLProcessor{
+		BaseETLProcessor: *baseETLProcessor,
+	}, nil
+}
+
+// newMarkdownTextSplitter 创建按 Markdown 结构切分的切分器
+// 表格按行合并到切片大小，每个切片都会带上表头，避免数据行与列名分离
+func newMarkdownTextSplitter(chunkSize, chunkOverlap int) textsplitter.TextSplitter {
+	return textsplitter.NewMarkdownTextSplitter(
 		textsplitter.WithChunkSize(chunkSize),
 		textsplitter.WithChunkOverlap(chunkOverlap),
 		textsplitter.WithHeadingHierarchy(true), // 保留父级标题信息
+		textsplitter.WithJoinTableRows(true),
 		textsplitter.WithSecondSplitter(textsplitter.NewRecursiveCharacter(
 			textsplitter.WithChunkSize(chunkSize),
 			textsplitter.WithChunkOverlap(chunkOverlap),
-			textsplitter.WithSeparators(separators),
+			textsplitter.WithSeparators(chineseSeparators),
 		)),
 	)
-
-	baseETLProcessor, err := NewBaseETLProcessor(textSplitter)
-	if err != nil {
-		return nil, err
-	}
-
-	return &MarkdownETLProcessor{
-		BaseETLProcessor: *baseETLProcessor,
-	}, nil
 }
 
 func (p *MarkdownETLProcessor) CanProcess(fileType model.FileType) bool {
 	return fileType == model.FileTypeMarkdown || fileType == model.FileTypeText
 }
 
-func (p *MarkdownETLProcessor) ExecuteETLPipeline(ctx context.Context, object []byte, objectName string) error {
-	reader := bytes.NewReader(object)
-	loader := documentloaders.NewText(reader)
+func (p *MarkdownETLProcessor) ExecuteETLPipeline(ctx context.Context, object *Object, metadata *Metadata) error {
+	p.reportProgress(metadata, model.StageSplitting, progressSplitting, 0)
 
-	docs, err := loader.LoadAndSplit(ctx, p.TextSplitter)
+	data, err := object.Bytes()
 	if err != nil {
-		return fmt.Errorf("error loading and spliting markdown: %v", err)
+		return fmt.Errorf("error reading markdown: %v", err)
 	}
 
-	// 过滤只有孤立标题的chunk
-	docs, err = p.filterStandaloneHeaders(docs)
+	return p.storeMarkdown(ctx, string(data), metadata)
+}
+
+// storeMarkdown 切分 Markdown 文本并写入向量存储，其他格式的文件转换为 Markdown 后复用此流程
+func (p *BaseETLProcessor) storeMarkdown(ctx context.Context, content string, metadata *Metadata) error {
+	textSplitter, err := p.textSplitter(metadata)
 	if err != nil {
-		return fmt.Errorf("error removing standalone headers: %v", err)
+		return err
 	}
 
-	texts := make([]string, 0, len(docs))
-	for _, doc := range docs {
-		texts = append(texts, doc.PageContent)
+	loader := documentloaders.NewText(strings.NewReader(content))
+
+	docs, err := loader.LoadAndSplit(ctx, textSplitter)
+	if err != nil {
+		return fmt.Errorf("error loading and spliting markdown: %v", err)
 	}
 
-	slog.Debug("split markdown successfully",
-		"object_name", objectName,
-		"texts_num", len(texts),
-	)
+	// 过滤只有孤立标题的chunk
+	docs = filterStandaloneHeaders(docs)
 
-	vectors, err := p.Embedder.EmbedDocuments(ctx, texts)
-	if err != nil {
-		return fmt.Errorf("error embedding markdown: %v", err)
+	// 记录切片开头的父级标题
+	for i := range docs {
+		if docs[i].Metadata == nil {
+			docs[i].Metadata = make(map[string]any)
+		}
+		docs[i].Metadata[chunkMetadataHeadingPath] = extractHeadingPath(docs[i].PageContent)
 	}
 
-	slog.Debug("embedded markdown successfully",
-		"object_name", objectName,
-		"vectors_num", len(vectors),
+	slog.Debug("split markdown successfully",
+		"object_name", metadata.ObjectName,
+		"texts_num", len(docs),
 	)
 
-	columns := make([]column.Column, 0)
-	columns = append(columns, column.NewColumnVarChar("text", texts))
-	columns = append(columns, column.NewColumnFloatVector("vector", vectorDim, vectors))
-
-	columns, err = addMetadataColumns(columns, len(texts), &Metadata{
-		objectName: objectName,
-	})
-	if err != nil {
-		return fmt.Errorf("error adding metadata columns: %v", err)
+	if err := p.storeChunks(ctx, docs, metadata); err != nil {
+		return fmt.Errorf("error storing markdown chunks: %v", err)
 	}
 
-	insertOption := client.NewColumnBasedInsertOption(CollectionName).WithColumns(columns...)
-	_, err = p.MilvusClient.Insert(ctx, insertOption)
-	if err != nil {
-		return fmt.Errorf("error inserting markdown chunks: %v", err)
-	}
+	p.extractLabResults(ctx, content, metadata)
 
-	err = knowledgebase.UpdateKnowledgeMetadataStatus(objectName, model.StatusProcessed)
-	if err != nil {
+	if err := p.activateVersion(ctx, metadata); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func (p *MarkdownETLProcessor) filterStandaloneHeaders(docs []schema.Document) ([]schema.Document, error) {
-	var filteredDocs []schema.Document
+// extractHeadingPath 解析切片开头由 WithHeadingHierarchy 拼接的标题行，返回形如 "一级标题 > 二级标题" 的标题路径
+func extractHeadingPath(content string) string {
+	var headings []string
+	for _, line := range strings.Split(strings.TrimSpace(content), "\n") {
+		matches := headingLineRegex.FindStringSubmatch(line)
+		if matches == nil {
+			break
+		}
+		headings = append(headings, strings.TrimSpace(matches[1]))
+	}
+	return strings.Join(headings, " > ")
+}
 
-	// 匹配形如 "# xxx ## xxx" 的chunk
-	headerOnlyRegex := regexp.MustCompile(`^\s*(?:#{1,6}\s+.+\n?)+\s*$`)
+// 匹配形如 "# xxx ## xxx" 的chunk
+var headerOnlyRegex = regexp.MustCompile(`^\s*(?:#{1,6}\s+.+\n?)+\s*$`)
 
+func filterStandaloneHeaders(docs []schema.Document) []schema.Document {
+	var filteredDocs []schema.Document
 	for _, doc := range docs {
 		content := strings.TrimSpace(doc.PageContent)
 		if content == "" {
@@ -129,5 +138,5 @@ func (p *MarkdownETLProcessor) filterStandaloneHeaders(docs []schema.Document) (
 
 		filteredDocs = append(filteredDocs, doc)
 	}
-	return filteredDocs, nil
+	return filteredDocs
 }
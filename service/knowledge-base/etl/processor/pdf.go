@@ -1,40 +1,48 @@
 package processor
 
 import (
-	"bytes"
 	"context"
 	"diabetes-agent-backend/model"
-	knowledgebase "diabetes-agent-backend/service/knowledge-base"
+	"diabetes-agent-backend/service/knowledge-base/ocr"
 	"fmt"
 	"log/slog"
+	"strings"
+	"unicode/utf8"
 
-	"github.com/milvus-io/milvus/client/v2/column"
-	client "github.com/milvus-io/milvus/client/v2/milvusclient"
 	"github.com/tmc/langchaingo/documentloaders"
+	"github.com/tmc/langchaingo/schema"
 	"github.com/tmc/langchaingo/textsplitter"
 )
 
+// 页面文本少于该字符数时视为扫描页，尝试 OCR 识别
+const minPageTextLength = 50
+
 type PDFETLProcessor struct {
 	BaseETLProcessor
+	OCR ocr.Engine
 }
 
 var _ ETLProcessor = &PDFETLProcessor{}
 
 func NewPDFETLProcessor() (*PDFETLProcessor, error) {
-	separators := []string{"\n\n", "\n", "。", "！", "？", "；", "，", " ", ""}
-	textSplitter := textsplitter.NewRecursiveCharacter(
-		textsplitter.WithSeparators(separators),
-		textsplitter.WithChunkSize(chunkSize),
-		textsplitter.WithChunkOverlap(chunkOverlap),
-	)
+	textSplitter, err := NewTextSplitter(model.DefaultChunking(model.FileTypePDF))
+	if err != nil {
+		return nil, err
+	}
 
 	baseETLProcessor, err := NewBaseETLProcessor(textSplitter)
 	if err != nil {
 		return nil, fmt.Errorf("error creating BaseETLProcessor: %v", err)
 	}
 
+	ocrEngine, err := ocr.NewEngine()
+	if err != nil {
+		return nil, fmt.Errorf("error creating ocr engine: %v", err)
+	}
+
 	return &PDFETLProcessor{
 		BaseETLProcessor: *baseETLProcessor,
+		OCR:              ocrEngine,
 	}, nil
 }
 
@@ -42,62 +50,138 @@ func (p *PDFETLProcessor) CanProcess(fileType model.FileType) bool {
 	return fileType == model.FileTypePDF
 }
 
-func (p *PDFETLProcessor) ExecuteETLPipeline(ctx context.Context, data []byte, objectName string) error {
-	reader := bytes.NewReader(data)
-	loader := documentloaders.NewPDF(reader, int64(len(data)))
+func (p *PDFETLProcessor) ExecuteETLPipeline(ctx context.Context, object *Object, metadata *Metadata) error {
+	p.reportProgress(metadata, model.StageSplitting, progressSplitting, 0)
 
-	// 切分文档
-	docs, err := loader.LoadAndSplit(ctx, p.TextSplitter)
+	loader := documentloaders.NewPDF(object, object.Size)
+
+	// 按页加载文档，切片保留所在页码
+	pages, err := loader.Load(ctx)
 	if err != nil {
-		return fmt.Errorf("error loading and spliting pdf: %v", err)
+		return fmt.Errorf("error loading pdf: %v", err)
 	}
 
-	texts := make([]string, 0, len(docs))
-	for _, doc := range docs {
-		texts = append(texts, doc.PageContent)
+	// 扫描页没有文本层，使用 OCR 识别页面图片
+	quality, err := p.recognizeLowTextPages(ctx, object, pages, metadata)
+	if err != nil {
+		return err
 	}
+	p.reportQuality(metadata, quality)
 
-	slog.Debug("split pdf successfully",
-		"object_name", objectName,
-		"texts_num", len(texts),
-	)
+	textSplitter, err := p.textSplitter(metadata)
+	if err != nil {
+		return err
+	}
 
-	// 生成文档切片的向量
-	vectors, err := p.Embedder.EmbedDocuments(ctx, texts)
+	docs, err := textsplitter.SplitDocuments(textSplitter, pages)
 	if err != nil {
-		return fmt.Errorf("error embedding pdf: %v", err)
+		return fmt.Errorf("error spliting pdf: %v", err)
 	}
 
-	slog.Debug("embedded pdf successfully",
-		"object_name", objectName,
-		"vectors_num", len(vectors),
+	slog.Debug("split pdf successfully",
+		"object_name", metadata.ObjectName,
+		"texts_num", len(docs),
+		"extraction_quality", quality,
 	)
 
-	// 组装列数据，包括文档切片、向量和元数据
-	columns := make([]column.Column, 0)
-	columns = append(columns, column.NewColumnVarChar("text", texts))
-	columns = append(columns, column.NewColumnFloatVector("vector", vectorDim, vectors))
+	// 向量化并加载数据到milvus
+	if err := p.storeChunks(ctx, docs, metadata); err != nil {
+		return fmt.Errorf("error storing pdf chunks: %v", err)
+	}
 
-	columns, err = addMetadataColumns(columns, len(texts), &Metadata{
-		objectName: objectName,
-	})
-	if err != nil {
-		return fmt.Errorf("error adding metadata columns: %v", err)
+	p.extractLabResults(ctx, joinPageContents(pages), metadata)
+
+	// 替换知识文件的当前版本，更新知识文件状态
+	if err := p.activateVersion(ctx, metadata); err != nil {
+		return err
 	}
 
-	insertOption := client.NewColumnBasedInsertOption(CollectionName).WithColumns(columns...)
+	return nil
+}
 
-	// 加载数据到milvus
-	_, err = p.MilvusClient.Insert(ctx, insertOption)
-	if err != nil {
-		return fmt.Errorf("error inserting pdf chunks: %v", err)
+// recognizeLowTextPages 对文本过少的页面进行 OCR，识别结果更长时替换页面文本，返回文本提取质量
+// 单页识别失败不影响其他页面，只会降低提取质量
+func (p *PDFETLProcessor) recognizeLowTextPages(ctx context.Context, object *Object, pages []schema.Document, metadata *Metadata) (model.ExtractionQuality, error) {
+	var lowTextPages []int
+	for i, page := range pages {
+		if textLength(page.PageContent) < minPageTextLength {
+			lowTextPages = append(lowTextPages, i)
+		}
+	}
+	if len(lowTextPages) == 0 {
+		return model.QualityGood, nil
 	}
 
-	// 更新知识文件状态
-	err = knowledgebase.UpdateKnowledgeMetadataStatus(objectName, model.StatusProcessed)
+	// 只有存在扫描页时才将文件读入内存查找图片
+	extractor, err := newPDFImageExtractor(object)
 	if err != nil {
-		return err
+		slog.Warn("failed to extract pdf images for ocr",
+			"object_name", metadata.ObjectName,
+			"err", err,
+		)
+		return extractionQuality(len(pages), len(lowTextPages), 0), nil
 	}
 
-	return nil
+	recognized := 0
+	for n, i := range lowTextPages {
+		progress := progressSplitting + (progressEmbeddingStart-progressSplitting)*n/len(lowTextPages)
+		p.reportProgress(metadata, model.StageRecognizing, progress, 0)
+
+		pageNum, _ := pages[i].Metadata[chunkMetadataPage].(int)
+		images, err := extractor.pageImages(pageNum)
+		if err != nil {
+			slog.Warn("failed to get pdf page images", "page", pageNum, "err", err)
+			continue
+		}
+
+		var texts []string
+		for _, image := range images {
+			text, err := p.OCR.Recognize(ctx, image)
+			if err != nil {
+				if ctx.Err() != nil {
+					return "", ctx.Err()
+				}
+				slog.Warn("failed to recognize pdf page image", "page", pageNum, "err", err)
+				continue
+			}
+			if text != "" {
+				texts = append(texts, text)
+			}
+		}
+
+		text := strings.Join(texts, "\n\n")
+		if textLength(text) > textLength(pages[i].PageContent) {
+			pages[i].PageContent = text
+		}
+		if textLength(pages[i].PageContent) >= minPageTextLength {
+			recognized++
+		}
+	}
+
+	return extractionQuality(len(pages), len(lowTextPages), recognized), nil
+}
+
+// extractionQuality 超过一半的页面仍然没有足够文本时认为提取质量差
+func extractionQuality(totalPages, lowTextPages, recognizedPages int) model.ExtractionQuality {
+	remaining := lowTextPages - recognizedPages
+	switch {
+	case totalPages == 0 || remaining*2 > totalPages:
+		return model.QualityPoor
+	case recognizedPages > 0:
+		return model.QualityOCR
+	default:
+		return model.QualityGood
+	}
+}
+
+func joinPageContents(pages []schema.Document) string {
+	contents := make([]string, 0, len(pages))
+	for _, page := range pages {
+		contents = append(contents, page.PageContent)
+	}
+	return strings.Join(contents, "\n\n")
+}
+
+func textLength(text string) int {
+	return utf8.RuneCountInString(strings.TrimSpace(text))
 }
@@ -0,0 +1,140 @@
+package processor
+
+import (
+	"bytes"
+	"diabetes-agent-backend/service/knowledge-base/ocr"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/ledongthuc/pdf"
+)
+
+var (
+	// 匹配对象字典结束后紧跟的 stream 关键字
+	pdfStreamStartRegex = regexp.MustCompile(`>>\s*stream(\r\n|\n|\r)`)
+	pdfWidthRegex       = regexp.MustCompile(`/Width\s+(\d+)`)
+	pdfHeightRegex      = regexp.MustCompile(`/Height\s+(\d+)`)
+)
+
+// pdfRawImage 直接从 PDF 文件中读取的 JPEG 图片流
+type pdfRawImage struct {
+	width  int64
+	height int64
+	data   []byte
+}
+
+// pdfImageExtractor 提取 PDF 页面内嵌的 JPEG 图片，用于对扫描页进行 OCR
+// PDF 解析库不支持 DCTDecode 过滤器，因此从文件中直接读取 JPEG 图片流，再按宽高与页面引用的图片对应
+type pdfImageExtractor struct {
+	reader *pdf.Reader
+	images []pdfRawImage
+	used   []bool
+}
+
+func newPDFImageExtractor(object *Object) (*pdfImageExtractor, error) {
+	reader, err := pdf.NewReader(object, object.Size)
+	if err != nil {
+		return nil, fmt.Errorf("error opening pdf: %v", err)
+	}
+	// 加密文件的图片流无法直接读取
+	if !reader.Trailer().Key("Encrypt").IsNull() {
+		return nil, fmt.Errorf("encrypted pdf is not supported")
+	}
+
+	data, err := object.Bytes()
+	if err != nil {
+		return nil, fmt.Errorf("error reading pdf: %v", err)
+	}
+
+	images := scanPDFJPEGStreams(data)
+	return &pdfImageExtractor{
+		reader: reader,
+		images: images,
+		used:   make([]bool, len(images)),
+	}, nil
+}
+
+// scanPDFJPEGStreams 按文件顺序读取所有 DCTDecode 编码的图片流
+func scanPDFJPEGStreams(data []byte) []pdfRawImage {
+	var images []pdfRawImage
+	for _, loc := range pdfStreamStartRegex.FindAllIndex(data, -1) {
+		objStart := bytes.LastIndex(data[:loc[0]], []byte(" obj"))
+		if objStart < 0 {
+			continue
+		}
+		dict := data[objStart:loc[0]]
+		if !bytes.Contains(dict, []byte("/Image")) || !bytes.Contains(dict, []byte("/DCTDecode")) {
+			continue
+		}
+
+		end := bytes.Index(data[loc[1]:], []byte("endstream"))
+		if end < 0 {
+			continue
+		}
+		stream := bytes.TrimRight(data[loc[1]:loc[1]+end], "\r\n")
+		if !bytes.HasPrefix(stream, []byte{0xFF, 0xD8}) {
+			continue
+		}
+
+		images = append(images, pdfRawImage{
+			width:  pdfDictInt(dict, pdfWidthRegex),
+			height: pdfDictInt(dict, pdfHeightRegex),
+			data:   stream,
+		})
+	}
+	return images
+}
+
+func pdfDictInt(dict []byte, re *regexp.Regexp) int64 {
+	matches := re.FindSubmatch(dict)
+	if matches == nil {
+		return 0
+	}
+	v, _ := strconv.ParseInt(string(matches[1]), 10, 64)
+	return v
+}
+
+// pageImages 返回指定页面（从 1 开始）引用的 JPEG 图片
+func (e *pdfImageExtractor) pageImages(pageNum int) (images []ocr.Image, err error) {
+	// PDF 解析库遇到格式异常的文件会 panic
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("error reading page %d: %v", pageNum, r)
+		}
+	}()
+
+	xObjects := e.reader.Page(pageNum).Resources().Key("XObject")
+	for _, name := range xObjects.Keys() {
+		xObject := xObjects.Key(name)
+		if xObject.Key("Subtype").Name() != "Image" || !isDCTEncoded(xObject) {
+			continue
+		}
+
+		width, height := xObject.Key("Width").Int64(), xObject.Key("Height").Int64()
+		for i, raw := range e.images {
+			if e.used[i] || raw.width != width || raw.height != height {
+				continue
+			}
+			e.used[i] = true
+			images = append(images, ocr.Image{Data: raw.data, MIMEType: "image/jpeg"})
+			break
+		}
+	}
+	return images, nil
+}
+
+func isDCTEncoded(xObject pdf.Value) bool {
+	filter := xObject.Key("Filter")
+	switch filter.Kind() {
+	case pdf.Name:
+		return filter.Name() == "DCTDecode"
+	case pdf.Array:
+		for i := 0; i < filter.Len(); i++ {
+			if filter.Index(i).Name() == "DCTDecode" {
+				return true
+			}
+		}
+	}
+	return false
+}
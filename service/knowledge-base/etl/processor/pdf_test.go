@@ -0,0 +1,194 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"diabetes-agent-backend/model"
+	"diabetes-agent-backend/service/knowledge-base/ocr"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// testPDFPage 测试 PDF 的一页，text 写入文本层，scan 作为整页的 JPEG 图片
+type testPDFPage struct {
+	text string
+	scan []byte
+}
+
+// buildTestPDF 生成只包含 Helvetica 文本和 DCTDecode 图片的最小 PDF
+// 每张图片使用不同的宽度，使图片流能与页面引用对应
+func buildTestPDF(t *testing.T, pages []testPDFPage) []byte {
+	t.Helper()
+
+	// 对象编号：1 目录，2 页面树，3 字体，之后每页依次为页面、内容流和图片
+	var objects []string
+	objects = append(objects, "<< /Type /Catalog /Pages 2 0 R >>", "", "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+
+	var kids []string
+	for i, page := range pages {
+		pageID := len(objects) + 1
+		contentID, imageID := pageID+1, pageID+2
+		kids = append(kids, fmt.Sprintf("%d 0 R", pageID))
+
+		resources := "/Font << /F1 3 0 R >>"
+		content := fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", page.text)
+		if page.scan != nil {
+			resources += fmt.Sprintf(" /XObject << /Im1 %d 0 R >>", imageID)
+			content = "q 400 0 0 400 72 300 cm /Im1 Do Q"
+		}
+
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << %s >> /Contents %d 0 R >>", resources, contentID),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+		)
+		if page.scan != nil {
+			objects = append(objects, fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width %d /Height 100 "+
+				"/ColorSpace /DeviceGray /BitsPerComponent 8 /Filter /DCTDecode /Length %d >>\nstream\n%s\nendstream",
+				100+i, len(page.scan), page.scan))
+		} else {
+			// 占位，保持每页三个对象编号
+			objects = append(objects, "null")
+		}
+	}
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages))
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return b.Bytes()
+}
+
+// fakeJPEG 返回带 JPEG 文件头的图片数据，FakeEngine 只按内容摘要返回文本
+func fakeJPEG(name string) []byte {
+	return []byte("\xFF\xD8\xFF\xE0" + name + "\xFF\xD9")
+}
+
+func imageHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func newTestPDFProcessor(t *testing.T, engine ocr.Engine) (*PDFETLProcessor, *testStores) {
+	t.Helper()
+
+	base, stores := newTestBase(t, model.FileTypePDF)
+	return &PDFETLProcessor{BaseETLProcessor: base, OCR: engine}, stores
+}
+
+func runPDF(t *testing.T, p *PDFETLProcessor, stores *testStores, data []byte) *Metadata {
+	t.Helper()
+
+	metadata := stores.newTestMetadata("report.pdf", model.FileTypePDF)
+	object := &Object{ReaderAt: bytes.NewReader(data), Size: int64(len(data))}
+	if err := p.ExecuteETLPipeline(context.Background(), object, metadata); err != nil {
+		t.Fatalf("ExecuteETLPipeline() error = %v", err)
+	}
+	return metadata
+}
+
+const (
+	textLayer = "Fasting glucose has been stable for three months and the diet plan is followed."
+	ocrText   = "扫描页识别结果：近三个月空腹血糖控制平稳，继续按照饮食计划执行，每周运动五次，每次三十分钟以上，三个月后复诊。"
+)
+
+func TestPDFRecognizesLowTextPagesWithOCR(t *testing.T) {
+	scan := fakeJPEG("scanned page 2")
+	engine := ocr.NewFakeEngine()
+	engine.Texts[imageHash(scan)] = ocrText
+
+	p, stores := newTestPDFProcessor(t, engine)
+	metadata := runPDF(t, p, stores, buildTestPDF(t, []testPDFPage{
+		{text: textLayer},
+		{scan: scan},
+	}))
+
+	if quality := stores.quality(t, metadata); quality != model.QualityOCR {
+		t.Errorf("quality = %s, want %s", quality, model.QualityOCR)
+	}
+
+	var pages []int64
+	for _, chunk := range stores.vectors.Chunks() {
+		if strings.Contains(chunk.Text, "扫描页识别结果") {
+			pages = append(pages, chunk.Page)
+		}
+	}
+	if len(pages) == 0 {
+		t.Fatal("OCR text of the scanned page was not stored")
+	}
+	for _, page := range pages {
+		if page != 2 {
+			t.Errorf("OCR chunk has page %d, want 2", page)
+		}
+	}
+}
+
+func TestPDFWithTextLayerSkipsOCR(t *testing.T) {
+	engine := ocr.NewFakeEngine()
+	p, stores := newTestPDFProcessor(t, engine)
+	metadata := runPDF(t, p, stores, buildTestPDF(t, []testPDFPage{
+		{text: textLayer},
+		{text: textLayer + " Page two."},
+	}))
+
+	if quality := stores.quality(t, metadata); quality != model.QualityGood {
+		t.Errorf("quality = %s, want %s", quality, model.QualityGood)
+	}
+	for _, chunk := range stores.vectors.Chunks() {
+		if strings.Contains(chunk.Text, "OCR text of") {
+			t.Errorf("chunk %d contains OCR output: %q", chunk.ChunkIndex, chunk.Text)
+		}
+	}
+}
+
+func TestPDFFlagsPoorQualityWhenOCRFindsLittleText(t *testing.T) {
+	first, second := fakeJPEG("blurry page 1"), fakeJPEG("blurry page 2")
+	engine := ocr.NewFakeEngine()
+	engine.Texts[imageHash(first)] = "血糖"
+	engine.Texts[imageHash(second)] = "模糊"
+
+	p, stores := newTestPDFProcessor(t, engine)
+	metadata := runPDF(t, p, stores, buildTestPDF(t, []testPDFPage{
+		{scan: first},
+		{scan: second},
+	}))
+
+	if quality := stores.quality(t, metadata); quality != model.QualityPoor {
+		t.Errorf("quality = %s, want %s", quality, model.QualityPoor)
+	}
+}
+
+func TestExtractionQuality(t *testing.T) {
+	tests := []struct {
+		name                       string
+		total, lowText, recognized int
+		want                       model.ExtractionQuality
+	}{
+		{"all pages have text", 4, 0, 0, model.QualityGood},
+		{"scanned pages recognized", 4, 2, 2, model.QualityOCR},
+		{"half of the pages still empty", 4, 3, 1, model.QualityOCR},
+		{"most pages still empty", 4, 3, 0, model.QualityPoor},
+		{"no pages", 0, 0, 0, model.QualityPoor},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := extractionQuality(tt.total, tt.lowText, tt.recognized); got != tt.want {
+				t.Errorf("extractionQuality(%d, %d, %d) = %s, want %s",
+					tt.total, tt.lowText, tt.recognized, got, tt.want)
+			}
+		})
+	}
+}
@@ -2,28 +2,32 @@ package processor
 
 import (
 	"context"
-	"diabetes-agent-backend/config"
 	"diabetes-agent-backend/model"
-	"diabetes-agent-backend/service/chat"
-	"diabetes-agent-backend/utils"
+	"diabetes-agent-backend/service/embedding"
+	knowledgebase "diabetes-agent-backend/service/knowledge-base"
+	"diabetes-agent-backend/service/knowledge-base/labreport"
+	"diabetes-agent-backend/service/knowledge-base/vectorstore"
 	"fmt"
+	"io"
+	"log/slog"
 	"strings"
 
-	"github.com/milvus-io/milvus/client/v2/column"
-	"github.com/milvus-io/milvus/client/v2/milvusclient"
 	"github.com/tmc/langchaingo/embeddings"
-	"github.com/tmc/langchaingo/llms/openai"
+	"github.com/tmc/langchaingo/schema"
 	"github.com/tmc/langchaingo/textsplitter"
 )
 
+const CollectionName = vectorstore.CollectionName
+
+// 各处理阶段开始时的进度百分比
 const (
-	embeddingModelName = "text-embedding-v4"
-	chunkSize          = 4000
-	chunkOverlap       = 400
-	embeddingBatchSize = 10
-	vectorDim          = 1024
+	progressSplitting      = 10
+	progressEmbeddingStart = 20
+	progressIndexing       = 90
+	progressExtracting     = 95
 
-	CollectionName = "knowledge_doc"
+	// 每批切片向量化并写入milvus后更新一次进度和检查点
+	insertBatchSize = 50
 )
 
 // ETLProcessor 知识文件ETL处理器
@@ -32,53 +36,59 @@ type ETLProcessor interface {
 	CanProcess(fileType model.FileType) bool
 
 	// 执行ETL流程
-	ExecuteETLPipeline(ctx context.Context, object []byte, objectName string) error
+	ExecuteETLPipeline(ctx context.Context, object *Object, metadata *Metadata) error
+
+	// 删除知识文件的向量存储
+	DeleteVectorStore(ctx context.Context, documentID string) error
+
+	// 删除知识文件指定版本的向量存储
+	DeleteVersionVectorStore(ctx context.Context, documentID string, knowledgeID uint) error
+}
 
-	// 删除向量存储
-	DeleteVectorStore(ctx context.Context, objectName string) error
+// LabExtractor 从文档文本中提取化验结果
+type LabExtractor interface {
+	Extract(ctx context.Context, text string) ([]labreport.ExtractedResult, error)
 }
 
 // BaseETLProcessor 基础ETL处理器，提供删除向量存储的默认实现
+// 向量化、向量存储和处理状态均通过接口注入，测试时可以替换为内存实现
 type BaseETLProcessor struct {
 	TextSplitter textsplitter.TextSplitter
 	Embedder     embeddings.Embedder
-	MilvusClient *milvusclient.Client
+	VectorStore  vectorstore.VectorStore
+	LabExtractor LabExtractor
+	LabResults   labreport.ResultStore
+	Knowledge    knowledgebase.ProcessingStore
 }
 
 var _ ETLProcessor = &BaseETLProcessor{}
 
+// NewBaseETLProcessor 创建基础处理器，向量化服务和 Milvus 客户端在所有处理器间共享
 func NewBaseETLProcessor(textSplitter textsplitter.TextSplitter) (*BaseETLProcessor, error) {
-	client, err := openai.New(
-		openai.WithEmbeddingModel(embeddingModelName),
-		openai.WithToken(config.Cfg.Model.APIKey),
-		openai.WithBaseURL(chat.BaseURL),
-		openai.WithHTTPClient(utils.DefaultHTTPClient()),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create embedder client: %v", err)
-	}
+	spec := vectorstore.DefaultSpec()
 
-	embedder, err := embeddings.NewEmbedder(client,
-		embeddings.WithBatchSize(embeddingBatchSize),
-		embeddings.WithStripNewLines(false),
-	)
+	embedder, err := embedding.Default()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create embedder: %v", err)
+		return nil, fmt.Errorf("failed to create embedding service: %v", err)
 	}
 
-	milvusConfig := milvusclient.ClientConfig{
-		Address: config.Cfg.Milvus.Endpoint,
-		APIKey:  config.Cfg.Milvus.APIKey,
+	milvusClient, err := vectorstore.DefaultClient(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create milvus client: %v", err)
 	}
 
-	milvusClient, err := milvusclient.New(context.Background(), &milvusConfig)
+	labExtractor, err := labreport.NewExtractor()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create milvus client: %v", err)
+		return nil, fmt.Errorf("failed to create lab report extractor: %v", err)
 	}
+
 	return &BaseETLProcessor{
 		TextSplitter: textSplitter,
 		Embedder:     embedder,
-		MilvusClient: milvusClient,
+		VectorStore:  vectorstore.NewMilvusStore(milvusClient, CollectionName, int(spec.Dim)),
+		LabExtractor: labExtractor,
+		LabResults:   labreport.DBResultStore{},
+		Knowledge:    knowledgebase.DBProcessingStore{},
 	}, nil
 }
 
@@ -86,53 +96,268 @@ func (p *BaseETLProcessor) CanProcess(fileType model.FileType) bool {
 	return false
 }
 
-func (p *BaseETLProcessor) ExecuteETLPipeline(ctx context.Context, object []byte, objectName string) error {
+func (p *BaseETLProcessor) ExecuteETLPipeline(ctx context.Context, object *Object, metadata *Metadata) error {
 	return nil
 }
 
-func (p *BaseETLProcessor) DeleteVectorStore(ctx context.Context, objectName string) error {
-	pathSegments := strings.Split(objectName, "/")
-	if len(pathSegments) < 2 {
-		return fmt.Errorf("invalid object name: %s", objectName)
+func (p *BaseETLProcessor) DeleteVectorStore(ctx context.Context, documentID string) error {
+	return p.VectorStore.DeleteByDocument(ctx, vectorstore.DocumentFilter{DocumentID: documentID})
+}
+
+func (p *BaseETLProcessor) DeleteVersionVectorStore(ctx context.Context, documentID string, knowledgeID uint) error {
+	return p.VectorStore.DeleteByDocument(ctx, vectorstore.DocumentFilter{
+		DocumentID:  documentID,
+		KnowledgeID: knowledgeID,
+	})
+}
+
+// deleteVersionChunks 删除知识文件指定版本中序号不小于 fromChunk 的切片，当前生效版本的切片不受影响
+func (p *BaseETLProcessor) deleteVersionChunks(ctx context.Context, metadata *Metadata, fromChunk int) error {
+	err := p.VectorStore.DeleteByDocument(ctx, vectorstore.DocumentFilter{
+		DocumentID:     metadata.DocumentID,
+		KnowledgeID:    metadata.KnowledgeID,
+		FromChunkIndex: fromChunk,
+	})
+	if err != nil {
+		return fmt.Errorf("error deleting existing chunks: %v", err)
 	}
 
-	userEmail := pathSegments[0]
-	fileName := pathSegments[len(pathSegments)-1]
+	return nil
+}
 
-	expression := fmt.Sprintf("user_email == '%s' and title == '%s'", userEmail, fileName)
-	deleteOption := milvusclient.NewDeleteOption(CollectionName).WithExpr(expression)
+// activateVersion 在新版本切片写入成功后，删除同一知识文件其他版本的切片，并将新版本设置为当前版本
+// 先写入后删除，替换过程中检索结果不会出现空窗
+func (p *BaseETLProcessor) activateVersion(ctx context.Context, metadata *Metadata) error {
+	err := p.VectorStore.DeleteByDocument(ctx, vectorstore.DocumentFilter{
+		DocumentID:         metadata.DocumentID,
+		ExcludeKnowledgeID: metadata.KnowledgeID,
+	})
+	if err != nil {
+		return fmt.Errorf("error deleting superseded chunks: %v", err)
+	}
 
-	_, err := p.MilvusClient.Delete(ctx, deleteOption)
+	return p.Knowledge.ActivateVersion(metadata.KnowledgeID)
+}
+
+// extractLabResults 从文档文本中提取化验结果，替换该知识文件之前版本的结果
+// 提取失败只记录日志并保留之前版本的结果，不影响向量检索
+func (p *BaseETLProcessor) extractLabResults(ctx context.Context, text string, metadata *Metadata) {
+	var results []model.LabResult
+	if labreport.LooksLikeLabReport(text) {
+		p.reportProgress(metadata, model.StageExtracting, progressExtracting, 0)
+
+		extracted, err := p.LabExtractor.Extract(ctx, text)
+		if err != nil {
+			slog.Warn("failed to extract lab results",
+				"document_id", metadata.DocumentID,
+				"err", err,
+			)
+			return
+		}
+		results = labreport.Normalize(extracted, labreport.Source{
+			UserEmail:   metadata.UserEmail,
+			DocumentID:  metadata.DocumentID,
+			KnowledgeID: metadata.KnowledgeID,
+		})
+	}
+
+	if err := p.LabResults.SaveLabResults(metadata.DocumentID, results); err != nil {
+		slog.Warn("failed to save lab results",
+			"document_id", metadata.DocumentID,
+			"err", err,
+		)
+	}
+}
+
+// Object 下载到本地的知识文件，支持随机读取，处理器按需读取而不是一次性读入内存
+type Object struct {
+	io.ReaderAt
+	Size int64
+}
+
+// NewReader 返回从头读取文件内容的 Reader
+func (o *Object) NewReader() *io.SectionReader {
+	return io.NewSectionReader(o.ReaderAt, 0, o.Size)
+}
+
+// Bytes 读取文件全部内容，只用于必须整体处理的格式
+func (o *Object) Bytes() ([]byte, error) {
+	return io.ReadAll(o.NewReader())
+}
+
+// Metadata 知识文件元数据
+type Metadata struct {
+	// 文件在OSS上的完整路径
+	ObjectName string
+
+	// 知识文件元数据 ID
+	KnowledgeID uint
+
+	// 知识文件的稳定标识
+	DocumentID string
+
+	UserEmail string
+	FileName  string
+
+	// 知识文件版本记录的切分配置
+	Chunking model.Chunking
+}
+
+const (
+	// 切片来源页码，由 PDF 加载器写入
+	chunkMetadataPage = "page"
+
+	// 切片所在的标题层级
+	chunkMetadataHeadingPath = "heading_path"
+)
+
+// storeChunks 对文档切片去重、向量化并写入milvus
+func (p *BaseETLProcessor) storeChunks(ctx context.Context, docs []schema.Document, metadata *Metadata) error {
+	// 同一文档内内容完全相同的切片只保留一份
+	seen := make(map[string]bool, len(docs))
+	chunks := make([]schema.Document, 0, len(docs))
+	hashes := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		hash := vectorstore.ContentHash(doc.PageContent)
+		if seen[hash] {
+			continue
+		}
+		seen[hash] = true
+		chunks = append(chunks, doc)
+		hashes = append(hashes, hash)
+	}
+
+	// 没有提取到文本时不能标记为处理完成
+	if len(chunks) == 0 {
+		return fmt.Errorf("no text extracted from document")
+	}
+
+	// 从上次处理成功的批次继续，清理未记录检查点的切片，保证重试不会产生重复向量
+	fingerprint := chunkFingerprint(hashes)
+	resumeFrom, err := p.resumeCheckpoint(metadata, fingerprint, len(chunks))
 	if err != nil {
-		return fmt.Errorf("error deleting document chunks: %v", err)
+		return err
+	}
+	if err := p.deleteVersionChunks(ctx, metadata, resumeFrom); err != nil {
+		return err
+	}
+	if resumeFrom > 0 {
+		slog.Info("resume storing chunks from checkpoint",
+			"object_name", metadata.ObjectName,
+			"checkpoint", resumeFrom,
+			"chunks_num", len(chunks),
+		)
+	}
+
+	// 分批向量化并写入milvus，每批完成后记录检查点
+	p.reportProgress(metadata, model.StageEmbedding, progressEmbeddingStart, len(chunks))
+	for start := resumeFrom; start < len(chunks); start += insertBatchSize {
+		end := min(start+insertBatchSize, len(chunks))
+		if err := p.insertBatch(ctx, chunks[start:end], hashes[start:end], start, metadata); err != nil {
+			return err
+		}
+
+		if err := p.Knowledge.SaveCheckpoint(metadata.KnowledgeID, fingerprint, end); err != nil {
+			return err
+		}
+
+		progress := progressEmbeddingStart + (progressIndexing-progressEmbeddingStart)*end/len(chunks)
+		p.reportProgress(metadata, model.StageEmbedding, progress, 0)
 	}
 
+	p.reportProgress(metadata, model.StageIndexing, progressIndexing, 0)
+
 	return nil
 }
 
-type Metadata struct {
-	objectName string
+// insertBatch 向量化一批切片并写入向量存储，offset 为该批第一个切片在文档中的序号
+func (p *BaseETLProcessor) insertBatch(ctx context.Context, docs []schema.Document, hashes []string, offset int, metadata *Metadata) error {
+	texts := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		texts = append(texts, doc.PageContent)
+	}
+
+	vectors, err := p.Embedder.EmbedDocuments(ctx, texts)
+	if err != nil {
+		return fmt.Errorf("error embedding chunks: %v", err)
+	}
+
+	chunks, err := newChunks(docs, vectors, hashes, offset, metadata)
+	if err != nil {
+		return err
+	}
+	if err := p.VectorStore.Upsert(ctx, chunks); err != nil {
+		return err
+	}
+
+	slog.Debug("inserted chunk batch successfully",
+		"object_name", metadata.ObjectName,
+		"offset", offset,
+		"chunks_num", len(chunks),
+	)
+	return nil
 }
 
-// 增加milvus元数据列
-func addMetadataColumns(columns []column.Column, recordNum int, metadata *Metadata) ([]column.Column, error) {
-	pathSegments := strings.Split(metadata.objectName, "/")
-	if len(pathSegments) < 2 {
-		return nil, fmt.Errorf("invalid object name: %s", metadata.objectName)
+// resumeCheckpoint 返回可以继续处理的切片序号
+// 切片指纹与检查点一致时从检查点继续，否则从头处理并重置检查点
+func (p *BaseETLProcessor) resumeCheckpoint(metadata *Metadata, fingerprint string, total int) (int, error) {
+	savedFingerprint, checkpoint, err := p.Knowledge.GetCheckpoint(metadata.KnowledgeID)
+	if err != nil {
+		return 0, err
+	}
+	if savedFingerprint == fingerprint && checkpoint > 0 && checkpoint <= total {
+		return checkpoint, nil
+	}
+
+	if err := p.Knowledge.SaveCheckpoint(metadata.KnowledgeID, fingerprint, 0); err != nil {
+		return 0, err
 	}
+	return 0, nil
+}
+
+// chunkFingerprint 根据切片内容哈希的顺序计算切片集合的指纹
+func chunkFingerprint(hashes []string) string {
+	return vectorstore.ContentHash(strings.Join(hashes, ","))
+}
 
-	userEmail := pathSegments[0]
-	title := pathSegments[len(pathSegments)-1]
+// reportProgress 记录知识文件处理进度，记录失败不影响 ETL 流程
+func (p *BaseETLProcessor) reportProgress(metadata *Metadata, stage model.Stage, progress, chunkCount int) {
+	_ = p.Knowledge.UpdateProgress(metadata.KnowledgeID, stage, progress, chunkCount)
+}
+
+// reportQuality 记录知识文件的文本提取质量，记录失败不影响 ETL 流程
+func (p *BaseETLProcessor) reportQuality(metadata *Metadata, quality model.ExtractionQuality) {
+	_ = p.Knowledge.UpdateQuality(metadata.KnowledgeID, quality)
+}
 
-	titles := make([]string, recordNum)
-	userEmails := make([]string, recordNum)
-	for i := range recordNum {
-		titles[i] = title
-		userEmails[i] = userEmail
+// newChunks 组装写入向量存储的切片及其元数据
+func newChunks(docs []schema.Document, vectors [][]float32, hashes []string, offset int, metadata *Metadata) ([]vectorstore.Chunk, error) {
+	if metadata.DocumentID == "" || metadata.UserEmail == "" || metadata.FileName == "" {
+		return nil, fmt.Errorf("incomplete metadata of object: %s", metadata.ObjectName)
 	}
 
-	columns = append(columns, column.NewColumnVarChar("title", titles))
-	columns = append(columns, column.NewColumnVarChar("user_email", userEmails))
+	chunks := make([]vectorstore.Chunk, len(docs))
+	for i, doc := range docs {
+		chunks[i] = vectorstore.Chunk{
+			Text:   doc.PageContent,
+			Vector: vectors[i],
+			ChunkMetadata: vectorstore.ChunkMetadata{
+				Title:       metadata.FileName,
+				UserEmail:   metadata.UserEmail,
+				ChunkIndex:  int64(offset + i),
+				KnowledgeID: int64(metadata.KnowledgeID),
+				DocumentID:  metadata.DocumentID,
+				ContentHash: hashes[i],
+			},
+		}
+
+		if page, ok := doc.Metadata[chunkMetadataPage].(int); ok {
+			chunks[i].Page = int64(page)
+		}
+		if headingPath, ok := doc.Metadata[chunkMetadataHeadingPath].(string); ok {
+			chunks[i].HeadingPath = headingPath
+		}
+	}
 
-	return columns, nil
+	return chunks, nil
 }
@@ -0,0 +1,93 @@
+package processor
+
+import (
+	"context"
+	"diabetes-agent-backend/model"
+	"diabetes-agent-backend/service/embedding"
+	knowledgebase "diabetes-agent-backend/service/knowledge-base"
+	"diabetes-agent-backend/service/knowledge-base/labreport"
+	"diabetes-agent-backend/service/knowledge-base/vectorstore"
+	"testing"
+
+	"github.com/google/uuid"
+	"golang.org/x/time/rate"
+)
+
+const testDim = 32
+
+// stubExtractor 返回固定的化验结果
+type stubExtractor struct {
+	results []labreport.ExtractedResult
+	err     error
+}
+
+func (e *stubExtractor) Extract(ctx context.Context, text string) ([]labreport.ExtractedResult, error) {
+	return e.results, e.err
+}
+
+type testStores struct {
+	vectors    *vectorstore.MemoryStore
+	knowledge  *knowledgebase.MemoryProcessingStore
+	extractor  *stubExtractor
+	labResults *labreport.MemoryResultStore
+}
+
+// newTestBase 创建使用内存存储和 HashClient 的基础处理器
+func newTestBase(t *testing.T, fileType model.FileType) (BaseETLProcessor, *testStores) {
+	t.Helper()
+
+	textSplitter, err := NewTextSplitter(model.DefaultChunking(fileType))
+	if err != nil {
+		t.Fatalf("failed to create text splitter: %v", err)
+	}
+
+	stores := &testStores{
+		vectors:    vectorstore.NewMemoryStore(testDim),
+		knowledge:  knowledgebase.NewMemoryProcessingStore(),
+		extractor:  &stubExtractor{},
+		labResults: labreport.NewMemoryResultStore(),
+	}
+	base := BaseETLProcessor{
+		TextSplitter: textSplitter,
+		Embedder: embedding.NewService(embedding.NewHashClient(testDim), embedding.NoopCache{},
+			"hash", testDim, rate.NewLimiter(rate.Inf, 0), 10, 1),
+		VectorStore:  stores.vectors,
+		LabExtractor: stores.extractor,
+		LabResults:   stores.labResults,
+		Knowledge:    stores.knowledge,
+	}
+	return base, stores
+}
+
+// newTestMetadata 添加一个待处理的知识文件版本
+func (s *testStores) newTestMetadata(fileName string, fileType model.FileType) *Metadata {
+	metadata := &Metadata{
+		ObjectName:  "patient@example.com/" + fileName,
+		KnowledgeID: 1,
+		DocumentID:  uuid.New().String(),
+		UserEmail:   "patient@example.com",
+		FileName:    fileName,
+	}
+	s.knowledge.Add(model.KnowledgeMetadata{
+		ID:         metadata.KnowledgeID,
+		UserEmail:  metadata.UserEmail,
+		DocumentID: metadata.DocumentID,
+		Version:    1,
+		FileName:   fileName,
+		FileType:   fileType,
+		ObjectName: metadata.ObjectName,
+		Status:     model.StatusUploaded,
+	})
+	return metadata
+}
+
+// quality 返回记录的文本提取质量
+func (s *testStores) quality(t *testing.T, metadata *Metadata) model.ExtractionQuality {
+	t.Helper()
+
+	record, ok := s.knowledge.Get(metadata.KnowledgeID)
+	if !ok {
+		t.Fatalf("knowledge %d not found", metadata.KnowledgeID)
+	}
+	return record.ExtractionQuality
+}
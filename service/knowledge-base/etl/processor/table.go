@@ -0,0 +1,90 @@
+package processor
+
+import (
+	"strings"
+)
+
+// 表格单元格中需要替换的字符，避免破坏 Markdown 表格结构
+var tableCellReplacer = strings.NewReplacer(
+	"|", "\\|",
+	"\r\n", " ",
+	"\n", " ",
+	"\r", " ",
+)
+
+// renderMarkdownTable 将二维表格渲染为 Markdown 表格，第一行作为表头
+// 各行列数不一致时按最大列数补齐，全空的行和列会被忽略
+func renderMarkdownTable(rows [][]string) string {
+	rows = trimEmptyColumns(trimEmptyRows(rows))
+	if len(rows) == 0 {
+		return ""
+	}
+
+	columns := 0
+	for _, row := range rows {
+		columns = max(columns, len(row))
+	}
+
+	var sb strings.Builder
+	for i, row := range rows {
+		writeTableRow(&sb, row, columns)
+		if i == 0 {
+			separators := make([]string, columns)
+			for j := range separators {
+				separators[j] = "---"
+			}
+			writeTableRow(&sb, separators, columns)
+		}
+	}
+	return sb.String()
+}
+
+func writeTableRow(sb *strings.Builder, row []string, columns int) {
+	sb.WriteString("|")
+	for i := range columns {
+		cell := ""
+		if i < len(row) {
+			cell = strings.TrimSpace(tableCellReplacer.Replace(row[i]))
+		}
+		sb.WriteString(" ")
+		sb.WriteString(cell)
+		sb.WriteString(" |")
+	}
+	sb.WriteString("\n")
+}
+
+func trimEmptyRows(rows [][]string) [][]string {
+	result := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		for _, cell := range row {
+			if strings.TrimSpace(cell) != "" {
+				result = append(result, row)
+				break
+			}
+		}
+	}
+	return result
+}
+
+func trimEmptyColumns(rows [][]string) [][]string {
+	nonEmpty := make(map[int]bool)
+	for _, row := range rows {
+		for i, cell := range row {
+			if strings.TrimSpace(cell) != "" {
+				nonEmpty[i] = true
+			}
+		}
+	}
+
+	result := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		trimmed := make([]string, 0, len(nonEmpty))
+		for i, cell := range row {
+			if nonEmpty[i] {
+				trimmed = append(trimmed, cell)
+			}
+		}
+		result = append(result, trimmed)
+	}
+	return result
+}
@@ -0,0 +1,297 @@
+package processor
+
+import (
+	"archive/zip"
+	"context"
+	"diabetes-agent-backend/model"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// XLSXETLProcessor Excel文件ETL处理器，每个工作表转换为带标题的表格
+type XLSXETLProcessor struct {
+	BaseETLProcessor
+}
+
+var _ ETLProcessor = &XLSXETLProcessor{}
+
+func NewXLSXETLProcessor() (*XLSXETLProcessor, error) {
+	baseETLProcessor, err := NewBaseETLProcessor(newMarkdownTextSplitter(model.DefaultChunkSize, model.DefaultChunkOverlap))
+	if err != nil {
+		return nil, fmt.Errorf("error creating BaseETLProcessor: %v", err)
+	}
+
+	return &XLSXETLProcessor{
+		BaseETLProcessor: *baseETLProcessor,
+	}, nil
+}
+
+func (p *XLSXETLProcessor) CanProcess(fileType model.FileType) bool {
+	return fileType == model.FileTypeXLSX
+}
+
+func (p *XLSXETLProcessor) ExecuteETLPipeline(ctx context.Context, object *Object, metadata *Metadata) error {
+	p.reportProgress(metadata, model.StageSplitting, progressSplitting, 0)
+
+	content, err := xlsxToMarkdown(object)
+	if err != nil {
+		return fmt.Errorf("error parsing xlsx: %v", err)
+	}
+
+	return p.storeMarkdown(ctx, content, metadata)
+}
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxRichText `xml:"si"`
+}
+
+// xlsxRichText 纯文本单元格只有 t 节点，富文本单元格由多个 r/t 片段组成
+type xlsxRichText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxRichText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+	var sb strings.Builder
+	for _, run := range t.Runs {
+		sb.WriteString(run.Text)
+	}
+	return sb.String()
+}
+
+type xlsxStyles struct {
+	NumFmts []struct {
+		ID   int    `xml:"numFmtId,attr"`
+		Code string `xml:"formatCode,attr"`
+	} `xml:"numFmts>numFmt"`
+	CellXfs []struct {
+		NumFmtID int `xml:"numFmtId,attr"`
+	} `xml:"cellXfs>xf"`
+}
+
+type xlsxWorksheet struct {
+	Rows []struct {
+		Cells []struct {
+			Ref       string       `xml:"r,attr"`
+			Type      string       `xml:"t,attr"`
+			Style     int          `xml:"s,attr"`
+			Value     string       `xml:"v"`
+			InlineStr xlsxRichText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// xlsxToMarkdown 解析 xlsx 的 OOXML 结构，每个工作表渲染为 "## 工作表名" 和一张 Markdown 表格
+func xlsxToMarkdown(object *Object) (string, error) {
+	reader, err := zip.NewReader(object, object.Size)
+	if err != nil {
+		return "", fmt.Errorf("invalid xlsx archive: %v", err)
+	}
+
+	files := make(map[string]*zip.File, len(reader.File))
+	for _, f := range reader.File {
+		files[f.Name] = f
+	}
+
+	var workbook xlsxWorkbook
+	if err := decodeZipXML(files, "xl/workbook.xml", &workbook); err != nil {
+		return "", err
+	}
+
+	var rels xlsxRelationships
+	if err := decodeZipXML(files, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return "", err
+	}
+	targets := make(map[string]string, len(rels.Relationships))
+	for _, rel := range rels.Relationships {
+		targets[rel.ID] = rel.Target
+	}
+
+	// 共享字符串表和样式表是可选的
+	var sharedStrings xlsxSharedStrings
+	if _, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := decodeZipXML(files, "xl/sharedStrings.xml", &sharedStrings); err != nil {
+			return "", err
+		}
+	}
+	var styles xlsxStyles
+	if _, ok := files["xl/styles.xml"]; ok {
+		if err := decodeZipXML(files, "xl/styles.xml", &styles); err != nil {
+			return "", err
+		}
+	}
+	dateStyles := xlsxDateStyles(&styles)
+
+	var sb strings.Builder
+	for _, sheet := range workbook.Sheets {
+		target, ok := targets[sheet.RID]
+		if !ok {
+			continue
+		}
+		// Target 可能是相对 xl/ 的路径，也可能是以 / 开头的包内绝对路径
+		name := strings.TrimPrefix(target, "/")
+		if !strings.HasPrefix(name, "xl/") {
+			name = path.Join("xl", name)
+		}
+
+		var worksheet xlsxWorksheet
+		if err := decodeZipXML(files, name, &worksheet); err != nil {
+			return "", err
+		}
+
+		var rows [][]string
+		for _, row := range worksheet.Rows {
+			var values []string
+			for i, cell := range row.Cells {
+				// 空单元格不会出现在 XML 中，按单元格引用中的列号定位
+				col := i
+				if cell.Ref != "" {
+					col = xlsxColumnIndex(cell.Ref)
+				}
+				for len(values) <= col {
+					values = append(values, "")
+				}
+
+				switch cell.Type {
+				case "s":
+					idx, err := strconv.Atoi(cell.Value)
+					if err == nil && idx >= 0 && idx < len(sharedStrings.Items) {
+						values[col] = sharedStrings.Items[idx].String()
+					}
+				case "inlineStr":
+					values[col] = cell.InlineStr.String()
+				case "b":
+					values[col] = strconv.FormatBool(cell.Value == "1")
+				case "", "n":
+					values[col] = formatXLSXNumber(cell.Value, dateStyles[cell.Style])
+				default:
+					values[col] = cell.Value
+				}
+			}
+			rows = append(rows, values)
+		}
+
+		table := renderMarkdownTable(rows)
+		if table == "" {
+			continue
+		}
+		fmt.Fprintf(&sb, "## %s\n\n%s\n", sheet.Name, table)
+	}
+
+	return sb.String(), nil
+}
+
+func decodeZipXML(files map[string]*zip.File, name string, v any) error {
+	f, ok := files[name]
+	if !ok {
+		return fmt.Errorf("missing %s", name)
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("error opening %s: %v", name, err)
+	}
+	defer rc.Close()
+
+	if err := xml.NewDecoder(io.LimitReader(rc, maxZipEntrySize)).Decode(v); err != nil {
+		return fmt.Errorf("error decoding %s: %v", name, err)
+	}
+	return nil
+}
+
+// 单个压缩包条目解压后的大小上限，防止压缩炸弹
+const maxZipEntrySize = 256 << 20
+
+// xlsxColumnIndex 将形如 "AB12" 的单元格引用转换为从 0 开始的列号
+func xlsxColumnIndex(ref string) int {
+	col := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A'+1)
+	}
+	return col - 1
+}
+
+// xlsxDateStyles 返回使用日期格式的单元格样式下标
+func xlsxDateStyles(styles *xlsxStyles) map[int]bool {
+	customDateFormats := make(map[int]bool)
+	for _, numFmt := range styles.NumFmts {
+		customDateFormats[numFmt.ID] = isDateFormatCode(numFmt.Code)
+	}
+
+	dateStyles := make(map[int]bool)
+	for i, xf := range styles.CellXfs {
+		id := xf.NumFmtID
+		// 14-22 为内置的日期时间格式
+		if (id >= 14 && id <= 22) || customDateFormats[id] {
+			dateStyles[i] = true
+		}
+	}
+	return dateStyles
+}
+
+// isDateFormatCode 判断自定义数字格式是否为日期格式，忽略引号和方括号中的内容
+func isDateFormatCode(code string) bool {
+	var inQuote, inBracket bool
+	for _, r := range strings.ToLower(code) {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+		case inQuote:
+		case r == '[':
+			inBracket = true
+		case r == ']':
+			inBracket = false
+		case inBracket:
+		case r == 'y' || r == 'd':
+			return true
+		}
+	}
+	return false
+}
+
+// Excel 日期序列号的起点（兼容 1900 年闰年问题）
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+func formatXLSXNumber(value string, isDate bool) string {
+	if !isDate || value == "" {
+		return value
+	}
+
+	serial, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return value
+	}
+
+	t := excelEpoch.Add(time.Duration(serial * float64(24*time.Hour))).Round(time.Second)
+	if t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 {
+		return t.Format(time.DateOnly)
+	}
+	return t.Format(time.DateTime)
+}
@@ -0,0 +1,178 @@
+package labreport
+
+import (
+	"bytes"
+	"context"
+	"diabetes-agent-backend/config"
+	"diabetes-agent-backend/service/chat"
+	"diabetes-agent-backend/utils"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/llms/openai"
+)
+
+const (
+	extractionModel = "qwen-plus"
+
+	// 单次提取的最大文本长度（字符数），超出时分段提取
+	maxSegmentLength = 12000
+)
+
+//go:embed prompts/lab_report.txt
+var extractionPrompt string
+
+var extractionTemplate = template.Must(template.New("lab_report").Parse(extractionPrompt))
+
+// 文档中出现这些关键词时才可能是检验报告，避免对所有文档调用大模型
+var labReportHintRegex = regexp.MustCompile(`(?i)(参考(范围|值|区间)|检验|化验|mmol/l|mg/dl|[μµu]mol/l|hba1c|糖化血红蛋白|reference\s*range)`)
+
+// ExtractedResult 大模型返回的单项检验结果
+type ExtractedResult struct {
+	Name           string `json:"name"`
+	Value          string `json:"value"`
+	Unit           string `json:"unit"`
+	ReferenceRange string `json:"reference_range"`
+	SampledAt      string `json:"sampled_at"`
+}
+
+type extractionResponse struct {
+	Results []ExtractedResult `json:"results"`
+}
+
+// extractionFormat 约束模型按 extractionResponse 的结构输出，JSON 模式只保证输出合法 JSON，
+// 字段名和嵌套结构仍可能与提示词不一致
+var extractionFormat = &openai.ResponseFormat{
+	Type: "json_schema",
+	JSONSchema: &openai.ResponseFormatJSONSchema{
+		Name:   "lab_results",
+		Strict: true,
+		Schema: &openai.ResponseFormatJSONSchemaProperty{
+			Type: "object",
+			Properties: map[string]*openai.ResponseFormatJSONSchemaProperty{
+				"results": {
+					Type: "array",
+					Items: &openai.ResponseFormatJSONSchemaProperty{
+						Type: "object",
+						Properties: map[string]*openai.ResponseFormatJSONSchemaProperty{
+							"name":            {Type: "string", Description: "检验项目名称"},
+							"value":           {Type: "string", Description: "检验结果数值"},
+							"unit":            {Type: "string", Description: "单位，没有时为空字符串"},
+							"reference_range": {Type: "string", Description: "参考范围，没有时为空字符串"},
+							"sampled_at":      {Type: "string", Description: "采样日期，格式为 YYYY-MM-DD，没有时为空字符串"},
+						},
+						Required: []string{"name", "value", "unit", "reference_range", "sampled_at"},
+					},
+				},
+			},
+			Required: []string{"results"},
+		},
+	},
+}
+
+// Extractor 使用大模型从文档文本中提取检验结果
+type Extractor struct {
+	llm llms.Model
+}
+
+func NewExtractor() (*Extractor, error) {
+	llm, err := openai.New(
+		openai.WithModel(extractionModel),
+		openai.WithToken(config.Cfg.Model.APIKey),
+		openai.WithBaseURL(chat.BaseURL),
+		openai.WithHTTPClient(utils.DefaultHTTPClient()),
+		openai.WithResponseFormat(extractionFormat),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error creating extraction model: %v", err)
+	}
+
+	return &Extractor{llm: llm}, nil
+}
+
+// LooksLikeLabReport 判断文本是否可能包含检验结果
+func LooksLikeLabReport(text string) bool {
+	return labReportHintRegex.MatchString(text)
+}
+
+// Extract 分段提取文本中的检验结果，各段结果合并去重
+func (e *Extractor) Extract(ctx context.Context, text string) ([]ExtractedResult, error) {
+	seen := make(map[ExtractedResult]bool)
+	var results []ExtractedResult
+	for _, segment := range splitSegments(text, maxSegmentLength) {
+		extracted, err := e.extractSegment(ctx, segment)
+		if err != nil {
+			return nil, err
+		}
+		for _, result := range extracted {
+			if strings.TrimSpace(result.Name) == "" || strings.TrimSpace(result.Value) == "" || seen[result] {
+				continue
+			}
+			seen[result] = true
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+func (e *Extractor) extractSegment(ctx context.Context, text string) ([]ExtractedResult, error) {
+	var prompt bytes.Buffer
+	if err := extractionTemplate.Execute(&prompt, map[string]string{"Text": text}); err != nil {
+		return nil, fmt.Errorf("error executing template: %v", err)
+	}
+
+	resp, err := e.llm.GenerateContent(ctx, []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeHuman, prompt.String()),
+	}, llms.WithTemperature(0))
+	if err != nil {
+		return nil, fmt.Errorf("error generating content: %v", err)
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("empty response from extraction model")
+	}
+
+	var parsed extractionResponse
+	if err := json.Unmarshal([]byte(trimCodeFence(resp.Choices[0].Content)), &parsed); err != nil {
+		return nil, fmt.Errorf("error parsing extraction result: %v", err)
+	}
+	return parsed.Results, nil
+}
+
+// trimCodeFence 去除模型输出中可能包含的 ```json 代码块标记
+func trimCodeFence(content string) string {
+	content = strings.TrimSpace(content)
+	content = strings.TrimPrefix(content, "```json")
+	content = strings.TrimPrefix(content, "```")
+	content = strings.TrimSuffix(content, "```")
+	return strings.TrimSpace(content)
+}
+
+// splitSegments 按行将文本切分为不超过 maxLength 个字符的片段，避免切断同一行的检验结果
+func splitSegments(text string, maxLength int) []string {
+	var (
+		segments []string
+		current  strings.Builder
+		length   int
+	)
+	for _, line := range strings.Split(text, "\n") {
+		lineLength := utf8.RuneCountInString(line) + 1
+		if length > 0 && length+lineLength > maxLength {
+			segments = append(segments, current.String())
+			current.Reset()
+			length = 0
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		length += lineLength
+	}
+	if strings.TrimSpace(current.String()) != "" {
+		segments = append(segments, current.String())
+	}
+	return segments
+}
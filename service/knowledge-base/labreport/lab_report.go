@@ -0,0 +1,79 @@
+package labreport
+
+import (
+	"diabetes-agent-backend/dao"
+	"diabetes-agent-backend/model"
+	"fmt"
+	"strings"
+)
+
+// Source 化验结果的来源知识文件
+type Source struct {
+	UserEmail   string
+	DocumentID  string
+	KnowledgeID uint
+}
+
+// Normalize 将提取结果转换为化验结果记录，识别检验项目并换算为标准单位
+// 无法识别的项目或单位保留原始结果
+func Normalize(extracted []ExtractedResult, source Source) []model.LabResult {
+	results := make([]model.LabResult, 0, len(extracted))
+	for _, item := range extracted {
+		value := parseNumber(item.Value)
+		low, high := parseReferenceRange(item.ReferenceRange)
+
+		result := model.LabResult{
+			UserEmail:      source.UserEmail,
+			DocumentID:     source.DocumentID,
+			KnowledgeID:    source.KnowledgeID,
+			Name:           strings.TrimSpace(item.Name),
+			Value:          value,
+			Unit:           strings.TrimSpace(item.Unit),
+			RawValue:       strings.TrimSpace(item.Value),
+			RawUnit:        strings.TrimSpace(item.Unit),
+			ReferenceLow:   low,
+			ReferenceHigh:  high,
+			ReferenceRange: strings.TrimSpace(item.ReferenceRange),
+			SampledAt:      parseDate(item.SampledAt),
+		}
+
+		if spec := lookupAnalyte(item.Name); spec != nil {
+			result.Analyte = spec.code
+			if converted, ok := spec.convert(value, item.Unit); ok {
+				result.Value = converted
+				result.Unit = spec.unit
+				// 参考范围与结果使用相同单位
+				result.ReferenceLow, _ = spec.convert(low, item.Unit)
+				result.ReferenceHigh, _ = spec.convert(high, item.Unit)
+			}
+		}
+
+		results = append(results, result)
+	}
+	return results
+}
+
+// SaveLabResults 保存知识文件最新处理版本的化验结果，替换之前版本的结果
+func SaveLabResults(documentID string, results []model.LabResult) error {
+	if err := dao.ReplaceLabResults(documentID, results); err != nil {
+		return fmt.Errorf("failed to replace lab results: %v", err)
+	}
+	return nil
+}
+
+// GetLabResults 查询用户的化验结果，analyte 为检验项目名称或代码
+func GetLabResults(email, analyte, documentID string) ([]model.LabResult, error) {
+	if analyte != "" {
+		if spec := lookupAnalyte(analyte); spec != nil {
+			analyte = spec.code
+		} else {
+			analyte = strings.ToUpper(analyte)
+		}
+	}
+
+	results, err := dao.GetLabResults(email, analyte, documentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lab results: %v", err)
+	}
+	return results, nil
+}
@@ -0,0 +1,269 @@
+package labreport
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 标准化后的检验项目代码
+const (
+	AnalyteHbA1c          = "HBA1C"
+	AnalyteFastingGlucose = "FPG"
+	AnalytePostprandial   = "PPG"
+	AnalyteGlucose        = "GLU"
+	AnalyteCreatinine     = "CREA"
+	AnalyteEGFR           = "EGFR"
+	AnalyteUricAcid       = "UA"
+	AnalyteUACR           = "UACR"
+	AnalyteTotalChol      = "TC"
+	AnalyteTriglycerides  = "TG"
+	AnalyteLDL            = "LDLC"
+	AnalyteHDL            = "HDLC"
+)
+
+// analyteSpec 检验项目的别名、标准单位以及其他单位到标准单位的换算
+type analyteSpec struct {
+	code    string
+	unit    string
+	aliases []string
+
+	// key 为 normalizeUnit 处理后的单位
+	conversions map[string]func(float64) float64
+}
+
+func identity(v float64) float64 { return v }
+
+func divide(factor float64) func(float64) float64 {
+	return func(v float64) float64 { return v / factor }
+}
+
+func multiply(factor float64) func(float64) float64 {
+	return func(v float64) float64 { return v * factor }
+}
+
+var (
+	// 葡萄糖 1 mmol/L = 18.016 mg/dL
+	glucoseConversions = map[string]func(float64) float64{
+		"mmol/l": identity,
+		"mg/dl":  divide(18.016),
+	}
+
+	// 胆固醇 1 mmol/L = 38.67 mg/dL
+	cholesterolConversions = map[string]func(float64) float64{
+		"mmol/l": identity,
+		"mg/dl":  divide(38.67),
+	}
+)
+
+var analyteSpecs = []analyteSpec{
+	{
+		code:    AnalyteHbA1c,
+		unit:    "%",
+		aliases: []string{"hba1c", "a1c", "糖化血红蛋白", "糖化血红蛋白a1c", "glycatedhemoglobin", "glycatedhaemoglobin"},
+		conversions: map[string]func(float64) float64{
+			"%": identity,
+			// IFCC 单位换算为 NGSP 百分比
+			"mmol/mol": func(v float64) float64 { return v/10.929 + 2.15 },
+		},
+	},
+	{
+		code:        AnalyteFastingGlucose,
+		unit:        "mmol/L",
+		aliases:     []string{"fpg", "fbg", "空腹血糖", "空腹葡萄糖", "空腹血浆葡萄糖", "fastingglucose", "fastingplasmaglucose", "fastingbloodglucose"},
+		conversions: glucoseConversions,
+	},
+	{
+		code:        AnalytePostprandial,
+		unit:        "mmol/L",
+		aliases:     []string{"ppg", "2hpg", "2hpbg", "餐后2小时血糖", "餐后两小时血糖", "餐后2h血糖", "餐后血糖", "postprandialglucose"},
+		conversions: glucoseConversions,
+	},
+	{
+		code:        AnalyteGlucose,
+		unit:        "mmol/L",
+		aliases:     []string{"glu", "glucose", "葡萄糖", "血糖", "血浆葡萄糖"},
+		conversions: glucoseConversions,
+	},
+	{
+		code:    AnalyteCreatinine,
+		unit:    "μmol/L",
+		aliases: []string{"crea", "cr", "scr", "creatinine", "肌酐", "血肌酐", "血清肌酐"},
+		conversions: map[string]func(float64) float64{
+			"umol/l": identity,
+			"mg/dl":  multiply(88.4),
+		},
+	},
+	{
+		code:    AnalyteEGFR,
+		unit:    "mL/min/1.73m²",
+		aliases: []string{"egfr", "估算肾小球滤过率", "肾小球滤过率", "估算的肾小球滤过率"},
+		conversions: map[string]func(float64) float64{
+			"ml/min/1.73m2": identity,
+			"ml/min/1.73m²": identity,
+			"ml/min":        identity,
+		},
+	},
+	{
+		code:    AnalyteUricAcid,
+		unit:    "μmol/L",
+		aliases: []string{"ua", "uricacid", "尿酸", "血尿酸"},
+		conversions: map[string]func(float64) float64{
+			"umol/l": identity,
+			"mg/dl":  multiply(59.48),
+		},
+	},
+	{
+		code:    AnalyteUACR,
+		unit:    "mg/g",
+		aliases: []string{"uacr", "acr", "尿白蛋白/肌酐比值", "尿微量白蛋白/肌酐比值", "尿白蛋白肌酐比值", "尿微量白蛋白肌酐比值"},
+		conversions: map[string]func(float64) float64{
+			"mg/g":    identity,
+			"mg/mmol": multiply(8.84),
+		},
+	},
+	{
+		code:        AnalyteTotalChol,
+		unit:        "mmol/L",
+		aliases:     []string{"tc", "cho", "chol", "totalcholesterol", "总胆固醇", "血清总胆固醇"},
+		conversions: cholesterolConversions,
+	},
+	{
+		code:    AnalyteTriglycerides,
+		unit:    "mmol/L",
+		aliases: []string{"tg", "trig", "triglyceride", "triglycerides", "甘油三酯", "甘油三脂"},
+		conversions: map[string]func(float64) float64{
+			"mmol/l": identity,
+			// 甘油三酯 1 mmol/L = 88.57 mg/dL
+			"mg/dl": divide(88.57),
+		},
+	},
+	{
+		code:        AnalyteLDL,
+		unit:        "mmol/L",
+		aliases:     []string{"ldl", "ldl-c", "ldlc", "低密度脂蛋白", "低密度脂蛋白胆固醇", "低密度脂蛋白-胆固醇"},
+		conversions: cholesterolConversions,
+	},
+	{
+		code:        AnalyteHDL,
+		unit:        "mmol/L",
+		aliases:     []string{"hdl", "hdl-c", "hdlc", "高密度脂蛋白", "高密度脂蛋白胆固醇", "高密度脂蛋白-胆固醇"},
+		conversions: cholesterolConversions,
+	},
+}
+
+// 项目别名到检验项目的索引
+var analyteIndex = func() map[string]*analyteSpec {
+	index := make(map[string]*analyteSpec)
+	for i := range analyteSpecs {
+		for _, alias := range analyteSpecs[i].aliases {
+			index[normalizeName(alias)] = &analyteSpecs[i]
+		}
+	}
+	return index
+}()
+
+var (
+	// 匹配项目名称中的括号内容，如 "葡萄糖(GLU)"
+	parenthesesRegex = regexp.MustCompile(`[（(]([^)）]*)[)）]`)
+
+	// 匹配结果中的第一个数值
+	numberRegex = regexp.MustCompile(`[-+]?\d+(?:\.\d+)?`)
+
+	// 匹配 "3.9-6.1"、"3.9~6.1"、"3.9—6.1" 形式的参考范围
+	rangeRegex = regexp.MustCompile(`([-+]?\d+(?:\.\d+)?)\s*[-~～—–至]+\s*([-+]?\d+(?:\.\d+)?)`)
+)
+
+func normalizeName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = strings.NewReplacer(" ", "", "　", "", "_", "", "·", "").Replace(name)
+	return name
+}
+
+func normalizeUnit(unit string) string {
+	unit = strings.ToLower(strings.TrimSpace(unit))
+	unit = strings.NewReplacer(" ", "", "µ", "u", "μ", "u", "／", "/").Replace(unit)
+	return unit
+}
+
+// lookupAnalyte 根据项目名称查找检验项目，依次尝试完整名称、括号外名称和括号内名称
+func lookupAnalyte(name string) *analyteSpec {
+	candidates := []string{name, parenthesesRegex.ReplaceAllString(name, "")}
+	for _, matches := range parenthesesRegex.FindAllStringSubmatch(name, -1) {
+		candidates = append(candidates, matches[1])
+	}
+
+	for _, candidate := range candidates {
+		if spec, ok := analyteIndex[normalizeName(candidate)]; ok {
+			return spec
+		}
+	}
+	return nil
+}
+
+// parseNumber 解析结果中的数值，忽略 "<"、"↑" 等修饰符号
+func parseNumber(value string) *float64 {
+	match := numberRegex.FindString(value)
+	if match == "" {
+		return nil
+	}
+	v, err := strconv.ParseFloat(match, 64)
+	if err != nil {
+		return nil
+	}
+	return &v
+}
+
+// parseReferenceRange 解析参考范围，支持 "a-b"、"<b"、">a" 等形式
+func parseReferenceRange(reference string) (low, high *float64) {
+	reference = strings.TrimSpace(reference)
+	if matches := rangeRegex.FindStringSubmatch(reference); matches != nil {
+		return parseNumber(matches[1]), parseNumber(matches[2])
+	}
+
+	switch {
+	case strings.HasPrefix(reference, "<"), strings.HasPrefix(reference, "≤"), strings.HasPrefix(reference, "＜"):
+		return nil, parseNumber(reference)
+	case strings.HasPrefix(reference, ">"), strings.HasPrefix(reference, "≥"), strings.HasPrefix(reference, "＞"):
+		return parseNumber(reference), nil
+	}
+	return nil, nil
+}
+
+// 化验单中常见的日期格式
+var dateLayouts = []string{
+	time.DateTime,
+	"2006-01-02 15:04",
+	time.DateOnly,
+	"2006/01/02 15:04:05",
+	"2006/01/02 15:04",
+	"2006/01/02",
+	"2006.01.02",
+	"2006年01月02日",
+	"2006年1月2日",
+}
+
+func parseDate(date string) *time.Time {
+	date = strings.TrimSpace(date)
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, date, time.Local); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
+
+// convert 将数值换算为标准单位并保留两位小数，无法换算时返回 false
+func (s *analyteSpec) convert(v *float64, unit string) (*float64, bool) {
+	conversion, ok := s.conversions[normalizeUnit(unit)]
+	if !ok {
+		return v, false
+	}
+	if v == nil {
+		return nil, true
+	}
+	converted := math.Round(conversion(*v)*100) / 100
+	return &converted, true
+}
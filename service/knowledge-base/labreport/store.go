@@ -0,0 +1,48 @@
+package labreport
+
+import (
+	"diabetes-agent-backend/model"
+	"slices"
+	"sync"
+)
+
+// ResultStore 保存知识文件的化验结果
+type ResultStore interface {
+	// SaveLabResults 替换知识文件之前版本的化验结果
+	SaveLabResults(documentID string, results []model.LabResult) error
+}
+
+// DBResultStore 将化验结果保存在 lab_result 表中
+type DBResultStore struct{}
+
+var _ ResultStore = DBResultStore{}
+
+func (DBResultStore) SaveLabResults(documentID string, results []model.LabResult) error {
+	return SaveLabResults(documentID, results)
+}
+
+// MemoryResultStore 进程内的化验结果，用于测试 ETL 流程
+type MemoryResultStore struct {
+	mu      sync.Mutex
+	results map[string][]model.LabResult
+}
+
+var _ ResultStore = &MemoryResultStore{}
+
+func NewMemoryResultStore() *MemoryResultStore {
+	return &MemoryResultStore{results: make(map[string][]model.LabResult)}
+}
+
+func (s *MemoryResultStore) SaveLabResults(documentID string, results []model.LabResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.results[documentID] = slices.Clone(results)
+	return nil
+}
+
+// Results 返回知识文件当前保存的化验结果
+func (s *MemoryResultStore) Results(documentID string) []model.LabResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.results[documentID])
+}
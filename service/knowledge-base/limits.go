@@ -0,0 +1,33 @@
+package knowledgebase
+
+import (
+	"diabetes-agent-backend/config"
+	"diabetes-agent-backend/model"
+	"errors"
+	"fmt"
+)
+
+// 未配置时知识文件的默认大小上限
+const defaultMaxFileSize = 50 << 20
+
+// ErrFileTooLarge 知识文件超过大小上限，重试也无法处理成功
+var ErrFileTooLarge = errors.New("file too large")
+
+// MaxFileSize 返回指定文件类型的大小上限（字节）
+func MaxFileSize(fileType model.FileType) int64 {
+	if size, ok := config.Cfg.KnowledgeBase.MaxFileSizeByType[string(fileType)]; ok && size > 0 {
+		return size
+	}
+	if config.Cfg.KnowledgeBase.MaxFileSize > 0 {
+		return config.Cfg.KnowledgeBase.MaxFileSize
+	}
+	return defaultMaxFileSize
+}
+
+// CheckFileSize 检查文件大小是否超过上限，超过时返回包装 ErrFileTooLarge 的错误
+func CheckFileSize(fileType model.FileType, size int64) error {
+	if limit := MaxFileSize(fileType); size > limit {
+		return fmt.Errorf("%w: %s file of %d bytes exceeds limit of %d bytes", ErrFileTooLarge, fileType, size, limit)
+	}
+	return nil
+}
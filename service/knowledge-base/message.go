@@ -0,0 +1,93 @@
+package knowledgebase
+
+import (
+	"diabetes-agent-backend/dao"
+	"diabetes-agent-backend/model"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 知识库 MQ 消息的 topic 和 tag
+const (
+	TopicKnowledgeBase = "topic_knowledge_base"
+	TagETL             = "tag_etl"
+	TagDelete          = "tag_delete"
+)
+
+// ETLMessage 知识文件版本的向量化任务
+type ETLMessage struct {
+	DocumentID  string         `json:"document_id"`
+	KnowledgeID uint           `json:"knowledge_id"`
+	UserEmail   string         `json:"user_email"`
+	FileName    string         `json:"file_name"`
+	FileType    model.FileType `json:"file_type"`
+	ObjectName  string         `json:"object_name"`
+
+	// 版本记录的切分配置，为空时使用处理器的默认切分方式
+	Chunking model.Chunking `json:"chunking"`
+}
+
+// DeleteMessage 删除知识文件OSS文件和向量存储的任务
+type DeleteMessage struct {
+	DocumentID string         `json:"document_id"`
+	FileType   model.FileType `json:"file_type"`
+
+	// 知识文件所有版本在OSS上的路径
+	ObjectNames []string `json:"object_names"`
+}
+
+// enqueueETLMessage 在事务中写入知识文件版本的向量化任务，事务提交后由 outbox relay 投递
+// 每次写入使用新的幂等键，重复投递的同一任务只处理一次，手动重新处理仍会生成新任务
+func enqueueETLMessage(tx *gorm.DB, metadata *model.KnowledgeMetadata) error {
+	key := fmt.Sprintf("%s:%d:%s", TagETL, metadata.ID, uuid.New().String())
+	return enqueueMessage(tx, metadata.UserEmail, metadata.DocumentID, TagETL, key, ETLMessage{
+		DocumentID:  metadata.DocumentID,
+		KnowledgeID: metadata.ID,
+		UserEmail:   metadata.UserEmail,
+		FileName:    metadata.FileName,
+		FileType:    metadata.FileType,
+		ObjectName:  metadata.ObjectName,
+		Chunking:    metadata.Chunking,
+	})
+}
+
+// enqueueDeleteMessage 在事务中写入清理知识文件所有版本的任务，同一知识文件只需清理一次
+func enqueueDeleteMessage(tx *gorm.DB, versions []model.KnowledgeMetadata) error {
+	objectNames := make([]string, 0, len(versions))
+	for _, version := range versions {
+		objectNames = append(objectNames, version.ObjectName)
+	}
+
+	key := fmt.Sprintf("%s:%s", TagDelete, versions[0].DocumentID)
+	return enqueueMessage(tx, versions[0].UserEmail, versions[0].DocumentID, TagDelete, key, DeleteMessage{
+		DocumentID:  versions[0].DocumentID,
+		FileType:    versions[0].FileType,
+		ObjectNames: objectNames,
+	})
+}
+
+func enqueueMessage(tx *gorm.DB, email, documentID, tag, key string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %v", err)
+	}
+
+	err = dao.CreateOutboxMessage(tx, &model.OutboxMessage{
+		UserEmail:      email,
+		DocumentID:     documentID,
+		Topic:          TopicKnowledgeBase,
+		Tag:            tag,
+		IdempotencyKey: key,
+		Payload:        string(data),
+		Status:         model.OutboxStatusPending,
+		NextAttemptAt:  time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create outbox message: %v", err)
+	}
+	return nil
+}
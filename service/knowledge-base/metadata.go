@@ -4,43 +4,340 @@ import (
 	"diabetes-agent-backend/dao"
 	"diabetes-agent-backend/model"
 	"diabetes-agent-backend/request"
+	"errors"
 	"fmt"
 	"log/slog"
-	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidUpload 上传的知识文件或切分配置不满足要求，错误信息可以直接返回给用户
+var ErrInvalidUpload = errors.New("invalid knowledge file upload")
+
+var (
+	// ErrKnowledgeNotFound 知识文件或指定版本不存在
+	ErrKnowledgeNotFound = errors.New("knowledge file not found")
+
+	// ErrKnowledgeConflict 版本的当前状态不允许该操作，例如回滚到当前生效的版本
+	ErrKnowledgeConflict = errors.New("knowledge version conflict")
 )
 
-func UploadKnowledgeMetadata(req request.UploadKnowledgeMetadataRequest, email string) error {
+func UploadKnowledgeMetadata(req request.UploadKnowledgeMetadataRequest, email string) (*model.KnowledgeMetadata, error) {
+	// 不支持的文件类型和超过大小上限的文件无法处理，在保存元数据前拒绝
+	if !model.FileType(req.FileType).IsSupported() {
+		return nil, fmt.Errorf("%w: unsupported file type: %s", ErrInvalidUpload, req.FileType)
+	}
+	if err := CheckFileSize(model.FileType(req.FileType), req.FileSize); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidUpload, err)
+	}
+
 	// 检查文件是否已经上传过
-	exists, err := dao.GetKnowledgeMetadataByEmailAndFileName(email, req.FileName)
+	latest, err := dao.GetKnowledgeMetadataByEmailAndFileName(email, req.FileName)
 	if err != nil {
-		return fmt.Errorf("failed to get knowledge metadata: %v", err)
+		return nil, fmt.Errorf("failed to get knowledge metadata: %v", err)
+	}
+
+	if latest == nil {
+		chunking, err := ResolveChunking(model.FileType(req.FileType), req.Chunking, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		return saveKnowledgeVersion(req, email, "", 1, chunking)
+	}
+
+	if req.UploadMode != request.UploadModeReplace {
+		return nil, fmt.Errorf("%w: file already exists", ErrInvalidUpload)
+	}
+
+	// 新版本必须上传到新的对象路径，否则会覆盖历史版本的文件，导致无法回滚
+	versions, err := dao.GetKnowledgeMetadataVersions(email, latest.DocumentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get knowledge metadata versions: %v", err)
+	}
+	for _, version := range versions {
+		if version.ObjectName == req.ObjectName {
+			return nil, fmt.Errorf("%w: object %s is already used by version %d", ErrInvalidUpload, req.ObjectName, version.Version)
+		}
+	}
+
+	// 文件类型变化时上一版本的切分配置不再适用
+	var previous *model.Chunking
+	if latest.FileType == model.FileType(req.FileType) {
+		previous = &latest.Chunking
+	}
+	chunking, err := ResolveChunking(model.FileType(req.FileType), req.Chunking, previous)
+	if err != nil {
+		return nil, err
+	}
+
+	return saveKnowledgeVersion(req, email, latest.DocumentID, latest.Version+1, chunking)
+}
+
+// saveKnowledgeVersion 保存知识文件版本，并在同一事务中写入向量化任务
+func saveKnowledgeVersion(req request.UploadKnowledgeMetadataRequest, email, documentID string,
+	version int, chunking model.Chunking) (*model.KnowledgeMetadata, error) {
+	var metadata *model.KnowledgeMetadata
+	err := dao.Transaction(func(tx *gorm.DB) error {
+		var err error
+		metadata, err = dao.SaveKnowledgeMetadata(tx, req, email, documentID, version, chunking)
+		if err != nil {
+			return fmt.Errorf("failed to save knowledge metadata: %v", err)
+		}
+		return enqueueETLMessage(tx, metadata)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return metadata, nil
+}
+
+// resetKnowledgeVersion 重置知识文件版本的处理状态，并在同一事务中写入向量化任务
+func resetKnowledgeVersion(metadata *model.KnowledgeMetadata) error {
+	err := dao.Transaction(func(tx *gorm.DB) error {
+		if err := dao.ResetKnowledgeMetadataProgress(tx, metadata.ID); err != nil {
+			return fmt.Errorf("failed to reset knowledge metadata progress: %v", err)
+		}
+		return enqueueETLMessage(tx, metadata)
+	})
+	if err != nil {
+		return err
+	}
+
+	metadata.Status = model.StatusUploaded
+	return nil
+}
+
+func UpdateKnowledgeMetadataStatus(knowledgeID uint, status model.Status) error {
+	err := dao.UpdateKnowledgeMetadataStatus(knowledgeID, status)
+	if err != nil {
+		slog.Error("failed to update knowledge metadata",
+			"knowledge_id", knowledgeID,
+			"err", err,
+		)
+		return err
+	}
+
+	return nil
+}
+
+// UpdateKnowledgeProgress 记录知识文件的处理阶段和进度
+func UpdateKnowledgeProgress(knowledgeID uint, stage model.Stage, progress, chunkCount int) error {
+	err := dao.UpdateKnowledgeMetadataProgress(knowledgeID, stage, progress, chunkCount)
+	if err != nil {
+		slog.Error("failed to update knowledge metadata progress",
+			"knowledge_id", knowledgeID,
+			"stage", stage,
+			"err", err,
+		)
+		return err
+	}
+
+	return nil
+}
+
+// GetKnowledgeCheckpoint 获取知识文件版本的切片指纹和已写入向量存储的切片数量
+func GetKnowledgeCheckpoint(knowledgeID uint) (string, int, error) {
+	metadata, err := dao.GetKnowledgeMetadataByID(knowledgeID)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to get knowledge metadata: %v", err)
+	}
+	if metadata == nil {
+		return "", 0, fmt.Errorf("knowledge metadata not found: %d", knowledgeID)
+	}
+
+	return metadata.ChunkFingerprint, metadata.CheckpointChunks, nil
+}
+
+// SaveKnowledgeCheckpoint 记录已写入向量存储的切片数量
+func SaveKnowledgeCheckpoint(knowledgeID uint, fingerprint string, checkpoint int) error {
+	if err := dao.UpdateKnowledgeMetadataCheckpoint(knowledgeID, fingerprint, checkpoint); err != nil {
+		return fmt.Errorf("failed to save knowledge checkpoint: %v", err)
+	}
+	return nil
+}
+
+// UpdateKnowledgeQuality 记录知识文件的文本提取质量
+func UpdateKnowledgeQuality(knowledgeID uint, quality model.ExtractionQuality) error {
+	err := dao.UpdateKnowledgeMetadataQuality(knowledgeID, quality)
+	if err != nil {
+		slog.Error("failed to update knowledge metadata quality",
+			"knowledge_id", knowledgeID,
+			"quality", quality,
+			"err", err,
+		)
+		return err
 	}
-	if exists != nil {
-		return fmt.Errorf("file already exists")
+
+	return nil
+}
+
+// RecordKnowledgeError 记录最近一次处理失败的原因，状态保持不变，等待 MQ 重试
+func RecordKnowledgeError(knowledgeID uint, reason string) error {
+	err := dao.UpdateKnowledgeMetadataError(knowledgeID, reason)
+	if err != nil {
+		slog.Error("failed to record knowledge metadata error",
+			"knowledge_id", knowledgeID,
+			"err", err,
+		)
+		return err
 	}
 
-	err = dao.SaveKnowledgeMetadata(req, email)
+	return nil
+}
+
+// MarkKnowledgeFailed 重试次数耗尽后标记知识文件处理失败
+func MarkKnowledgeFailed(knowledgeID uint, reason string) error {
+	err := dao.MarkKnowledgeMetadataFailed(knowledgeID, reason)
 	if err != nil {
-		return fmt.Errorf("failed to save knowledge metadata: %v", err)
+		slog.Error("failed to mark knowledge metadata failed",
+			"knowledge_id", knowledgeID,
+			"err", err,
+		)
+		return err
 	}
 
 	return nil
 }
 
-func UpdateKnowledgeMetadataStatus(objectName string, status model.Status) error {
-	pathSegments := strings.Split(objectName, "/")
-	if len(pathSegments) < 2 {
-		return fmt.Errorf("invalid object name: %s", objectName)
+// MarkKnowledgeDeadLettered 在 ETL 消息进入死信队列后调用，以最近一次记录的失败原因标记处理失败
+// 已处理成功的版本（例如死信到达前已被手动重新处理）不受影响
+func MarkKnowledgeDeadLettered(knowledgeID uint) error {
+	metadata, err := dao.GetKnowledgeMetadataByID(knowledgeID)
+	if err != nil {
+		return fmt.Errorf("failed to get knowledge metadata: %v", err)
+	}
+	if metadata == nil || metadata.Status == model.StatusProcessed {
+		return nil
 	}
 
-	userEmail := pathSegments[0]
-	fileName := pathSegments[len(pathSegments)-1]
+	reason := metadata.ErrorMessage
+	if reason == "" {
+		reason = "ETL retries exhausted"
+	}
 
-	err := dao.UpdateKnowledgeMetadataStatus(userEmail, fileName, status)
+	return MarkKnowledgeFailed(knowledgeID, reason)
+}
+
+// GetKnowledgeStatus 获取知识文件的处理状态，version 为 0 时返回最近更新的版本
+func GetKnowledgeStatus(email, documentID string, version int) (*model.KnowledgeMetadata, error) {
+	var (
+		metadata *model.KnowledgeMetadata
+		err      error
+	)
+	if version > 0 {
+		metadata, err = dao.GetKnowledgeMetadataVersion(email, documentID, version)
+	} else {
+		metadata, err = dao.GetLatestUpdatedKnowledgeMetadata(email, documentID)
+	}
 	if err != nil {
-		slog.Error("failed to update knowledge metadata", "err", err)
+		return nil, fmt.Errorf("failed to get knowledge metadata: %v", err)
+	}
+	if metadata == nil {
+		return nil, fmt.Errorf("%w: document %s", ErrKnowledgeNotFound, documentID)
+	}
+
+	return metadata, nil
+}
+
+// ActivateKnowledgeVersion 在知识文件版本向量化完成后调用，将其设置为当前版本
+func ActivateKnowledgeVersion(knowledgeID uint) error {
+	err := dao.ActivateKnowledgeMetadataVersion(knowledgeID)
+	if err != nil {
+		slog.Error("failed to activate knowledge metadata version",
+			"knowledge_id", knowledgeID,
+			"err", err,
+		)
 		return err
 	}
 
 	return nil
 }
+
+// GetActiveKnowledgeVersionIDs 返回用户知识文件当前生效版本的 ID，检索时只返回这些版本的切片
+func GetActiveKnowledgeVersionIDs(email string, documentIDs []string) ([]uint, error) {
+	ids, err := dao.GetActiveKnowledgeMetadataIDs(email, documentIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active knowledge versions: %v", err)
+	}
+	return ids, nil
+}
+
+// DeleteKnowledgeMetadata 删除知识文件所有版本的元数据，并写入清理OSS文件和向量存储的任务
+func DeleteKnowledgeMetadata(email, fileName string) error {
+	latest, err := dao.GetKnowledgeMetadataByEmailAndFileName(email, fileName)
+	if err != nil {
+		return fmt.Errorf("failed to get knowledge metadata: %v", err)
+	}
+	if latest == nil {
+		return fmt.Errorf("%w: %s", ErrKnowledgeNotFound, fileName)
+	}
+
+	versions, err := dao.GetKnowledgeMetadataVersions(email, latest.DocumentID)
+	if err != nil {
+		return fmt.Errorf("failed to get knowledge metadata versions: %v", err)
+	}
+
+	// 删除元数据和写入清理任务在同一事务中完成，OSS 文件和向量存储不会因为消息丢失而残留
+	return dao.Transaction(func(tx *gorm.DB) error {
+		if err := dao.DeleteKnowledgeMetadataByDocumentID(tx, latest.DocumentID); err != nil {
+			return fmt.Errorf("failed to delete knowledge metadata: %v", err)
+		}
+		if err := dao.DeleteLabResultsByDocumentID(tx, latest.DocumentID); err != nil {
+			return fmt.Errorf("failed to delete lab results: %v", err)
+		}
+		return enqueueDeleteMessage(tx, versions)
+	})
+}
+
+func GetKnowledgeVersions(email, documentID string) ([]model.KnowledgeMetadata, error) {
+	versions, err := dao.GetKnowledgeMetadataVersions(email, documentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get knowledge metadata versions: %v", err)
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("%w: document %s", ErrKnowledgeNotFound, documentID)
+	}
+
+	return versions, nil
+}
+
+// RollbackKnowledgeVersion 回滚到历史版本
+// 被替换的版本不保留向量存储，需要重新执行 ETL，处理成功后再替换当前版本
+func RollbackKnowledgeVersion(email, documentID string, version int) (*model.KnowledgeMetadata, error) {
+	metadata, err := dao.GetKnowledgeMetadataVersion(email, documentID, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get knowledge metadata version: %v", err)
+	}
+	if metadata == nil {
+		return nil, fmt.Errorf("%w: version %d of document %s", ErrKnowledgeNotFound, version, documentID)
+	}
+	if !metadata.Superseded && metadata.Status == model.StatusProcessed {
+		return nil, fmt.Errorf("%w: version %d is already active", ErrKnowledgeConflict, version)
+	}
+
+	if err := resetKnowledgeVersion(metadata); err != nil {
+		return nil, err
+	}
+
+	return metadata, nil
+}
+
+// ReprocessKnowledge 重置知识文件版本的处理状态，用于重新发送向量化任务
+// version 为 0 时重新处理最近更新的版本，已被替换的历史版本需通过回滚重新处理
+func ReprocessKnowledge(email, documentID string, version int) (*model.KnowledgeMetadata, error) {
+	metadata, err := GetKnowledgeStatus(email, documentID, version)
+	if err != nil {
+		return nil, err
+	}
+	if metadata.Superseded {
+		return nil, fmt.Errorf("%w: version %d is superseded, rollback instead", ErrKnowledgeConflict, metadata.Version)
+	}
+
+	if err := resetKnowledgeVersion(metadata); err != nil {
+		return nil, err
+	}
+
+	return metadata, nil
+}
@@ -0,0 +1,39 @@
+package ocr
+
+import (
+	"context"
+	"diabetes-agent-backend/config"
+	"fmt"
+)
+
+const (
+	// 使用视觉大模型识别文字
+	ProviderVision = "vision"
+
+	// 返回确定性结果的假实现，用于本地开发和测试
+	ProviderFake = "fake"
+)
+
+// Image 待识别的图片
+type Image struct {
+	Data     []byte
+	MIMEType string
+}
+
+// Engine 文字识别引擎
+type Engine interface {
+	// Recognize 识别图片中的文字，返回 Markdown 格式的文本
+	Recognize(ctx context.Context, image Image) (string, error)
+}
+
+// NewEngine 根据配置创建文字识别引擎，未配置时使用视觉大模型
+func NewEngine() (Engine, error) {
+	switch config.Cfg.Model.OCR.Provider {
+	case "", ProviderVision:
+		return NewVisionEngine(config.Cfg.Model.OCR.Name)
+	case ProviderFake:
+		return NewFakeEngine(), nil
+	default:
+		return nil, fmt.Errorf("unknown ocr provider: %s", config.Cfg.Model.OCR.Provider)
+	}
+}
@@ -0,0 +1,67 @@
+package ocr
+
+import (
+	"context"
+	"diabetes-agent-backend/config"
+	"diabetes-agent-backend/service/chat"
+	"diabetes-agent-backend/utils"
+	_ "embed"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/llms/openai"
+)
+
+const defaultVisionModel = "qwen-vl-ocr-latest"
+
+//go:embed prompts/ocr.txt
+var ocrPrompt string
+
+// VisionEngine 基于视觉大模型的文字识别引擎
+type VisionEngine struct {
+	llm llms.Model
+}
+
+var _ Engine = &VisionEngine{}
+
+func NewVisionEngine(modelName string) (*VisionEngine, error) {
+	if modelName == "" {
+		modelName = defaultVisionModel
+	}
+
+	llm, err := openai.New(
+		openai.WithModel(modelName),
+		openai.WithToken(config.Cfg.Model.APIKey),
+		openai.WithBaseURL(chat.BaseURL),
+		openai.WithHTTPClient(utils.DefaultHTTPClient()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error creating vision model: %v", err)
+	}
+
+	return &VisionEngine{llm: llm}, nil
+}
+
+func (e *VisionEngine) Recognize(ctx context.Context, image Image) (string, error) {
+	dataURL := fmt.Sprintf("data:%s;base64,%s", image.MIMEType, base64.StdEncoding.EncodeToString(image.Data))
+
+	resp, err := e.llm.GenerateContent(ctx, []llms.MessageContent{
+		{
+			Role: llms.ChatMessageTypeHuman,
+			Parts: []llms.ContentPart{
+				llms.ImageURLPart(dataURL),
+				llms.TextPart(ocrPrompt),
+			},
+		},
+	}, llms.WithTemperature(0))
+	if err != nil {
+		return "", fmt.Errorf("error recognizing image: %v", err)
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("empty response from vision model")
+	}
+
+	return strings.TrimSpace(resp.Choices[0].Content), nil
+}
@@ -0,0 +1,103 @@
+package retrieval
+
+import (
+	"context"
+	"diabetes-agent-backend/service/embedding"
+	knowledgebase "diabetes-agent-backend/service/knowledge-base"
+	"diabetes-agent-backend/service/knowledge-base/vectorstore"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/tmc/langchaingo/embeddings"
+)
+
+// 默认返回的切片数量
+const defaultTopK = 5
+
+var (
+	defaultRetriever     *Retriever
+	defaultRetrieverErr  error
+	defaultRetrieverOnce sync.Once
+)
+
+// VersionStore 查询知识文件当前生效的版本
+type VersionStore interface {
+	ActiveVersionIDs(email string, documentIDs []string) ([]uint, error)
+}
+
+// Retriever 检索用户知识库中与问题最相关的切片，只返回知识文件当前生效版本的切片
+type Retriever struct {
+	Embedder    embeddings.Embedder
+	VectorStore vectorstore.VectorStore
+	Versions    VersionStore
+}
+
+// Default 返回使用共享向量化服务和 Milvus 客户端的检索器
+func Default() (*Retriever, error) {
+	defaultRetrieverOnce.Do(func() {
+		spec := vectorstore.DefaultSpec()
+
+		embedder, err := embedding.Default()
+		if err != nil {
+			defaultRetrieverErr = fmt.Errorf("failed to create embedding service: %v", err)
+			return
+		}
+
+		milvusClient, err := vectorstore.DefaultClient(context.Background())
+		if err != nil {
+			defaultRetrieverErr = fmt.Errorf("failed to create milvus client: %v", err)
+			return
+		}
+
+		defaultRetriever = &Retriever{
+			Embedder:    embedder,
+			VectorStore: vectorstore.NewMilvusStore(milvusClient, vectorstore.CollectionName, int(spec.Dim)),
+			Versions:    knowledgebase.DBProcessingStore{},
+		}
+	})
+	return defaultRetriever, defaultRetrieverErr
+}
+
+// Search 返回用户知识库中与 query 最相似的 topK 个切片，documentIDs 为空时检索全部知识文件
+func (r *Retriever) Search(ctx context.Context, email, query string, topK int, documentIDs ...string) ([]vectorstore.SearchResult, error) {
+	if topK <= 0 {
+		topK = defaultTopK
+	}
+
+	// 未处理完成、处理失败或已被替换的版本可能残留部分切片，只检索当前生效的版本
+	knowledgeIDs, err := r.Versions.ActiveVersionIDs(email, documentIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(knowledgeIDs) == 0 {
+		return nil, nil
+	}
+
+	vector, err := r.Embedder.EmbedQuery(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %v", err)
+	}
+
+	results, err := r.VectorStore.Search(ctx, vector, topK, vectorstore.SearchFilter{
+		UserEmail:    email,
+		DocumentIDs:  documentIDs,
+		KnowledgeIDs: knowledgeIDs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search knowledge base: %v", err)
+	}
+	return results, nil
+}
+
+// FormatResults 将检索结果格式化为带引用来源的文本，Agent 回答时按编号引用
+func FormatResults(results []vectorstore.SearchResult) string {
+	var b strings.Builder
+	for i, result := range results {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		fmt.Fprintf(&b, "[%d] %s\n%s", i+1, result.Citation(), strings.TrimSpace(result.Text))
+	}
+	return b.String()
+}
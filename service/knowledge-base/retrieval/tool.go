@@ -0,0 +1,77 @@
+package retrieval
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/tmc/langchaingo/tools"
+)
+
+// 工具单次最多返回的切片数量
+const toolMaxTopK = 10
+
+// Tool 供 Agent 检索当前用户知识库的进程内工具，结果附带引用来源
+type Tool struct {
+	Email string
+
+	// 为空时使用 Default
+	Retriever *Retriever
+}
+
+var _ tools.Tool = &Tool{}
+
+func NewTool(email string) *Tool {
+	return &Tool{Email: email}
+}
+
+func (t *Tool) Name() string {
+	return "search_knowledge_base"
+}
+
+func (t *Tool) Description() string {
+	return `Search the user's uploaded knowledge files (lab reports, guidelines, notes) for passages relevant to a question. ` +
+		`Input is a JSON object: {"query": "HbA1c result in the latest lab report", "top_k": 5}; top_k is optional. ` +
+		`Each result starts with a numbered source such as "[1] report.pdf / 第3页" or "[2] guide.md / Diet > Carbs". ` +
+		`When answering from a result, cite its source so the user can find the original passage.`
+}
+
+type toolInput struct {
+	Query string `json:"query"`
+	TopK  int    `json:"top_k"`
+}
+
+// Call 输入有误时将错误信息作为结果返回，由 Agent 修正输入后重试
+func (t *Tool) Call(ctx context.Context, input string) (string, error) {
+	input = strings.TrimSpace(input)
+	input = strings.TrimPrefix(input, "```json")
+	input = strings.Trim(input, "`\n ")
+
+	var parsed toolInput
+	if err := json.Unmarshal([]byte(input), &parsed); err != nil {
+		// 兼容直接输入问题
+		parsed = toolInput{Query: input}
+	}
+	parsed.Query = strings.TrimSpace(parsed.Query)
+	if parsed.Query == "" {
+		return "Invalid input: query is required", nil
+	}
+	parsed.TopK = min(parsed.TopK, toolMaxTopK)
+
+	retriever := t.Retriever
+	if retriever == nil {
+		var err error
+		if retriever, err = Default(); err != nil {
+			return "", err
+		}
+	}
+
+	results, err := retriever.Search(ctx, t.Email, parsed.Query, parsed.TopK)
+	if err != nil {
+		return "", err
+	}
+	if len(results) == 0 {
+		return "No relevant passages found in the user's knowledge base.", nil
+	}
+	return FormatResults(results), nil
+}
@@ -0,0 +1,205 @@
+package knowledgebase
+
+import (
+	"diabetes-agent-backend/model"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// ProcessingStore 读写知识文件版本的处理状态，ETL 流程通过它记录进度、检查点和处理结果
+type ProcessingStore interface {
+	UpdateProgress(knowledgeID uint, stage model.Stage, progress, chunkCount int) error
+	UpdateQuality(knowledgeID uint, quality model.ExtractionQuality) error
+	GetCheckpoint(knowledgeID uint) (string, int, error)
+	SaveCheckpoint(knowledgeID uint, fingerprint string, checkpoint int) error
+
+	// ActivateVersion 将版本标记为处理完成的当前版本
+	ActivateVersion(knowledgeID uint) error
+
+	RecordError(knowledgeID uint, reason string) error
+	MarkFailed(knowledgeID uint, reason string) error
+	MarkDeadLettered(knowledgeID uint) error
+
+	// ActiveVersionIDs 返回用户知识文件当前生效版本的 ID，documentIDs 为空时返回全部知识文件
+	ActiveVersionIDs(email string, documentIDs []string) ([]uint, error)
+}
+
+// DBProcessingStore 将处理状态保存在 knowledge_metadata 表中
+type DBProcessingStore struct{}
+
+var _ ProcessingStore = DBProcessingStore{}
+
+func (DBProcessingStore) UpdateProgress(knowledgeID uint, stage model.Stage, progress, chunkCount int) error {
+	return UpdateKnowledgeProgress(knowledgeID, stage, progress, chunkCount)
+}
+
+func (DBProcessingStore) UpdateQuality(knowledgeID uint, quality model.ExtractionQuality) error {
+	return UpdateKnowledgeQuality(knowledgeID, quality)
+}
+
+func (DBProcessingStore) GetCheckpoint(knowledgeID uint) (string, int, error) {
+	return GetKnowledgeCheckpoint(knowledgeID)
+}
+
+func (DBProcessingStore) SaveCheckpoint(knowledgeID uint, fingerprint string, checkpoint int) error {
+	return SaveKnowledgeCheckpoint(knowledgeID, fingerprint, checkpoint)
+}
+
+func (DBProcessingStore) ActivateVersion(knowledgeID uint) error {
+	return ActivateKnowledgeVersion(knowledgeID)
+}
+
+func (DBProcessingStore) RecordError(knowledgeID uint, reason string) error {
+	return RecordKnowledgeError(knowledgeID, reason)
+}
+
+func (DBProcessingStore) MarkFailed(knowledgeID uint, reason string) error {
+	return MarkKnowledgeFailed(knowledgeID, reason)
+}
+
+func (DBProcessingStore) MarkDeadLettered(knowledgeID uint) error {
+	return MarkKnowledgeDeadLettered(knowledgeID)
+}
+
+func (DBProcessingStore) ActiveVersionIDs(email string, documentIDs []string) ([]uint, error) {
+	return GetActiveKnowledgeVersionIDs(email, documentIDs)
+}
+
+// MemoryProcessingStore 进程内的处理状态，语义与 DBProcessingStore 一致，用于测试 ETL 流程
+type MemoryProcessingStore struct {
+	mu      sync.Mutex
+	records map[uint]*model.KnowledgeMetadata
+}
+
+var _ ProcessingStore = &MemoryProcessingStore{}
+
+func NewMemoryProcessingStore() *MemoryProcessingStore {
+	return &MemoryProcessingStore{records: make(map[uint]*model.KnowledgeMetadata)}
+}
+
+// Add 添加一个知识文件版本
+func (s *MemoryProcessingStore) Add(metadata model.KnowledgeMetadata) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[metadata.ID] = &metadata
+}
+
+// Get 返回知识文件版本的当前状态
+func (s *MemoryProcessingStore) Get(knowledgeID uint) (model.KnowledgeMetadata, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[knowledgeID]
+	if !ok {
+		return model.KnowledgeMetadata{}, false
+	}
+	return *record, true
+}
+
+func (s *MemoryProcessingStore) update(knowledgeID uint, apply func(*model.KnowledgeMetadata)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[knowledgeID]
+	if !ok {
+		return fmt.Errorf("knowledge metadata not found: %d", knowledgeID)
+	}
+	apply(record)
+	return nil
+}
+
+func (s *MemoryProcessingStore) UpdateProgress(knowledgeID uint, stage model.Stage, progress, chunkCount int) error {
+	return s.update(knowledgeID, func(record *model.KnowledgeMetadata) {
+		record.Stage = stage
+		record.Progress = progress
+		if chunkCount > 0 {
+			record.ChunkCount = chunkCount
+		}
+	})
+}
+
+func (s *MemoryProcessingStore) UpdateQuality(knowledgeID uint, quality model.ExtractionQuality) error {
+	return s.update(knowledgeID, func(record *model.KnowledgeMetadata) {
+		record.ExtractionQuality = quality
+	})
+}
+
+func (s *MemoryProcessingStore) GetCheckpoint(knowledgeID uint) (string, int, error) {
+	record, ok := s.Get(knowledgeID)
+	if !ok {
+		return "", 0, fmt.Errorf("knowledge metadata not found: %d", knowledgeID)
+	}
+	return record.ChunkFingerprint, record.CheckpointChunks, nil
+}
+
+func (s *MemoryProcessingStore) SaveCheckpoint(knowledgeID uint, fingerprint string, checkpoint int) error {
+	return s.update(knowledgeID, func(record *model.KnowledgeMetadata) {
+		record.ChunkFingerprint = fingerprint
+		record.CheckpointChunks = checkpoint
+	})
+}
+
+func (s *MemoryProcessingStore) ActivateVersion(knowledgeID uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	active, ok := s.records[knowledgeID]
+	if !ok {
+		return fmt.Errorf("knowledge metadata not found: %d", knowledgeID)
+	}
+	for _, record := range s.records {
+		if record.DocumentID == active.DocumentID {
+			record.Superseded = record.ID != knowledgeID
+		}
+	}
+	active.Status = model.StatusProcessed
+	active.Stage = model.StageCompleted
+	active.Progress = 100
+	active.ErrorMessage = ""
+	return nil
+}
+
+func (s *MemoryProcessingStore) RecordError(knowledgeID uint, reason string) error {
+	return s.update(knowledgeID, func(record *model.KnowledgeMetadata) {
+		record.ErrorMessage = reason
+	})
+}
+
+func (s *MemoryProcessingStore) MarkFailed(knowledgeID uint, reason string) error {
+	return s.update(knowledgeID, func(record *model.KnowledgeMetadata) {
+		record.Status = model.StatusProcessedFailed
+		record.ErrorMessage = reason
+	})
+}
+
+func (s *MemoryProcessingStore) MarkDeadLettered(knowledgeID uint) error {
+	record, ok := s.Get(knowledgeID)
+	if !ok || record.Status == model.StatusProcessed {
+		return nil
+	}
+
+	reason := record.ErrorMessage
+	if reason == "" {
+		reason = "ETL retries exhausted"
+	}
+	return s.MarkFailed(knowledgeID, reason)
+}
+
+func (s *MemoryProcessingStore) ActiveVersionIDs(email string, documentIDs []string) ([]uint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ids []uint
+	for _, record := range s.records {
+		if record.UserEmail != email || record.Status != model.StatusProcessed || record.Superseded {
+			continue
+		}
+		if len(documentIDs) > 0 && !slices.Contains(documentIDs, record.DocumentID) {
+			continue
+		}
+		ids = append(ids, record.ID)
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
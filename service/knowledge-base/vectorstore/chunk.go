@@ -0,0 +1,48 @@
+package vectorstore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// CitationFields 检索时需要返回的元数据字段，用于生成引用来源
+var CitationFields = []string{
+	FieldTitle,
+	FieldPage,
+	FieldHeadingPath,
+	FieldChunkIndex,
+	FieldKnowledgeID,
+	FieldDocumentID,
+}
+
+// ChunkMetadata 文档切片的元数据
+type ChunkMetadata struct {
+	Title       string
+	UserEmail   string
+	Page        int64
+	HeadingPath string
+	ChunkIndex  int64
+	KnowledgeID int64
+	DocumentID  string
+	ContentHash string
+}
+
+// Citation 生成切片的引用来源，形如 "化验单.pdf / 第3页" 或 "指南.md / 饮食 > 碳水"
+func (m ChunkMetadata) Citation() string {
+	parts := []string{m.Title}
+	if m.Page > 0 {
+		parts = append(parts, fmt.Sprintf("第%d页", m.Page))
+	}
+	if m.HeadingPath != "" {
+		parts = append(parts, m.HeadingPath)
+	}
+	return strings.Join(parts, " / ")
+}
+
+// ContentHash 计算切片内容的摘要，用于去重
+func ContentHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
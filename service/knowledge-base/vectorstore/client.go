@@ -0,0 +1,33 @@
+package vectorstore
+
+import (
+	"context"
+	"diabetes-agent-backend/config"
+	"sync"
+
+	"github.com/milvus-io/milvus/client/v2/milvusclient"
+)
+
+var (
+	defaultClient     *milvusclient.Client
+	defaultClientErr  error
+	defaultClientOnce sync.Once
+)
+
+// DefaultClient 返回进程内共享的 Milvus 客户端
+func DefaultClient(ctx context.Context) (*milvusclient.Client, error) {
+	defaultClientOnce.Do(func() {
+		defaultClient, defaultClientErr = NewMilvusClient(ctx)
+	})
+	return defaultClient, defaultClientErr
+}
+
+// NewMilvusClient 根据配置创建 Milvus 客户端
+func NewMilvusClient(ctx context.Context) (*milvusclient.Client, error) {
+	return milvusclient.New(ctx, &milvusclient.ClientConfig{
+		Address:  config.Cfg.Milvus.Endpoint,
+		APIKey:   config.Cfg.Milvus.APIKey,
+		Username: config.Cfg.Milvus.Username,
+		Password: config.Cfg.Milvus.Password,
+	})
+}
@@ -0,0 +1,85 @@
+package vectorstore
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/milvus-io/milvus-proto/go-api/v2/schemapb"
+)
+
+// expression 参数化的过滤表达式，取值通过模板参数传递，不拼接到表达式中
+type expression struct {
+	clauses []string
+	params  map[string]any
+}
+
+func newExpression() *expression {
+	return &expression{params: make(map[string]any)}
+}
+
+// add 追加一个条件，clause 中以 {key} 引用参数
+func (e *expression) add(clause, key string, value any) {
+	e.clauses = append(e.clauses, clause)
+	e.params[key] = value
+}
+
+func (e *expression) String() string {
+	return strings.Join(e.clauses, " and ")
+}
+
+func documentExpression(filter DocumentFilter) *expression {
+	expr := newExpression()
+	expr.add(FieldDocumentID+" == {document_id}", "document_id", filter.DocumentID)
+	if filter.KnowledgeID != 0 {
+		expr.add(FieldKnowledgeID+" == {knowledge_id}", "knowledge_id", int64(filter.KnowledgeID))
+	}
+	if filter.ExcludeKnowledgeID != 0 {
+		expr.add(FieldKnowledgeID+" != {exclude_knowledge_id}", "exclude_knowledge_id", int64(filter.ExcludeKnowledgeID))
+	}
+	if filter.FromChunkIndex > 0 {
+		expr.add(FieldChunkIndex+" >= {from_chunk_index}", "from_chunk_index", int64(filter.FromChunkIndex))
+	}
+	return expr
+}
+
+func searchExpression(filter SearchFilter) *expression {
+	expr := newExpression()
+	expr.add(FieldUserEmail+" == {user_email}", "user_email", filter.UserEmail)
+	if len(filter.DocumentIDs) > 0 {
+		expr.add(FieldDocumentID+" in {document_ids}", "document_ids", filter.DocumentIDs)
+	}
+	if len(filter.KnowledgeIDs) > 0 {
+		knowledgeIDs := make([]int64, 0, len(filter.KnowledgeIDs))
+		for _, id := range filter.KnowledgeIDs {
+			knowledgeIDs = append(knowledgeIDs, int64(id))
+		}
+		expr.add(FieldKnowledgeID+" in {knowledge_ids}", "knowledge_ids", knowledgeIDs)
+	}
+	return expr
+}
+
+// templateValues 将参数转换为 Milvus 的模板参数
+func (e *expression) templateValues() (map[string]*schemapb.TemplateValue, error) {
+	values := make(map[string]*schemapb.TemplateValue, len(e.params))
+	for key, param := range e.params {
+		value := &schemapb.TemplateValue{}
+		switch v := param.(type) {
+		case string:
+			value.Val = &schemapb.TemplateValue_StringVal{StringVal: v}
+		case int64:
+			value.Val = &schemapb.TemplateValue_Int64Val{Int64Val: v}
+		case []string:
+			value.Val = &schemapb.TemplateValue_ArrayVal{ArrayVal: &schemapb.TemplateArrayValue{
+				Data: &schemapb.TemplateArrayValue_StringData{StringData: &schemapb.StringArray{Data: v}},
+			}}
+		case []int64:
+			value.Val = &schemapb.TemplateValue_ArrayVal{ArrayVal: &schemapb.TemplateArrayValue{
+				Data: &schemapb.TemplateArrayValue_LongData{LongData: &schemapb.LongArray{Data: v}},
+			}}
+		default:
+			return nil, fmt.Errorf("unsupported template value type: %T", param)
+		}
+		values[key] = value
+	}
+	return values, nil
+}
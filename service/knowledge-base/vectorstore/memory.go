@@ -0,0 +1,130 @@
+package vectorstore
+
+import (
+	"context"
+	"math"
+	"slices"
+	"sync"
+)
+
+// MemoryStore 内存中的向量存储，按余弦相似度暴力检索，用于测试 ETL 和检索流程
+type MemoryStore struct {
+	mu     sync.RWMutex
+	dim    int
+	chunks []Chunk
+}
+
+var _ VectorStore = &MemoryStore{}
+
+func NewMemoryStore(dim int) *MemoryStore {
+	return &MemoryStore{dim: dim}
+}
+
+func (s *MemoryStore) Upsert(ctx context.Context, chunks []Chunk) error {
+	for _, chunk := range chunks {
+		if err := chunk.validate(s.dim); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, chunk := range chunks {
+		s.chunks = slices.DeleteFunc(s.chunks, func(existing Chunk) bool {
+			return existing.DocumentID == chunk.DocumentID &&
+				existing.KnowledgeID == chunk.KnowledgeID &&
+				existing.ChunkIndex == chunk.ChunkIndex
+		})
+		s.chunks = append(s.chunks, chunk)
+	}
+	return nil
+}
+
+func (s *MemoryStore) DeleteByDocument(ctx context.Context, filter DocumentFilter) error {
+	if err := filter.validate(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.chunks = slices.DeleteFunc(s.chunks, func(chunk Chunk) bool {
+		return matchDocument(chunk.ChunkMetadata, filter)
+	})
+	return nil
+}
+
+func (s *MemoryStore) Search(ctx context.Context, vector []float32, topK int, filter SearchFilter) ([]SearchResult, error) {
+	if err := filter.validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var results []SearchResult
+	for _, chunk := range s.chunks {
+		if chunk.UserEmail != filter.UserEmail {
+			continue
+		}
+		if len(filter.DocumentIDs) > 0 && !slices.Contains(filter.DocumentIDs, chunk.DocumentID) {
+			continue
+		}
+		if len(filter.KnowledgeIDs) > 0 && !slices.Contains(filter.KnowledgeIDs, uint(chunk.KnowledgeID)) {
+			continue
+		}
+		results = append(results, SearchResult{
+			Text:          chunk.Text,
+			ChunkMetadata: chunk.ChunkMetadata,
+			Score:         cosine(vector, chunk.Vector),
+		})
+	}
+
+	slices.SortStableFunc(results, func(a, b SearchResult) int {
+		switch {
+		case a.Score > b.Score:
+			return -1
+		case a.Score < b.Score:
+			return 1
+		}
+		return 0
+	})
+	if len(results) > topK {
+		results = results[:topK]
+	}
+	return results, nil
+}
+
+// Chunks 返回当前存储的所有切片
+func (s *MemoryStore) Chunks() []Chunk {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(s.chunks)
+}
+
+func matchDocument(chunk ChunkMetadata, filter DocumentFilter) bool {
+	if chunk.DocumentID != filter.DocumentID {
+		return false
+	}
+	if filter.KnowledgeID != 0 && chunk.KnowledgeID != int64(filter.KnowledgeID) {
+		return false
+	}
+	if filter.ExcludeKnowledgeID != 0 && chunk.KnowledgeID == int64(filter.ExcludeKnowledgeID) {
+		return false
+	}
+	return chunk.ChunkIndex >= int64(filter.FromChunkIndex)
+}
+
+func cosine(a, b []float32) float32 {
+	var dot, normA, normB float64
+	for i := range min(len(a), len(b)) {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return float32(dot / (math.Sqrt(normA) * math.Sqrt(normB)))
+}
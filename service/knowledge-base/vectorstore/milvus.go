@@ -0,0 +1,219 @@
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/milvus-io/milvus-proto/go-api/v2/milvuspb"
+	"github.com/milvus-io/milvus/client/v2/column"
+	"github.com/milvus-io/milvus/client/v2/entity"
+	"github.com/milvus-io/milvus/client/v2/milvusclient"
+)
+
+// MilvusStore 基于 Milvus 集合的向量存储，过滤条件通过模板参数传递
+type MilvusStore struct {
+	Client     *milvusclient.Client
+	Collection string
+	Dim        int
+}
+
+var _ VectorStore = &MilvusStore{}
+
+func NewMilvusStore(client *milvusclient.Client, collection string, dim int) *MilvusStore {
+	return &MilvusStore{
+		Client:     client,
+		Collection: collection,
+		Dim:        dim,
+	}
+}
+
+// Upsert 按版本删除序号相同的旧切片后写入，集合主键自增，不能直接使用 Milvus 的 Upsert
+func (s *MilvusStore) Upsert(ctx context.Context, chunks []Chunk) error {
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	type version struct {
+		documentID  string
+		knowledgeID int64
+	}
+	indexes := make(map[version][]int64)
+	for _, chunk := range chunks {
+		if err := chunk.validate(s.Dim); err != nil {
+			return err
+		}
+		key := version{chunk.DocumentID, chunk.KnowledgeID}
+		indexes[key] = append(indexes[key], chunk.ChunkIndex)
+	}
+
+	for key, chunkIndexes := range indexes {
+		filter := DocumentFilter{DocumentID: key.documentID, KnowledgeID: uint(key.knowledgeID)}
+		if err := filter.validate(); err != nil {
+			return err
+		}
+		expr := documentExpression(filter)
+		expr.add(FieldChunkIndex+" in {chunk_indexes}", "chunk_indexes", chunkIndexes)
+		if err := s.delete(ctx, expr); err != nil {
+			return fmt.Errorf("error deleting replaced chunks: %v", err)
+		}
+	}
+
+	insertOption := milvusclient.NewColumnBasedInsertOption(s.Collection).WithColumns(chunkColumns(chunks, s.Dim)...)
+	if _, err := s.Client.Insert(ctx, insertOption); err != nil {
+		return fmt.Errorf("error inserting chunks: %v", err)
+	}
+	return nil
+}
+
+func (s *MilvusStore) DeleteByDocument(ctx context.Context, filter DocumentFilter) error {
+	if err := filter.validate(); err != nil {
+		return err
+	}
+	if err := s.delete(ctx, documentExpression(filter)); err != nil {
+		return fmt.Errorf("error deleting document chunks: %v", err)
+	}
+	return nil
+}
+
+func (s *MilvusStore) Search(ctx context.Context, vector []float32, topK int, filter SearchFilter) ([]SearchResult, error) {
+	if err := filter.validate(); err != nil {
+		return nil, err
+	}
+
+	expr := searchExpression(filter)
+	searchOption := milvusclient.NewSearchOption(s.Collection, topK, []entity.Vector{entity.FloatVector(vector)}).
+		WithANNSField(FieldVector).
+		WithFilter(expr.String()).
+		WithOutputFields(searchOutputFields...)
+	for key, value := range expr.params {
+		searchOption = searchOption.WithTemplateParam(key, value)
+	}
+
+	resultSets, err := s.Client.Search(ctx, searchOption)
+	if err != nil {
+		return nil, fmt.Errorf("error searching chunks: %v", err)
+	}
+	if len(resultSets) == 0 {
+		return nil, nil
+	}
+
+	return parseSearchResults(resultSets[0])
+}
+
+// delete 执行参数化的删除，客户端的 DeleteOption 不支持模板参数，直接构造请求
+func (s *MilvusStore) delete(ctx context.Context, expr *expression) error {
+	values, err := expr.templateValues()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.Client.Delete(ctx, templateDeleteOption{
+		request: &milvuspb.DeleteRequest{
+			CollectionName:     s.Collection,
+			Expr:               expr.String(),
+			ExprTemplateValues: values,
+		},
+	})
+	return err
+}
+
+type templateDeleteOption struct {
+	request *milvuspb.DeleteRequest
+}
+
+func (opt templateDeleteOption) Request() *milvuspb.DeleteRequest {
+	return opt.request
+}
+
+// 检索时返回的字段
+var searchOutputFields = append([]string{FieldText, FieldUserEmail, FieldContentHash}, CitationFields...)
+
+// chunkColumns 将切片组装为按列写入的数据
+func chunkColumns(chunks []Chunk, dim int) []column.Column {
+	n := len(chunks)
+	texts := make([]string, n)
+	vectors := make([][]float32, n)
+	titles := make([]string, n)
+	userEmails := make([]string, n)
+	pages := make([]int64, n)
+	headingPaths := make([]string, n)
+	chunkIndexes := make([]int64, n)
+	knowledgeIDs := make([]int64, n)
+	contentHashes := make([]string, n)
+	documentIDs := make([]string, n)
+	for i, chunk := range chunks {
+		texts[i] = chunk.Text
+		vectors[i] = chunk.Vector
+		titles[i] = chunk.Title
+		userEmails[i] = chunk.UserEmail
+		pages[i] = chunk.Page
+		headingPaths[i] = chunk.HeadingPath
+		chunkIndexes[i] = chunk.ChunkIndex
+		knowledgeIDs[i] = chunk.KnowledgeID
+		contentHashes[i] = chunk.ContentHash
+		documentIDs[i] = chunk.DocumentID
+	}
+
+	return []column.Column{
+		column.NewColumnVarChar(FieldText, texts),
+		column.NewColumnFloatVector(FieldVector, dim, vectors),
+		column.NewColumnVarChar(FieldTitle, titles),
+		column.NewColumnVarChar(FieldUserEmail, userEmails),
+		column.NewColumnInt64(FieldPage, pages),
+		column.NewColumnVarChar(FieldHeadingPath, headingPaths),
+		column.NewColumnInt64(FieldChunkIndex, chunkIndexes),
+		column.NewColumnInt64(FieldKnowledgeID, knowledgeIDs),
+		column.NewColumnVarChar(FieldContentHash, contentHashes),
+		column.NewColumnVarChar(FieldDocumentID, documentIDs),
+	}
+}
+
+func parseSearchResults(rs milvusclient.ResultSet) ([]SearchResult, error) {
+	results := make([]SearchResult, rs.ResultCount)
+	for i := range results {
+		results[i].Score = rs.Scores[i]
+	}
+
+	stringFields := map[string]func(*SearchResult, string){
+		FieldText:        func(r *SearchResult, v string) { r.Text = v },
+		FieldTitle:       func(r *SearchResult, v string) { r.Title = v },
+		FieldUserEmail:   func(r *SearchResult, v string) { r.UserEmail = v },
+		FieldHeadingPath: func(r *SearchResult, v string) { r.HeadingPath = v },
+		FieldDocumentID:  func(r *SearchResult, v string) { r.DocumentID = v },
+		FieldContentHash: func(r *SearchResult, v string) { r.ContentHash = v },
+	}
+	for field, set := range stringFields {
+		col := rs.GetColumn(field)
+		if col == nil {
+			continue
+		}
+		for i := range results {
+			value, err := col.GetAsString(i)
+			if err != nil {
+				return nil, fmt.Errorf("error reading field %s: %v", field, err)
+			}
+			set(&results[i], value)
+		}
+	}
+
+	int64Fields := map[string]func(*SearchResult, int64){
+		FieldPage:        func(r *SearchResult, v int64) { r.Page = v },
+		FieldChunkIndex:  func(r *SearchResult, v int64) { r.ChunkIndex = v },
+		FieldKnowledgeID: func(r *SearchResult, v int64) { r.KnowledgeID = v },
+	}
+	for field, set := range int64Fields {
+		col := rs.GetColumn(field)
+		if col == nil {
+			continue
+		}
+		for i := range results {
+			value, err := col.GetAsInt64(i)
+			if err != nil {
+				return nil, fmt.Errorf("error reading field %s: %v", field, err)
+			}
+			set(&results[i], value)
+		}
+	}
+
+	return results, nil
+}
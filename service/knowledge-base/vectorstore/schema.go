@@ -0,0 +1,214 @@
+package vectorstore
+
+import (
+	"diabetes-agent-backend/config"
+	"fmt"
+	"strconv"
+
+	"github.com/milvus-io/milvus/client/v2/entity"
+	"github.com/milvus-io/milvus/client/v2/index"
+	"github.com/milvus-io/milvus/client/v2/milvusclient"
+)
+
+const (
+	// SchemaVersion 知识文件集合的 schema 版本，字段或索引变更时递增
+	SchemaVersion = 3
+
+	// CollectionName 业务侧访问的集合名称，指向实际存储数据的集合（或别名）
+	CollectionName = "knowledge_doc"
+
+	FieldID        = "id"
+	FieldText      = "text"
+	FieldVector    = "vector"
+	FieldTitle     = "title"
+	FieldUserEmail = "user_email"
+
+	// 切片来源页码，非分页文档为 0
+	FieldPage = "page"
+
+	// 切片所在的标题层级，形如 "一级标题 > 二级标题"
+	FieldHeadingPath = "heading_path"
+
+	// 切片在文档中的序号
+	FieldChunkIndex = "chunk_index"
+
+	// 切片所属知识文件元数据 ID
+	FieldKnowledgeID = "knowledge_id"
+
+	// 切片所属知识文件的稳定标识
+	FieldDocumentID = "document_id"
+
+	// 切片内容的 SHA-256 摘要
+	FieldContentHash = "content_hash"
+
+	// 集合属性中记录 schema 版本与向量化模型信息的键
+	PropertySchemaVersion  = "diabetes.schema_version"
+	PropertyEmbeddingModel = "diabetes.embedding_model"
+	PropertyEmbeddingDim   = "diabetes.embedding_dim"
+
+	MetricType = entity.COSINE
+
+	defaultEmbeddingModel = "text-embedding-v4"
+	defaultVectorDim      = 1024
+
+	maxTextLength      = 65535
+	maxTitleLength     = 512
+	maxUserEmailLength = 256
+	maxHeadingLength   = 1024
+	contentHashLength  = 64
+	documentIDLength   = 36
+)
+
+// CollectionSpec 描述一个知识文件集合的期望状态
+type CollectionSpec struct {
+	Name           string
+	EmbeddingModel string
+	Dim            int64
+}
+
+// PhysicalCollectionName 返回指定 schema 版本对应的实际集合名称，CollectionName 作为别名指向它
+func PhysicalCollectionName(version int) string {
+	return fmt.Sprintf("%s_v%d", CollectionName, version)
+}
+
+// NewSchema 构建知识文件集合的 schema
+// user_email 作为分区键，使同一用户的数据落在同一分区，检索时按用户过滤不需要扫描全量分区
+func NewSchema(spec CollectionSpec) *entity.Schema {
+	return entity.NewSchema().
+		WithName(spec.Name).
+		WithDescription("knowledge document chunks").
+		WithField(entity.NewField().
+			WithName(FieldID).
+			WithDataType(entity.FieldTypeInt64).
+			WithIsPrimaryKey(true).
+			WithIsAutoID(true)).
+		WithField(entity.NewField().
+			WithName(FieldText).
+			WithDataType(entity.FieldTypeVarChar).
+			WithMaxLength(maxTextLength)).
+		WithField(entity.NewField().
+			WithName(FieldVector).
+			WithDataType(entity.FieldTypeFloatVector).
+			WithDim(spec.Dim)).
+		WithField(entity.NewField().
+			WithName(FieldTitle).
+			WithDataType(entity.FieldTypeVarChar).
+			WithMaxLength(maxTitleLength)).
+		WithField(entity.NewField().
+			WithName(FieldUserEmail).
+			WithDataType(entity.FieldTypeVarChar).
+			WithMaxLength(maxUserEmailLength).
+			WithIsPartitionKey(true)).
+		// 以下字段设置默认值，兼容从旧版本集合迁移的数据
+		WithField(entity.NewField().
+			WithName(FieldPage).
+			WithDataType(entity.FieldTypeInt64).
+			WithDefaultValueLong(0)).
+		WithField(entity.NewField().
+			WithName(FieldHeadingPath).
+			WithDataType(entity.FieldTypeVarChar).
+			WithMaxLength(maxHeadingLength).
+			WithDefaultValueString("")).
+		WithField(entity.NewField().
+			WithName(FieldChunkIndex).
+			WithDataType(entity.FieldTypeInt64).
+			WithDefaultValueLong(0)).
+		WithField(entity.NewField().
+			WithName(FieldKnowledgeID).
+			WithDataType(entity.FieldTypeInt64).
+			WithDefaultValueLong(0)).
+		WithField(entity.NewField().
+			WithName(FieldContentHash).
+			WithDataType(entity.FieldTypeVarChar).
+			WithMaxLength(contentHashLength).
+			WithDefaultValueString("")).
+		WithField(entity.NewField().
+			WithName(FieldDocumentID).
+			WithDataType(entity.FieldTypeVarChar).
+			WithMaxLength(documentIDLength).
+			WithDefaultValueString(""))
+}
+
+// NewIndexOptions 构建知识文件集合的索引
+func NewIndexOptions(collectionName string) []milvusclient.CreateIndexOption {
+	return []milvusclient.CreateIndexOption{
+		milvusclient.NewCreateIndexOption(collectionName, FieldVector, index.NewAutoIndex(MetricType)),
+		milvusclient.NewCreateIndexOption(collectionName, FieldUserEmail, index.NewInvertedIndex()),
+		milvusclient.NewCreateIndexOption(collectionName, FieldTitle, index.NewInvertedIndex()),
+		milvusclient.NewCreateIndexOption(collectionName, FieldKnowledgeID, index.NewInvertedIndex()),
+		milvusclient.NewCreateIndexOption(collectionName, FieldContentHash, index.NewInvertedIndex()),
+		milvusclient.NewCreateIndexOption(collectionName, FieldDocumentID, index.NewInvertedIndex()),
+	}
+}
+
+// Properties 返回需要记录在集合属性中的版本信息
+func (spec CollectionSpec) Properties() map[string]string {
+	return map[string]string{
+		PropertySchemaVersion:  strconv.Itoa(SchemaVersion),
+		PropertyEmbeddingModel: spec.EmbeddingModel,
+		PropertyEmbeddingDim:   strconv.FormatInt(spec.Dim, 10),
+	}
+}
+
+// Validate 校验已有集合与期望 schema 是否一致，返回所有不一致项
+func Validate(coll *entity.Collection, spec CollectionSpec) []string {
+	var problems []string
+
+	expected := NewSchema(spec)
+	actualFields := make(map[string]*entity.Field)
+	for _, field := range coll.Schema.Fields {
+		actualFields[field.Name] = field
+	}
+
+	for _, field := range expected.Fields {
+		actual, ok := actualFields[field.Name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("missing field %s", field.Name))
+			continue
+		}
+		if actual.DataType != field.DataType {
+			problems = append(problems, fmt.Sprintf("field %s has type %s, expected %s",
+				field.Name, actual.DataType, field.DataType))
+			continue
+		}
+		if actual.PrimaryKey != field.PrimaryKey {
+			problems = append(problems, fmt.Sprintf("field %s primary key mismatch", field.Name))
+		}
+		if actual.IsPartitionKey != field.IsPartitionKey {
+			problems = append(problems, fmt.Sprintf("field %s partition key mismatch", field.Name))
+		}
+		if field.DataType == entity.FieldTypeFloatVector {
+			dim, err := actual.GetDim()
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("field %s: %v", field.Name, err))
+			} else if dim != spec.Dim {
+				problems = append(problems, fmt.Sprintf("field %s has dim %d, expected %d",
+					field.Name, dim, spec.Dim))
+			}
+		}
+	}
+
+	for key, value := range spec.Properties() {
+		if actual := coll.Properties[key]; actual != value {
+			problems = append(problems, fmt.Sprintf("property %s is %q, expected %q", key, actual, value))
+		}
+	}
+
+	return problems
+}
+
+// DefaultSpec 返回当前配置下业务侧使用的集合规格，未配置向量化模型时使用默认值
+func DefaultSpec() CollectionSpec {
+	spec := CollectionSpec{
+		Name:           CollectionName,
+		EmbeddingModel: config.Cfg.Model.Embedding.Name,
+		Dim:            config.Cfg.Model.Embedding.Dim,
+	}
+	if spec.EmbeddingModel == "" {
+		spec.EmbeddingModel = defaultEmbeddingModel
+	}
+	if spec.Dim == 0 {
+		spec.Dim = defaultVectorDim
+	}
+	return spec
+}
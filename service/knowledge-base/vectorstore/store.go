@@ -0,0 +1,85 @@
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// Chunk 写入向量存储的文档切片
+type Chunk struct {
+	Text   string
+	Vector []float32
+	ChunkMetadata
+}
+
+// SearchResult 检索命中的切片，Score 为与查询向量的相似度
+type SearchResult struct {
+	Text string
+	ChunkMetadata
+	Score float32
+}
+
+// DocumentFilter 限定知识文件的切片范围，零值字段不参与过滤
+type DocumentFilter struct {
+	// 知识文件的稳定标识，必填
+	DocumentID string
+
+	// 只匹配该版本的切片
+	KnowledgeID uint
+
+	// 排除该版本的切片
+	ExcludeKnowledgeID uint
+
+	// 只匹配序号不小于该值的切片
+	FromChunkIndex int
+}
+
+// SearchFilter 检索条件，零值字段不参与过滤
+type SearchFilter struct {
+	// 只检索该用户的切片，必填
+	UserEmail string
+
+	// 只检索这些知识文件的切片
+	DocumentIDs []string
+
+	// 只检索这些版本的切片，通常为知识文件当前生效的版本
+	KnowledgeIDs []uint
+}
+
+// VectorStore 知识文件切片的向量存储
+type VectorStore interface {
+	// Upsert 写入切片，同一版本中序号相同的切片会被替换
+	Upsert(ctx context.Context, chunks []Chunk) error
+
+	// DeleteByDocument 删除知识文件中匹配条件的切片
+	DeleteByDocument(ctx context.Context, filter DocumentFilter) error
+
+	// Search 返回与查询向量最相似的 topK 个切片
+	Search(ctx context.Context, vector []float32, topK int, filter SearchFilter) ([]SearchResult, error)
+}
+
+func (f DocumentFilter) validate() error {
+	if _, err := uuid.Parse(f.DocumentID); err != nil {
+		return fmt.Errorf("invalid document id %q: %v", f.DocumentID, err)
+	}
+	return nil
+}
+
+func (f SearchFilter) validate() error {
+	if f.UserEmail == "" {
+		return fmt.Errorf("user email is required for search")
+	}
+	return nil
+}
+
+func (c Chunk) validate(dim int) error {
+	if c.DocumentID == "" || c.UserEmail == "" || c.Title == "" {
+		return fmt.Errorf("incomplete metadata of chunk %d", c.ChunkIndex)
+	}
+	if len(c.Vector) != dim {
+		return fmt.Errorf("chunk %d has dim %d, expected %d", c.ChunkIndex, len(c.Vector), dim)
+	}
+	return nil
+}
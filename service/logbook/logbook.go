@@ -0,0 +1,215 @@
+package logbook
+
+import (
+	"diabetes-agent-backend/dao"
+	"diabetes-agent-backend/model"
+	"diabetes-agent-backend/request"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidEntry 日志记录或操作校验失败，错误信息可以直接返回给用户
+	ErrInvalidEntry = errors.New("invalid log entry")
+
+	ErrEntryNotFound  = errors.New("log entry not found")
+	ErrActionNotFound = errors.New("pending log action not found")
+)
+
+const (
+	maxNameLength = 200
+	maxDoseLength = 50
+	maxNoteLength = 200
+
+	// 单次注射的胰岛素剂量上限（U）和单条饮食记录的碳水上限（克）
+	maxInsulinUnits = 100
+	maxCarbs        = 500
+
+	// 允许的记录时间误差，避免客户端时钟偏差导致拒绝
+	futureTolerance = 5 * time.Minute
+)
+
+// CreateEntry 校验并写入一条日志记录
+func CreateEntry(email string, req request.LogEntryRequest) (*model.LogEntry, error) {
+	entry, err := toEntry(email, req)
+	if err != nil {
+		return nil, err
+	}
+	entry.Source = model.LogSourceManual
+
+	if err := dao.CreateLogEntry(dao.DB, entry); err != nil {
+		return nil, fmt.Errorf("failed to create log entry: %v", err)
+	}
+	return entry, nil
+}
+
+// GetEntries 返回 [from, to) 内的日志记录，kind 为空时返回所有类型
+func GetEntries(email, kind string, from, to time.Time) ([]model.LogEntry, error) {
+	if kind != "" && !model.LogKind(kind).IsValid() {
+		return nil, invalid("unsupported kind: %s", kind)
+	}
+
+	entries, err := dao.GetLogEntries(email, model.LogKind(kind), from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get log entries: %v", err)
+	}
+	return entries, nil
+}
+
+// UpdateEntry 整体替换一条日志记录的内容，记录的类型不能修改
+func UpdateEntry(email string, id uint, req request.LogEntryRequest) (*model.LogEntry, error) {
+	existing, err := getEntry(email, id)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := toUpdatedEntry(existing, req)
+	if err != nil {
+		return nil, err
+	}
+
+	found, err := dao.UpdateLogEntry(dao.DB, email, id, entryUpdates(entry))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update log entry: %v", err)
+	}
+	if !found {
+		return nil, ErrEntryNotFound
+	}
+	return entry, nil
+}
+
+func DeleteEntry(email string, id uint) error {
+	found, err := dao.DeleteLogEntry(dao.DB, email, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete log entry: %v", err)
+	}
+	if !found {
+		return ErrEntryNotFound
+	}
+	return nil
+}
+
+func getEntry(email string, id uint) (*model.LogEntry, error) {
+	entry, err := dao.GetLogEntry(email, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get log entry: %v", err)
+	}
+	if entry == nil {
+		return nil, ErrEntryNotFound
+	}
+	return entry, nil
+}
+
+// toUpdatedEntry 校验修改后的内容，保留原记录的 ID、类型和来源
+func toUpdatedEntry(existing *model.LogEntry, req request.LogEntryRequest) (*model.LogEntry, error) {
+	if req.Kind != "" && model.LogKind(req.Kind) != existing.Kind {
+		return nil, invalid("kind cannot be changed")
+	}
+	req.Kind = string(existing.Kind)
+
+	entry, err := toEntry(existing.UserEmail, req)
+	if err != nil {
+		return nil, err
+	}
+	entry.ID = existing.ID
+	entry.CreatedAt = existing.CreatedAt
+	entry.Source = existing.Source
+	return entry, nil
+}
+
+// toEntry 校验记录内容，清空与记录类型无关的字段
+func toEntry(email string, req request.LogEntryRequest) (*model.LogEntry, error) {
+	entry := &model.LogEntry{
+		UserEmail:  email,
+		Kind:       model.LogKind(req.Kind),
+		Name:       strings.TrimSpace(req.Name),
+		OccurredAt: req.OccurredAt,
+		Note:       strings.TrimSpace(req.Note),
+	}
+
+	if !entry.Kind.IsValid() {
+		return nil, invalid("kind must be medication, insulin or meal")
+	}
+	if len([]rune(entry.Name)) > maxNameLength {
+		return nil, invalid("name is too long")
+	}
+	if len([]rune(entry.Note)) > maxNoteLength {
+		return nil, invalid("note is too long")
+	}
+	if entry.OccurredAt.IsZero() || entry.OccurredAt.After(time.Now().Add(futureTolerance)) {
+		return nil, invalid("occurred_at is missing or in the future")
+	}
+
+	switch entry.Kind {
+	case model.LogKindMedication:
+		if entry.Name == "" {
+			return nil, invalid("medication name is required")
+		}
+		entry.Dose = strings.TrimSpace(req.Dose)
+		if len([]rune(entry.Dose)) > maxDoseLength {
+			return nil, invalid("dose is too long")
+		}
+
+	case model.LogKindInsulin:
+		entry.InsulinType = model.InsulinType(req.InsulinType)
+		if entry.InsulinType == "" {
+			entry.InsulinType = model.InsulinTypeOther
+		}
+		if !entry.InsulinType.IsValid() {
+			return nil, invalid("unsupported insulin type: %s", req.InsulinType)
+		}
+		if req.Units == nil || math.IsNaN(*req.Units) || *req.Units <= 0 || *req.Units > maxInsulinUnits {
+			return nil, invalid("insulin units must be between 0 and %d", maxInsulinUnits)
+		}
+		entry.Units = req.Units
+
+	case model.LogKindMeal:
+		if entry.Name == "" {
+			return nil, invalid("meal description is required")
+		}
+		entry.MealType = model.MealType(req.MealType)
+		if entry.MealType != "" && !entry.MealType.IsValid() {
+			return nil, invalid("unsupported meal type: %s", req.MealType)
+		}
+		if req.Carbs != nil && (math.IsNaN(*req.Carbs) || *req.Carbs < 0 || *req.Carbs > maxCarbs) {
+			return nil, invalid("carbs must be between 0 and %d grams", maxCarbs)
+		}
+		entry.Carbs = req.Carbs
+	}
+	return entry, nil
+}
+
+// toRequest 将已有记录转换为请求，用于在原记录的基础上修改部分字段
+func toRequest(entry *model.LogEntry) request.LogEntryRequest {
+	return request.LogEntryRequest{
+		Kind:        string(entry.Kind),
+		Name:        entry.Name,
+		Dose:        entry.Dose,
+		InsulinType: string(entry.InsulinType),
+		Units:       entry.Units,
+		Carbs:       entry.Carbs,
+		MealType:    string(entry.MealType),
+		OccurredAt:  entry.OccurredAt,
+		Note:        entry.Note,
+	}
+}
+
+func entryUpdates(entry *model.LogEntry) map[string]any {
+	return map[string]any{
+		"name":         entry.Name,
+		"dose":         entry.Dose,
+		"insulin_type": entry.InsulinType,
+		"units":        entry.Units,
+		"carbs":        entry.Carbs,
+		"meal_type":    entry.MealType,
+		"occurred_at":  entry.OccurredAt,
+		"note":         entry.Note,
+	}
+}
+
+func invalid(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalidEntry, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,219 @@
+package logbook
+
+import (
+	"diabetes-agent-backend/dao"
+	"diabetes-agent-backend/model"
+	"diabetes-agent-backend/request"
+	"diabetes-agent-backend/service/glucose"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 待确认操作的有效期，过期后需要重新提出
+const pendingTTL = 30 * time.Minute
+
+// ProposeAction 校验 Agent 提出的操作并保存为待确认状态，用户确认前不修改日志记录
+// 修改和删除时 entryID 为目标记录，删除时忽略 req
+func ProposeAction(email, sessionID string, action model.LogAction, entryID uint,
+	req request.LogEntryRequest) (*model.PendingLogAction, error) {
+	pending := &model.PendingLogAction{
+		UserEmail: email,
+		SessionID: sessionID,
+		Action:    action,
+		EntryID:   entryID,
+		Status:    model.PendingStatusPending,
+		ExpiresAt: time.Now().Add(pendingTTL),
+	}
+
+	// 确认提示中的时间使用用户所在的时区
+	loc, err := glucose.Location(email)
+	if err != nil {
+		return nil, err
+	}
+
+	var entry *model.LogEntry
+	switch action {
+	case model.LogActionCreate:
+		var err error
+		if entry, err = toEntry(email, req); err != nil {
+			return nil, err
+		}
+		entry.Source = model.LogSourceAgent
+		pending.EntryID = 0
+		pending.Summary = "Record " + Describe(entry, loc)
+
+	case model.LogActionUpdate:
+		existing, err := getEntry(email, entryID)
+		if err != nil {
+			return nil, err
+		}
+		if entry, err = toUpdatedEntry(existing, req); err != nil {
+			return nil, err
+		}
+		pending.Summary = fmt.Sprintf("Change entry #%d from %s to %s", entryID, Describe(existing, loc), Describe(entry, loc))
+
+	case model.LogActionDelete:
+		existing, err := getEntry(email, entryID)
+		if err != nil {
+			return nil, err
+		}
+		pending.Summary = fmt.Sprintf("Delete entry #%d: %s", entryID, Describe(existing, loc))
+
+	default:
+		return nil, invalid("action must be create, update or delete")
+	}
+
+	if entry != nil {
+		payload, err := json.Marshal(entry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal log entry: %v", err)
+		}
+		pending.Payload = string(payload)
+	}
+
+	if err := dao.CreatePendingLogAction(pending); err != nil {
+		return nil, fmt.Errorf("failed to create pending log action: %v", err)
+	}
+	return pending, nil
+}
+
+// GetPendingAction 查询用户的一条待确认操作
+func GetPendingAction(email string, id uint) (*model.PendingLogAction, error) {
+	pending, err := dao.GetPendingLogAction(email, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending log action: %v", err)
+	}
+	if pending == nil {
+		return nil, ErrActionNotFound
+	}
+	return pending, nil
+}
+
+// GetPendingActions 返回用户尚未处理且未过期的操作
+func GetPendingActions(email string) ([]model.PendingLogAction, error) {
+	actions, err := dao.GetPendingLogActions(email, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending log actions: %v", err)
+	}
+	return actions, nil
+}
+
+// ConfirmAction 执行用户确认的操作，操作状态的更新与日志记录的修改在同一事务中，
+// 重复确认不会重复写入。返回写入或修改后的记录，删除时返回 nil
+func ConfirmAction(email string, id uint) (*model.LogEntry, error) {
+	pending, err := GetPendingAction(email, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var entry *model.LogEntry
+	if pending.Payload != "" {
+		entry = &model.LogEntry{}
+		if err := json.Unmarshal([]byte(pending.Payload), entry); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal log entry: %v", err)
+		}
+		entry.UserEmail = email
+	}
+
+	err = dao.Transaction(func(tx *gorm.DB) error {
+		resolved, err := dao.ResolvePendingLogAction(tx, email, id, model.PendingStatusConfirmed, time.Now())
+		if err != nil {
+			return fmt.Errorf("failed to resolve pending log action: %v", err)
+		}
+		if !resolved {
+			return invalid("action #%d has already been handled or has expired", id)
+		}
+
+		switch pending.Action {
+		case model.LogActionCreate:
+			entry.ID = 0
+			if err := dao.CreateLogEntry(tx, entry); err != nil {
+				return fmt.Errorf("failed to create log entry: %v", err)
+			}
+			return nil
+
+		case model.LogActionUpdate:
+			found, err := dao.UpdateLogEntry(tx, email, pending.EntryID, entryUpdates(entry))
+			if err != nil {
+				return fmt.Errorf("failed to update log entry: %v", err)
+			}
+			if !found {
+				return ErrEntryNotFound
+			}
+			return nil
+
+		case model.LogActionDelete:
+			found, err := dao.DeleteLogEntry(tx, email, pending.EntryID)
+			if err != nil {
+				return fmt.Errorf("failed to delete log entry: %v", err)
+			}
+			if !found {
+				return ErrEntryNotFound
+			}
+			return nil
+		}
+		return fmt.Errorf("unknown log action: %s", pending.Action)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// CancelAction 放弃一条待确认的操作
+func CancelAction(email string, id uint) error {
+	if _, err := GetPendingAction(email, id); err != nil {
+		return err
+	}
+
+	resolved, err := dao.ResolvePendingLogAction(dao.DB, email, id, model.PendingStatusCancelled, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to resolve pending log action: %v", err)
+	}
+	if !resolved {
+		return invalid("action #%d has already been handled or has expired", id)
+	}
+	return nil
+}
+
+// Describe 返回日志记录的简短英文描述，用于确认提示和工具结果，时间按 loc 时区显示
+func Describe(entry *model.LogEntry, loc *time.Location) string {
+	var b strings.Builder
+	switch entry.Kind {
+	case model.LogKindMedication:
+		b.WriteString("medication " + entry.Name)
+		if entry.Dose != "" {
+			b.WriteString(" " + entry.Dose)
+		}
+
+	case model.LogKindInsulin:
+		b.WriteString(string(entry.InsulinType) + " insulin")
+		if entry.Name != "" {
+			b.WriteString(" " + entry.Name)
+		}
+		if entry.Units != nil {
+			fmt.Fprintf(&b, " %gU", *entry.Units)
+		}
+
+	case model.LogKindMeal:
+		if entry.MealType != "" {
+			b.WriteString(string(entry.MealType) + " ")
+		} else {
+			b.WriteString("meal ")
+		}
+		b.WriteString(entry.Name)
+		if entry.Carbs != nil {
+			fmt.Fprintf(&b, " (%gg carbs)", *entry.Carbs)
+		}
+	}
+
+	b.WriteString(" at " + entry.OccurredAt.In(loc).Format("2006-01-02 15:04"))
+	if entry.Note != "" {
+		b.WriteString(", note: " + entry.Note)
+	}
+	return b.String()
+}
@@ -0,0 +1,306 @@
+package logbook
+
+import (
+	"context"
+	"diabetes-agent-backend/model"
+	"diabetes-agent-backend/request"
+	"diabetes-agent-backend/service/glucose"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tmc/langchaingo/tools"
+)
+
+// 列表工具结果中最多返回的记录条数
+const toolMaxEntries = 50
+
+// toolScope 工具的作用范围：当前用户、会话和本轮对话的开始时间
+// 待确认的操作只能在之后的对话轮次中确认，保证写入前用户已经明确回复
+type toolScope struct {
+	Email         string
+	SessionID     string
+	TurnStartedAt time.Time
+}
+
+// NewTools 返回 Agent 查询、提出和确认日志操作的进程内工具
+func NewTools(email, sessionID string, turnStartedAt time.Time) []tools.Tool {
+	scope := toolScope{
+		Email:         email,
+		SessionID:     sessionID,
+		TurnStartedAt: turnStartedAt,
+	}
+	return []tools.Tool{
+		&ListTool{scope},
+		&ProposeTool{scope},
+		&ConfirmTool{scope},
+	}
+}
+
+// ListTool 查询当前用户的用药、胰岛素和饮食记录
+type ListTool struct {
+	toolScope
+}
+
+func (t *ListTool) Name() string {
+	return "list_log_entries"
+}
+
+func (t *ListTool) Description() string {
+	return `List the user's medication, insulin and meal log entries with their IDs. ` +
+		`Input is a JSON object: {"kind": "insulin", "days": 7} or {"from": "YYYY-MM-DD", "to": "YYYY-MM-DD"}. ` +
+		`kind is optional (medication, insulin or meal). Empty input means all entries of the last 14 days. ` +
+		`Use it to find the ID of an entry before proposing a correction or deletion.`
+}
+
+func (t *ListTool) Call(ctx context.Context, input string) (string, error) {
+	loc, err := glucose.Location(t.Email)
+	if err != nil {
+		return "", err
+	}
+
+	from, to, err := glucose.ParseToolRange(input, loc)
+	if err != nil {
+		return "Invalid input: " + err.Error(), nil
+	}
+
+	var parsed struct {
+		Kind string `json:"kind"`
+	}
+	_ = json.Unmarshal([]byte(cleanInput(input)), &parsed)
+
+	entries, err := GetEntries(t.Email, parsed.Kind, from, to)
+	if errors.Is(err, ErrInvalidEntry) {
+		return "Invalid input: " + err.Error(), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 0 {
+		return fmt.Sprintf("No log entries recorded between %s and %s.",
+			from.Format(time.DateOnly), to.Format(time.DateOnly)), nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Log entries from %s to %s:\n", from.Format(time.DateOnly), to.Format(time.DateOnly))
+	if len(entries) > toolMaxEntries {
+		fmt.Fprintf(&b, "(showing the latest %d of %d)\n", toolMaxEntries, len(entries))
+		entries = entries[len(entries)-toolMaxEntries:]
+	}
+	for i := range entries {
+		fmt.Fprintf(&b, "#%d %s\n", entries[i].ID, Describe(&entries[i], loc))
+	}
+	return b.String(), nil
+}
+
+// ProposeTool 提出新建、修改或删除日志记录的操作，用户确认前不写入
+type ProposeTool struct {
+	toolScope
+}
+
+func (t *ProposeTool) Name() string {
+	return "propose_log_entry"
+}
+
+func (t *ProposeTool) Description() string {
+	return `Propose recording, correcting or deleting a medication, insulin or meal log entry. Nothing is saved by this tool: ` +
+		`it returns a pending action that the user must explicitly confirm. Call it once per entry, e.g. insulin and a meal are two calls. ` +
+		`Input is a JSON object: {"action": "create", "kind": "insulin", "name": "NovoRapid", "insulin_type": "rapid", "units": 6, "time": "12:30"}. ` +
+		`action is create, update or delete; update and delete require "id" from list_log_entries, and update only needs the fields to change. ` +
+		`kind is medication (name, dose such as "500 mg"), insulin (insulin_type: rapid, short, intermediate, long, premixed or other; units; optional name) ` +
+		`or meal (name describing the food, carbs in grams, meal_type: breakfast, lunch, dinner or snack). ` +
+		`time is RFC3339, "YYYY-MM-DD HH:MM" or "HH:MM" today; it defaults to now. An optional note may be added. ` +
+		`After calling it, show the summary to the user and ask them to confirm; do not claim anything was saved.`
+}
+
+type proposeInput struct {
+	Action      string   `json:"action"`
+	ID          uint     `json:"id"`
+	Kind        string   `json:"kind"`
+	Name        string   `json:"name"`
+	Dose        string   `json:"dose"`
+	InsulinType string   `json:"insulin_type"`
+	Units       *float64 `json:"units"`
+	Carbs       *float64 `json:"carbs"`
+	MealType    string   `json:"meal_type"`
+	Time        string   `json:"time"`
+	Note        string   `json:"note"`
+}
+
+func (t *ProposeTool) Call(ctx context.Context, input string) (string, error) {
+	var parsed proposeInput
+	if err := json.Unmarshal([]byte(cleanInput(input)), &parsed); err != nil {
+		return "Invalid input: input must be a JSON object", nil
+	}
+
+	action := model.LogAction(parsed.Action)
+	if action == "" {
+		action = model.LogActionCreate
+	}
+
+	loc, err := glucose.Location(t.Email)
+	if err != nil {
+		return "", err
+	}
+
+	var req request.LogEntryRequest
+	switch action {
+	case model.LogActionCreate:
+		req = request.LogEntryRequest{OccurredAt: time.Now()}
+	case model.LogActionUpdate:
+		existing, err := getEntry(t.Email, parsed.ID)
+		if errors.Is(err, ErrEntryNotFound) {
+			return fmt.Sprintf("Invalid input: log entry #%d not found", parsed.ID), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		req = toRequest(existing)
+	}
+	if err := parsed.applyTo(&req, time.Now().In(loc)); err != nil {
+		return "Invalid input: " + err.Error(), nil
+	}
+
+	pending, err := ProposeAction(t.Email, t.SessionID, action, parsed.ID, req)
+	if errors.Is(err, ErrInvalidEntry) || errors.Is(err, ErrEntryNotFound) {
+		return "Invalid input: " + err.Error(), nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Pending action #%d: %s. Nothing has been saved yet. "+
+		"Show this to the user and ask them to confirm. Only after the user explicitly confirms in their next message, "+
+		`call confirm_log_entry with {"pending_id": %d}. The pending action expires in %d minutes.`,
+		pending.ID, pending.Summary, pending.ID, int(pendingTTL.Minutes())), nil
+}
+
+// applyTo 将输入中设置的字段覆盖到 req 上，now 的时区用于解析不带时区的时间
+func (p proposeInput) applyTo(req *request.LogEntryRequest, now time.Time) error {
+	if p.Kind != "" {
+		req.Kind = p.Kind
+	}
+	if p.Name != "" {
+		req.Name = p.Name
+	}
+	if p.Dose != "" {
+		req.Dose = p.Dose
+	}
+	if p.InsulinType != "" {
+		req.InsulinType = p.InsulinType
+	}
+	if p.Units != nil {
+		req.Units = p.Units
+	}
+	if p.Carbs != nil {
+		req.Carbs = p.Carbs
+	}
+	if p.MealType != "" {
+		req.MealType = p.MealType
+	}
+	if p.Note != "" {
+		req.Note = p.Note
+	}
+	if p.Time != "" {
+		occurredAt, err := parseToolTime(p.Time, now)
+		if err != nil {
+			return err
+		}
+		req.OccurredAt = occurredAt
+	}
+	return nil
+}
+
+// parseToolTime 解析工具输入的时间，不带时区的时间使用 now 的时区，只有时分时视为当天
+func parseToolTime(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04", value, now.Location()); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("15:04", value); err == nil {
+		return time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location()), nil
+	}
+	return time.Time{}, fmt.Errorf(`time must be RFC3339, "YYYY-MM-DD HH:MM" or "HH:MM"`)
+}
+
+// ConfirmTool 在用户明确同意后执行或放弃待确认的操作
+type ConfirmTool struct {
+	toolScope
+}
+
+func (t *ConfirmTool) Name() string {
+	return "confirm_log_entry"
+}
+
+func (t *ConfirmTool) Description() string {
+	return `Apply a pending action from propose_log_entry after the user has explicitly confirmed it in their reply, ` +
+		`or discard it if the user declines. Input is a JSON object: {"pending_id": 12} to apply, ` +
+		`or {"pending_id": 12, "cancel": true} to discard. Never call it in the same turn as propose_log_entry.`
+}
+
+func (t *ConfirmTool) Call(ctx context.Context, input string) (string, error) {
+	var parsed struct {
+		PendingID uint `json:"pending_id"`
+		Cancel    bool `json:"cancel"`
+	}
+	if err := json.Unmarshal([]byte(cleanInput(input)), &parsed); err != nil {
+		return "Invalid input: input must be a JSON object", nil
+	}
+
+	pending, err := GetPendingAction(t.Email, parsed.PendingID)
+	if errors.Is(err, ErrActionNotFound) || (err == nil && pending.SessionID != t.SessionID) {
+		return fmt.Sprintf("Invalid input: pending action #%d not found in this conversation", parsed.PendingID), nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if parsed.Cancel {
+		if err := CancelAction(t.Email, pending.ID); err != nil {
+			if errors.Is(err, ErrInvalidEntry) {
+				return "Invalid input: " + err.Error(), nil
+			}
+			return "", err
+		}
+		return fmt.Sprintf("Pending action #%d was discarded; nothing was saved.", pending.ID), nil
+	}
+
+	// 本轮对话中提出的操作，用户还没有机会回复
+	if !pending.CreatedAt.Before(t.TurnStartedAt) {
+		return fmt.Sprintf("The user has not confirmed pending action #%d yet. "+
+			"Ask the user to confirm and wait for their reply before calling this tool.", pending.ID), nil
+	}
+
+	entry, err := ConfirmAction(t.Email, pending.ID)
+	if errors.Is(err, ErrInvalidEntry) || errors.Is(err, ErrEntryNotFound) {
+		return "Invalid input: " + err.Error(), nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	loc, err := glucose.Location(t.Email)
+	if err != nil {
+		return "", err
+	}
+
+	switch pending.Action {
+	case model.LogActionCreate:
+		return fmt.Sprintf("Saved entry #%d: %s.", entry.ID, Describe(entry, loc)), nil
+	case model.LogActionUpdate:
+		return fmt.Sprintf("Updated entry #%d: %s.", pending.EntryID, Describe(entry, loc)), nil
+	default:
+		return fmt.Sprintf("Deleted entry #%d.", pending.EntryID), nil
+	}
+}
+
+func cleanInput(input string) string {
+	input = strings.TrimSpace(input)
+	input = strings.TrimPrefix(input, "```json")
+	return strings.Trim(input, "`\n ")
+}
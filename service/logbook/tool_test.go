@@ -0,0 +1,38 @@
+package logbook
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseToolTimeUsesUserTimezone(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skipf("tzdata not available: %v", err)
+	}
+	// 上海的 3 月 2 日早上，UTC 仍是 3 月 1 日
+	now := time.Date(2026, 3, 1, 23, 0, 0, 0, time.UTC).In(shanghai)
+
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"12:30", time.Date(2026, 3, 2, 4, 30, 0, 0, time.UTC)},
+		{"2026-03-01 22:00", time.Date(2026, 3, 1, 14, 0, 0, 0, time.UTC)},
+		{"2026-03-01T22:00:00Z", time.Date(2026, 3, 1, 22, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := parseToolTime(tt.value, now)
+		if err != nil {
+			t.Errorf("parseToolTime(%q) error = %v", tt.value, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseToolTime(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+
+	if _, err := parseToolTime("tomorrow", now); err == nil {
+		t.Error(`parseToolTime("tomorrow") error = nil, want error`)
+	}
+}
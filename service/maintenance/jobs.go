@@ -0,0 +1,88 @@
+package maintenance
+
+import (
+	"context"
+	"diabetes-agent-backend/dao"
+	"diabetes-agent-backend/service/mq"
+	"diabetes-agent-backend/service/summarization"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// 维护任务的 topic 和 tag
+const (
+	TopicMaintenance  = "topic_maintenance"
+	TagRetentionPurge = "tag_retention_purge"
+	TagSummarySweep   = "tag_summary_sweep"
+)
+
+const (
+	// 每天凌晨清理过期数据
+	retentionPurgeSpec = "0 3 * * *"
+
+	// 每 30 分钟补生成遗漏的会话摘要
+	summarySweepSpec = "*/30 * * * *"
+
+	// 已投递的 outbox 消息的保留时间
+	sentOutboxRetention = 7 * 24 * time.Hour
+
+	// 消息处理记录的保留时间，需要覆盖 MQ 重复投递的时间窗口
+	processedMessageRetention = 7 * 24 * time.Hour
+)
+
+// RegisterJobs 注册维护任务的周期调度和消息处理器
+func RegisterJobs() {
+	mq.Register(TopicMaintenance, TagRetentionPurge, mq.Handler{
+		Handle: HandleRetentionPurge,
+	})
+	mq.Register(TopicMaintenance, TagSummarySweep, mq.Handler{
+		Handle: HandleSummarySweep,
+	})
+
+	mq.RegisterJob(mq.Job{
+		Name:  "retention_purge",
+		Spec:  retentionPurgeSpec,
+		Topic: TopicMaintenance,
+		Tag:   TagRetentionPurge,
+	})
+	mq.RegisterJob(mq.Job{
+		Name:  "summary_sweep",
+		Spec:  summarySweepSpec,
+		Topic: TopicMaintenance,
+		Tag:   TagSummarySweep,
+	})
+}
+
+// HandleRetentionPurge 清理超过保留时间的 outbox 消息和消息处理记录
+func HandleRetentionPurge(ctx context.Context, msg *mq.Delivery) error {
+	now := time.Now()
+
+	sent, err := dao.DeleteSentOutboxMessages(now.Add(-sentOutboxRetention))
+	if err != nil {
+		return fmt.Errorf("failed to purge sent outbox messages: %v", err)
+	}
+
+	processed, err := dao.DeleteProcessedMessages(now.Add(-processedMessageRetention))
+	if err != nil {
+		return fmt.Errorf("failed to purge processed messages: %v", err)
+	}
+
+	slog.Info("Retention purge finished",
+		"msg_id", msg.MsgID,
+		"sent_outbox_messages", sent,
+		"processed_messages", processed,
+	)
+	return nil
+}
+
+// HandleSummarySweep 为积压了较多聊天记录的会话补生成摘要
+func HandleSummarySweep(ctx context.Context, msg *mq.Delivery) error {
+	count, err := summarization.SummarizerInstance.Sweep(ctx)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Summary sweep finished", "msg_id", msg.MsgID, "sessions", count)
+	return nil
+}
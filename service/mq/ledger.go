@@ -0,0 +1,131 @@
+package mq
+
+import (
+	"context"
+	"diabetes-agent-backend/dao"
+	"diabetes-agent-backend/model"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// 处理权租约时间，需要覆盖单条消息的最长处理时间
+const processingLease = 30 * time.Minute
+
+// ErrMessageInProgress 同一幂等键的消息正在被其他消费者处理，稍后重试
+var ErrMessageInProgress = errors.New("message with the same idempotency key is in progress")
+
+// Ledger 消息处理记录，按幂等键去重
+type Ledger interface {
+	// Claim 领取消息的处理权，completed 为 true 表示该消息已处理完成
+	Claim(ctx context.Context, delivery *Delivery) (claimed, completed bool, err error)
+
+	// Complete 记录消息处理完成
+	Complete(ctx context.Context, key string) error
+
+	// Release 处理失败后释放处理权
+	Release(ctx context.Context, key string) error
+}
+
+// 全局消息处理记录
+var ledger Ledger = DBLedger{}
+
+// SetLedger 替换消息处理记录，需要在 Run 之前调用
+func SetLedger(l Ledger) {
+	ledger = l
+}
+
+// idempotent 跳过已处理完成的消息，没有幂等键的消息直接处理
+func idempotent(ctx context.Context, delivery *Delivery, handle MessageHandler) error {
+	if delivery.Key == "" {
+		return handle(ctx, delivery)
+	}
+
+	claimed, completed, err := ledger.Claim(ctx, delivery)
+	if err != nil {
+		return fmt.Errorf("failed to claim message %s: %v", delivery.Key, err)
+	}
+	if completed {
+		slog.Info("Skip duplicate message",
+			"topic", delivery.Topic,
+			"msg_id", delivery.MsgID,
+			"key", delivery.Key)
+		return nil
+	}
+	if !claimed {
+		return ErrMessageInProgress
+	}
+
+	if err := handle(ctx, delivery); err != nil {
+		if err := ledger.Release(ctx, delivery.Key); err != nil {
+			slog.Error("Failed to release message", "key", delivery.Key, "err", err)
+		}
+		return err
+	}
+
+	// 记录失败时消息可能被重复处理，处理器本身仍需容忍重复消息
+	if err := ledger.Complete(ctx, delivery.Key); err != nil {
+		slog.Error("Failed to complete message", "key", delivery.Key, "err", err)
+	}
+	return nil
+}
+
+// DBLedger 将处理记录保存在 processed_message 表中，多个实例共享
+type DBLedger struct{}
+
+func (DBLedger) Claim(ctx context.Context, delivery *Delivery) (bool, bool, error) {
+	return dao.ClaimProcessedMessage(&model.ProcessedMessage{
+		IdempotencyKey: delivery.Key,
+		Topic:          delivery.Topic,
+		Tag:            delivery.Tag,
+		MsgID:          delivery.MsgID,
+	}, processingLease)
+}
+
+func (DBLedger) Complete(ctx context.Context, key string) error {
+	return dao.CompleteProcessedMessage(key)
+}
+
+func (DBLedger) Release(ctx context.Context, key string) error {
+	return dao.ReleaseProcessedMessage(key)
+}
+
+// MemoryLedger 进程内的处理记录，用于测试
+type MemoryLedger struct {
+	mu     sync.Mutex
+	status map[string]model.ProcessedStatus
+}
+
+func NewMemoryLedger() *MemoryLedger {
+	return &MemoryLedger{status: make(map[string]model.ProcessedStatus)}
+}
+
+func (l *MemoryLedger) Claim(ctx context.Context, delivery *Delivery) (bool, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	switch l.status[delivery.Key] {
+	case model.ProcessedStatusCompleted:
+		return false, true, nil
+	case model.ProcessedStatusProcessing:
+		return false, false, nil
+	}
+	l.status[delivery.Key] = model.ProcessedStatusProcessing
+	return true, false, nil
+}
+
+func (l *MemoryLedger) Complete(ctx context.Context, key string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.status[key] = model.ProcessedStatusCompleted
+	return nil
+}
+
+func (l *MemoryLedger) Release(ctx context.Context, key string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.status, key)
+	return nil
+}
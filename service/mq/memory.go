@@ -0,0 +1,135 @@
+package mq
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	memoryQueueSize   = 1024
+	memoryWorkerNum   = 10
+	memoryRetryMaxGap = time.Minute
+)
+
+// MemoryBroker 进程内的消息中间件，用于本地开发和测试
+// 与 RocketMQ 一致，处理失败的消息按退避时间重试，重试 maxReconsumeTimes 次后交给死信处理器
+// 消息只保存在内存中，进程退出后未处理的消息会丢失
+type MemoryBroker struct {
+	queue    chan *Delivery
+	registry *Registry
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	// 已发送但尚未处理结束的消息，重试中的消息也计算在内
+	pending sync.WaitGroup
+}
+
+var _ Broker = &MemoryBroker{}
+
+func NewMemoryBroker() *MemoryBroker {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &MemoryBroker{
+		queue:  make(chan *Delivery, memoryQueueSize),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+func (b *MemoryBroker) Start(registry *Registry) error {
+	b.registry = registry
+	for i := 0; i < memoryWorkerNum; i++ {
+		b.wg.Add(1)
+		go b.consume()
+	}
+	return nil
+}
+
+func (b *MemoryBroker) Send(ctx context.Context, message *Message) error {
+	payloadJSON, err := marshalPayload(message)
+	if err != nil {
+		return err
+	}
+
+	b.pending.Add(1)
+	b.enqueue(&Delivery{
+		Topic: message.Topic,
+		Tag:   message.Tag,
+		MsgID: uuid.New().String(),
+		Key:   message.Key,
+		Body:  payloadJSON,
+	}, message.Delay)
+	return nil
+}
+
+func (b *MemoryBroker) Shutdown() {
+	b.cancel()
+	b.wg.Wait()
+}
+
+// Wait 等待已发送的消息处理成功或进入死信队列，用于测试
+func (b *MemoryBroker) Wait() {
+	b.pending.Wait()
+}
+
+// enqueue 在 delay 后将消息放入队列
+func (b *MemoryBroker) enqueue(delivery *Delivery, delay time.Duration) {
+	if delay <= 0 {
+		b.push(delivery)
+		return
+	}
+	time.AfterFunc(delay, func() {
+		b.push(delivery)
+	})
+}
+
+func (b *MemoryBroker) push(delivery *Delivery) {
+	select {
+	case b.queue <- delivery:
+	case <-b.ctx.Done():
+		slog.Warn("Message dropped on shutdown", "topic", delivery.Topic, "msg_id", delivery.MsgID)
+		b.pending.Done()
+	}
+}
+
+func (b *MemoryBroker) consume() {
+	defer b.wg.Done()
+	for {
+		select {
+		case <-b.ctx.Done():
+			return
+		case delivery := <-b.queue:
+			b.handle(delivery)
+		}
+	}
+}
+
+func (b *MemoryBroker) handle(delivery *Delivery) {
+	if err := dispatch(b.ctx, b.registry, delivery); err == nil {
+		b.pending.Done()
+		return
+	}
+
+	if delivery.ReconsumeTimes >= maxReconsumeTimes {
+		if err := dispatchDeadLetter(b.ctx, b.registry, delivery); err != nil {
+			slog.Error("Dead-lettered message dropped", "topic", delivery.Topic, "msg_id", delivery.MsgID)
+		}
+		b.pending.Done()
+		return
+	}
+
+	retry := *delivery
+	retry.ReconsumeTimes++
+	b.enqueue(&retry, memoryRetryDelay(retry.ReconsumeTimes))
+}
+
+// memoryRetryDelay 第 n 次重试前的等待时间，从 1 秒开始翻倍
+func memoryRetryDelay(n int32) time.Duration {
+	delay := time.Second << (n - 1)
+	return min(delay, memoryRetryMaxGap)
+}
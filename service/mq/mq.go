@@ -3,179 +3,220 @@ package mq
 import (
 	"context"
 	"diabetes-agent-backend/config"
-	"diabetes-agent-backend/service/knowledge-base/etl"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
-	"strings"
-
-	"github.com/apache/rocketmq-client-go/v2"
-	c "github.com/apache/rocketmq-client-go/v2/consumer"
-	"github.com/apache/rocketmq-client-go/v2/primitive"
-	"github.com/apache/rocketmq-client-go/v2/producer"
-	"github.com/apache/rocketmq-client-go/v2/rlog"
-	"github.com/avast/retry-go/v4"
+	"sync"
+	"time"
 )
 
 const (
-	TopicKnowledgeBase = "topic_knowledge_base"
-	TagETL             = "tag_etl"
-	TagDelete          = "tag_delete"
-
-	consumeGroupKnowledgeBase = "cg_knowledge_base"
-	sendMessageAttempts       = 3
-	maxReconsumeTimes         = 5
-	consumeGoroutineNums      = 10
+	BrokerRocketMQ = "rocketmq"
+	BrokerMemory   = "memory"
+
+	// 消息处理失败后的最大重试次数，超过后进入死信队列
+	maxReconsumeTimes = 5
 )
 
-var (
-	// 全局生产者
-	producerInstance rocketmq.Producer
+// Broker 消息中间件，负责投递消息并将消息分发给注册的处理器
+type Broker interface {
+	// Start 按注册表订阅消息并启动投递
+	Start(registry *Registry) error
 
-	// 知识库业务消费者
-	consumerKnowledgeBase rocketmq.PushConsumer
-)
+	// Send 发送消息，Delay 大于 0 时延迟投递
+	Send(ctx context.Context, message *Message) error
 
-type MessageHandler func(context.Context, *primitive.MessageExt) error
+	Shutdown()
+}
 
 type Message struct {
 	Topic   string
 	Tag     string
 	Payload any
+
+	// 业务幂等键，相同幂等键的消息只会被成功处理一次，为空时不去重
+	Key string
+
+	// 延迟投递的时间
+	Delay time.Duration
 }
 
-func init() {
-	// 设置RocketMQ客户端（使用rlog）的日志级别
-	rlog.SetLogLevel("warn")
+// Delivery 投递给处理器的消息
+type Delivery struct {
+	Topic string
+	Tag   string
+	MsgID string
+	Key   string
+	Body  []byte
 
-	var err error
-	consumerKnowledgeBase, err = rocketmq.NewPushConsumer(
-		c.WithNameServer(config.Cfg.MQ.NameServer),
-		c.WithGroupName(consumeGroupKnowledgeBase),
-		c.WithConsumerModel(c.Clustering),
-		c.WithConsumeFromWhere(c.ConsumeFromLastOffset),
-		c.WithMaxReconsumeTimes(maxReconsumeTimes),
-		c.WithConsumeGoroutineNums(consumeGoroutineNums),
-	)
-	if err != nil {
-		panic(fmt.Sprintf("Failed to create consumer: %v", err))
-	}
+	// 已重试的次数，首次投递为 0
+	ReconsumeTimes int32
+}
 
-	producerInstance, err = rocketmq.NewProducer(
-		producer.WithNameServer(config.Cfg.MQ.NameServer),
-	)
-	if err != nil {
-		panic(fmt.Sprintf("Failed to create producer: %v", err))
-	}
+type MessageHandler func(context.Context, *Delivery) error
+
+// ExhaustedHandler 在消息最后一次重试仍然失败、即将进入死信队列时调用
+type ExhaustedHandler func(context.Context, *Delivery, error) error
+
+// Handler 一个 topic 和 tag 对应的消息处理器
+type Handler struct {
+	Handle MessageHandler
+
+	// 可选，重试次数耗尽时调用
+	OnExhausted ExhaustedHandler
+
+	// 可选，消费进入死信队列的消息
+	OnDeadLetter MessageHandler
 }
 
-func Run() error {
-	// 注册消息处理器
-	err := consumerSubscribe(consumerKnowledgeBase, TopicKnowledgeBase,
-		TagETL,
-		TagDelete,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to register handler, topic: %s, tags: %s, err: %v",
-			TopicKnowledgeBase,
-			fmt.Sprintf("%s,%s", TagETL, TagDelete),
-			err,
-		)
-	}
+// Registry 按 topic 和 tag 注册的消息处理器
+type Registry struct {
+	mu       sync.RWMutex
+	handlers map[string]map[string]Handler
+}
 
-	if err := producerInstance.Start(); err != nil {
-		return fmt.Errorf("failed to start producer: %v", err)
-	}
+func NewRegistry() *Registry {
+	return &Registry{handlers: make(map[string]map[string]Handler)}
+}
+
+// Register 注册 topic 下 tag 的处理器，重复注册时覆盖
+func (r *Registry) Register(topic, tag string, handler Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	if err := consumerKnowledgeBase.Start(); err != nil {
-		return fmt.Errorf("failed to start consumer: %v", err)
+	if r.handlers[topic] == nil {
+		r.handlers[topic] = make(map[string]Handler)
 	}
-	return nil
+	r.handlers[topic][tag] = handler
+}
+
+// Lookup 返回 topic 下 tag 的处理器
+func (r *Registry) Lookup(topic, tag string) (Handler, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	handler, ok := r.handlers[topic][tag]
+	return handler, ok
 }
 
-// consumerSubscribe 消费者订阅
-func consumerSubscribe(consumer rocketmq.PushConsumer, topic string, tags ...string) error {
-	var expression string
-	expression = strings.Join(tags, "||")
+// Topics 返回已注册的 topic 及其 tag
+func (r *Registry) Topics() map[string][]string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 
-	selector := c.MessageSelector{}
-	if expression != "" {
-		selector = c.MessageSelector{
-			Type:       c.TAG,
-			Expression: expression,
+	topics := make(map[string][]string, len(r.handlers))
+	for topic, handlers := range r.handlers {
+		for tag := range handlers {
+			topics[topic] = append(topics[topic], tag)
 		}
 	}
+	return topics
+}
 
-	err := consumer.Subscribe(topic, selector, func(ctx context.Context, messages ...*primitive.MessageExt) (c.ConsumeResult, error) {
-		for _, msg := range messages {
-			var handler MessageHandler
-			switch msg.GetTags() {
-			case TagETL:
-				handler = etl.HandleETLMessage
-			case TagDelete:
-				handler = etl.HandleDeleteMessage
-			default:
-				slog.Warn("No message handler found for topic", "topic", msg.Topic, "tags", msg.GetTags())
-				continue
-			}
-
-			if err := handler(ctx, msg); err != nil {
-				slog.Error("Failed to process message",
-					"topic", msg.Topic,
-					"msg_id", msg.MsgId,
-					"error", err)
-				return c.ConsumeRetryLater, err
-			}
-		}
-		return c.ConsumeSuccess, nil
-	})
+var (
+	// 全局消息处理器注册表
+	registry = NewRegistry()
 
+	// 全局消息中间件，Run 时按配置创建
+	broker Broker
+)
+
+// Register 注册消息处理器，需要在 Run 之前调用
+func Register(topic, tag string, handler Handler) {
+	registry.Register(topic, tag, handler)
+}
+
+// Run 按配置创建消息中间件并启动
+func Run() error {
+	var err error
+	switch config.Cfg.MQ.Broker {
+	case "", BrokerRocketMQ:
+		broker, err = NewRocketMQBroker(config.Cfg.MQ.NameServer)
+	case BrokerMemory:
+		broker = NewMemoryBroker()
+	default:
+		err = fmt.Errorf("unsupported broker: %s", config.Cfg.MQ.Broker)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to subscribe to topic %s: %v", topic, err)
+		return err
 	}
 
-	return nil
+	return broker.Start(registry)
 }
 
 // SendMessage 向 MQ 发送消息
 func SendMessage(ctx context.Context, message *Message) error {
-	payloadJSON, err := json.Marshal(message.Payload)
-	if err != nil {
-		return fmt.Errorf("failed to marshal payload: %v", err)
+	if broker == nil {
+		return fmt.Errorf("mq service is not running")
 	}
+	return broker.Send(ctx, message)
+}
 
-	msg := primitive.NewMessage(message.Topic, payloadJSON)
-	if message.Tag != "" {
-		msg = msg.WithTag(message.Tag)
+// Shutdown 关闭 MQ 服务
+func Shutdown() {
+	if broker != nil {
+		broker.Shutdown()
 	}
+}
 
-	err = retry.Do(
-		func() error {
-			_, err := producerInstance.SendSync(ctx, msg)
-			return err
-		},
-		retry.Attempts(sendMessageAttempts),
-		retry.DelayType(retry.BackOffDelay),
-		retry.OnRetry(func(n uint, err error) {
-			slog.Warn("Retrying to send message",
-				"attempt", n+1,
-				"topic", msg.Topic,
-				"err", err)
-		}),
-	)
-	if err != nil {
-		return fmt.Errorf("failed to send message to topic %s after retries: %v", msg.Topic, err)
+// dispatch 将消息交给注册的处理器，已处理完成的幂等键直接确认，处理失败且重试次数耗尽时调用 OnExhausted
+func dispatch(ctx context.Context, registry *Registry, delivery *Delivery) error {
+	handler, ok := registry.Lookup(delivery.Topic, delivery.Tag)
+	if !ok {
+		slog.Warn("No message handler found for topic", "topic", delivery.Topic, "tags", delivery.Tag)
+		return nil
 	}
 
-	return nil
+	err := idempotent(ctx, delivery, handler.Handle)
+	if err == nil {
+		return nil
+	}
+
+	slog.Error("Failed to process message",
+		"topic", delivery.Topic,
+		"msg_id", delivery.MsgID,
+		"reconsume_times", delivery.ReconsumeTimes,
+		"error", err)
+
+	// 同一幂等键的消息仍在处理时不视为处理失败
+	if handler.OnExhausted != nil && delivery.ReconsumeTimes >= maxReconsumeTimes &&
+		!errors.Is(err, ErrMessageInProgress) {
+		if err := handler.OnExhausted(ctx, delivery, err); err != nil {
+			slog.Error("Failed to handle exhausted message",
+				"topic", delivery.Topic,
+				"msg_id", delivery.MsgID,
+				"error", err)
+		}
+	}
+	return err
 }
 
-// Shutdown 关闭 MQ 服务
-func Shutdown() {
-	if producerInstance != nil {
-		producerInstance.Shutdown()
+// dispatchDeadLetter 将死信消息交给注册的死信处理器
+func dispatchDeadLetter(ctx context.Context, registry *Registry, delivery *Delivery) error {
+	handler, ok := registry.Lookup(delivery.Topic, delivery.Tag)
+	if !ok || handler.OnDeadLetter == nil {
+		slog.Warn("Dead-lettered message dropped",
+			"topic", delivery.Topic,
+			"msg_id", delivery.MsgID,
+			"tags", delivery.Tag)
+		return nil
 	}
-	if consumerKnowledgeBase != nil {
-		consumerKnowledgeBase.Shutdown()
+
+	if err := handler.OnDeadLetter(ctx, delivery); err != nil {
+		slog.Error("Failed to process dead-lettered message",
+			"topic", delivery.Topic,
+			"msg_id", delivery.MsgID,
+			"error", err)
+		return err
+	}
+	return nil
+}
+
+func marshalPayload(message *Message) ([]byte, error) {
+	payloadJSON, err := json.Marshal(message.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %v", err)
 	}
+	return payloadJSON, nil
 }
@@ -0,0 +1,223 @@
+package mq
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/apache/rocketmq-client-go/v2"
+	c "github.com/apache/rocketmq-client-go/v2/consumer"
+	"github.com/apache/rocketmq-client-go/v2/primitive"
+	"github.com/apache/rocketmq-client-go/v2/producer"
+	"github.com/apache/rocketmq-client-go/v2/rlog"
+	"github.com/avast/retry-go/v4"
+)
+
+const (
+	sendMessageAttempts  = 3
+	consumeGoroutineNums = 10
+
+	// 死信队列 topic 前缀，消息重试 maxReconsumeTimes 次仍失败后由 RocketMQ 投递到 "%DLQ%<消费组>"
+	// 死信 topic 默认只有写权限，需要在 RocketMQ 中开启读权限
+	dlqTopicPrefix = "%DLQ%"
+)
+
+// RocketMQ 支持的延迟级别，级别从 1 开始
+var delayLevels = []time.Duration{
+	time.Second, 5 * time.Second, 10 * time.Second, 30 * time.Second,
+	time.Minute, 2 * time.Minute, 3 * time.Minute, 4 * time.Minute, 5 * time.Minute,
+	6 * time.Minute, 7 * time.Minute, 8 * time.Minute, 9 * time.Minute, 10 * time.Minute,
+	20 * time.Minute, 30 * time.Minute, time.Hour, 2 * time.Hour,
+}
+
+// RocketMQBroker 基于 RocketMQ 的消息中间件，每个 topic 使用独立的消费组
+type RocketMQBroker struct {
+	nameServer []string
+	producer   rocketmq.Producer
+	consumers  []rocketmq.PushConsumer
+}
+
+var _ Broker = &RocketMQBroker{}
+
+func NewRocketMQBroker(nameServer []string) (*RocketMQBroker, error) {
+	// 设置RocketMQ客户端（使用rlog）的日志级别
+	rlog.SetLogLevel("warn")
+
+	p, err := rocketmq.NewProducer(producer.WithNameServer(nameServer))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create producer: %v", err)
+	}
+
+	return &RocketMQBroker{
+		nameServer: nameServer,
+		producer:   p,
+	}, nil
+}
+
+// consumerGroup 返回 topic 的消费组名称，形如 topic_knowledge_base 对应 cg_knowledge_base
+func consumerGroup(topic string) string {
+	return "cg_" + strings.TrimPrefix(topic, "topic_")
+}
+
+func (b *RocketMQBroker) Start(registry *Registry) error {
+	if err := b.producer.Start(); err != nil {
+		return fmt.Errorf("failed to start producer: %v", err)
+	}
+
+	for topic, tags := range registry.Topics() {
+		group := consumerGroup(topic)
+		consumer, err := rocketmq.NewPushConsumer(
+			c.WithNameServer(b.nameServer),
+			c.WithGroupName(group),
+			c.WithConsumerModel(c.Clustering),
+			c.WithConsumeFromWhere(c.ConsumeFromLastOffset),
+			c.WithMaxReconsumeTimes(maxReconsumeTimes),
+			c.WithConsumeGoroutineNums(consumeGoroutineNums),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create consumer: %v", err)
+		}
+
+		selector := c.MessageSelector{
+			Type:       c.TAG,
+			Expression: strings.Join(tags, "||"),
+		}
+		err = consumer.Subscribe(topic, selector, func(ctx context.Context, messages ...*primitive.MessageExt) (c.ConsumeResult, error) {
+			for _, msg := range messages {
+				if err := dispatch(ctx, registry, toDelivery(topic, msg)); err != nil {
+					return c.ConsumeRetryLater, err
+				}
+			}
+			return c.ConsumeSuccess, nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to register handler, topic: %s, tags: %s, err: %v",
+				topic, strings.Join(tags, ","), err)
+		}
+
+		if err := consumer.Start(); err != nil {
+			return fmt.Errorf("failed to start consumer of topic %s: %v", topic, err)
+		}
+		b.consumers = append(b.consumers, consumer)
+
+		if hasDeadLetterHandler(registry, topic, tags) {
+			if err := b.startDeadLetterConsumer(registry, topic, group); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// startDeadLetterConsumer 订阅消费组的死信队列，死信消息保留原始 tag，按原始 topic 和 tag 分发
+func (b *RocketMQBroker) startDeadLetterConsumer(registry *Registry, topic, group string) error {
+	consumer, err := rocketmq.NewPushConsumer(
+		c.WithNameServer(b.nameServer),
+		c.WithGroupName(group+"_dlq"),
+		c.WithConsumerModel(c.Clustering),
+		c.WithConsumeFromWhere(c.ConsumeFromLastOffset),
+		c.WithMaxReconsumeTimes(maxReconsumeTimes),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create DLQ consumer: %v", err)
+	}
+
+	dlqTopic := dlqTopicPrefix + group
+	err = consumer.Subscribe(dlqTopic, c.MessageSelector{}, func(ctx context.Context, messages ...*primitive.MessageExt) (c.ConsumeResult, error) {
+		for _, msg := range messages {
+			if err := dispatchDeadLetter(ctx, registry, toDelivery(topic, msg)); err != nil {
+				return c.ConsumeRetryLater, err
+			}
+		}
+		return c.ConsumeSuccess, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to register DLQ handler, topic: %s, err: %v", dlqTopic, err)
+	}
+
+	if err := consumer.Start(); err != nil {
+		return fmt.Errorf("failed to start DLQ consumer: %v", err)
+	}
+	b.consumers = append(b.consumers, consumer)
+	return nil
+}
+
+func (b *RocketMQBroker) Send(ctx context.Context, message *Message) error {
+	payloadJSON, err := marshalPayload(message)
+	if err != nil {
+		return err
+	}
+
+	msg := primitive.NewMessage(message.Topic, payloadJSON)
+	if message.Tag != "" {
+		msg = msg.WithTag(message.Tag)
+	}
+	if message.Key != "" {
+		msg = msg.WithKeys([]string{message.Key})
+	}
+	if message.Delay > 0 {
+		msg = msg.WithDelayTimeLevel(delayLevel(message.Delay))
+	}
+
+	err = retry.Do(
+		func() error {
+			_, err := b.producer.SendSync(ctx, msg)
+			return err
+		},
+		retry.Attempts(sendMessageAttempts),
+		retry.DelayType(retry.BackOffDelay),
+		retry.OnRetry(func(n uint, err error) {
+			slog.Warn("Retrying to send message",
+				"attempt", n+1,
+				"topic", msg.Topic,
+				"err", err)
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to send message to topic %s after retries: %v", msg.Topic, err)
+	}
+
+	return nil
+}
+
+func (b *RocketMQBroker) Shutdown() {
+	if b.producer != nil {
+		b.producer.Shutdown()
+	}
+	for _, consumer := range b.consumers {
+		consumer.Shutdown()
+	}
+}
+
+// delayLevel 返回不小于 delay 的最小延迟级别，超过最大级别时使用最大级别
+func delayLevel(delay time.Duration) int {
+	for i, level := range delayLevels {
+		if delay <= level {
+			return i + 1
+		}
+	}
+	return len(delayLevels)
+}
+
+func toDelivery(topic string, msg *primitive.MessageExt) *Delivery {
+	return &Delivery{
+		Topic:          topic,
+		Tag:            msg.GetTags(),
+		MsgID:          msg.MsgId,
+		Key:            msg.GetKeys(),
+		Body:           msg.Body,
+		ReconsumeTimes: msg.ReconsumeTimes,
+	}
+}
+
+func hasDeadLetterHandler(registry *Registry, topic string, tags []string) bool {
+	for _, tag := range tags {
+		if handler, ok := registry.Lookup(topic, tag); ok && handler.OnDeadLetter != nil {
+			return true
+		}
+	}
+	return false
+}
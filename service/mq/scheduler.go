@@ -0,0 +1,223 @@
+package mq
+
+import (
+	"context"
+	"diabetes-agent-backend/dao"
+	"diabetes-agent-backend/model"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/robfig/cron"
+	"gorm.io/gorm"
+)
+
+const (
+	schedulePollInterval = time.Second
+	scheduleBatchSize    = 100
+)
+
+// Job 按 cron 表达式周期执行的任务，到期时向 Topic 发送消息，由注册的消息处理器执行
+type Job struct {
+	// 任务名称，多个实例之间唯一
+	Name string
+
+	// 标准 5 段 cron 表达式，使用服务器本地时区
+	Spec string
+
+	Topic   string
+	Tag     string
+	Payload any
+}
+
+// Scheduler 调度持久化在 scheduled_job 表中的周期任务和延迟消息
+// 到期的任务在同一事务中写入 outbox 并计算下次执行时间，多个实例同时运行时每次触发只会投递一次
+type Scheduler struct {
+	mu   sync.Mutex
+	jobs []Job
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// SchedulerInstance Scheduler单例实例
+var SchedulerInstance = &Scheduler{}
+
+// RegisterJob 注册周期任务，需要在 Scheduler 启动之前调用
+func RegisterJob(job Job) {
+	SchedulerInstance.mu.Lock()
+	defer SchedulerInstance.mu.Unlock()
+	SchedulerInstance.jobs = append(SchedulerInstance.jobs, job)
+}
+
+// ScheduleMessage 持久化一条延迟消息，在 at 时刻投递
+// 与 Message.Delay 不同，延迟时间不受 RocketMQ 延迟级别的限制，服务重启后也不会丢失
+func ScheduleMessage(message *Message, at time.Time) error {
+	payloadJSON, err := marshalPayload(message)
+	if err != nil {
+		return err
+	}
+
+	err = dao.CreateScheduledJob(&model.ScheduledJob{
+		Name:           "message:" + uuid.New().String(),
+		Topic:          message.Topic,
+		Tag:            message.Tag,
+		IdempotencyKey: message.Key,
+		Payload:        string(payloadJSON),
+		Enabled:        true,
+		NextRunAt:      at,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create scheduled job: %v", err)
+	}
+	return nil
+}
+
+// Run 写入注册的周期任务并启动调度协程，应在 outbox relay 启动后调用
+func (s *Scheduler) Run() error {
+	if err := s.syncJobs(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+
+	go s.loop(ctx)
+	return nil
+}
+
+// Shutdown 停止调度协程，等待正在调度的批次完成
+func (s *Scheduler) Shutdown() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	<-s.done
+}
+
+func (s *Scheduler) syncJobs() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for _, job := range s.jobs {
+		schedule, err := cron.ParseStandard(job.Spec)
+		if err != nil {
+			return fmt.Errorf("invalid cron spec of job %s: %v", job.Name, err)
+		}
+
+		payloadJSON, err := marshalPayload(&Message{Payload: job.Payload})
+		if err != nil {
+			return err
+		}
+
+		err = dao.SyncScheduledJob(&model.ScheduledJob{
+			Name:      job.Name,
+			CronSpec:  job.Spec,
+			Topic:     job.Topic,
+			Tag:       job.Tag,
+			Payload:   string(payloadJSON),
+			Enabled:   true,
+			NextRunAt: schedule.Next(now),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to sync scheduled job %s: %v", job.Name, err)
+		}
+	}
+	return nil
+}
+
+func (s *Scheduler) loop(ctx context.Context) {
+	defer close(s.done)
+	slog.Info("Starting job scheduler")
+
+	ticker := time.NewTicker(schedulePollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Job scheduler shutting down")
+			return
+		case <-ticker.C:
+		}
+
+		// 一次领满说明还有积压，继续调度直到清空
+		for {
+			fired, err := s.fireDueJobs()
+			if err != nil {
+				slog.Error("Failed to fire scheduled jobs", "err", err)
+				break
+			}
+			if fired < scheduleBatchSize || ctx.Err() != nil {
+				break
+			}
+		}
+	}
+}
+
+// fireDueJobs 将到期的任务写入 outbox，返回领取的任务数量
+func (s *Scheduler) fireDueJobs() (int, error) {
+	var count int
+	err := dao.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		jobs, err := dao.ClaimDueScheduledJobs(tx, now, scheduleBatchSize)
+		if err != nil {
+			return err
+		}
+		count = len(jobs)
+
+		for _, job := range jobs {
+			if err := fireJob(tx, &job, now); err != nil {
+				return fmt.Errorf("failed to fire job %s: %v", job.Name, err)
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+func fireJob(tx *gorm.DB, job *model.ScheduledJob, now time.Time) error {
+	if job.CronSpec == "" {
+		if err := enqueueJob(tx, job); err != nil {
+			return err
+		}
+		return dao.DeleteScheduledJob(tx, job.ID)
+	}
+
+	schedule, err := cron.ParseStandard(job.CronSpec)
+	if err != nil {
+		slog.Error("Disable scheduled job with invalid cron spec",
+			"job", job.Name,
+			"cron_spec", job.CronSpec,
+			"err", err)
+		return dao.DisableScheduledJob(tx, job.ID)
+	}
+
+	if err := enqueueJob(tx, job); err != nil {
+		return err
+	}
+
+	// 服务停机期间错过的多次触发只补执行一次
+	return dao.AdvanceScheduledJob(tx, job.ID, now, schedule.Next(now))
+}
+
+// enqueueJob 将任务的本次触发写入 outbox，幂等键包含计划执行时间，消费者不会重复执行同一次触发
+func enqueueJob(tx *gorm.DB, job *model.ScheduledJob) error {
+	key := job.IdempotencyKey
+	if key == "" {
+		key = fmt.Sprintf("job:%s:%d", job.Name, job.NextRunAt.Unix())
+	}
+
+	return dao.CreateOutboxMessage(tx, &model.OutboxMessage{
+		Topic:          job.Topic,
+		Tag:            job.Tag,
+		IdempotencyKey: key,
+		Payload:        job.Payload,
+		Status:         model.OutboxStatusPending,
+		NextAttemptAt:  time.Now(),
+	})
+}
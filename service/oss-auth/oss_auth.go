@@ -26,6 +26,7 @@ import (
 const (
 	OSSKeyPrefixKnowledgeBase = "knowledge-base"
 	OSSKeyPrefixUpload        = "upload"
+	OSSKeyPrefixReport        = "report"
 
 	// STS 临时凭证的会话有效期（单位为秒）
 	roleSessionExpiration = 3600
@@ -34,12 +35,7 @@ const (
 	preSignedExpires = 15 * time.Minute
 )
 
-var (
-	bucketName = config.Cfg.OSS.BucketName
-	region     = config.Cfg.OSS.Region
-
-	httpClient *http.Client = utils.DefaultHTTPClient()
-)
+var httpClient *http.Client = utils.DefaultHTTPClient()
 
 // GeneratePolicyToken 应用以 RAM 用户身份扮演 RAM 角色获取 STS 临时凭证，前端使用该凭证访问 OSS
 func GeneratePolicyToken(req request.OSSAuthRequest) (*response.GetPolicyTokenResponse, error) {
@@ -70,9 +66,9 @@ func GeneratePolicyToken(req request.OSSAuthRequest) (*response.GetPolicyTokenRe
 	policyMap := map[string]any{
 		"expiration": expiration.Format("2006-01-02T15:04:05.000Z"),
 		"conditions": []any{
-			map[string]string{"bucket": bucketName},
+			map[string]string{"bucket": config.Cfg.OSS.BucketName},
 			map[string]string{"x-oss-signature-version": "OSS4-HMAC-SHA256"},
-			map[string]string{"x-oss-credential": fmt.Sprintf("%v/%v/%v/%v/aliyun_v4_request", *cred.AccessKeyId, date, region, "oss")},
+			map[string]string{"x-oss-credential": fmt.Sprintf("%v/%v/%v/%v/aliyun_v4_request", *cred.AccessKeyId, date, config.Cfg.OSS.Region, "oss")},
 			map[string]string{"x-oss-date": utcTime.Format("20060102T150405Z")},
 			map[string]string{"x-oss-security-token": *cred.SecurityToken},
 		},
@@ -96,10 +92,10 @@ func GeneratePolicyToken(req request.OSSAuthRequest) (*response.GetPolicyTokenRe
 		Policy:           stringToSign,
 		SecurityToken:    *cred.SecurityToken,
 		SignatureVersion: "OSS4-HMAC-SHA256",
-		Credential:       fmt.Sprintf("%v/%v/%v/%v/aliyun_v4_request", *cred.AccessKeyId, date, region, "oss"),
+		Credential:       fmt.Sprintf("%v/%v/%v/%v/aliyun_v4_request", *cred.AccessKeyId, date, config.Cfg.OSS.Region, "oss"),
 		Date:             utcTime.UTC().Format("20060102T150405Z"),
 		Signature:        generatePolicyTokenSignature(stringToSign, cred, date),
-		Host:             fmt.Sprintf("https://%s.oss-%s.aliyuncs.com", bucketName, region),
+		Host:             fmt.Sprintf("https://%s.oss-%s.aliyuncs.com", config.Cfg.OSS.BucketName, config.Cfg.OSS.Region),
 		Key:              key,
 	}
 
@@ -118,7 +114,7 @@ func generatePolicyTokenSignature(stringToSign string, cred *credentials.Credent
 	h1Key := h1.Sum(nil)
 
 	h2 := hmac.New(hmacHash, h1Key)
-	io.WriteString(h2, region)
+	io.WriteString(h2, config.Cfg.OSS.Region)
 	h2Key := h2.Sum(nil)
 
 	h3 := hmac.New(hmacHash, h2Key)
@@ -136,16 +132,29 @@ func generatePolicyTokenSignature(stringToSign string, cred *credentials.Credent
 	return signature
 }
 
+// ObjectName 返回文件在 OSS 上的路径，与前端上传时使用的路径一致
+func ObjectName(req request.OSSAuthRequest) (string, error) {
+	return generateKey(req)
+}
+
 func generateKey(req request.OSSAuthRequest) (string, error) {
 	switch req.Namespace {
 	// 对象路径格式：knowledge-base/{email}/{fileName}
+	// 知识文件的后续版本：knowledge-base/{email}/v{version}/{fileName}
 	case OSSKeyPrefixKnowledgeBase:
+		if req.Version > 1 {
+			return strings.Join([]string{OSSKeyPrefixKnowledgeBase, req.Email, fmt.Sprintf("v%d", req.Version), req.FileName}, "/"), nil
+		}
 		return strings.Join([]string{OSSKeyPrefixKnowledgeBase, req.Email, req.FileName}, "/"), nil
 
 	// 对象路径格式：upload/{email}/{sessionID}/{fileName}
 	case OSSKeyPrefixUpload:
 		return strings.Join([]string{OSSKeyPrefixUpload, req.Email, req.SessionID, req.FileName}, "/"), nil
 
+	// 对象路径格式：report/{email}/{fileName}
+	case OSSKeyPrefixReport:
+		return strings.Join([]string{OSSKeyPrefixReport, req.Email, req.FileName}, "/"), nil
+
 	default:
 		return "", fmt.Errorf("invalid namespace: %v", req.Namespace)
 	}
@@ -153,31 +162,57 @@ func generateKey(req request.OSSAuthRequest) (string, error) {
 
 // GeneratePresignedURL 生成预签名URL，用于前端获取临时下载链接
 func GeneratePresignedURL(req request.OSSAuthRequest) (string, error) {
-	cfg := &oss.Config{
-		Region: oss.Ptr(config.Cfg.OSS.Region),
-		CredentialsProvider: osscredentials.NewStaticCredentialsProvider(
-			config.Cfg.OSS.AccessKeyID,
-			config.Cfg.OSS.AccessKeySecret,
-		),
-		HttpClient: httpClient,
-	}
-	client := oss.NewClient(cfg)
+	return PresignObject(req, preSignedExpires)
+}
 
+// PresignObject 生成指定有效期的预签名下载链接，有效期最长为 7 天
+func PresignObject(req request.OSSAuthRequest, expires time.Duration) (string, error) {
 	key, err := generateKey(req)
 	if err != nil {
 		return "", fmt.Errorf("fail to generate oss key: %v", err)
 	}
 
 	getObjectRequest := &oss.GetObjectRequest{
-		Bucket: oss.Ptr(bucketName),
+		Bucket: oss.Ptr(config.Cfg.OSS.BucketName),
 		Key:    oss.Ptr(key),
 	}
 
 	ctx := context.Background()
-	result, err := client.Presign(ctx, getObjectRequest, oss.PresignExpires(preSignedExpires))
+	result, err := newClient().Presign(ctx, getObjectRequest, oss.PresignExpires(expires))
 	if err != nil {
 		return "", fmt.Errorf("failed to get object presign %v", err)
 	}
 
 	return result.URL, nil
 }
+
+// UploadObject 由服务端上传文件，如生成的报告
+func UploadObject(ctx context.Context, req request.OSSAuthRequest, body io.Reader, contentType string) error {
+	key, err := generateKey(req)
+	if err != nil {
+		return fmt.Errorf("fail to generate oss key: %v", err)
+	}
+
+	_, err = newClient().PutObject(ctx, &oss.PutObjectRequest{
+		Bucket:      oss.Ptr(config.Cfg.OSS.BucketName),
+		Key:         oss.Ptr(key),
+		ContentType: oss.Ptr(contentType),
+		Body:        body,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put object: %v", err)
+	}
+	return nil
+}
+
+func newClient() *oss.Client {
+	cfg := &oss.Config{
+		Region: oss.Ptr(config.Cfg.OSS.Region),
+		CredentialsProvider: osscredentials.NewStaticCredentialsProvider(
+			config.Cfg.OSS.AccessKeyID,
+			config.Cfg.OSS.AccessKeySecret,
+		),
+		HttpClient: httpClient,
+	}
+	return oss.NewClient(cfg)
+}
@@ -0,0 +1,154 @@
+package outbox
+
+import (
+	"context"
+	"diabetes-agent-backend/dao"
+	"diabetes-agent-backend/model"
+	"diabetes-agent-backend/service/mq"
+	"encoding/json"
+	"log/slog"
+	"time"
+)
+
+const (
+	pollInterval = time.Second
+	batchSize    = 100
+
+	// 领取消息后的租约时间，投递结果在此期间内未记录时消息会被重新领取
+	claimLease = time.Minute
+
+	retryBaseDelay = time.Second
+	retryMaxDelay  = 5 * time.Minute
+
+	// 超过该时间仍未投递的消息视为卡住
+	StuckAfter = 5 * time.Minute
+
+	// 卡住消息的告警间隔
+	stuckReportInterval = time.Minute
+)
+
+// Relay 轮询 outbox 表，将事务提交后的消息投递到 MQ
+type Relay struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// RelayInstance Relay单例实例
+var RelayInstance = &Relay{}
+
+// Run 启动投递协程，应在 MQ 生产者启动后调用
+func (r *Relay) Run() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.done = make(chan struct{})
+
+	go r.loop(ctx)
+}
+
+// Shutdown 停止投递协程，等待正在投递的批次完成
+func (r *Relay) Shutdown() {
+	if r.cancel == nil {
+		return
+	}
+	r.cancel()
+	<-r.done
+}
+
+func (r *Relay) loop(ctx context.Context) {
+	defer close(r.done)
+	slog.Info("Starting outbox relay")
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	var lastStuckReport time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Outbox relay shutting down")
+			return
+		case <-ticker.C:
+		}
+
+		// 一次领满说明还有积压，继续投递直到清空
+		for {
+			if r.relayBatch(ctx) < batchSize || ctx.Err() != nil {
+				break
+			}
+		}
+
+		if time.Since(lastStuckReport) >= stuckReportInterval {
+			lastStuckReport = time.Now()
+			reportStuckMessages()
+		}
+	}
+}
+
+// relayBatch 领取并投递一批消息，返回领取的消息数量
+func (r *Relay) relayBatch(ctx context.Context) int {
+	messages, err := dao.ClaimOutboxMessages(batchSize, claimLease)
+	if err != nil {
+		slog.Error("Failed to claim outbox messages", "err", err)
+		return 0
+	}
+
+	for _, message := range messages {
+		r.relay(ctx, &message)
+	}
+	return len(messages)
+}
+
+func (r *Relay) relay(ctx context.Context, message *model.OutboxMessage) {
+	err := mq.SendMessage(ctx, &mq.Message{
+		Topic:   message.Topic,
+		Tag:     message.Tag,
+		Key:     message.IdempotencyKey,
+		Payload: json.RawMessage(message.Payload),
+	})
+	if err != nil {
+		nextAttemptAt := time.Now().Add(retryDelay(message.Attempts))
+		slog.Warn("Failed to relay outbox message",
+			"outbox_id", message.ID,
+			"topic", message.Topic,
+			"tag", message.Tag,
+			"attempts", message.Attempts+1,
+			"next_attempt_at", nextAttemptAt,
+			"err", err,
+		)
+		if err := dao.MarkOutboxMessageFailed(message.ID, err.Error(), nextAttemptAt); err != nil {
+			slog.Error("Failed to record outbox message failure", "outbox_id", message.ID, "err", err)
+		}
+		return
+	}
+
+	// 投递成功但记录失败时，租约到期后消息会被再次投递，消费者需要容忍重复消息
+	if err := dao.MarkOutboxMessageSent(message.ID); err != nil {
+		slog.Error("Failed to mark outbox message sent", "outbox_id", message.ID, "err", err)
+	}
+}
+
+// retryDelay 按已尝试次数指数退避
+func retryDelay(attempts int) time.Duration {
+	delay := retryBaseDelay
+	for i := 0; i < attempts && delay < retryMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, retryMaxDelay)
+}
+
+// reportStuckMessages 对超时未投递的消息告警
+func reportStuckMessages() {
+	count, err := dao.CountStuckOutboxMessages(time.Now().Add(-StuckAfter))
+	if err != nil {
+		slog.Error("Failed to count stuck outbox messages", "err", err)
+		return
+	}
+	if count > 0 {
+		slog.Warn("Outbox messages are stuck", "count", count, "stuck_after", StuckAfter)
+	}
+}
+
+// GetStuckMessages 返回用户超时未投递的消息
+func GetStuckMessages(email string) ([]model.OutboxMessage, error) {
+	return dao.GetStuckOutboxMessages(email, time.Now().Add(-StuckAfter), batchSize)
+}
@@ -0,0 +1,284 @@
+package patientmemory
+
+import (
+	"bytes"
+	"context"
+	"diabetes-agent-backend/config"
+	"diabetes-agent-backend/dao"
+	"diabetes-agent-backend/model"
+	"diabetes-agent-backend/service/chat"
+	"diabetes-agent-backend/service/mq"
+	"diabetes-agent-backend/utils"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/llms/openai"
+	"gorm.io/gorm"
+)
+
+// 长期记忆 MQ 消息的 topic 和 tag
+const (
+	TopicPatientMemory = "topic_patient_memory"
+	TagExtract         = "tag_extract"
+)
+
+const (
+	extractionModel = "qwen-plus"
+
+	// 低于该置信度的提取结果不保存
+	minConfidence = 0.6
+
+	// 提取时单条聊天记录保留的 token 数
+	maxTokensPerMessage = 2000
+)
+
+//go:embed prompts/extraction.txt
+var extractionPrompt string
+
+var extractionTemplate = template.Must(template.New("extraction").Parse(extractionPrompt))
+
+// ExtractionTask 一轮对话结束后提取长期记忆的任务
+type ExtractionTask struct {
+	UserEmail      string `json:"user_email"`
+	SessionID      string `json:"session_id"`
+	UserMessageID  uint   `json:"user_message_id"`
+	AgentMessageID uint   `json:"agent_message_id"`
+}
+
+// ExtractedFact 大模型返回的单条记忆
+type ExtractedFact struct {
+	Category   model.FactCategory `json:"category"`
+	Content    string             `json:"content"`
+	Confidence float64            `json:"confidence"`
+	ReplacesID uint               `json:"replaces_id"`
+}
+
+type extractionResponse struct {
+	Facts []ExtractedFact `json:"facts"`
+}
+
+var (
+	extractor     llms.Model
+	extractorOnce sync.Once
+	extractorErr  error
+)
+
+func getExtractor() (llms.Model, error) {
+	extractorOnce.Do(func() {
+		extractor, extractorErr = openai.New(
+			openai.WithModel(extractionModel),
+			openai.WithToken(config.Cfg.Model.APIKey),
+			openai.WithBaseURL(chat.BaseURL),
+			openai.WithHTTPClient(utils.DefaultHTTPClient()),
+		)
+	})
+	return extractor, extractorErr
+}
+
+// RegisterHandlers 注册长期记忆提取的消息处理器
+func RegisterHandlers() {
+	mq.Register(TopicPatientMemory, TagExtract, mq.Handler{
+		Handle: HandleExtractionMessage,
+	})
+}
+
+// RegisterExtractionTask 将提取任务写入 outbox，不等待提取完成
+func RegisterExtractionTask(task ExtractionTask) error {
+	if task.UserMessageID == 0 || task.AgentMessageID == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(task)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %v", err)
+	}
+
+	err = dao.CreateOutboxMessage(dao.DB, &model.OutboxMessage{
+		UserEmail:      task.UserEmail,
+		Topic:          TopicPatientMemory,
+		Tag:            TagExtract,
+		IdempotencyKey: fmt.Sprintf("%s:%d", TagExtract, task.AgentMessageID),
+		Payload:        string(data),
+		Status:         model.OutboxStatusPending,
+		NextAttemptAt:  time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create outbox message: %v", err)
+	}
+	return nil
+}
+
+// HandleExtractionMessage 从一轮对话中提取长期记忆，提取失败时返回错误由 MQ 重试
+func HandleExtractionMessage(ctx context.Context, msg *mq.Delivery) error {
+	var task ExtractionTask
+	if err := json.Unmarshal(msg.Body, &task); err != nil {
+		return fmt.Errorf("failed to unmarshal message body: %v", err)
+	}
+
+	userMessage, err := dao.GetMessageByID(task.UserMessageID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// 会话已被删除
+			return nil
+		}
+		return fmt.Errorf("failed to get message %d: %v", task.UserMessageID, err)
+	}
+	agentMessage, err := dao.GetMessageByID(task.AgentMessageID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to get message %d: %v", task.AgentMessageID, err)
+	}
+
+	facts, err := GetFacts(task.UserEmail)
+	if err != nil {
+		return err
+	}
+
+	extracted, err := extractFacts(ctx, facts, userMessage.Content, agentMessage.Content)
+	if err != nil {
+		return err
+	}
+
+	saved, err := applyFacts(&task, facts, extracted)
+	if err != nil {
+		return err
+	}
+
+	if saved > 0 {
+		slog.Info("Patient facts extracted",
+			"msg_id", msg.MsgID,
+			"session_id", task.SessionID,
+			"facts", saved)
+	}
+	return nil
+}
+
+func extractFacts(ctx context.Context, facts []model.PatientFact, userMessage, agentMessage string) ([]ExtractedFact, error) {
+	llm, err := getExtractor()
+	if err != nil {
+		return nil, fmt.Errorf("error creating extraction model: %v", err)
+	}
+
+	var prompt bytes.Buffer
+	if err := extractionTemplate.Execute(&prompt, map[string]any{
+		"Facts":        facts,
+		"UserMessage":  utils.TruncateTokens(userMessage, maxTokensPerMessage),
+		"AgentMessage": utils.TruncateTokens(agentMessage, maxTokensPerMessage),
+	}); err != nil {
+		return nil, fmt.Errorf("error executing template: %v", err)
+	}
+
+	resp, err := llm.GenerateContent(ctx, []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeHuman, prompt.String()),
+	}, llms.WithJSONMode(), llms.WithTemperature(0))
+	if err != nil {
+		return nil, fmt.Errorf("error generating content: %v", err)
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("empty response from extraction model")
+	}
+
+	var parsed extractionResponse
+	if err := json.Unmarshal([]byte(trimCodeFence(resp.Choices[0].Content)), &parsed); err != nil {
+		return nil, fmt.Errorf("error parsing extraction result: %v", err)
+	}
+	return parsed.Facts, nil
+}
+
+// applyFacts 保存提取的记忆，返回新增或更新的记忆数量
+// 用户编辑过的记忆不会被覆盖，用户删除过的记忆不会被重新添加
+func applyFacts(task *ExtractionTask, facts []model.PatientFact, extracted []ExtractedFact) (int, error) {
+	deleted, err := dao.GetDeletedPatientFacts(task.UserEmail)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get deleted patient facts: %v", err)
+	}
+
+	existing := make(map[uint]*model.PatientFact, len(facts))
+	for i := range facts {
+		existing[facts[i].ID] = &facts[i]
+	}
+
+	now := time.Now()
+	var saved int
+	for _, item := range extracted {
+		item.Content = strings.TrimSpace(item.Content)
+		if !item.Category.IsValid() || item.Content == "" || item.Confidence < minConfidence {
+			continue
+		}
+
+		// 与已有记忆相同时只更新提到的时间
+		if same := findFact(facts, item); same != nil {
+			if _, err := dao.UpdatePatientFact(task.UserEmail, same.ID, map[string]any{
+				"confidence":        max(same.Confidence, item.Confidence),
+				"source_session_id": task.SessionID,
+				"source_message_id": task.UserMessageID,
+				"observed_at":       now,
+			}); err != nil {
+				return saved, fmt.Errorf("failed to update patient fact: %v", err)
+			}
+			continue
+		}
+		if findFact(deleted, item) != nil {
+			continue
+		}
+
+		if replaced := existing[item.ReplacesID]; replaced != nil && replaced.Source == model.FactSourceExtracted {
+			if _, err := dao.UpdatePatientFact(task.UserEmail, replaced.ID, map[string]any{
+				"category":          item.Category,
+				"content":           item.Content,
+				"confidence":        item.Confidence,
+				"source_session_id": task.SessionID,
+				"source_message_id": task.UserMessageID,
+				"observed_at":       now,
+			}); err != nil {
+				return saved, fmt.Errorf("failed to update patient fact: %v", err)
+			}
+			saved++
+			continue
+		}
+
+		if err := dao.CreatePatientFact(&model.PatientFact{
+			UserEmail:       task.UserEmail,
+			Category:        item.Category,
+			Content:         item.Content,
+			Confidence:      item.Confidence,
+			Source:          model.FactSourceExtracted,
+			SourceSessionID: task.SessionID,
+			SourceMessageID: task.UserMessageID,
+			ObservedAt:      now,
+		}); err != nil {
+			return saved, fmt.Errorf("failed to create patient fact: %v", err)
+		}
+		saved++
+	}
+	return saved, nil
+}
+
+// findFact 查找分类和内容相同的记忆
+func findFact(facts []model.PatientFact, item ExtractedFact) *model.PatientFact {
+	for i := range facts {
+		if facts[i].Category == item.Category && strings.EqualFold(facts[i].Content, item.Content) {
+			return &facts[i]
+		}
+	}
+	return nil
+}
+
+// trimCodeFence 去除模型输出中可能包含的 ```json 代码块标记
+func trimCodeFence(content string) string {
+	content = strings.TrimSpace(content)
+	content = strings.TrimPrefix(content, "```json")
+	content = strings.TrimPrefix(content, "```")
+	content = strings.TrimSuffix(content, "```")
+	return strings.TrimSpace(content)
+}
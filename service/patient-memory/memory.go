@@ -0,0 +1,165 @@
+package patientmemory
+
+import (
+	"diabetes-agent-backend/dao"
+	"diabetes-agent-backend/model"
+	"diabetes-agent-backend/utils"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// 注入 Agent 提示词的记忆 token 上限
+	promptTokenBudget = 1000
+
+	// 与用户问题相关时提高记忆的排序
+	relevanceBoost = 0.5
+)
+
+var (
+	// ErrInvalidFact 编辑的记忆分类或内容无效，错误信息可以直接返回给用户
+	ErrInvalidFact = errors.New("invalid patient fact")
+
+	// ErrFactNotFound 记忆不存在或不属于该用户
+	ErrFactNotFound = errors.New("patient fact not found")
+)
+
+// 始终注入提示词的记忆分类，其余分类按与问题的相关程度选取
+var coreCategories = map[model.FactCategory]bool{
+	model.FactDiabetesType: true,
+	model.FactMedication:   true,
+	model.FactComplication: true,
+	model.FactAllergy:      true,
+}
+
+func GetFacts(email string) ([]model.PatientFact, error) {
+	facts, err := dao.GetPatientFacts(email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get patient facts: %v", err)
+	}
+	return facts, nil
+}
+
+// UpdateFact 用户编辑记忆，编辑后的记忆不会被自动提取覆盖
+func UpdateFact(email string, id uint, category model.FactCategory, content string) error {
+	content = strings.TrimSpace(content)
+	if !category.IsValid() {
+		return fmt.Errorf("%w: unsupported category: %s", ErrInvalidFact, category)
+	}
+	if content == "" {
+		return fmt.Errorf("%w: content is empty", ErrInvalidFact)
+	}
+
+	found, err := dao.UpdatePatientFact(email, id, map[string]any{
+		"category":    category,
+		"content":     content,
+		"confidence":  1.0,
+		"source":      model.FactSourceUser,
+		"observed_at": time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update patient fact: %v", err)
+	}
+	if !found {
+		return fmt.Errorf("%w: %d", ErrFactNotFound, id)
+	}
+	return nil
+}
+
+// DeleteFact 删除记忆，之后的对话中不会再提取相同的记忆
+func DeleteFact(email string, id uint) error {
+	found, err := dao.DeletePatientFact(email, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete patient fact: %v", err)
+	}
+	if !found {
+		return fmt.Errorf("%w: %d", ErrFactNotFound, id)
+	}
+	return nil
+}
+
+// PromptContext 选取与本轮问题相关的记忆，格式化后注入 Agent 提示词，没有记忆时返回空字符串
+func PromptContext(email, query string) (string, error) {
+	facts, err := GetFacts(email)
+	if err != nil {
+		return "", err
+	}
+	if len(facts) == 0 {
+		return "", nil
+	}
+
+	// 核心分类优先，其余按相关程度和置信度排序
+	score := func(fact model.PatientFact) float64 {
+		s := fact.Confidence
+		if coreCategories[fact.Category] {
+			s += 2
+		}
+		if relevant(fact.Content, query) {
+			s += relevanceBoost
+		}
+		return s
+	}
+	slices.SortStableFunc(facts, func(a, b model.PatientFact) int {
+		if sa, sb := score(a), score(b); sa != sb {
+			if sa > sb {
+				return -1
+			}
+			return 1
+		}
+		return b.ObservedAt.Compare(a.ObservedAt)
+	})
+
+	var (
+		lines  []string
+		budget = promptTokenBudget
+	)
+	for _, fact := range facts {
+		line := fmt.Sprintf("- [%s] %s (%s)", fact.Category, fact.Content, fact.ObservedAt.Format(time.DateOnly))
+		tokens := utils.CountTokens(line)
+		if tokens > budget {
+			break
+		}
+		budget -= tokens
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return "", nil
+	}
+
+	return "Known facts about the user from previous conversations (may be outdated, confirm when important):\n" +
+		strings.Join(lines, "\n"), nil
+}
+
+// relevant 判断记忆与问题是否有共同的词语，中文按相邻两个字匹配
+func relevant(content, query string) bool {
+	query = strings.ToLower(query)
+	for _, term := range terms(strings.ToLower(content)) {
+		if strings.Contains(query, term) {
+			return true
+		}
+	}
+	return false
+}
+
+func terms(text string) []string {
+	var result []string
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool {
+		return strings.ContainsRune(" \t\n,.;:!?，。；：！？、()（）", r)
+	}) {
+		runes := []rune(word)
+		if len(runes) == len(word) {
+			// ASCII 单词整体匹配，忽略过短的单词
+			if len(word) >= 3 {
+				result = append(result, word)
+			}
+			continue
+		}
+		for i := 0; i+1 < len(runes); i++ {
+			result = append(result, string(runes[i:i+2]))
+		}
+	}
+	return result
+}
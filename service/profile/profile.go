@@ -0,0 +1,276 @@
+package profile
+
+import (
+	"diabetes-agent-backend/dao"
+	"diabetes-agent-backend/model"
+	"diabetes-agent-backend/request"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// ErrInvalidProfile 健康档案校验失败，错误信息可以直接返回给用户
+var ErrInvalidProfile = errors.New("invalid patient profile")
+
+const (
+	minHeightCM = 50
+	maxHeightCM = 250
+	minWeightKG = 20
+	maxWeightKG = 300
+
+	// 目标血糖范围的上下限（mmol/L）
+	minTargetMmolL = 3.0
+	maxTargetMmolL = 15.0
+
+	maxListItems      = 50
+	maxItemLength     = 100
+	maxInsulinUnits   = 200
+	maxCarbRatio      = 150
+	maxCorrectionMmol = 20
+)
+
+func GetProfile(email string) (*model.PatientProfile, error) {
+	profile, err := dao.GetPatientProfile(email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get patient profile: %v", err)
+	}
+	return profile, nil
+}
+
+// SaveProfile 校验并保存健康档案，血糖相关的值换算为 mmol/L 存储
+func SaveProfile(email string, req request.SavePatientProfileRequest) (*model.PatientProfile, error) {
+	profile, err := toProfile(email, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := dao.SavePatientProfile(profile); err != nil {
+		return nil, fmt.Errorf("failed to save patient profile: %v", err)
+	}
+	return profile, nil
+}
+
+// FillTimezone 将用户在其他地方提供的时区记录到健康档案中，已设置的时区不会被覆盖
+func FillTimezone(email, timezone string) error {
+	if err := dao.FillPatientProfileTimezone(email, timezone); err != nil {
+		return fmt.Errorf("failed to fill patient timezone: %v", err)
+	}
+	return nil
+}
+
+func DeleteProfile(email string) error {
+	if err := dao.DeletePatientProfile(email); err != nil {
+		return fmt.Errorf("failed to delete patient profile: %v", err)
+	}
+	return nil
+}
+
+func toProfile(email string, req request.SavePatientProfileRequest) (*model.PatientProfile, error) {
+	profile := &model.PatientProfile{
+		UserEmail:    email,
+		DiabetesType: model.DiabetesType(req.DiabetesType),
+		HeightCM:     req.HeightCM,
+		WeightKG:     req.WeightKG,
+		GlucoseUnit:  model.GlucoseUnit(req.GlucoseUnit),
+		Timezone:     strings.TrimSpace(req.Timezone),
+	}
+
+	if profile.DiabetesType != "" && !profile.DiabetesType.IsValid() {
+		return nil, invalid("unsupported diabetes type: %s", req.DiabetesType)
+	}
+	if profile.GlucoseUnit == "" {
+		profile.GlucoseUnit = model.GlucoseUnitMmolL
+	}
+	if !profile.GlucoseUnit.IsValid() {
+		return nil, invalid("unsupported glucose unit: %s", req.GlucoseUnit)
+	}
+	// LoadLocation 将空字符串视为 UTC，为空时不校验
+	if profile.Timezone != "" {
+		if _, err := time.LoadLocation(profile.Timezone); err != nil {
+			return nil, invalid("unknown timezone: %s", req.Timezone)
+		}
+	}
+
+	if req.DiagnosisDate != "" {
+		date, err := time.ParseInLocation(time.DateOnly, req.DiagnosisDate, Location(profile))
+		if err != nil {
+			return nil, invalid("diagnosis date must be YYYY-MM-DD")
+		}
+		if date.After(time.Now()) || date.Year() < 1900 {
+			return nil, invalid("diagnosis date is out of range")
+		}
+		profile.DiagnosisDate = &date
+	}
+
+	if err := checkRange("height_cm", req.HeightCM, minHeightCM, maxHeightCM); err != nil {
+		return nil, err
+	}
+	if err := checkRange("weight_kg", req.WeightKG, minWeightKG, maxWeightKG); err != nil {
+		return nil, err
+	}
+
+	for _, item := range req.Medications {
+		medication := model.Medication{
+			Name:      strings.TrimSpace(item.Name),
+			Dose:      strings.TrimSpace(item.Dose),
+			Frequency: strings.TrimSpace(item.Frequency),
+		}
+		if medication.Name == "" {
+			return nil, invalid("medication name is required")
+		}
+		if len([]rune(medication.Name)) > maxItemLength ||
+			len([]rune(medication.Dose)) > maxItemLength ||
+			len([]rune(medication.Frequency)) > maxItemLength {
+			return nil, invalid("medication name, dose and frequency must be at most %d characters", maxItemLength)
+		}
+		profile.Medications = append(profile.Medications, medication)
+	}
+	if len(profile.Medications) > maxListItems {
+		return nil, invalid("too many medications")
+	}
+
+	var err error
+	if profile.Allergies, err = cleanList("allergies", req.Allergies); err != nil {
+		return nil, err
+	}
+	if profile.Comorbidities, err = cleanList("comorbidities", req.Comorbidities); err != nil {
+		return nil, err
+	}
+
+	if profile.InsulinRegimen, err = toInsulinRegimen(req.InsulinRegimen, profile.GlucoseUnit); err != nil {
+		return nil, err
+	}
+
+	if (req.TargetLow == nil) != (req.TargetHigh == nil) {
+		return nil, invalid("target_low and target_high must be set together")
+	}
+	if req.TargetLow != nil {
+		low := profile.GlucoseUnit.ToMmolL(*req.TargetLow)
+		high := profile.GlucoseUnit.ToMmolL(*req.TargetHigh)
+		if low < minTargetMmolL || high > maxTargetMmolL || low >= high {
+			return nil, invalid("target glucose range must be within %.1f-%.1f mmol/L and low < high",
+				minTargetMmolL, maxTargetMmolL)
+		}
+		profile.TargetLow = &low
+		profile.TargetHigh = &high
+	}
+
+	return profile, nil
+}
+
+func toInsulinRegimen(req *request.InsulinRegimenRequest, unit model.GlucoseUnit) (*model.InsulinRegimen, error) {
+	if req == nil {
+		return nil, nil
+	}
+
+	regimen := &model.InsulinRegimen{
+		Type:      model.InsulinRegimenType(req.Type),
+		CarbRatio: req.CarbRatio,
+	}
+	if !regimen.Type.IsValid() {
+		return nil, invalid("unsupported insulin regimen type: %s", req.Type)
+	}
+
+	var err error
+	if regimen.Basal, err = toInsulinDoses("basal", req.Basal); err != nil {
+		return nil, err
+	}
+	if regimen.Bolus, err = toInsulinDoses("bolus", req.Bolus); err != nil {
+		return nil, err
+	}
+
+	if err := checkRange("carb_ratio", req.CarbRatio, 1, maxCarbRatio); err != nil {
+		return nil, err
+	}
+	if req.CorrectionFactor != nil {
+		factor := unit.ToMmolL(*req.CorrectionFactor)
+		if factor <= 0 || factor > maxCorrectionMmol {
+			return nil, invalid("correction_factor is out of range")
+		}
+		regimen.CorrectionFactor = &factor
+	}
+	return regimen, nil
+}
+
+func toInsulinDoses(field string, items []request.InsulinDoseRequest) ([]model.InsulinDose, error) {
+	if len(items) > maxListItems {
+		return nil, invalid("too many %s doses", field)
+	}
+
+	doses := make([]model.InsulinDose, 0, len(items))
+	for _, item := range items {
+		dose := model.InsulinDose{
+			Name:  strings.TrimSpace(item.Name),
+			Units: item.Units,
+			Time:  strings.TrimSpace(item.Time),
+		}
+		if dose.Name == "" {
+			return nil, invalid("%s insulin name is required", field)
+		}
+		if dose.Units <= 0 || dose.Units > maxInsulinUnits {
+			return nil, invalid("%s insulin units must be between 0 and %d", field, maxInsulinUnits)
+		}
+		doses = append(doses, dose)
+	}
+	return doses, nil
+}
+
+// cleanList 去除空白和重复的条目
+func cleanList(field string, items []string) ([]string, error) {
+	if len(items) > maxListItems {
+		return nil, invalid("too many %s", field)
+	}
+
+	seen := make(map[string]bool, len(items))
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if item == "" || seen[item] {
+			continue
+		}
+		if len([]rune(item)) > maxItemLength {
+			return nil, invalid("%s item is too long", field)
+		}
+		seen[item] = true
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+func checkRange(field string, value *float64, low, high float64) error {
+	if value == nil {
+		return nil
+	}
+	if math.IsNaN(*value) || *value < low || *value > high {
+		return invalid("%s must be between %g and %g", field, low, high)
+	}
+	return nil
+}
+
+func invalid(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalidProfile, fmt.Sprintf(format, args...))
+}
+
+// Location 返回健康档案中的时区，未填写档案或时区时使用服务器时区
+func Location(patientProfile *model.PatientProfile) *time.Location {
+	if patientProfile == nil || patientProfile.Timezone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(patientProfile.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// BMI 根据身高体重计算体重指数，未填写时返回 nil
+func BMI(profile *model.PatientProfile) *float64 {
+	if profile.HeightCM == nil || profile.WeightKG == nil {
+		return nil
+	}
+	meters := *profile.HeightCM / 100
+	bmi := math.Round(*profile.WeightKG/(meters*meters)*10) / 10
+	return &bmi
+}
@@ -0,0 +1,123 @@
+package profile
+
+import (
+	"diabetes-agent-backend/model"
+	"fmt"
+	"strings"
+)
+
+var diabetesTypeNames = map[model.DiabetesType]string{
+	model.DiabetesType1:           "Type 1 diabetes",
+	model.DiabetesType2:           "Type 2 diabetes",
+	model.DiabetesTypeGestational: "Gestational diabetes",
+	model.DiabetesTypePrediabetes: "Prediabetes",
+	model.DiabetesTypeLADA:        "LADA",
+	model.DiabetesTypeMODY:        "MODY",
+	model.DiabetesTypeOther:       "Other diabetes type",
+}
+
+// PromptContext 返回注入 Agent 提示词的健康档案摘要，用户没有填写档案时返回空字符串
+func PromptContext(email string) (string, error) {
+	profile, err := GetProfile(email)
+	if err != nil || profile == nil {
+		return "", err
+	}
+	return summarize(profile), nil
+}
+
+// summarize 将健康档案压缩为一段简短的英文描述
+func summarize(profile *model.PatientProfile) string {
+	var parts []string
+
+	if name := diabetesTypeNames[profile.DiabetesType]; name != "" {
+		if profile.DiagnosisDate != nil {
+			name += ", diagnosed " + profile.DiagnosisDate.Format("2006-01")
+		}
+		parts = append(parts, name)
+	}
+
+	var body []string
+	if profile.HeightCM != nil {
+		body = append(body, fmt.Sprintf("height %g cm", *profile.HeightCM))
+	}
+	if profile.WeightKG != nil {
+		body = append(body, fmt.Sprintf("weight %g kg", *profile.WeightKG))
+	}
+	if bmi := BMI(profile); bmi != nil {
+		body = append(body, fmt.Sprintf("BMI %.1f", *bmi))
+	}
+	if len(body) > 0 {
+		parts = append(parts, strings.Join(body, ", "))
+	}
+
+	if len(profile.Medications) > 0 {
+		medications := make([]string, 0, len(profile.Medications))
+		for _, item := range profile.Medications {
+			medications = append(medications, joinNonEmpty(" ", item.Name, item.Dose, item.Frequency))
+		}
+		parts = append(parts, "Medications: "+strings.Join(medications, "; "))
+	}
+
+	if insulin := summarizeInsulin(profile.InsulinRegimen, profile.GlucoseUnit); insulin != "" {
+		parts = append(parts, "Insulin: "+insulin)
+	}
+
+	if len(profile.Allergies) > 0 {
+		parts = append(parts, "Allergies: "+strings.Join(profile.Allergies, ", "))
+	}
+	if len(profile.Comorbidities) > 0 {
+		parts = append(parts, "Comorbidities: "+strings.Join(profile.Comorbidities, ", "))
+	}
+
+	unit := profile.GlucoseUnit
+	if !unit.IsValid() {
+		unit = model.GlucoseUnitMmolL
+	}
+	if profile.TargetLow != nil && profile.TargetHigh != nil {
+		parts = append(parts, fmt.Sprintf("Target glucose %s-%s %s",
+			formatGlucose(unit, *profile.TargetLow), formatGlucose(unit, *profile.TargetHigh), unit))
+	}
+
+	if len(parts) == 0 {
+		return ""
+	}
+	parts = append(parts, fmt.Sprintf("Use %s for glucose values in answers", unit))
+	return "Patient profile: " + strings.Join(parts, ". ") + "."
+}
+
+func summarizeInsulin(regimen *model.InsulinRegimen, unit model.GlucoseUnit) string {
+	if regimen == nil || regimen.Type == "" || regimen.Type == model.InsulinNone {
+		return ""
+	}
+
+	parts := []string{strings.ReplaceAll(string(regimen.Type), "_", "-")}
+	for _, dose := range append(regimen.Basal, regimen.Bolus...) {
+		parts = append(parts, joinNonEmpty(" ", dose.Name, fmt.Sprintf("%gU", dose.Units), dose.Time))
+	}
+	if regimen.CarbRatio != nil {
+		parts = append(parts, fmt.Sprintf("carb ratio 1U:%gg", *regimen.CarbRatio))
+	}
+	if regimen.CorrectionFactor != nil && unit.IsValid() {
+		parts = append(parts, fmt.Sprintf("correction factor 1U:%s %s",
+			formatGlucose(unit, *regimen.CorrectionFactor), unit))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatGlucose 将 mmol/L 的血糖值换算为 unit 单位并格式化，mg/dL 取整
+func formatGlucose(unit model.GlucoseUnit, value float64) string {
+	if unit == model.GlucoseUnitMgDL {
+		return fmt.Sprintf("%.0f", unit.FromMmolL(value))
+	}
+	return fmt.Sprintf("%.1f", value)
+}
+
+func joinNonEmpty(sep string, items ...string) string {
+	var result []string
+	for _, item := range items {
+		if item != "" {
+			result = append(result, item)
+		}
+	}
+	return strings.Join(result, sep)
+}
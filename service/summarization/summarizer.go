@@ -7,51 +7,94 @@ import (
 	"diabetes-agent-backend/dao"
 	"diabetes-agent-backend/model"
 	"diabetes-agent-backend/service/chat"
+	"diabetes-agent-backend/service/mq"
 	"diabetes-agent-backend/utils"
 	_ "embed"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"html/template"
 	"log/slog"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
 
 	"github.com/tmc/langchaingo/llms"
 	"github.com/tmc/langchaingo/llms/openai"
 	"gorm.io/gorm"
 )
 
+// 对话摘要 MQ 消息的 topic 和 tag
 const (
-	modelName       = "deepseek-v3"
-	taskChanSize    = 100
-	workerNum       = 10
-	updateBatchSize = 1
+	TopicSummarization = "topic_summarization"
+	TagSummarize       = "tag_summarize"
+)
+
+const (
+	modelName = "deepseek-v3"
+
+	// 最近对话之前至少积累多少条聊天记录才合并到摘要中，避免每轮对话都调用模型
+	minMessagesToFold = 4
+
+	// 单次合并的聊天记录数量和 token 上限，积压较多时分多次合并
+	maxMessagesToFold = 40
+	maxTokensToFold   = 12000
 
-	// 触发生成对话摘要的最小对话长度（字节数）
-	minContentLengthForSummary = 2500
+	// 合并时单条聊天记录保留的 token 数
+	maxTokensPerMessage = 2000
+
+	// 补生成摘要时扫描的时间范围，跳过最近有新消息、可能仍在生成摘要的会话
+	sweepLookback = 7 * 24 * time.Hour
+	sweepGrace    = 10 * time.Minute
+	sweepLimit    = 500
 )
 
 //go:embed prompts/summarization.txt
 var summaryPrompt string
 
+var summaryTemplate = template.Must(template.New("prompt").Parse(summaryPrompt))
+
+// ErrSummarizerClosed 服务关闭后不再处理新的摘要任务，消息由 MQ 重新投递
+var ErrSummarizerClosed = errors.New("summarizer is shutting down")
+
+// SummaryTask 一轮对话结束后更新会话摘要的任务
 type SummaryTask struct {
-	MessageIDs []uint
+	// 对话所属的用户，用于排查未投递的任务
+	UserEmail string
+	SessionID string
+
+	// 本轮对话的最后一条聊天记录
+	LastMessageID uint
 }
 
-// Summarizer 负责生成对话摘要
+// SummaryMessage 更新会话摘要的 MQ 消息
+type SummaryMessage struct {
+	SessionID     string `json:"session_id"`
+	LastMessageID uint   `json:"last_message_id"`
+}
+
+// Summarizer 负责维护会话的滚动摘要
+// 最近 chat.RecentTurns 轮对话之前的聊天记录逐步合并到会话摘要中，Agent 加载记忆时使用摘要代替这些记录
+// 摘要任务随 outbox 持久化并经 MQ 投递，服务重启后未完成的任务会被重新投递
 type Summarizer struct {
-	llm             llms.Model
-	taskChan        chan SummaryTask
-	workerNum       int
-	updateBatchSize int
+	llm llms.Model
+
+	mu       sync.Mutex
+	closed   bool
+	inflight sync.WaitGroup
 }
 
 // SummarizerInstance Summarizer单例实例
 var SummarizerInstance *Summarizer
 
-func init() {
+// Init 创建 Summarizer 单例，需要在 config.Init 之后调用
+func Init() error {
 	var err error
 	SummarizerInstance, err = newSummarizer()
 	if err != nil {
-		panic(fmt.Sprintf("Failed to create summarizer: %v", err))
+		return fmt.Errorf("failed to create summarizer: %v", err)
 	}
+	return nil
 }
 
 func newSummarizer() (*Summarizer, error) {
@@ -66,131 +109,197 @@ func newSummarizer() (*Summarizer, error) {
 		return nil, err
 	}
 
-	return &Summarizer{
-		llm:             llm,
-		taskChan:        make(chan SummaryTask, taskChanSize),
-		workerNum:       workerNum,
-		updateBatchSize: updateBatchSize,
-	}, nil
+	return &Summarizer{llm: llm}, nil
 }
 
-func (s *Summarizer) Run() {
-	ctx := context.Background()
-	for i := 1; i <= s.workerNum; i++ {
-		go s.executeSummarization(ctx, i)
-	}
+// RegisterHandlers 注册摘要任务的消息处理器
+func (s *Summarizer) RegisterHandlers() {
+	mq.Register(TopicSummarization, TagSummarize, mq.Handler{
+		Handle: s.HandleSummaryMessage,
+	})
 }
 
-func (s *Summarizer) RegisterSummaryTask(task SummaryTask) {
-	s.taskChan <- task
+// Shutdown 停止接收新的摘要任务，等待正在生成的摘要完成
+func (s *Summarizer) Shutdown() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+
+	s.inflight.Wait()
+	slog.Info("Summarizer drained")
+}
+
+// RegisterSummaryTask 将摘要任务写入 outbox，不等待摘要生成
+func (s *Summarizer) RegisterSummaryTask(task SummaryTask) error {
+	return dao.Transaction(func(tx *gorm.DB) error {
+		return enqueueSummaryMessage(tx, task.UserEmail, task.SessionID, task.LastMessageID)
+	})
 }
 
-func (s *Summarizer) executeSummarization(ctx context.Context, id int) {
-	slog.Info("Starting summary worker", "worker_id", id)
-
-	// 暂存等待批量更新的message
-	updates := make([]*model.Message, 0, s.updateBatchSize)
-
-	defer func() {
-		slog.Info("Summary worker exit", "worker_id", id)
-		s.flushBatchUpdates(updates)
-	}()
-
-	for task := range s.taskChan {
-		select {
-		case <-ctx.Done():
-			slog.Info("Summary worker shutting down", "worker_id", id)
-			return
-		default:
-			for _, msgID := range task.MessageIDs {
-				msg, err := dao.GetMessageByID(msgID)
-				if err != nil {
-					slog.Error("Failed to get message",
-						"msg_id", msgID,
-						"err", err,
-					)
-					continue
-				}
-
-				if len(msg.Content) < minContentLengthForSummary || msg.Summary != "" {
-					continue
-				}
-
-				res, err := s.summarizeMessage(ctx, msg.Role, msg.Content)
-				if err != nil {
-					slog.Error("Failed to summary message",
-						"msg_id", msgID,
-						"err", err,
-					)
-					continue
-				}
-
-				msg.Summary = res
-				updates = append(updates, msg)
-
-				if len(updates) >= s.updateBatchSize {
-					updates, err = s.flushBatchUpdates(updates)
-					if err != nil {
-						slog.Error("Failed to flush batch updates", "err", err)
-					}
-				}
-
-				if len(updates) > 0 {
-					updates, err = s.flushBatchUpdates(updates)
-					if err != nil {
-						slog.Error("Failed to flush batch updates", "err", err)
-					}
-				}
+// Sweep 为摘要任务丢失或失败、积压了较多聊天记录的会话补写摘要任务，返回写入的会话数量
+func (s *Summarizer) Sweep(ctx context.Context) (int, error) {
+	now := time.Now()
+	backlogs, err := dao.GetSessionBacklogs(2*chat.RecentTurns()+minMessagesToFold-1,
+		now.Add(-sweepLookback), now.Add(-sweepGrace), sweepLimit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get session backlogs: %v", err)
+	}
+	if len(backlogs) == 0 {
+		return 0, nil
+	}
+
+	err = dao.Transaction(func(tx *gorm.DB) error {
+		for _, backlog := range backlogs {
+			if err := enqueueSummaryMessage(tx, "", backlog.SessionID, backlog.LastMessageID); err != nil {
+				return err
 			}
 		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
 	}
+	return len(backlogs), nil
 }
 
-func (s *Summarizer) summarizeMessage(ctx context.Context, role, content string) (string, error) {
-	tmpl, err := template.New("prompt").Parse(summaryPrompt)
+// enqueueSummaryMessage 写入会话的摘要任务，幂等键为会话和最后一条聊天记录
+func enqueueSummaryMessage(tx *gorm.DB, email, sessionID string, lastMessageID uint) error {
+	data, err := json.Marshal(SummaryMessage{
+		SessionID:     sessionID,
+		LastMessageID: lastMessageID,
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to parse prompt template: %v", err)
+		return fmt.Errorf("failed to marshal payload: %v", err)
 	}
 
-	var buf bytes.Buffer
-	data := struct {
-		Role    string
-		Content string
-	}{
-		Role:    role,
-		Content: content,
+	err = dao.CreateOutboxMessage(tx, &model.OutboxMessage{
+		UserEmail:      email,
+		Topic:          TopicSummarization,
+		Tag:            TagSummarize,
+		IdempotencyKey: fmt.Sprintf("%s:%s:%d", TagSummarize, sessionID, lastMessageID),
+		Payload:        string(data),
+		Status:         model.OutboxStatusPending,
+		NextAttemptAt:  time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create outbox message: %v", err)
 	}
+	return nil
+}
 
-	if err := tmpl.Execute(&buf, data); err != nil {
-		return "", fmt.Errorf("failed to execute template: %v", err)
+// HandleSummaryMessage 更新会话摘要，生成失败时返回错误由 MQ 重试
+func (s *Summarizer) HandleSummaryMessage(ctx context.Context, msg *mq.Delivery) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrSummarizerClosed
 	}
+	s.inflight.Add(1)
+	s.mu.Unlock()
+	defer s.inflight.Done()
 
-	resp, err := llms.GenerateFromSinglePrompt(ctx, s.llm, buf.String())
+	var summaryMessage SummaryMessage
+	if err := json.Unmarshal(msg.Body, &summaryMessage); err != nil {
+		return fmt.Errorf("failed to unmarshal message body: %v", err)
+	}
+
+	folded, err := s.summarizeSession(ctx, summaryMessage.SessionID)
 	if err != nil {
-		return "", fmt.Errorf("llm call error: %w", err)
+		return fmt.Errorf("failed to summarize session %s: %v", summaryMessage.SessionID, err)
 	}
 
-	return resp, nil
+	if folded > 0 {
+		slog.Info("Session summary updated",
+			"msg_id", msg.MsgID,
+			"session_id", summaryMessage.SessionID,
+			"folded_messages", folded)
+	}
+	return nil
 }
 
-func (s *Summarizer) flushBatchUpdates(updates []*model.Message) ([]*model.Message, error) {
-	if len(updates) == 0 {
-		return updates, nil
+// summarizeSession 将最近对话之前尚未合并的聊天记录合并到会话摘要中，返回合并的聊天记录数量
+func (s *Summarizer) summarizeSession(ctx context.Context, sessionID string) (int, error) {
+	recentMessages := 2 * chat.RecentTurns()
+
+	var folded int
+	for {
+		session, err := dao.GetSession(sessionID)
+		if err != nil {
+			return folded, fmt.Errorf("failed to get session: %v", err)
+		}
+		if session == nil {
+			// 会话已被删除
+			return folded, nil
+		}
+
+		messages, err := dao.GetMessagesAfter(sessionID, session.SummaryMessageID, maxMessagesToFold+recentMessages)
+		if err != nil {
+			return folded, fmt.Errorf("failed to get messages: %v", err)
+		}
+		if len(messages)-recentMessages < minMessagesToFold {
+			return folded, nil
+		}
+
+		batch := foldBatch(messages[:len(messages)-recentMessages])
+		summary, err := s.mergeSummary(ctx, session.Summary, batch)
+		if err != nil {
+			return folded, err
+		}
+
+		lastMessageID := batch[len(batch)-1].ID
+		updated, err := dao.UpdateSessionSummary(sessionID, session.SummaryMessageID, lastMessageID, summary)
+		if err != nil {
+			return folded, fmt.Errorf("failed to update session summary: %v", err)
+		}
+		if !updated {
+			// 其他消费者已经更新了摘要
+			return folded, nil
+		}
+		folded += len(batch)
 	}
+}
 
-	err := dao.DB.Transaction(func(tx *gorm.DB) error {
-		for _, msg := range updates {
-			if err := tx.Model(&model.Message{}).
-				Where("id = ?", msg.ID).
-				Update("summary", msg.Summary).Error; err != nil {
-				return fmt.Errorf("failed to update message %d: %v", msg.ID, err)
-			}
+// foldBatch 从最早的聊天记录开始选取一批，token 数不超过 maxTokensToFold，至少包含一条
+func foldBatch(messages []model.Message) []model.Message {
+	var tokens int
+	for i := range messages {
+		messages[i].Content = utils.TruncateTokens(messages[i].Content, maxTokensPerMessage)
+		tokens += utils.CountTokens(messages[i].Content)
+		if tokens > maxTokensToFold && i > 0 {
+			return messages[:i]
 		}
-		return nil
-	})
+	}
+	return messages
+}
+
+// mergeSummary 将一批聊天记录合并到已有摘要中
+func (s *Summarizer) mergeSummary(ctx context.Context, summary string, messages []model.Message) (string, error) {
+	var conversation strings.Builder
+	for _, message := range messages {
+		fmt.Fprintf(&conversation, "%s: %s\n", message.Role, message.Content)
+	}
+
+	if summary == "" {
+		summary = "无"
+	}
+
+	var buf bytes.Buffer
+	data := struct {
+		Summary      string
+		Conversation string
+	}{
+		Summary:      summary,
+		Conversation: conversation.String(),
+	}
+
+	if err := summaryTemplate.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to execute template: %v", err)
+	}
+
+	resp, err := llms.GenerateFromSinglePrompt(ctx, s.llm, buf.String())
 	if err != nil {
-		return updates, fmt.Errorf("failed to update messages batch: %v", err)
+		return "", fmt.Errorf("llm call error: %w", err)
 	}
 
-	return updates[:0], nil
+	return strings.TrimSpace(resp), nil
 }
@@ -36,7 +36,8 @@ type WSConnectionPool struct {
 
 var wsConnectionPool *WSConnectionPool
 
-func init() {
+// Init 创建语音识别服务的连接池，需要在 config.Init 之后调用
+func Init() {
 	header := make(http.Header)
 	header.Add("Authorization", fmt.Sprintf("bearer %s", config.Cfg.Model.APIKey))
 	header.Add("X-DashScope-DataInspection", "enable")
@@ -6,6 +6,7 @@ const (
 	EventImmediateSteps = "immediate_steps"
 	EventFinalAnswer    = "final_answer"
 	EventToolCallResult = "tool_call_results"
+	EventStatus         = "status"
 	EventError          = "error"
 	EventDone           = "done"
 )
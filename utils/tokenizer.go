@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"log/slog"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/pkoukk/tiktoken-go"
+)
+
+// 计算 token 数使用的编码，与各模型的分词器不完全一致，用于估算上下文占用
+// 首次使用时 tiktoken 会下载编码文件，可通过 TIKTOKEN_CACHE_DIR 指定本地缓存目录
+const tokenEncoding = "cl100k_base"
+
+var (
+	encoding     *tiktoken.Tiktoken
+	encodingOnce sync.Once
+)
+
+func getEncoding() *tiktoken.Tiktoken {
+	encodingOnce.Do(func() {
+		var err error
+		encoding, err = tiktoken.GetEncoding(tokenEncoding)
+		if err != nil {
+			slog.Error("Failed to load token encoding, fall back to rune count", "err", err)
+		}
+	})
+	return encoding
+}
+
+// CountTokens 计算文本的 token 数，编码文件加载失败时按字符数估算
+func CountTokens(text string) int {
+	if enc := getEncoding(); enc != nil {
+		return len(enc.EncodeOrdinary(text))
+	}
+	return utf8.RuneCountInString(text)
+}
+
+// TruncateTokens 保留文本开头不超过 maxTokens 个 token 的部分
+func TruncateTokens(text string, maxTokens int) string {
+	if maxTokens <= 0 {
+		return ""
+	}
+
+	enc := getEncoding()
+	if enc == nil {
+		if utf8.RuneCountInString(text) <= maxTokens {
+			return text
+		}
+		return string([]rune(text)[:maxTokens])
+	}
+
+	tokens := enc.EncodeOrdinary(text)
+	if len(tokens) <= maxTokens {
+		return text
+	}
+	// 截断位置可能落在多字节字符中间
+	return strings.ToValidUTF8(enc.Decode(tokens[:maxTokens]), "")
+}